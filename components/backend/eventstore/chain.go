@@ -0,0 +1,142 @@
+package eventstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"ambient-code-backend/types"
+)
+
+// Every store chains the events it appends: an event's "hash" field is
+// EventHash of the previous event's hash and the event, so a log edited
+// after the fact no longer verifies.  The first event of a log chains to
+// the empty hash.
+
+// EventHash returns the hex SHA-256 of prevHash, a newline and the event's
+// canonical JSON (without its "hash" field).  The event is encoded as it
+// reads back from a store, with object keys sorted and numbers as float64,
+// so a stored event hashes the same as when it was written.
+func EventHash(prevHash string, event map[string]interface{}) (string, error) {
+	body := make(map[string]interface{}, len(event))
+	for k, v := range event {
+		if k != "hash" {
+			body[k] = v
+		}
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+	var canonical interface{}
+	if err := json.Unmarshal(data, &canonical); err != nil {
+		return "", err
+	}
+	if data, err = json.Marshal(canonical); err != nil {
+		return "", err
+	}
+	sum := sha256.New()
+	sum.Write([]byte(prevHash))
+	sum.Write([]byte{'\n'})
+	sum.Write(data)
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
+
+// Hash returns the chain hash stored in an event, or "" if it has none
+func Hash(event map[string]interface{}) string {
+	hash, _ := event["hash"].(string)
+	return hash
+}
+
+// seal numbers event and chains it to prevHash, then encodes it
+func seal(event map[string]interface{}, seq int64, prevHash string) ([]byte, error) {
+	event["seq"] = seq
+	delete(event, "hash")
+	hash, err := EventHash(prevHash, event)
+	if err != nil {
+		unseal(event)
+		return nil, fmt.Errorf("marshal event: %w", err)
+	}
+	event["hash"] = hash
+	data, err := json.Marshal(event)
+	if err != nil {
+		unseal(event)
+		return nil, fmt.Errorf("marshal event: %w", err)
+	}
+	return data, nil
+}
+
+// unseal undoes seal after a failed append
+func unseal(event map[string]interface{}) {
+	delete(event, "seq")
+	delete(event, "hash")
+}
+
+// storedHash returns the chain hash of an encoded event
+func storedHash(data []byte) string {
+	var event struct {
+		Hash string `json:"hash"`
+	}
+	_ = json.Unmarshal(data, &event)
+	return event.Hash
+}
+
+// ChainHead returns the stored hash and seq of the last chained event in
+// events, or "" and 0 if none is chained
+func ChainHead(events []map[string]interface{}) (string, int64) {
+	for i := len(events) - 1; i >= 0; i-- {
+		if hash := Hash(events[i]); hash != "" {
+			return hash, Seq(events[i])
+		}
+	}
+	return "", 0
+}
+
+// VerifyChain checks the hash chain of a log as returned by Load.  genesis
+// is the seq of the log's first chained event when it was recorded (a
+// session created after chaining existed chains from 1): every event from
+// it on must verify, so hashes stripped from any of them break the chain.
+// With genesis 0 the chain start is unknown, and events before the first
+// chained one are taken to predate chaining and counted as unchained.
+func VerifyChain(events []map[string]interface{}, genesis int64) types.EventChain {
+	return VerifyChainExcept(events, genesis, nil)
+}
+
+// VerifyChainExcept is VerifyChain for a log in which the events with the
+// given seqs were knowingly changed after they were stored (as exports
+// re-redact events).  Their hashes must still be the ones the next events
+// chain to, but may not match their content; those that do not are listed
+// as Unverified rather than breaking the chain.
+func VerifyChainExcept(events []map[string]interface{}, genesis int64, changed map[int64]bool) types.EventChain {
+	result := types.EventChain{Valid: true, Events: len(events), Genesis: genesis}
+	prev := ""
+	chained := false
+	for _, event := range events {
+		hash := Hash(event)
+		if hash == "" && !chained && (genesis <= 0 || Seq(event) < genesis) {
+			result.Unchained++
+			continue
+		}
+		chained = true
+		want, err := EventHash(prev, event)
+		switch {
+		case hash == "":
+			result.Error = "event has no hash"
+		case err != nil:
+			result.Error = err.Error()
+		case want != hash && !changed[Seq(event)]:
+			result.Error = "hash does not match the event and the one before it"
+		case want != hash:
+			result.Unverified = append(result.Unverified, Seq(event))
+		}
+		if result.Error != "" {
+			result.Valid = false
+			result.BrokenAt = Seq(event)
+			return result
+		}
+		prev = hash
+		result.HeadSeq, result.HeadHash = Seq(event), hash
+	}
+	return result
+}
@@ -0,0 +1,88 @@
+package eventstore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStoresChainEvents(t *testing.T) {
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			for _, event := range []map[string]interface{}{
+				{"type": "RUN_STARTED", "timestamp": float64(1700000000123)},
+				{"type": "TEXT_MESSAGE_CONTENT", "delta": "<b>é & 1e21</b>", "nested": map[string]interface{}{"z": 1, "a": []interface{}{true, nil}}},
+				{"type": "RUN_FINISHED", "hash": "supplied by the caller"},
+			} {
+				if _, err := store.Append(ctx, "chained", event); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			events, err := store.Load(ctx, "chained", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			chain := VerifyChain(events, 1)
+			if !chain.Valid || chain.Events != 3 || chain.HeadSeq != 3 || chain.HeadHash != Hash(events[2]) {
+				t.Fatalf("VerifyChain() = %+v", chain)
+			}
+			if head, seq := ChainHead(events); head != chain.HeadHash || seq != 3 {
+				t.Errorf("ChainHead() = %s, %d", head, seq)
+			}
+
+			// Editing, removing or reordering events breaks the chain
+			events[1]["delta"] = "edited"
+			if chain := VerifyChain(events, 1); chain.Valid || chain.BrokenAt != 2 {
+				t.Errorf("after an edit: VerifyChain() = %+v", chain)
+			}
+			if chain := VerifyChainExcept(events, 1, map[int64]bool{2: true}); !chain.Valid {
+				t.Errorf("with event 2 declared changed: VerifyChainExcept() = %+v", chain)
+			}
+			events, _ = store.Load(ctx, "chained", 0)
+			if chain := VerifyChain(append(events[:1:1], events[2]), 1); chain.Valid || chain.BrokenAt != 3 {
+				t.Errorf("after a removal: VerifyChain() = %+v", chain)
+			}
+
+			// Stripping every hash passes for a log written before chaining
+			// only while the chain start is unknown
+			events, _ = store.Load(ctx, "chained", 0)
+			for _, event := range events {
+				delete(event, "hash")
+			}
+			if chain := VerifyChain(events, 0); !chain.Valid || chain.Unchained != 3 {
+				t.Errorf("without hashes or genesis: VerifyChain() = %+v", chain)
+			}
+			if chain := VerifyChain(events, 1); chain.Valid || chain.BrokenAt != 1 || chain.Genesis != 1 {
+				t.Errorf("without hashes: VerifyChain() = %+v", chain)
+			}
+		})
+	}
+}
+
+func TestJSONLStoreChainsAfterLegacyLinesAndRestart(t *testing.T) {
+	dir := t.TempDir()
+	sessionDir := filepath.Join(dir, "sessions", "legacy")
+	if err := os.MkdirAll(sessionDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sessionDir, jsonlFileName), []byte("{\"type\":\"RUN_STARTED\"}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := NewJSONL(dir).Append(ctx, "legacy", map[string]interface{}{"type": "RAW"}); err != nil {
+		t.Fatal(err)
+	}
+	// A restarted backend chains to the last stored hash
+	store := NewJSONL(dir)
+	if _, err := store.Append(ctx, "legacy", map[string]interface{}{"type": "RUN_FINISHED"}); err != nil {
+		t.Fatal(err)
+	}
+
+	events, _ := store.Load(ctx, "legacy", 0)
+	if chain := VerifyChain(events, 0); !chain.Valid || chain.Unchained != 1 || chain.HeadSeq != 3 {
+		t.Errorf("VerifyChain() = %+v", chain)
+	}
+}
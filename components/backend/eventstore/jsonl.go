@@ -0,0 +1,284 @@
+package eventstore
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	jsonlFileName = "agui-events.jsonl"
+
+	// Per-session write state is evicted after jsonlEvictAge of inactivity to
+	// prevent unbounded growth on long-running backends.
+	jsonlEvictAge      = 30 * time.Minute
+	jsonlSweepInterval = 10 * time.Minute
+)
+
+// JSONLStore keeps each session's log in <dir>/sessions/<id>/agui-events.jsonl,
+// one event per line. An event's sequence number is its line number, so logs
+// written before sequence numbers existed are numbered the same way.
+type JSONLStore struct {
+	dir       string
+	sessions  sync.Map // sessionID → *jsonlSession
+	lastSweep atomic.Int64
+}
+
+// jsonlSession serialises appends to one log, preventing interleaved writes
+// from concurrent goroutines (e.g. run handler + feedback handler), and holds
+// the last sequence number and chain hash, loaded from the file on first use.
+type jsonlSession struct {
+	mu        sync.Mutex
+	lastUsed  atomic.Int64 // unix seconds
+	seq       int64        // guarded by mu
+	hash      string       // guarded by mu
+	seqLoaded bool         // guarded by mu
+}
+
+// NewJSONL returns a store rooted at dir
+func NewJSONL(dir string) *JSONLStore {
+	s := &JSONLStore{dir: dir}
+	s.lastSweep.Store(time.Now().Unix())
+	return s
+}
+
+func (s *JSONLStore) path(sessionID string) string {
+	return filepath.Join(s.dir, "sessions", sessionID, jsonlFileName)
+}
+
+// Append implements Store
+func (s *JSONLStore) Append(_ context.Context, sessionID string, event map[string]interface{}) (int64, error) {
+	if err := checkSessionID(sessionID); err != nil {
+		return 0, err
+	}
+	path := s.path(sessionID)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, err
+	}
+
+	session := s.session(sessionID)
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	if !session.seqLoaded {
+		data, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return 0, err
+		}
+		lines := splitLines(data)
+		session.seq = int64(len(lines))
+		// Chain to the last event Load returns, skipping a torn line
+		for i := len(lines) - 1; i >= 0; i-- {
+			if json.Valid(lines[i]) {
+				session.hash = storedHash(lines[i])
+				break
+			}
+		}
+		session.seqLoaded = true
+	}
+	seq := session.seq + 1
+	data, err := seal(event, seq, session.hash)
+	if err != nil {
+		return 0, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		unseal(event)
+		return 0, err
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		unseal(event)
+		return 0, err
+	}
+	session.seq, session.hash = seq, Hash(event)
+	return seq, nil
+}
+
+// Load implements Store
+func (s *JSONLStore) Load(ctx context.Context, sessionID string, after int64) ([]map[string]interface{}, error) {
+	var events []map[string]interface{}
+	err := s.Scan(ctx, sessionID, after, func(event map[string]interface{}) error {
+		events = append(events, event)
+		return nil
+	})
+	return events, err
+}
+
+// Scan implements Scanner, reading the file a line at a time
+func (s *JSONLStore) Scan(_ context.Context, sessionID string, after int64, fn func(event map[string]interface{}) error) error {
+	if err := checkSessionID(sessionID); err != nil {
+		return err
+	}
+	f, err := os.Open(s.path(sessionID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	var seq int64
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if line = bytes.TrimSuffix(line, []byte{'\n'}); len(line) > 0 {
+			seq++
+			var event map[string]interface{}
+			// A torn or corrupt line keeps its number but is skipped
+			if seq > after && json.Unmarshal(line, &event) == nil {
+				// Events written before sequence numbers existed get their line number
+				if Seq(event) == 0 {
+					event["seq"] = seq
+				}
+				if err := fn(event); err != nil {
+					return err
+				}
+			}
+		}
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+// LoadFromOffset implements OffsetLoader.  A trailing line without its
+// newline is still being written and is left for the next call.
+func (s *JSONLStore) LoadFromOffset(_ context.Context, sessionID string, offset, seq int64) ([]map[string]interface{}, int64, error) {
+	if err := checkSessionID(sessionID); err != nil {
+		return nil, 0, err
+	}
+	f, err := os.Open(s.path(sessionID))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, 0, err
+		}
+		if offset > 0 {
+			return nil, 0, ErrStaleOffset
+		}
+		return nil, 0, nil
+	}
+	defer f.Close()
+
+	start := offset
+	if offset > 0 {
+		start = offset - 1 // include the newline that must precede offset
+	}
+	if _, err := f.Seek(start, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, 0, err
+	}
+	if offset > 0 {
+		if len(data) == 0 || data[0] != '\n' {
+			return nil, 0, ErrStaleOffset
+		}
+		data = data[1:]
+	}
+	complete := bytes.LastIndexByte(data, '\n') + 1
+	end := offset + int64(complete)
+
+	var events []map[string]interface{}
+	for _, line := range splitLines(data[:complete]) {
+		seq++
+		var event map[string]interface{}
+		if err := json.Unmarshal(line, &event); err != nil {
+			continue // numbered like Load, which skips it too
+		}
+		if Seq(event) == 0 {
+			event["seq"] = seq
+		}
+		events = append(events, event)
+	}
+	return events, end, nil
+}
+
+// Sessions implements Store
+func (s *JSONLStore) Sessions(_ context.Context) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, "sessions"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var ids []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(s.path(entry.Name())); err == nil {
+			ids = append(ids, entry.Name())
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Delete implements Store.  The session's directory is removed too if
+// nothing else is left in it.
+func (s *JSONLStore) Delete(_ context.Context, sessionID string) error {
+	if err := checkSessionID(sessionID); err != nil {
+		return err
+	}
+	session := s.session(sessionID)
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	path := s.path(sessionID)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	_ = os.Remove(filepath.Dir(path)) // fails unless empty
+	session.seq, session.hash, session.seqLoaded = 0, "", false
+	return nil
+}
+
+// Close implements Store
+func (s *JSONLStore) Close() error { return nil }
+
+func (s *JSONLStore) session(sessionID string) *jsonlSession {
+	now := time.Now().Unix()
+	if last := s.lastSweep.Load(); now-last > int64(jsonlSweepInterval.Seconds()) && s.lastSweep.CompareAndSwap(last, now) {
+		s.evictIdle(now - int64(jsonlEvictAge.Seconds()))
+	}
+	val, _ := s.sessions.LoadOrStore(sessionID, &jsonlSession{})
+	session := val.(*jsonlSession)
+	session.lastUsed.Store(now)
+	return session
+}
+
+// evictIdle drops the write state of sessions not used since threshold (unix seconds)
+func (s *JSONLStore) evictIdle(threshold int64) {
+	s.sessions.Range(func(key, value interface{}) bool {
+		if value.(*jsonlSession).lastUsed.Load() < threshold {
+			s.sessions.Delete(key)
+		}
+		return true
+	})
+}
+
+// splitLines splits data into its non-empty lines
+func splitLines(data []byte) [][]byte {
+	var lines [][]byte
+	for _, line := range bytes.Split(data, []byte{'\n'}) {
+		if len(line) > 0 {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
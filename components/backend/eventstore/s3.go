@@ -0,0 +1,255 @@
+package eventstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"ambient-code-shared/objstore"
+)
+
+const (
+	// s3AppendAttempts bounds retries when another replica took the next seq first
+	s3AppendAttempts = 5
+	// s3LoadConcurrency is how many events Load fetches in parallel
+	s3LoadConcurrency = 16
+	// s3ScanBatch is how many events Scan holds at a time
+	s3ScanBatch = 256
+)
+
+// S3Store keeps each event as its own object,
+// <prefix>sessions/<id>/events/<seq, 20 digits>.json, so appends never rewrite
+// existing objects and listing returns a log in order. Appends use conditional
+// writes, so replicas sharing a bucket cannot assign the same seq twice.
+type S3Store struct {
+	client   *objstore.Client
+	prefix   string
+	sessions sync.Map // sessionID → *s3Session
+}
+
+type s3Session struct {
+	mu     sync.Mutex
+	seq    int64  // guarded by mu
+	hash   string // guarded by mu
+	loaded bool   // guarded by mu
+}
+
+// NewS3 returns a store writing under prefix (e.g. "ambient/") in client's bucket
+func NewS3(client *objstore.Client, prefix string) *S3Store {
+	return &S3Store{client: client, prefix: prefix}
+}
+
+func (s *S3Store) sessionPrefix(sessionID string) string {
+	return s.prefix + "sessions/" + sessionID + "/events/"
+}
+
+func (s *S3Store) key(sessionID string, seq int64) string {
+	return fmt.Sprintf("%s%020d.json", s.sessionPrefix(sessionID), seq)
+}
+
+// Append implements Store
+func (s *S3Store) Append(ctx context.Context, sessionID string, event map[string]interface{}) (int64, error) {
+	if err := checkSessionID(sessionID); err != nil {
+		return 0, err
+	}
+	val, _ := s.sessions.LoadOrStore(sessionID, &s3Session{})
+	session := val.(*s3Session)
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	if !session.loaded {
+		last, hash, err := s.tail(ctx, sessionID, 0)
+		if err != nil {
+			return 0, err
+		}
+		session.seq, session.hash, session.loaded = last, hash, true
+	}
+
+	for attempt := 0; attempt < s3AppendAttempts; attempt++ {
+		seq := session.seq + 1
+		data, err := seal(event, seq, session.hash)
+		if err != nil {
+			return 0, err
+		}
+		err = s.client.Create(ctx, s.key(sessionID, seq), data)
+		if err == nil {
+			session.seq, session.hash = seq, Hash(event)
+			return seq, nil
+		}
+		if !errors.Is(err, objstore.ErrExists) {
+			unseal(event)
+			return 0, err
+		}
+		// Another writer appended first; continue after its events
+		last, hash, err := s.tail(ctx, sessionID, session.seq)
+		if err != nil {
+			unseal(event)
+			return 0, err
+		}
+		session.seq, session.hash = last, hash
+	}
+	unseal(event)
+	return 0, fmt.Errorf("append to %s: too many concurrent writers", sessionID)
+}
+
+// tail returns the highest seq stored for the session, listing from after,
+// and the chain hash of that event
+func (s *S3Store) tail(ctx context.Context, sessionID string, after int64) (int64, string, error) {
+	last, err := s.lastSeq(ctx, sessionID, after)
+	if err != nil || last == 0 {
+		return last, "", err
+	}
+	data, err := s.client.Get(ctx, s.key(sessionID, last))
+	if err != nil {
+		return 0, "", err
+	}
+	return last, storedHash(data), nil
+}
+
+// lastSeq returns the highest seq stored for the session, listing from after
+func (s *S3Store) lastSeq(ctx context.Context, sessionID string, after int64) (int64, error) {
+	last := after
+	startAfter := ""
+	if after > 0 {
+		startAfter = s.key(sessionID, after)
+	}
+	err := s.client.List(ctx, s.sessionPrefix(sessionID), startAfter, func(key string) bool {
+		if seq := s.seqOf(sessionID, key); seq > last {
+			last = seq
+		}
+		return true
+	})
+	return last, err
+}
+
+func (s *S3Store) seqOf(sessionID, key string) int64 {
+	name := strings.TrimSuffix(strings.TrimPrefix(key, s.sessionPrefix(sessionID)), ".json")
+	seq, _ := strconv.ParseInt(name, 10, 64)
+	return seq
+}
+
+// Load implements Store
+func (s *S3Store) Load(ctx context.Context, sessionID string, after int64) ([]map[string]interface{}, error) {
+	keys, err := s.keys(ctx, sessionID, after)
+	if err != nil || len(keys) == 0 {
+		return nil, err
+	}
+	return s.fetch(ctx, keys)
+}
+
+// Scan implements Scanner.  Keys are listed up front; events are fetched
+// s3ScanBatch at a time.
+func (s *S3Store) Scan(ctx context.Context, sessionID string, after int64, fn func(event map[string]interface{}) error) error {
+	keys, err := s.keys(ctx, sessionID, after)
+	if err != nil {
+		return err
+	}
+	for len(keys) > 0 {
+		batch := keys[:min(len(keys), s3ScanBatch)]
+		keys = keys[len(batch):]
+		events, err := s.fetch(ctx, batch)
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// keys lists the keys of the session's events after after, in order
+func (s *S3Store) keys(ctx context.Context, sessionID string, after int64) ([]string, error) {
+	if err := checkSessionID(sessionID); err != nil {
+		return nil, err
+	}
+	startAfter := ""
+	if after > 0 {
+		startAfter = s.key(sessionID, after)
+	}
+	var keys []string
+	if err := s.client.List(ctx, s.sessionPrefix(sessionID), startAfter, func(key string) bool {
+		keys = append(keys, key)
+		return true
+	}); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// fetch reads the events stored at keys, s3LoadConcurrency at a time
+func (s *S3Store) fetch(ctx context.Context, keys []string) ([]map[string]interface{}, error) {
+	events := make([]map[string]interface{}, len(keys))
+	errs := make([]error, len(keys))
+	sem := make(chan struct{}, s3LoadConcurrency)
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, key string) {
+			defer func() { <-sem; wg.Done() }()
+			data, err := s.client.Get(ctx, key)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			errs[i] = json.Unmarshal(data, &events[i])
+		}(i, key)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", keys[i], err)
+		}
+	}
+	return events, nil
+}
+
+// Sessions implements Store. It issues one list request per session, skipping
+// over each session's events.
+func (s *S3Store) Sessions(ctx context.Context) ([]string, error) {
+	root := s.prefix + "sessions/"
+	var ids []string
+	startAfter := ""
+	for {
+		next := ""
+		if err := s.client.List(ctx, root, startAfter, func(key string) bool {
+			next, _, _ = strings.Cut(strings.TrimPrefix(key, root), "/")
+			return false
+		}); err != nil {
+			return nil, err
+		}
+		if next == "" {
+			return ids, nil
+		}
+		ids = append(ids, next)
+		// '0' sorts right after '/', so this skips every key of the session
+		startAfter = root + next + "0"
+	}
+}
+
+// Delete implements Store.  Another replica appending meanwhile may leave
+// events behind; they are removed by the next Delete.
+func (s *S3Store) Delete(ctx context.Context, sessionID string) error {
+	if err := checkSessionID(sessionID); err != nil {
+		return err
+	}
+	val, _ := s.sessions.LoadOrStore(sessionID, &s3Session{})
+	session := val.(*s3Session)
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if _, err := s.client.DeletePrefix(ctx, s.sessionPrefix(sessionID)); err != nil {
+		return err
+	}
+	session.seq, session.hash, session.loaded = 0, "", false
+	return nil
+}
+
+// Close implements Store
+func (s *S3Store) Close() error { return nil }
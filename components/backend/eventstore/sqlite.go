@@ -0,0 +1,175 @@
+package eventstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	// Registers the "sqlite3" driver. It needs cgo, so the image is built with CGO_ENABLED=1.
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqliteScanPage is how many events Scan reads per query
+const sqliteScanPage = 500
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS events (
+	session_id TEXT    NOT NULL,
+	seq        INTEGER NOT NULL,
+	data       TEXT    NOT NULL,
+	PRIMARY KEY (session_id, seq)
+) WITHOUT ROWID`
+
+// SQLiteStore keeps all logs in one SQLite database, one row per event
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLite opens (and if needed creates) the database at path
+func NewSQLite(path string) (*SQLiteStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	// WAL lets replays read while a run appends; _txlock=immediate takes the write
+	// lock when an append starts, so two appends cannot pick the same seq.
+	db, err := sql.Open("sqlite3", "file:"+path+"?_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate")
+	if err != nil {
+		return nil, err
+	}
+	// A single connection serialises writers in-process instead of having them
+	// contend for the database lock.
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create sqlite schema: %w", err)
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+// Append implements Store
+func (s *SQLiteStore) Append(ctx context.Context, sessionID string, event map[string]interface{}) (int64, error) {
+	if err := checkSessionID(sessionID); err != nil {
+		return 0, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var (
+		last     int64
+		lastData string
+	)
+	err = tx.QueryRowContext(ctx, `SELECT seq, data FROM events WHERE session_id = ? ORDER BY seq DESC LIMIT 1`, sessionID).Scan(&last, &lastData)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+	seq := last + 1
+	data, err := seal(event, seq, storedHash([]byte(lastData)))
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO events (session_id, seq, data) VALUES (?, ?, ?)`, sessionID, seq, string(data)); err != nil {
+		unseal(event)
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		unseal(event)
+		return 0, err
+	}
+	return seq, nil
+}
+
+// Load implements Store
+func (s *SQLiteStore) Load(ctx context.Context, sessionID string, after int64) ([]map[string]interface{}, error) {
+	if err := checkSessionID(sessionID); err != nil {
+		return nil, err
+	}
+	return s.load(ctx, sessionID, after, -1)
+}
+
+// Scan implements Scanner.  Events are read sqliteScanPage at a time, and
+// fn is called between reads, so a slow reader does not hold the store's
+// only connection.
+func (s *SQLiteStore) Scan(ctx context.Context, sessionID string, after int64, fn func(event map[string]interface{}) error) error {
+	if err := checkSessionID(sessionID); err != nil {
+		return err
+	}
+	for {
+		events, err := s.load(ctx, sessionID, after, sqliteScanPage)
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			if err := fn(event); err != nil {
+				return err
+			}
+			after = Seq(event)
+		}
+		if len(events) < sqliteScanPage {
+			return nil
+		}
+	}
+}
+
+// load returns up to limit of the session's events after after; a
+// negative limit means all of them.
+func (s *SQLiteStore) load(ctx context.Context, sessionID string, after int64, limit int) ([]map[string]interface{}, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT data FROM events WHERE session_id = ? AND seq > ? ORDER BY seq LIMIT ?`, sessionID, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []map[string]interface{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var event map[string]interface{}
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return nil, fmt.Errorf("decode event of %s: %w", sessionID, err)
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// Sessions implements Store
+func (s *SQLiteStore) Sessions(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT DISTINCT session_id FROM events ORDER BY session_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Delete implements Store
+func (s *SQLiteStore) Delete(ctx context.Context, sessionID string) error {
+	if err := checkSessionID(sessionID); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, `DELETE FROM events WHERE session_id = ?`, sessionID)
+	return err
+}
+
+// Close implements Store
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
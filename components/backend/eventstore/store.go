@@ -0,0 +1,212 @@
+// Package eventstore persists the AG-UI event log of each session. The backend appends
+// every event it proxies from a runner and replays the log to clients that connect or
+// reconnect to a session.
+//
+// Three stores are available, selected with EVENT_STORE:
+//
+//	jsonl   one append-only agui-events.jsonl file per session under STATE_BASE_DIR (default)
+//	sqlite  a single SQLite database file (EVENT_STORE_SQLITE_PATH)
+//	s3      one object per event in an S3-compatible bucket (EVENT_STORE_S3_*)
+//
+// Copy moves existing logs from one store to another; the backend binary exposes it as
+// the migrate-events command.
+package eventstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"ambient-code-shared/objstore"
+)
+
+// Store is an append-only event log per session. Events are JSON objects.
+// Implementations are safe for concurrent use.
+type Store interface {
+	// Append adds event to the end of the session's log. It assigns the event the
+	// session's next sequence number (starting at 1), records it in the event's
+	// "seq" field, chains it to the previous event in its "hash" field (see
+	// EventHash) and returns the sequence number.
+	Append(ctx context.Context, sessionID string, event map[string]interface{}) (int64, error)
+	// Load returns the session's events with a sequence number greater than after,
+	// oldest first. A session without events has an empty log, not an error.
+	Load(ctx context.Context, sessionID string, after int64) ([]map[string]interface{}, error)
+	// Sessions returns the IDs of all sessions with a log
+	Sessions(ctx context.Context) ([]string, error)
+	// Delete removes the session's log. Deleting a log that does not exist is
+	// not an error; a later Append starts a new log at sequence number 1.
+	Delete(ctx context.Context, sessionID string) error
+	Close() error
+}
+
+// Store backends accepted by Config.Backend
+const (
+	BackendJSONL  = "jsonl"
+	BackendSQLite = "sqlite"
+	BackendS3     = "s3"
+)
+
+// Config selects and configures a Store
+type Config struct {
+	Backend string
+	// Dir is the root of the JSONL store; logs are <Dir>/sessions/<id>/agui-events.jsonl
+	Dir string
+	// SQLitePath is the database file of the SQLite store
+	SQLitePath string
+	// S3 is the bucket of the S3 store, and S3Prefix the key prefix of its logs
+	S3       objstore.Config
+	S3Prefix string
+}
+
+// ConfigFromEnv reads the store configuration from the environment. stateBaseDir is
+// the backend's STATE_BASE_DIR, the default location of the JSONL and SQLite stores.
+func ConfigFromEnv(stateBaseDir string) Config {
+	cfg := Config{
+		Backend:    os.Getenv("EVENT_STORE"),
+		Dir:        stateBaseDir,
+		SQLitePath: os.Getenv("EVENT_STORE_SQLITE_PATH"),
+		S3: objstore.Config{
+			Endpoint:  os.Getenv("EVENT_STORE_S3_ENDPOINT"),
+			Bucket:    os.Getenv("EVENT_STORE_S3_BUCKET"),
+			Region:    os.Getenv("EVENT_STORE_S3_REGION"),
+			AccessKey: os.Getenv("EVENT_STORE_S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("EVENT_STORE_S3_SECRET_KEY"),
+		},
+		S3Prefix: os.Getenv("EVENT_STORE_S3_PREFIX"),
+	}
+	if cfg.Backend == "" {
+		cfg.Backend = BackendJSONL
+	}
+	if cfg.SQLitePath == "" {
+		cfg.SQLitePath = stateBaseDir + "/agui-events.db"
+	}
+	return cfg
+}
+
+// Open returns the store selected by cfg.Backend
+func Open(cfg Config) (Store, error) {
+	switch cfg.Backend {
+	case BackendJSONL, "":
+		return NewJSONL(cfg.Dir), nil
+	case BackendSQLite:
+		return NewSQLite(cfg.SQLitePath)
+	case BackendS3:
+		client, err := objstore.New(cfg.S3)
+		if err != nil {
+			return nil, err
+		}
+		return NewS3(client, cfg.S3Prefix), nil
+	default:
+		return nil, fmt.Errorf("unknown event store %q (want %s, %s or %s)", cfg.Backend, BackendJSONL, BackendSQLite, BackendS3)
+	}
+}
+
+// OffsetLoader is implemented by stores whose logs are files that can be
+// read from a byte offset, so a caller that remembers where it stopped does
+// not have to reread the whole log.
+type OffsetLoader interface {
+	// LoadFromOffset returns the events stored after byte offset, where the
+	// event at offset has sequence number seq+1, and the offset just past the
+	// last complete event.  It returns ErrStaleOffset if the log no longer
+	// has an event boundary at offset.
+	LoadFromOffset(ctx context.Context, sessionID string, offset, seq int64) ([]map[string]interface{}, int64, error)
+}
+
+// Scanner is implemented by stores that can read a log a few events at a
+// time, so a reader of a long log does not hold all of it in memory.
+type Scanner interface {
+	// Scan calls fn with each of the session's events with a sequence number
+	// greater than after, oldest first.  It stops at the first error fn
+	// returns, and returns it.
+	Scan(ctx context.Context, sessionID string, after int64, fn func(event map[string]interface{}) error) error
+}
+
+// Scan calls fn with the session's events after after, oldest first,
+// reading them incrementally when store is a Scanner.
+func Scan(ctx context.Context, store Store, sessionID string, after int64, fn func(event map[string]interface{}) error) error {
+	if scanner, ok := store.(Scanner); ok {
+		return scanner.Scan(ctx, sessionID, after, fn)
+	}
+	events, err := store.Load(ctx, sessionID, after)
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ErrStaleOffset reports an offset that does not belong to the current log
+var ErrStaleOffset = errors.New("offset is not an event boundary of the log")
+
+// Seq returns the sequence number of a stored event, or 0 if it has none
+func Seq(event map[string]interface{}) int64 {
+	switch v := event["seq"].(type) {
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	case json.Number:
+		n, _ := v.Int64()
+		return n
+	}
+	return 0
+}
+
+// CopyResult counts the work done by Copy
+type CopyResult struct {
+	Sessions int
+	Events   int
+	// Skipped counts sessions that already had a log in the destination
+	Skipped int
+}
+
+// Copy copies every session log in from to to. Sessions that already have events in
+// to are skipped, so an interrupted copy can be re-run. Events are renumbered from 1
+// and rechained in the destination.
+func Copy(ctx context.Context, from, to Store) (CopyResult, error) {
+	var result CopyResult
+	sessions, err := from.Sessions(ctx)
+	if err != nil {
+		return result, fmt.Errorf("list sessions: %w", err)
+	}
+	for _, sessionID := range sessions {
+		existing, err := to.Load(ctx, sessionID, 0)
+		if err != nil {
+			return result, fmt.Errorf("read %s from destination: %w", sessionID, err)
+		}
+		if len(existing) > 0 {
+			result.Skipped++
+			continue
+		}
+		events, err := from.Load(ctx, sessionID, 0)
+		if err != nil {
+			return result, fmt.Errorf("read %s: %w", sessionID, err)
+		}
+		for _, event := range events {
+			unseal(event) // renumbered and rechained by to
+			if _, err := to.Append(ctx, sessionID, event); err != nil {
+				return result, fmt.Errorf("write %s: %w", sessionID, err)
+			}
+		}
+		result.Sessions++
+		result.Events += len(events)
+	}
+	return result, nil
+}
+
+var errInvalidSessionID = errors.New("invalid session ID")
+
+// checkSessionID rejects IDs that could escape the session's directory or key prefix
+func checkSessionID(sessionID string) error {
+	if sessionID == "" || sessionID == "." || sessionID == ".." || strings.ContainsAny(sessionID, "/\\") {
+		return fmt.Errorf("%w %q", errInvalidSessionID, sessionID)
+	}
+	return nil
+}
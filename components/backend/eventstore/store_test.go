@@ -0,0 +1,278 @@
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"ambient-code-shared/objstore"
+	"ambient-code-shared/objstore/objstoretest"
+)
+
+func newS3TestStore(t *testing.T, server *objstoretest.Server) *S3Store {
+	t.Helper()
+	client, err := objstore.New(objstore.Config{Endpoint: server.URL, Bucket: "events", AccessKey: "key", SecretKey: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewS3(client, "ambient/")
+}
+
+// testStores returns one empty store of every kind
+func testStores(t *testing.T) map[string]Store {
+	t.Helper()
+	sqlite, err := NewSQLite(filepath.Join(t.TempDir(), "events.db"))
+	if err != nil {
+		t.Fatalf("NewSQLite() error = %v", err)
+	}
+	t.Cleanup(func() { sqlite.Close() })
+
+	server := objstoretest.NewServer()
+	server.PageSize = 3 // exercise list pagination
+	t.Cleanup(server.Close)
+
+	return map[string]Store{
+		BackendJSONL:  NewJSONL(t.TempDir()),
+		BackendSQLite: sqlite,
+		BackendS3:     newS3TestStore(t, server),
+	}
+}
+
+func TestStores(t *testing.T) {
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			if events, err := store.Load(ctx, "empty", 0); err != nil || len(events) != 0 {
+				t.Fatalf("Load() of an unknown session = %v, %v", events, err)
+			}
+			for i, eventType := range []string{"RUN_STARTED", "TEXT_MESSAGE_CONTENT", "RUN_FINISHED"} {
+				event := map[string]interface{}{"type": eventType}
+				seq, err := store.Append(ctx, "session-a", event)
+				if err != nil {
+					t.Fatalf("Append() error = %v", err)
+				}
+				if seq != int64(i+1) || Seq(event) != seq {
+					t.Errorf("Append() = %d (event seq %d), want %d", seq, Seq(event), i+1)
+				}
+			}
+			if _, err := store.Append(ctx, "session-b", map[string]interface{}{"type": "RUN_STARTED"}); err != nil {
+				t.Fatal(err)
+			}
+
+			events, err := store.Load(ctx, "session-a", 0)
+			if err != nil || len(events) != 3 {
+				t.Fatalf("Load() = %v, %v", events, err)
+			}
+			for i, event := range events {
+				if Seq(event) != int64(i+1) {
+					t.Errorf("event %d has seq %d", i, Seq(event))
+				}
+			}
+			if events, _ := store.Load(ctx, "session-a", 2); len(events) != 1 || events[0]["type"] != "RUN_FINISHED" {
+				t.Errorf("Load(after 2) = %v", events)
+			}
+
+			sessions, err := store.Sessions(ctx)
+			if err != nil || len(sessions) != 2 || sessions[0] != "session-a" || sessions[1] != "session-b" {
+				t.Errorf("Sessions() = %v, %v", sessions, err)
+			}
+
+			if _, err := store.Append(ctx, "../escape", map[string]interface{}{}); err == nil {
+				t.Error("expected an invalid session ID to be rejected")
+			}
+
+			if err := store.Delete(ctx, "session-a"); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if events, err := store.Load(ctx, "session-a", 0); err != nil || len(events) != 0 {
+				t.Errorf("Load() after Delete() = %v, %v", events, err)
+			}
+			if sessions, _ := store.Sessions(ctx); len(sessions) != 1 || sessions[0] != "session-b" {
+				t.Errorf("Sessions() after Delete() = %v", sessions)
+			}
+			// A new log starts over, chained from its first event
+			event := map[string]interface{}{"type": "RUN_STARTED"}
+			if seq, err := store.Append(ctx, "session-a", event); err != nil || seq != 1 {
+				t.Errorf("Append() after Delete() = %d, %v; want 1", seq, err)
+			}
+			if events, _ := store.Load(ctx, "session-a", 0); !VerifyChain(events, 1).Valid {
+				t.Errorf("log recreated after Delete() does not verify from 1: %v", events)
+			}
+			if err := store.Delete(ctx, "never-written"); err != nil {
+				t.Errorf("Delete() of an unknown session = %v", err)
+			}
+		})
+	}
+}
+
+func TestScan(t *testing.T) {
+	errStop := errors.New("stop")
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			for i := 0; i < 5; i++ {
+				if _, err := store.Append(ctx, "scanned", map[string]interface{}{"type": "CUSTOM"}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var seqs []int64
+			err := Scan(ctx, store, "scanned", 1, func(event map[string]interface{}) error {
+				seqs = append(seqs, Seq(event))
+				return nil
+			})
+			if err != nil || len(seqs) != 4 || seqs[0] != 2 || seqs[3] != 5 {
+				t.Errorf("Scan(after 1) = %v, %v; want 2..5", seqs, err)
+			}
+
+			// An error from fn stops the scan
+			seqs = nil
+			err = Scan(ctx, store, "scanned", 0, func(event map[string]interface{}) error {
+				seqs = append(seqs, Seq(event))
+				if len(seqs) == 2 {
+					return errStop
+				}
+				return nil
+			})
+			if !errors.Is(err, errStop) || len(seqs) != 2 {
+				t.Errorf("Scan() stopped after %v with %v", seqs, err)
+			}
+		})
+	}
+}
+
+func TestJSONLStoreNumbersLegacyLines(t *testing.T) {
+	dir := t.TempDir()
+	sessionDir := filepath.Join(dir, "sessions", "legacy")
+	if err := os.MkdirAll(sessionDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Written before sequence numbers existed, with a torn line
+	log := "{\"type\":\"RUN_STARTED\"}\n{\"type\":\n{\"type\":\"RUN_FINISHED\"}\n"
+	if err := os.WriteFile(filepath.Join(sessionDir, jsonlFileName), []byte(log), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewJSONL(dir)
+	events, err := store.Load(context.Background(), "legacy", 0)
+	if err != nil || len(events) != 2 || Seq(events[0]) != 1 || Seq(events[1]) != 3 {
+		t.Fatalf("Load() = %v, %v", events, err)
+	}
+	if seq, err := store.Append(context.Background(), "legacy", map[string]interface{}{"type": "RUN_STARTED"}); err != nil || seq != 4 {
+		t.Errorf("Append() = %d, %v, want 4", seq, err)
+	}
+}
+
+func TestS3StoreConcurrentReplicas(t *testing.T) {
+	server := objstoretest.NewServer()
+	defer server.Close()
+	replicaA, replicaB := newS3TestStore(t, server), newS3TestStore(t, server)
+	ctx := context.Background()
+
+	if seq, _ := replicaA.Append(ctx, "shared", map[string]interface{}{"type": "RUN_STARTED"}); seq != 1 {
+		t.Fatalf("replica A Append() = %d, want 1", seq)
+	}
+	if seq, _ := replicaB.Append(ctx, "shared", map[string]interface{}{"type": "RAW"}); seq != 2 {
+		t.Fatalf("replica B Append() = %d, want 2", seq)
+	}
+	// Replica A's cached seq is stale; the conditional write makes it catch up
+	seq, err := replicaA.Append(ctx, "shared", map[string]interface{}{"type": "RUN_FINISHED"})
+	if err != nil || seq != 3 {
+		t.Fatalf("replica A Append() = %d, %v, want 3", seq, err)
+	}
+	if keys := server.Keys("events"); len(keys) != 3 {
+		t.Errorf("objects = %v", keys)
+	}
+	// ...and chains to replica B's event
+	events, _ := replicaB.Load(ctx, "shared", 0)
+	if chain := VerifyChain(events, 1); !chain.Valid || chain.HeadSeq != 3 {
+		t.Errorf("VerifyChain() = %+v", chain)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	stores := testStores(t)
+	from, to := stores[BackendJSONL], stores[BackendS3]
+	ctx := context.Background()
+
+	for _, sessionID := range []string{"one", "two"} {
+		for i := 0; i < 3; i++ {
+			if _, err := from.Append(ctx, sessionID, map[string]interface{}{"type": "CUSTOM", "value": float64(i)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// Already migrated sessions are left alone
+	if _, err := to.Append(ctx, "two", map[string]interface{}{"type": "CUSTOM"}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Copy(ctx, from, to)
+	if err != nil {
+		t.Fatalf("Copy() error = %v", err)
+	}
+	if result != (CopyResult{Sessions: 1, Events: 3, Skipped: 1}) {
+		t.Errorf("Copy() = %+v", result)
+	}
+	events, _ := to.Load(ctx, "one", 0)
+	if len(events) != 3 || events[2]["value"] != float64(2) || Seq(events[2]) != 3 {
+		t.Errorf("copied events = %v", events)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	if _, err := Open(Config{Backend: "redis"}); err == nil {
+		t.Error("expected an error for an unknown backend")
+	}
+	if _, err := Open(Config{Backend: BackendS3}); err == nil {
+		t.Error("expected an error for an S3 store without a bucket")
+	}
+	store, err := Open(Config{Backend: BackendJSONL, Dir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.(*JSONLStore); !ok {
+		t.Errorf("Open(jsonl) = %T", store)
+	}
+}
+
+func TestJSONLStoreLoadFromOffset(t *testing.T) {
+	dir := t.TempDir()
+	store := NewJSONL(dir)
+	ctx := context.Background()
+	for _, eventType := range []string{"RUN_STARTED", "RUN_FINISHED"} {
+		if _, err := store.Append(ctx, "s", map[string]interface{}{"type": eventType}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events, offset, err := store.LoadFromOffset(ctx, "s", 0, 0)
+	if err != nil || len(events) != 2 {
+		t.Fatalf("LoadFromOffset(0) = %v, %v", events, err)
+	}
+	if events, end, _ := store.LoadFromOffset(ctx, "s", offset, 2); len(events) != 0 || end != offset {
+		t.Errorf("LoadFromOffset(end) = %v, %d, want nothing at %d", events, end, offset)
+	}
+
+	// A line still being written is left for the next read
+	f, err := os.OpenFile(filepath.Join(dir, "sessions", "s", jsonlFileName), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"type":"RAW"}` + "\n" + `{"type":`)
+	f.Close()
+	events, end, err := store.LoadFromOffset(ctx, "s", offset, 2)
+	if err != nil || len(events) != 1 || Seq(events[0]) != 3 || end != offset+int64(len(`{"type":"RAW"}`)+1) {
+		t.Errorf("LoadFromOffset() = %v, %d, %v", events, end, err)
+	}
+
+	if _, _, err := store.LoadFromOffset(ctx, "s", offset+3, 2); !errors.Is(err, ErrStaleOffset) {
+		t.Errorf("LoadFromOffset(mid-line) error = %v, want ErrStaleOffset", err)
+	}
+	if _, _, err := store.LoadFromOffset(ctx, "s", 1<<20, 2); !errors.Is(err, ErrStaleOffset) {
+		t.Errorf("LoadFromOffset(past end) error = %v, want ErrStaleOffset", err)
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"ambient-code-shared/budget"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+// budgetExceeded describes an exhausted budget. Daily budgets map to 429 (retry
+// after the UTC day rolls over); monthly budgets map to 402.
+type budgetExceeded struct {
+	Period     string
+	StatusCode int
+	Message    string
+	RetryAfter time.Duration
+}
+
+// checkProjectBudget returns the first exhausted budget of the project, or nil
+// when the project has no budget or spend is below every limit.
+func checkProjectBudget(ctx context.Context, dyn dynamic.Interface, project string, now time.Time) (*budgetExceeded, error) {
+	obj, err := dyn.Resource(GetProjectSettingsResource()).Namespace(project).Get(ctx, "projectsettings", v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read project settings: %w", err)
+	}
+	spec, _, _ := unstructured.NestedMap(obj.Object, "spec", "budget")
+	limits := budget.ParseLimits(spec)
+	if !limits.Enabled() {
+		return nil, nil
+	}
+	ledger, _, _ := unstructured.NestedMap(obj.Object, "status", "usageLedger")
+
+	list, err := dyn.Resource(GetAgenticSessionV1Alpha1Resource()).Namespace(project).List(ctx, v1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	return evaluateBudget(limits, ledger, list.Items, now), nil
+}
+
+// evaluateBudget compares the project's spend in the UTC day and month of now,
+// from its sessions and the usage ledger of its deleted sessions, against the
+// budget, in the same order as the operator's admission check.
+func evaluateBudget(limits budget.Limits, ledger map[string]interface{}, sessions []unstructured.Unstructured, now time.Time) *budgetExceeded {
+	now = now.UTC()
+	var spend budget.Spend
+	spend.AddLedger(ledger, now)
+	for i := range sessions {
+		usage, _, _ := unstructured.NestedMap(sessions[i].Object, "status", "usage")
+		spend.Add(usage, now)
+	}
+
+	exceeded := budget.Check(limits, spend, 100)
+	if exceeded == nil {
+		return nil
+	}
+	result := &budgetExceeded{Period: exceeded.Period, Message: "Project " + exceeded.Message}
+	if exceeded.Period == budget.PeriodDaily {
+		tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+		result.StatusCode = http.StatusTooManyRequests
+		result.RetryAfter = tomorrow.Sub(now)
+	} else {
+		result.StatusCode = http.StatusPaymentRequired
+	}
+	return result
+}
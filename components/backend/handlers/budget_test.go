@@ -0,0 +1,90 @@
+//go:build test
+
+package handlers
+
+import (
+	test_constants "ambient-code-backend/tests/constants"
+	"ambient-code-shared/budget"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("Project Budgets", Label(test_constants.LabelUnit, test_constants.LabelHandlers, test_constants.LabelSessions), func() {
+	now := time.Date(2026, 3, 15, 18, 0, 0, 0, time.UTC)
+
+	sessionWithUsage := func(name, user string, daily map[string]interface{}) unstructured.Unstructured {
+		return unstructured.Unstructured{Object: map[string]interface{}{
+			"metadata": map[string]interface{}{"name": name},
+			"spec": map[string]interface{}{
+				"userContext": map[string]interface{}{"userId": user},
+				"llmSettings": map[string]interface{}{"model": "sonnet"},
+			},
+			"status": map[string]interface{}{
+				"usage": map[string]interface{}{"daily": daily},
+			},
+		}}
+	}
+	bucket := func(cost float64, input, output int64) map[string]interface{} {
+		return map[string]interface{}{"costUsd": cost, "inputTokens": input, "outputTokens": output, "runs": int64(1)}
+	}
+
+	sessions := []unstructured.Unstructured{
+		sessionWithUsage("a", "alice", map[string]interface{}{
+			"2026-02-28": bucket(50, 1000, 1000), // previous month: ignored
+			"2026-03-01": bucket(10, 100, 100),
+			"2026-03-15": bucket(3, 300, 200),
+		}),
+		sessionWithUsage("b", "bob", map[string]interface{}{
+			"2026-03-15": bucket(2, 100, 50),
+		}),
+	}
+
+	It("Should aggregate usage per user, model and day within the range", func() {
+		usage := aggregateProjectUsage(sessions, nil, "2026-03-01", "2026-03-15")
+
+		Expect(usage.Sessions).To(Equal(2))
+		Expect(usage.Total.CostUSD).To(BeNumerically("~", 15))
+		Expect(usage.ByUser).To(HaveLen(2))
+		Expect(usage.ByUser[0].Key).To(Equal("alice"), "rollups are ordered by cost")
+		Expect(usage.ByModel).To(HaveLen(1))
+		Expect(usage.ByDay).To(HaveLen(2))
+		Expect(usage.ByDay[0].Key).To(Equal("2026-03-01"))
+	})
+
+	It("Should allow sessions when spend is below every budget", func() {
+		limits := budget.Limits{DailyCostUSD: 10, MonthlyCostUSD: 100, DailyTokens: 10000}
+		Expect(evaluateBudget(limits, nil, sessions, now)).To(BeNil())
+	})
+
+	It("Should count the spend of deleted sessions in the usage ledger", func() {
+		ledger := map[string]interface{}{
+			"carol": map[string]interface{}{"daily": map[string]interface{}{"2026-03-15": bucket(6, 10, 10)}},
+		}
+		exceeded := evaluateBudget(budget.Limits{DailyCostUSD: 10}, ledger, sessions, now)
+
+		Expect(exceeded).NotTo(BeNil())
+		Expect(exceeded.Message).To(ContainSubstring("$11.00 of $10.00"))
+	})
+
+	It("Should reject with 429 and Retry-After when the daily budget is used up", func() {
+		exceeded := evaluateBudget(budget.Limits{DailyCostUSD: 5}, nil, sessions, now)
+
+		Expect(exceeded).NotTo(BeNil())
+		Expect(exceeded.StatusCode).To(Equal(http.StatusTooManyRequests))
+		Expect(exceeded.Period).To(Equal("daily"))
+		Expect(exceeded.RetryAfter).To(Equal(6 * time.Hour))
+	})
+
+	It("Should reject with 402 when the monthly budget is used up", func() {
+		exceeded := evaluateBudget(budget.Limits{MonthlyTokens: 850}, nil, sessions, now)
+
+		Expect(exceeded).NotTo(BeNil())
+		Expect(exceeded.StatusCode).To(Equal(http.StatusPaymentRequired))
+		Expect(exceeded.Period).To(Equal("monthly"))
+		Expect(exceeded.Message).To(ContainSubstring("850 of 850"))
+	})
+})
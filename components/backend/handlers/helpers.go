@@ -33,6 +33,15 @@ func GetProjectSettingsResource() schema.GroupVersionResource {
 	}
 }
 
+// GetScheduledSessionResource returns the GroupVersionResource for ScheduledSession
+func GetScheduledSessionResource() schema.GroupVersionResource {
+	return schema.GroupVersionResource{
+		Group:    "vteam.ambient-code",
+		Version:  "v1alpha1",
+		Resource: "scheduledsessions",
+	}
+}
+
 // RetryWithBackoff attempts an operation with exponential backoff
 // Used for operations that may temporarily fail due to async resource creation
 // This is a generic utility that can be used by any handler
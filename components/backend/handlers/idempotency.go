@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+	"unicode"
+
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	// IdempotencyKeyHeader lets clients retry POST /agentic-sessions without creating duplicates
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// idempotencyKeyRetention is how long a session answers repeated creates with its key
+	idempotencyKeyRetention = 24 * time.Hour
+	idempotencyKeyMaxLength = 255
+
+	// The key is stored verbatim in an annotation and hashed with the caller's user ID
+	// into a label for lookups, since label values are limited to 63 characters.
+	idempotencyKeyAnnotation     = "ambient-code.io/idempotency-key"
+	idempotencyPayloadAnnotation = "ambient-code.io/idempotency-payload-hash"
+	idempotencyKeyLabel          = "ambient-code.io/idempotency-key-hash"
+)
+
+// idempotencyKey returns the request's Idempotency-Key header, or "" when it is not set
+func idempotencyKey(c *gin.Context) (string, error) {
+	key := c.GetHeader(IdempotencyKeyHeader)
+	if len(key) > idempotencyKeyMaxLength {
+		return "", fmt.Errorf("%s must be at most %d characters", IdempotencyKeyHeader, idempotencyKeyMaxLength)
+	}
+	for _, r := range key {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) {
+			return "", fmt.Errorf("%s must contain printable ASCII characters only", IdempotencyKeyHeader)
+		}
+	}
+	return key, nil
+}
+
+// idempotencyPayloadHash fingerprints a create request so a reused key with a different
+// body can be rejected
+func idempotencyPayloadHash(req types.CreateAgenticSessionRequest) (string, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// idempotencyKeyHash scopes key to the caller, so two users sending the same key
+// never see each other's sessions
+func idempotencyKeyHash(userID, key string) string {
+	sum := sha256.Sum256([]byte(userID + "\n" + key))
+	return hex.EncodeToString(sum[:16])
+}
+
+// idempotentSessionName derives the name of the session created for the caller's key,
+// so concurrent retries collide on create instead of both creating a session. The name
+// changes every idempotencyKeyRetention so a key can be reused once it has expired.
+func idempotentSessionName(userID, key string, now time.Time) string {
+	window := now.Unix() / int64(idempotencyKeyRetention/time.Second)
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\n%s\n%d", userID, key, window)))
+	return "session-" + hex.EncodeToString(sum[:8])
+}
+
+// setIdempotencyMetadata records key and payloadHash on the metadata of a new session
+func setIdempotencyMetadata(metadata map[string]interface{}, userID, key, payloadHash string) {
+	labels, _ := metadata["labels"].(map[string]interface{})
+	if labels == nil {
+		labels = map[string]interface{}{}
+		metadata["labels"] = labels
+	}
+	labels[idempotencyKeyLabel] = idempotencyKeyHash(userID, key)
+
+	annotations, _ := metadata["annotations"].(map[string]interface{})
+	if annotations == nil {
+		annotations = map[string]interface{}{}
+		metadata["annotations"] = annotations
+	}
+	annotations[idempotencyKeyAnnotation] = key
+	annotations[idempotencyPayloadAnnotation] = payloadHash
+}
+
+// findIdempotentSession returns the newest session userID created with key within the
+// retention window, or nil if there is none
+func findIdempotentSession(ctx context.Context, k8sDyn dynamic.Interface, project, userID, key string, now time.Time) (*unstructured.Unstructured, error) {
+	list, err := k8sDyn.Resource(GetAgenticSessionV1Alpha1Resource()).Namespace(project).List(ctx, v1.ListOptions{
+		LabelSelector: idempotencyKeyLabel + "=" + idempotencyKeyHash(userID, key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var found *unstructured.Unstructured
+	for i := range list.Items {
+		item := &list.Items[i]
+		if item.GetAnnotations()[idempotencyKeyAnnotation] != key {
+			continue
+		}
+		created := item.GetCreationTimestamp().Time
+		if !created.IsZero() && now.Sub(created) > idempotencyKeyRetention {
+			continue
+		}
+		if found == nil || created.After(found.GetCreationTimestamp().Time) {
+			found = item
+		}
+	}
+	return found, nil
+}
+
+// replayIdempotentSession answers a retried create with the session the first request
+// created, or 409 when the key was used with a different body
+func replayIdempotentSession(c *gin.Context, existing *unstructured.Unstructured, payloadHash string) {
+	if existing.GetAnnotations()[idempotencyPayloadAnnotation] != payloadHash {
+		c.JSON(http.StatusConflict, gin.H{"error": "Idempotency-Key was already used with a different request body"})
+		return
+	}
+	c.Header("Idempotent-Replayed", "true")
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Agentic session already created for this Idempotency-Key",
+		"name":       existing.GetName(),
+		"uid":        existing.GetUID(),
+		"autoBranch": ComputeAutoBranch(existing.GetName()),
+	})
+}
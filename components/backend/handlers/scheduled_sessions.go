@@ -0,0 +1,467 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// cronFieldRegex matches a single cron field: values, names, ranges, lists and steps.
+// Full range validation is done by the operator, which reports errors via status conditions.
+var cronFieldRegex = regexp.MustCompile(`^[0-9A-Za-z*?,/-]+$`)
+
+var cronDescriptors = map[string]bool{
+	"@yearly": true, "@annually": true, "@monthly": true, "@weekly": true,
+	"@daily": true, "@midnight": true, "@hourly": true,
+}
+
+// validateCronSchedule performs a structural check of a five-field cron expression
+func validateCronSchedule(schedule string) error {
+	schedule = strings.TrimSpace(schedule)
+	if cronDescriptors[strings.ToLower(schedule)] {
+		return nil
+	}
+	fields := strings.Fields(schedule)
+	if len(fields) != 5 {
+		return fmt.Errorf("schedule must have 5 fields (minute hour day-of-month month day-of-week) or be a descriptor such as @daily")
+	}
+	for _, f := range fields {
+		if !cronFieldRegex.MatchString(f) {
+			return fmt.Errorf("invalid schedule field %q", f)
+		}
+	}
+	return nil
+}
+
+func validateConcurrencyPolicy(policy string) error {
+	switch policy {
+	case "", "Allow", "Forbid", "Replace":
+		return nil
+	}
+	return fmt.Errorf("concurrencyPolicy must be one of Allow, Forbid, Replace")
+}
+
+func validateTimeZone(tz string) error {
+	if tz == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(tz); err != nil {
+		return fmt.Errorf("invalid timeZone %q", tz)
+	}
+	return nil
+}
+
+// buildScheduledSessionTemplate converts a session create request into the
+// template stored on a ScheduledSession, applying the same defaults as CreateSession.
+// Repo branches are left empty so the operator can derive one per created session.
+func buildScheduledSessionTemplate(c *gin.Context, project string, req types.CreateAgenticSessionRequest) map[string]interface{} {
+	spec := newSessionSpec(project, req)
+	if req.Interactive != nil {
+		spec["interactive"] = *req.Interactive
+	}
+	if len(req.EnvironmentVariables) > 0 {
+		envVars := make(map[string]interface{}, len(req.EnvironmentVariables))
+		for k, v := range req.EnvironmentVariables {
+			envVars[k] = v
+		}
+		spec["environmentVariables"] = envVars
+	}
+	if len(req.Repos) > 0 {
+		arr := make([]interface{}, 0, len(req.Repos))
+		for _, r := range req.Repos {
+			m := map[string]interface{}{"url": r.URL}
+			if r.Branch != nil && strings.TrimSpace(*r.Branch) != "" {
+				m["branch"] = *r.Branch
+			}
+			if r.AutoPush != nil {
+				m["autoPush"] = *r.AutoPush
+			}
+			arr = append(arr, m)
+		}
+		spec["repos"] = arr
+	}
+	// Scheduled runs execute on behalf of the user who created the schedule
+	if userContext := callerUserContext(c, req.UserContext); userContext != nil {
+		spec["userContext"] = userContext
+	}
+
+	template := map[string]interface{}{"spec": spec}
+	metadata := map[string]interface{}{}
+	if len(req.Labels) > 0 {
+		labels := map[string]interface{}{}
+		for k, v := range req.Labels {
+			labels[k] = v
+		}
+		metadata["labels"] = labels
+	}
+	if len(req.Annotations) > 0 {
+		annotations := map[string]interface{}{}
+		for k, v := range req.Annotations {
+			annotations[k] = v
+		}
+		metadata["annotations"] = annotations
+	}
+	if len(metadata) > 0 {
+		template["metadata"] = metadata
+	}
+	return template
+}
+
+// parseScheduledSession converts an unstructured ScheduledSession into its API type
+func parseScheduledSession(item *unstructured.Unstructured) types.ScheduledSession {
+	result := types.ScheduledSession{
+		APIVersion: item.GetAPIVersion(),
+		Kind:       item.GetKind(),
+	}
+	if metadata, ok := item.Object["metadata"].(map[string]interface{}); ok {
+		result.Metadata = metadata
+	}
+
+	if spec, ok := item.Object["spec"].(map[string]interface{}); ok {
+		if schedule, ok := spec["schedule"].(string); ok {
+			result.Spec.Schedule = schedule
+		}
+		if tz, ok := spec["timeZone"].(string); ok {
+			result.Spec.TimeZone = tz
+		}
+		if suspend, ok := spec["suspend"].(bool); ok {
+			result.Spec.Suspend = suspend
+		}
+		if policy, ok := spec["concurrencyPolicy"].(string); ok {
+			result.Spec.ConcurrencyPolicy = policy
+		}
+		if v, found, _ := unstructured.NestedInt64(item.Object, "spec", "startingDeadlineSeconds"); found {
+			result.Spec.StartingDeadlineSeconds = &v
+		}
+		if v, found, _ := unstructured.NestedInt64(item.Object, "spec", "successfulSessionsHistoryLimit"); found {
+			result.Spec.SuccessfulSessionsHistoryLimit = types.IntPtr(int(v))
+		}
+		if v, found, _ := unstructured.NestedInt64(item.Object, "spec", "failedSessionsHistoryLimit"); found {
+			result.Spec.FailedSessionsHistoryLimit = types.IntPtr(int(v))
+		}
+		if tmpl, ok := spec["template"].(map[string]interface{}); ok {
+			if tmplSpec, ok := tmpl["spec"].(map[string]interface{}); ok {
+				result.Spec.Template.Spec = parseSpec(tmplSpec)
+			}
+			if labels, found, _ := unstructured.NestedStringMap(tmpl, "metadata", "labels"); found {
+				result.Spec.Template.Labels = labels
+			}
+			if annotations, found, _ := unstructured.NestedStringMap(tmpl, "metadata", "annotations"); found {
+				result.Spec.Template.Annotations = annotations
+			}
+		}
+	}
+
+	if status, ok := item.Object["status"].(map[string]interface{}); ok {
+		st := &types.ScheduledSessionStatus{}
+		if og, found, _ := unstructured.NestedInt64(status, "observedGeneration"); found {
+			st.ObservedGeneration = og
+		}
+		if active, found, _ := unstructured.NestedStringSlice(status, "active"); found {
+			st.Active = active
+		}
+		if t, ok := status["lastScheduleTime"].(string); ok && t != "" {
+			st.LastScheduleTime = types.StringPtr(t)
+		}
+		if t, ok := status["lastSuccessfulTime"].(string); ok && t != "" {
+			st.LastSuccessfulTime = types.StringPtr(t)
+		}
+		if t, ok := status["nextScheduleTime"].(string); ok && t != "" {
+			st.NextScheduleTime = types.StringPtr(t)
+		}
+		// Conditions share the AgenticSession status shape
+		if parsed := parseStatus(map[string]interface{}{"conditions": status["conditions"]}); parsed != nil {
+			st.Conditions = parsed.Conditions
+		}
+		result.Status = st
+	}
+	return result
+}
+
+// ListScheduledSessions handles GET /api/projects/:projectName/scheduled-sessions
+func ListScheduledSessions(c *gin.Context) {
+	project := c.GetString("project")
+	_, k8sDyn := GetK8sClientsForRequest(c)
+	if k8sDyn == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing token"})
+		c.Abort()
+		return
+	}
+
+	list, err := k8sDyn.Resource(GetScheduledSessionResource()).Namespace(project).List(context.TODO(), v1.ListOptions{})
+	if err != nil {
+		log.Printf("Failed to list scheduled sessions in project %s: %v", project, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list scheduled sessions"})
+		return
+	}
+
+	items := make([]types.ScheduledSession, 0, len(list.Items))
+	for i := range list.Items {
+		items = append(items, parseScheduledSession(&list.Items[i]))
+	}
+	sort.Slice(items, func(i, j int) bool {
+		ni, _ := items[i].Metadata["name"].(string)
+		nj, _ := items[j].Metadata["name"].(string)
+		return ni < nj
+	})
+
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// CreateScheduledSession handles POST /api/projects/:projectName/scheduled-sessions
+func CreateScheduledSession(c *gin.Context) {
+	project := c.GetString("project")
+	reqK8s, k8sDyn := GetK8sClientsForRequest(c)
+	if reqK8s == nil || k8sDyn == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User token required"})
+		c.Abort()
+		return
+	}
+
+	var req types.CreateScheduledSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if err := validateCronSchedule(req.Schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateConcurrencyPolicy(req.ConcurrencyPolicy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateTimeZone(req.TimeZone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateSessionNext(req.Template.Next); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = fmt.Sprintf("schedule-%d", time.Now().Unix())
+	} else if !isValidKubernetesName(name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid name"})
+		return
+	}
+
+	spec := map[string]interface{}{
+		"schedule": strings.TrimSpace(req.Schedule),
+		"template": buildScheduledSessionTemplate(c, project, req.Template),
+	}
+	if req.TimeZone != "" {
+		spec["timeZone"] = req.TimeZone
+	}
+	if req.Suspend != nil {
+		spec["suspend"] = *req.Suspend
+	}
+	if req.ConcurrencyPolicy != "" {
+		spec["concurrencyPolicy"] = req.ConcurrencyPolicy
+	}
+	if req.StartingDeadlineSeconds != nil {
+		spec["startingDeadlineSeconds"] = *req.StartingDeadlineSeconds
+	}
+	if req.SuccessfulSessionsHistoryLimit != nil {
+		spec["successfulSessionsHistoryLimit"] = int64(*req.SuccessfulSessionsHistoryLimit)
+	}
+	if req.FailedSessionsHistoryLimit != nil {
+		spec["failedSessionsHistoryLimit"] = int64(*req.FailedSessionsHistoryLimit)
+	}
+
+	metadata := map[string]interface{}{
+		"name":      name,
+		"namespace": project,
+	}
+	if uid, ok := c.Get("userID"); ok {
+		if s, ok := uid.(string); ok && strings.TrimSpace(s) != "" {
+			metadata["annotations"] = map[string]interface{}{
+				"ambient-code.io/created-by": s,
+			}
+		}
+	}
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "vteam.ambient-code/v1alpha1",
+		"kind":       "ScheduledSession",
+		"metadata":   metadata,
+		"spec":       spec,
+	}}
+
+	// Create using user token (enforces user RBAC permissions)
+	created, err := k8sDyn.Resource(GetScheduledSessionResource()).Namespace(project).Create(context.TODO(), obj, v1.CreateOptions{})
+	if err != nil {
+		if errors.IsAlreadyExists(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Scheduled session already exists"})
+			return
+		}
+		log.Printf("Failed to create scheduled session in project %s: %v", project, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create scheduled session"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, parseScheduledSession(created))
+}
+
+// GetScheduledSession handles GET /api/projects/:projectName/scheduled-sessions/:scheduleName
+func GetScheduledSession(c *gin.Context) {
+	project := c.GetString("project")
+	name := c.Param("scheduleName")
+	_, k8sDyn := GetK8sClientsForRequest(c)
+	if k8sDyn == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing token"})
+		c.Abort()
+		return
+	}
+
+	item, err := k8sDyn.Resource(GetScheduledSessionResource()).Namespace(project).Get(context.TODO(), name, v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Scheduled session not found"})
+			return
+		}
+		log.Printf("Failed to get scheduled session %s in project %s: %v", name, project, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get scheduled session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, parseScheduledSession(item))
+}
+
+// UpdateScheduledSession handles PUT /api/projects/:projectName/scheduled-sessions/:scheduleName.
+// Only fields present in the request are changed; setting suspend pauses or resumes the schedule.
+func UpdateScheduledSession(c *gin.Context) {
+	project := c.GetString("project")
+	name := c.Param("scheduleName")
+	_, k8sDyn := GetK8sClientsForRequest(c)
+	if k8sDyn == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing token"})
+		c.Abort()
+		return
+	}
+
+	var req types.UpdateScheduledSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if req.Schedule != nil {
+		if err := validateCronSchedule(*req.Schedule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.ConcurrencyPolicy != nil {
+		if err := validateConcurrencyPolicy(*req.ConcurrencyPolicy); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.TimeZone != nil {
+		if err := validateTimeZone(*req.TimeZone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.Template != nil {
+		if err := validateSessionNext(req.Template.Next); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	gvr := GetScheduledSessionResource()
+	item, err := k8sDyn.Resource(gvr).Namespace(project).Get(context.TODO(), name, v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Scheduled session not found"})
+			return
+		}
+		log.Printf("Failed to get scheduled session %s in project %s: %v", name, project, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get scheduled session"})
+		return
+	}
+
+	spec, ok := item.Object["spec"].(map[string]interface{})
+	if !ok {
+		spec = map[string]interface{}{}
+		item.Object["spec"] = spec
+	}
+	if req.Schedule != nil {
+		spec["schedule"] = strings.TrimSpace(*req.Schedule)
+	}
+	if req.TimeZone != nil {
+		spec["timeZone"] = *req.TimeZone
+	}
+	if req.Suspend != nil {
+		spec["suspend"] = *req.Suspend
+	}
+	if req.ConcurrencyPolicy != nil {
+		spec["concurrencyPolicy"] = *req.ConcurrencyPolicy
+	}
+	if req.StartingDeadlineSeconds != nil {
+		spec["startingDeadlineSeconds"] = *req.StartingDeadlineSeconds
+	}
+	if req.SuccessfulSessionsHistoryLimit != nil {
+		spec["successfulSessionsHistoryLimit"] = int64(*req.SuccessfulSessionsHistoryLimit)
+	}
+	if req.FailedSessionsHistoryLimit != nil {
+		spec["failedSessionsHistoryLimit"] = int64(*req.FailedSessionsHistoryLimit)
+	}
+	if req.Template != nil {
+		spec["template"] = buildScheduledSessionTemplate(c, project, *req.Template)
+	}
+
+	updated, err := k8sDyn.Resource(gvr).Namespace(project).Update(context.TODO(), item, v1.UpdateOptions{})
+	if err != nil {
+		if errors.IsConflict(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Scheduled session was modified concurrently, retry the update"})
+			return
+		}
+		log.Printf("Failed to update scheduled session %s in project %s: %v", name, project, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update scheduled session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, parseScheduledSession(updated))
+}
+
+// DeleteScheduledSession handles DELETE /api/projects/:projectName/scheduled-sessions/:scheduleName.
+// Sessions created by the schedule are garbage collected through their OwnerReferences.
+func DeleteScheduledSession(c *gin.Context) {
+	project := c.GetString("project")
+	name := c.Param("scheduleName")
+	_, k8sDyn := GetK8sClientsForRequest(c)
+	if k8sDyn == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing token"})
+		c.Abort()
+		return
+	}
+
+	err := k8sDyn.Resource(GetScheduledSessionResource()).Namespace(project).Delete(context.TODO(), name, v1.DeleteOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Scheduled session not found"})
+			return
+		}
+		log.Printf("Failed to delete scheduled session %s in project %s: %v", name, project, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete scheduled session"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
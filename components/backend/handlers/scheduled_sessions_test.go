@@ -0,0 +1,187 @@
+//go:build test
+
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ambient-code-backend/tests/config"
+	test_constants "ambient-code-backend/tests/constants"
+	"ambient-code-backend/tests/test_utils"
+
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+var _ = Describe("Scheduled Sessions Handler", Label(test_constants.LabelUnit, test_constants.LabelHandlers, test_constants.LabelSessions), func() {
+	var (
+		httpUtils     *test_utils.HTTPTestUtils
+		k8sUtils      *test_utils.K8sTestUtils
+		testNamespace string
+	)
+
+	BeforeEach(func() {
+		httpUtils = test_utils.NewHTTPTestUtils()
+		k8sUtils = test_utils.NewK8sTestUtils(false, *config.TestNamespace)
+		SetupHandlerDependencies(k8sUtils)
+		testNamespace = "test-project-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	})
+
+	request := func(method, path string, body interface{}, params ...gin.Param) *gin.Context {
+		c := httpUtils.CreateTestGinContext(method, path, body)
+		c.Params = params
+		httpUtils.SetAuthHeader("test-token")
+		httpUtils.SetUserContext("alice", "Alice", "alice@example.com")
+		httpUtils.SetProjectContext(testNamespace)
+		return c
+	}
+	scheduleParam := func(name string) gin.Param {
+		return gin.Param{Key: "scheduleName", Value: name}
+	}
+	createSchedule := func(name string) {
+		CreateScheduledSession(request("POST", "/scheduled-sessions", map[string]interface{}{
+			"name":     name,
+			"schedule": "0 9 * * 1-5",
+			"template": map[string]interface{}{"initialPrompt": "Triage new issues"},
+		}))
+		httpUtils.AssertHTTPStatus(http.StatusCreated)
+	}
+
+	Describe("CreateScheduledSession", func() {
+		It("Should store the template with the CreateSession defaults", func() {
+			createSchedule("nightly")
+
+			obj, err := k8sUtils.DynamicClient.Resource(GetScheduledSessionResource()).Namespace(testNamespace).Get(context.Background(), "nightly", v1.GetOptions{})
+			Expect(err).NotTo(HaveOccurred())
+
+			model, _, _ := unstructured.NestedString(obj.Object, "spec", "template", "spec", "llmSettings", "model")
+			Expect(model).To(Equal("sonnet"))
+			timeout, _, _ := unstructured.NestedFieldNoCopy(obj.Object, "spec", "template", "spec", "timeout")
+			Expect(timeout).To(BeNumerically("==", 300))
+			userID, _, _ := unstructured.NestedString(obj.Object, "spec", "template", "spec", "userContext", "userId")
+			Expect(userID).To(Equal("alice"))
+			Expect(obj.GetAnnotations()).To(HaveKeyWithValue("ambient-code.io/created-by", "alice"))
+		})
+
+		It("Should reject an invalid cron expression", func() {
+			CreateScheduledSession(request("POST", "/scheduled-sessions", map[string]interface{}{
+				"schedule": "every monday",
+				"template": map[string]interface{}{},
+			}))
+			httpUtils.AssertHTTPStatus(http.StatusBadRequest)
+		})
+
+		It("Should reject an unknown concurrency policy", func() {
+			CreateScheduledSession(request("POST", "/scheduled-sessions", map[string]interface{}{
+				"schedule":          "@daily",
+				"concurrencyPolicy": "Queue",
+				"template":          map[string]interface{}{},
+			}))
+			httpUtils.AssertHTTPStatus(http.StatusBadRequest)
+		})
+
+		It("Should return 409 when the name is taken", func() {
+			createSchedule("nightly")
+			CreateScheduledSession(request("POST", "/scheduled-sessions", map[string]interface{}{
+				"name":     "nightly",
+				"schedule": "@daily",
+				"template": map[string]interface{}{},
+			}))
+			httpUtils.AssertHTTPStatus(http.StatusConflict)
+		})
+	})
+
+	Describe("ListScheduledSessions", func() {
+		It("Should return schedules sorted by name", func() {
+			createSchedule("weekly")
+			createSchedule("daily")
+
+			ListScheduledSessions(request("GET", "/scheduled-sessions", nil))
+			httpUtils.AssertHTTPStatus(http.StatusOK)
+
+			var response struct {
+				Items []struct {
+					Metadata map[string]interface{} `json:"metadata"`
+				} `json:"items"`
+			}
+			httpUtils.GetResponseJSON(&response)
+			Expect(response.Items).To(HaveLen(2))
+			Expect(response.Items[0].Metadata["name"]).To(Equal("daily"))
+		})
+	})
+
+	Describe("UpdateScheduledSession", func() {
+		It("Should change only the fields present in the request", func() {
+			createSchedule("nightly")
+
+			UpdateScheduledSession(request("PUT", "/scheduled-sessions/nightly", map[string]interface{}{"suspend": true}, scheduleParam("nightly")))
+			httpUtils.AssertHTTPStatus(http.StatusOK)
+
+			var response map[string]interface{}
+			httpUtils.GetResponseJSON(&response)
+			Expect(response["spec"]).To(HaveKeyWithValue("suspend", true))
+			Expect(response["spec"]).To(HaveKeyWithValue("schedule", "0 9 * * 1-5"))
+		})
+
+		It("Should return 404 for an unknown schedule", func() {
+			UpdateScheduledSession(request("PUT", "/scheduled-sessions/missing", map[string]interface{}{"suspend": true}, scheduleParam("missing")))
+			httpUtils.AssertHTTPStatus(http.StatusNotFound)
+		})
+
+		It("Should return 409 when the schedule changed concurrently", func() {
+			createSchedule("nightly")
+			DynamicClient = conflictingDynamicClient{DynamicClient}
+			UpdateScheduledSession(request("PUT", "/scheduled-sessions/nightly", map[string]interface{}{"suspend": true}, scheduleParam("nightly")))
+			httpUtils.AssertHTTPStatus(http.StatusConflict)
+		})
+	})
+
+	Describe("DeleteScheduledSession", func() {
+		It("Should delete the schedule and return 404 afterwards", func() {
+			createSchedule("nightly")
+
+			c := request("DELETE", "/scheduled-sessions/nightly", nil, scheduleParam("nightly"))
+			DeleteScheduledSession(c)
+			Expect(c.Writer.Status()).To(Equal(http.StatusNoContent))
+
+			GetScheduledSession(request("GET", "/scheduled-sessions/nightly", nil, scheduleParam("nightly")))
+			httpUtils.AssertHTTPStatus(http.StatusNotFound)
+		})
+	})
+})
+
+// conflictingDynamicClient fails every Update with a Conflict error, as the API
+// server does when the object changed since it was read.
+type conflictingDynamicClient struct{ dynamic.Interface }
+
+func (d conflictingDynamicClient) Resource(gvr schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return conflictingResource{d.Interface.Resource(gvr), gvr}
+}
+
+type conflictingResource struct {
+	dynamic.NamespaceableResourceInterface
+	gvr schema.GroupVersionResource
+}
+
+func (r conflictingResource) Namespace(ns string) dynamic.ResourceInterface {
+	return conflictingNamespacedResource{r.NamespaceableResourceInterface.Namespace(ns), r.gvr}
+}
+
+type conflictingNamespacedResource struct {
+	dynamic.ResourceInterface
+	gvr schema.GroupVersionResource
+}
+
+func (r conflictingNamespacedResource) Update(_ context.Context, obj *unstructured.Unstructured, _ v1.UpdateOptions, _ ...string) (*unstructured.Unstructured, error) {
+	return nil, errors.NewConflict(r.gvr.GroupResource(), obj.GetName(), fmt.Errorf("the object has been modified"))
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"ambient-code-backend/types"
+
+	"ambient-code-shared/artifacts"
+)
+
+// maxSessionChainSteps bounds how many follow-up sessions a single spec.next pipeline can declare
+const maxSessionChainSteps = 10
+
+// validateSessionNext checks a spec.next pipeline before it is stored on the session.
+// The operator re-validates artifact paths with the same shared rules when it creates each follow-up.
+func validateSessionNext(next *types.SessionNext) error {
+	for step := 1; next != nil; step++ {
+		if step > maxSessionChainSteps {
+			return fmt.Errorf("session chains are limited to %d follow-up sessions", maxSessionChainSteps)
+		}
+		if strings.TrimSpace(next.InitialPrompt) == "" {
+			return fmt.Errorf("next.initialPrompt is required (step %d)", step)
+		}
+		if _, err := artifacts.CleanPaths(next.Artifacts); err != nil {
+			return fmt.Errorf("%w (step %d)", err, step)
+		}
+		next = next.Next
+	}
+	return nil
+}
+
+// sessionNextToMap converts a SessionNext into the unstructured spec.next representation.
+func sessionNextToMap(next *types.SessionNext) map[string]interface{} {
+	if next == nil {
+		return nil
+	}
+	m := map[string]interface{}{
+		"initialPrompt": next.InitialPrompt,
+	}
+	if next.DisplayName != "" {
+		m["displayName"] = next.DisplayName
+	}
+	if next.LLMSettings != nil {
+		m["llmSettings"] = map[string]interface{}{
+			"model":       next.LLMSettings.Model,
+			"temperature": next.LLMSettings.Temperature,
+			"maxTokens":   next.LLMSettings.MaxTokens,
+		}
+	}
+	if next.Timeout != nil {
+		m["timeout"] = *next.Timeout
+	}
+	if next.Interactive != nil {
+		m["interactive"] = *next.Interactive
+	}
+	if next.CarryRepos != nil {
+		m["carryRepos"] = *next.CarryRepos
+	}
+	if len(next.Artifacts) > 0 {
+		artifacts := make([]interface{}, 0, len(next.Artifacts))
+		for _, a := range next.Artifacts {
+			artifacts = append(artifacts, a)
+		}
+		m["artifacts"] = artifacts
+	}
+	if next.Next != nil {
+		m["next"] = sessionNextToMap(next.Next)
+	}
+	return m
+}
+
+// parseSessionNext parses the unstructured spec.next of an AgenticSession.
+func parseSessionNext(m map[string]interface{}) *types.SessionNext {
+	next := &types.SessionNext{}
+	if prompt, ok := m["initialPrompt"].(string); ok {
+		next.InitialPrompt = prompt
+	}
+	if displayName, ok := m["displayName"].(string); ok {
+		next.DisplayName = displayName
+	}
+	if llmSettings, ok := m["llmSettings"].(map[string]interface{}); ok {
+		next.LLMSettings = &types.LLMSettings{}
+		if model, ok := llmSettings["model"].(string); ok {
+			next.LLMSettings.Model = model
+		}
+		if temperature, ok := llmSettings["temperature"].(float64); ok {
+			next.LLMSettings.Temperature = temperature
+		}
+		if maxTokens, ok := llmSettings["maxTokens"].(float64); ok {
+			next.LLMSettings.MaxTokens = int(maxTokens)
+		}
+	}
+	if timeout, ok := m["timeout"].(float64); ok {
+		t := int(timeout)
+		next.Timeout = &t
+	}
+	if interactive, ok := m["interactive"].(bool); ok {
+		next.Interactive = types.BoolPtr(interactive)
+	}
+	if carryRepos, ok := m["carryRepos"].(bool); ok {
+		next.CarryRepos = types.BoolPtr(carryRepos)
+	}
+	if artifacts, ok := m["artifacts"].([]interface{}); ok {
+		for _, a := range artifacts {
+			if s, ok := a.(string); ok {
+				next.Artifacts = append(next.Artifacts, s)
+			}
+		}
+	}
+	if following, ok := m["next"].(map[string]interface{}); ok {
+		next.Next = parseSessionNext(following)
+	}
+	return next
+}
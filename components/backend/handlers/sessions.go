@@ -14,6 +14,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -44,6 +45,13 @@ var (
 	// LEGACY: SendMessageToSession removed - AG-UI server uses HTTP/SSE instead of WebSocket
 )
 
+// EventChainGenesisAnnotation records the seq a session's event log chains
+// from (see eventstore.VerifyChain).  It is set when the session is
+// created, so a log whose hashes were stripped fails verification instead
+// of passing as one written before chaining existed.  Callers cannot set
+// or change it.
+const EventChainGenesisAnnotation = "ambient-code.io/event-chain-genesis"
+
 // ootbWorkflowsCache provides in-memory caching for OOTB workflows to avoid GitHub API rate limits.
 // The cache stores workflows by repo URL key and expires after ootbCacheTTL.
 type ootbWorkflowsCache struct {
@@ -122,6 +130,18 @@ func parseSpec(spec map[string]interface{}) types.AgenticSessionSpec {
 		result.Timeout = int(timeout)
 	}
 
+	if priority, ok := spec["priority"].(float64); ok {
+		result.Priority = int(priority)
+	}
+
+	switch ttl := spec["ttlSecondsAfterFinished"].(type) {
+	case int64:
+		result.TTLSecondsAfterFinished = &ttl
+	case float64:
+		seconds := int64(ttl)
+		result.TTLSecondsAfterFinished = &seconds
+	}
+
 	if llmSettings, ok := spec["llmSettings"].(map[string]interface{}); ok {
 		if model, ok := llmSettings["model"].(string); ok {
 			result.LLMSettings.Model = model
@@ -207,6 +227,10 @@ func parseSpec(spec map[string]interface{}) types.AgenticSessionSpec {
 		result.ActiveWorkflow = ws
 	}
 
+	if next, ok := spec["next"].(map[string]interface{}); ok {
+		result.Next = parseSessionNext(next)
+	}
+
 	return result
 }
 
@@ -360,6 +384,8 @@ func parseStatus(status map[string]interface{}) *types.AgenticSessionStatus {
 		}
 	}
 
+	result.Usage = ParseSessionUsage(status["usage"])
+
 	return result
 }
 
@@ -541,33 +567,61 @@ func CreateSession(c *gin.Context) {
 
 	// Validation for multi-repo can be added here if needed
 
-	// Set defaults for LLM settings if not provided
-	llmSettings := types.LLMSettings{
-		Model:       "sonnet",
-		Temperature: 0.7,
-		MaxTokens:   4000,
+	if err := validateSessionNext(req.Next); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	if req.LLMSettings != nil {
-		if req.LLMSettings.Model != "" {
-			llmSettings.Model = req.LLMSettings.Model
+
+	// A retried request with the same Idempotency-Key returns the session created
+	// by the first one instead of creating a duplicate.
+	key, err := idempotencyKey(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var payloadHash string
+	if key != "" {
+		if payloadHash, err = idempotencyPayloadHash(req); err != nil {
+			log.Printf("Failed to hash create request in project %s: %v", project, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create agentic session"})
+			return
 		}
-		if req.LLMSettings.Temperature != 0 {
-			llmSettings.Temperature = req.LLMSettings.Temperature
+		existing, err := findIdempotentSession(c.Request.Context(), k8sDyn, project, c.GetString("userID"), key, time.Now())
+		if err != nil {
+			log.Printf("Failed to look up Idempotency-Key in project %s: %v", project, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create agentic session"})
+			return
 		}
-		if req.LLMSettings.MaxTokens != 0 {
-			llmSettings.MaxTokens = req.LLMSettings.MaxTokens
+		if existing != nil {
+			replayIdempotentSession(c, existing, payloadHash)
+			return
 		}
 	}
 
-	timeout := 300
-	if req.Timeout != nil {
-		timeout = *req.Timeout
+	// Reject new sessions once the project's token/cost budget is used up.
+	// Lookup failures are logged and do not block session creation.
+	if exceeded, err := checkProjectBudget(c.Request.Context(), k8sDyn, project, time.Now()); err != nil {
+		log.Printf("Budget check failed for project %s: %v", project, err)
+	} else if exceeded != nil {
+		if exceeded.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(exceeded.RetryAfter.Seconds())))
+		}
+		c.JSON(exceeded.StatusCode, gin.H{
+			"error":  exceeded.Message,
+			"reason": "BudgetExceeded",
+			"period": exceeded.Period,
+		})
+		return
 	}
 
 	// Generate unique name (timestamp-based)
 	// Note: Runner will create branch as "ambient/{session-name}"
+	// A key names the session after itself so concurrent retries cannot both create one.
 	timestamp := time.Now().Unix()
 	name := fmt.Sprintf("session-%d", timestamp)
+	if key != "" {
+		name = idempotentSessionName(c.GetString("userID"), key, time.Now())
+	}
 
 	// Create the custom resource
 	// Metadata
@@ -590,19 +644,16 @@ func CreateSession(c *gin.Context) {
 		metadata["annotations"] = annotations
 	}
 
-	spec := map[string]interface{}{
-		"displayName": req.DisplayName,
-		"project":     project,
-		"llmSettings": map[string]interface{}{
-			"model":       llmSettings.Model,
-			"temperature": llmSettings.Temperature,
-			"maxTokens":   llmSettings.MaxTokens,
-		},
-		"timeout": timeout,
+	if key != "" {
+		setIdempotencyMetadata(metadata, c.GetString("userID"), key, payloadHash)
 	}
-	if strings.TrimSpace(req.InitialPrompt) != "" {
-		spec["initialPrompt"] = req.InitialPrompt
+	// New logs are chained from their first event
+	if metadata["annotations"] == nil {
+		metadata["annotations"] = make(map[string]interface{})
 	}
+	metadata["annotations"].(map[string]interface{})[EventChainGenesisAnnotation] = "1"
+
+	spec := newSessionSpec(project, req)
 
 	session := map[string]interface{}{
 		"apiVersion": "vteam.ambient-code/v1alpha1",
@@ -666,36 +717,8 @@ func CreateSession(c *gin.Context) {
 	}
 
 	// Add userContext derived from authenticated caller; ignore client-supplied userId
-	{
-		uidVal, _ := c.Get("userID")
-		uid, _ := uidVal.(string)
-		uid = strings.TrimSpace(uid)
-		if uid != "" {
-			displayName := ""
-			if v, ok := c.Get("userName"); ok {
-				if s, ok2 := v.(string); ok2 {
-					displayName = s
-				}
-			}
-			groups := []string{}
-			if v, ok := c.Get("userGroups"); ok {
-				if gg, ok2 := v.([]string); ok2 {
-					groups = gg
-				}
-			}
-			// Fallbacks for non-identity fields only
-			if displayName == "" && req.UserContext != nil {
-				displayName = req.UserContext.DisplayName
-			}
-			if len(groups) == 0 && req.UserContext != nil {
-				groups = req.UserContext.Groups
-			}
-			session["spec"].(map[string]interface{})["userContext"] = map[string]interface{}{
-				"userId":      uid,
-				"displayName": displayName,
-				"groups":      groups,
-			}
-		}
+	if userContext := callerUserContext(c, req.UserContext); userContext != nil {
+		session["spec"].(map[string]interface{})["userContext"] = userContext
 	}
 
 	gvr := GetAgenticSessionV1Alpha1Resource()
@@ -703,6 +726,14 @@ func CreateSession(c *gin.Context) {
 
 	// Create AgenticSession using user token (enforces user RBAC permissions)
 	created, err := k8sDyn.Resource(gvr).Namespace(project).Create(context.TODO(), obj, v1.CreateOptions{})
+	if errors.IsAlreadyExists(err) && key != "" {
+		// A concurrent retry with the same key created it first
+		existing, getErr := k8sDyn.Resource(gvr).Namespace(project).Get(context.TODO(), name, v1.GetOptions{})
+		if getErr == nil && existing.GetAnnotations()[idempotencyKeyAnnotation] == key {
+			replayIdempotentSession(c, existing, payloadHash)
+			return
+		}
+	}
 	if err != nil {
 		log.Printf("Failed to create agentic session in project %s: %v", project, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create agentic session"})
@@ -744,6 +775,147 @@ func CreateSession(c *gin.Context) {
 	})
 }
 
+// newSessionSpec builds the AgenticSession spec fields shared by CreateSession and
+// ScheduledSession templates, applying the default LLM settings and timeout.
+func newSessionSpec(project string, req types.CreateAgenticSessionRequest) map[string]interface{} {
+	llmSettings := types.LLMSettings{
+		Model:       "sonnet",
+		Temperature: 0.7,
+		MaxTokens:   4000,
+	}
+	if req.LLMSettings != nil {
+		if req.LLMSettings.Model != "" {
+			llmSettings.Model = req.LLMSettings.Model
+		}
+		if req.LLMSettings.Temperature != 0 {
+			llmSettings.Temperature = req.LLMSettings.Temperature
+		}
+		if req.LLMSettings.MaxTokens != 0 {
+			llmSettings.MaxTokens = req.LLMSettings.MaxTokens
+		}
+	}
+
+	timeout := 300
+	if req.Timeout != nil {
+		timeout = *req.Timeout
+	}
+
+	spec := map[string]interface{}{
+		"displayName": req.DisplayName,
+		"project":     project,
+		"llmSettings": map[string]interface{}{
+			"model":       llmSettings.Model,
+			"temperature": llmSettings.Temperature,
+			"maxTokens":   llmSettings.MaxTokens,
+		},
+		"timeout": timeout,
+	}
+	if strings.TrimSpace(req.InitialPrompt) != "" {
+		spec["initialPrompt"] = req.InitialPrompt
+	}
+	if req.Priority != nil {
+		spec["priority"] = *req.Priority
+	}
+	if req.TTLSecondsAfterFinished != nil {
+		spec["ttlSecondsAfterFinished"] = *req.TTLSecondsAfterFinished
+	}
+	if req.Next != nil {
+		spec["next"] = sessionNextToMap(req.Next)
+	}
+	return spec
+}
+
+// ImportedSessionSpec rebuilds a session spec read from an export the way
+// CreateSession builds one from a request: only the fields a create request
+// accepts are kept, with the same validation and defaults, and userContext is
+// always the caller's.  Repos without a branch get the one of session name.
+func ImportedSessionSpec(c *gin.Context, project, name string, exported map[string]interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(exported)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sessionSpec: %v", err)
+	}
+	var req types.CreateAgenticSessionRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, fmt.Errorf("invalid sessionSpec: %v", err)
+	}
+	if err := validateSessionNext(req.Next); err != nil {
+		return nil, fmt.Errorf("invalid sessionSpec: %v", err)
+	}
+
+	spec := newSessionSpec(project, req)
+	if req.Interactive != nil {
+		spec["interactive"] = *req.Interactive
+	}
+	if len(req.EnvironmentVariables) > 0 {
+		envVars := make(map[string]interface{}, len(req.EnvironmentVariables))
+		for k, v := range req.EnvironmentVariables {
+			envVars[k] = v
+		}
+		spec["environmentVariables"] = envVars
+	}
+	if len(req.Repos) > 0 {
+		arr := make([]interface{}, 0, len(req.Repos))
+		for _, r := range req.Repos {
+			m := map[string]interface{}{"url": r.URL, "branch": ComputeAutoBranch(name)}
+			if r.Branch != nil && strings.TrimSpace(*r.Branch) != "" {
+				m["branch"] = *r.Branch
+			}
+			if r.AutoPush != nil {
+				m["autoPush"] = *r.AutoPush
+			}
+			arr = append(arr, m)
+		}
+		spec["repos"] = arr
+	}
+	// The exported userContext names whoever ran the original session
+	if userContext := callerUserContext(c, nil); userContext != nil {
+		spec["userContext"] = userContext
+	}
+	return spec, nil
+}
+
+// CallerUserContext is the spec.userContext of a session the caller creates
+// from another's spec, or nil when there is no caller identity.
+func CallerUserContext(c *gin.Context) map[string]interface{} {
+	return callerUserContext(c, nil)
+}
+
+// callerUserContext builds the spec.userContext map from the authenticated caller.
+// The userId always comes from the auth token; the client-supplied context is only
+// used as a fallback for display name and groups. Returns nil when there is no caller identity.
+func callerUserContext(c *gin.Context, fallback *types.UserContext) map[string]interface{} {
+	uidVal, _ := c.Get("userID")
+	uid, _ := uidVal.(string)
+	uid = strings.TrimSpace(uid)
+	if uid == "" {
+		return nil
+	}
+	displayName := ""
+	if v, ok := c.Get("userName"); ok {
+		if s, ok2 := v.(string); ok2 {
+			displayName = s
+		}
+	}
+	groups := []string{}
+	if v, ok := c.Get("userGroups"); ok {
+		if gg, ok2 := v.([]string); ok2 {
+			groups = gg
+		}
+	}
+	// Fallbacks for non-identity fields only
+	if displayName == "" && fallback != nil {
+		displayName = fallback.DisplayName
+	}
+	if len(groups) == 0 && fallback != nil {
+		groups = fallback.Groups
+	}
+	return map[string]interface{}{
+		"userId":      uid,
+		"displayName": displayName,
+		"groups":      groups,
+	}
+}
+
 func GetSession(c *gin.Context) {
 	project := c.GetString("project")
 	sessionName := c.Param("sessionName")
@@ -946,6 +1118,9 @@ func PatchSession(c *gin.Context) {
 				anns = map[string]interface{}{}
 			}
 			for k, v := range annsPatch {
+				if k == EventChainGenesisAnnotation {
+					continue
+				}
 				anns[k] = v
 			}
 			_ = unstructured.SetNestedMap(metadata, anns, "annotations")
@@ -1989,6 +2164,9 @@ func CloneSession(c *gin.Context) {
 		"metadata": map[string]interface{}{
 			"name":      finalName,
 			"namespace": req.TargetProject,
+			"annotations": map[string]interface{}{
+				EventChainGenesisAnnotation: "1",
+			},
 		},
 		"spec": sourceItem.Object["spec"],
 		"status": map[string]interface{}{
@@ -408,6 +408,95 @@ var _ = Describe("Sessions Handler", Label(test_constants.LabelUnit, test_consta
 				httpUtils.AssertHTTPStatus(http.StatusCreated)
 			})
 		})
+
+		Context("When an Idempotency-Key is sent", func() {
+			createAsUser := func(userID, key string, request map[string]interface{}) map[string]interface{} {
+				httpUtils = test_utils.NewHTTPTestUtils()
+				context := httpUtils.CreateTestGinContext("POST", "/api/projects/"+testNamespace+"/agentic-sessions", request)
+				context.Request.Header.Set(IdempotencyKeyHeader, key)
+				context.Set("userID", userID)
+				httpUtils.SetAuthHeader(testToken)
+				httpUtils.SetProjectContext(testNamespace)
+
+				CreateSession(context)
+
+				var response map[string]interface{}
+				httpUtils.GetResponseJSON(&response)
+				return response
+			}
+			createWithKey := func(key string, request map[string]interface{}) map[string]interface{} {
+				return createAsUser("test-user", key, request)
+			}
+
+			It("Should return the existing session when the request is retried", func() {
+				request := map[string]interface{}{"initialPrompt": "Run the nightly checks"}
+
+				first := createWithKey("ci-run-42", request)
+				httpUtils.AssertHTTPStatus(http.StatusCreated)
+
+				second := createWithKey("ci-run-42", request)
+				httpUtils.AssertHTTPStatus(http.StatusOK)
+				Expect(httpUtils.GetResponseRecorder().Header().Get("Idempotent-Replayed")).To(Equal("true"))
+				Expect(second["name"]).To(Equal(first["name"]))
+
+				list, err := k8sUtils.DynamicClient.Resource(sessionGVR).Namespace(testNamespace).List(ctx, v1.ListOptions{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(list.Items).To(HaveLen(1))
+				Expect(list.Items[0].GetAnnotations()).To(HaveKeyWithValue("ambient-code.io/idempotency-key", "ci-run-42"))
+			})
+
+			It("Should reject a reused key with a different payload", func() {
+				createWithKey("ci-run-43", map[string]interface{}{"initialPrompt": "First prompt"})
+				httpUtils.AssertHTTPStatus(http.StatusCreated)
+
+				response := createWithKey("ci-run-43", map[string]interface{}{"initialPrompt": "Second prompt"})
+				httpUtils.AssertHTTPStatus(http.StatusConflict)
+				Expect(response["error"]).To(ContainSubstring("Idempotency-Key"))
+			})
+
+			It("Should not return another user's session for the same key", func() {
+				request := map[string]interface{}{"initialPrompt": "Run the nightly checks"}
+
+				first := createAsUser("alice", "shared-key", request)
+				httpUtils.AssertHTTPStatus(http.StatusCreated)
+
+				second := createAsUser("bob", "shared-key", request)
+				httpUtils.AssertHTTPStatus(http.StatusCreated)
+				Expect(second["name"]).NotTo(Equal(first["name"]))
+			})
+
+			It("Should replay a session created by a concurrent retry", func() {
+				request := map[string]interface{}{"initialPrompt": "Run the nightly checks"}
+				payloadHash, err := idempotencyPayloadHash(types.CreateAgenticSessionRequest{InitialPrompt: "Run the nightly checks"})
+				Expect(err).NotTo(HaveOccurred())
+
+				// The concurrent request created the session after this one's lookup
+				name := idempotentSessionName("test-user", "ci-run-44", time.Now())
+				concurrent := &unstructured.Unstructured{Object: map[string]interface{}{
+					"apiVersion": "vteam.ambient-code/v1alpha1",
+					"kind":       "AgenticSession",
+					"metadata": map[string]interface{}{
+						"name":      name,
+						"namespace": testNamespace,
+						"annotations": map[string]interface{}{
+							idempotencyKeyAnnotation:     "ci-run-44",
+							idempotencyPayloadAnnotation: payloadHash,
+						},
+					},
+				}}
+				_, err = k8sUtils.DynamicClient.Resource(sessionGVR).Namespace(testNamespace).Create(ctx, concurrent, v1.CreateOptions{})
+				Expect(err).NotTo(HaveOccurred())
+
+				response := createWithKey("ci-run-44", request)
+				httpUtils.AssertHTTPStatus(http.StatusOK)
+				Expect(response["name"]).To(Equal(name))
+			})
+
+			It("Should reject keys with non-printable characters", func() {
+				createWithKey("bad\tkey", map[string]interface{}{"initialPrompt": "Test prompt"})
+				httpUtils.AssertHTTPStatus(http.StatusBadRequest)
+			})
+		})
 	})
 
 	Describe("GetSession", func() {
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"ambient-code-backend/types"
+	"ambient-code-shared/budget"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// UsageDayFormat is the key format of status.usage.daily buckets (UTC days)
+	UsageDayFormat = "2006-01-02"
+	// usageMonthFormat is the key format of status.usage.monthly buckets
+	usageMonthFormat = "2006-01"
+	// defaultUsageWindowDays is the range used when no from query param is given
+	defaultUsageWindowDays = 30
+	// UnknownUsageKey is reported for usage with no user or model
+	UnknownUsageKey = budget.UnknownKey
+)
+
+// ParseSessionUsage converts status.usage from its unstructured form.
+// Returns nil when the session has no usage recorded.
+func ParseSessionUsage(v interface{}) *types.SessionUsage {
+	m, ok := v.(map[string]interface{})
+	if !ok || len(m) == 0 {
+		return nil
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil
+	}
+	var usage types.SessionUsage
+	if err := json.Unmarshal(b, &usage); err != nil {
+		log.Printf("Ignoring malformed session usage: %v", err)
+		return nil
+	}
+	return &usage
+}
+
+// GetProjectUsage handles GET /api/projects/:projectName/usage
+// Optional query params: from and to (YYYY-MM-DD or RFC3339, inclusive, UTC days).
+// Defaults to the last 30 days.
+func GetProjectUsage(c *gin.Context) {
+	project := c.GetString("project")
+	_, k8sDyn := GetK8sClientsForRequest(c)
+	if k8sDyn == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing token"})
+		c.Abort()
+		return
+	}
+
+	from, to, msg := parseUsageRange(c.Query("from"), c.Query("to"), time.Now().UTC())
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Usage of deleted sessions, kept by the operator in ProjectSettings
+	var ledger map[string]interface{}
+	settings, err := k8sDyn.Resource(GetProjectSettingsResource()).Namespace(project).Get(ctx, "projectsettings", v1.GetOptions{})
+	if err == nil {
+		ledger, _, _ = unstructured.NestedMap(settings.Object, "status", "usageLedger")
+	} else if !errors.IsNotFound(err) {
+		log.Printf("Failed to read project settings of %s: %v", project, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read project settings"})
+		return
+	}
+
+	gvr := GetAgenticSessionV1Alpha1Resource()
+	list, err := k8sDyn.Resource(gvr).Namespace(project).List(ctx, v1.ListOptions{})
+	if err != nil {
+		log.Printf("Failed to list agentic sessions in project %s: %v", project, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list agentic sessions"})
+		return
+	}
+
+	resp := aggregateProjectUsage(list.Items, ledger, from, to)
+	resp.Project = project
+	c.JSON(http.StatusOK, resp)
+}
+
+// parseUsageRange returns the inclusive [from, to] day keys, or an error message.
+func parseUsageRange(fromParam, toParam string, now time.Time) (string, string, string) {
+	to := now.Format(UsageDayFormat)
+	if toParam != "" {
+		day, ok := parseUsageDay(toParam)
+		if !ok {
+			return "", "", "Invalid to: expected YYYY-MM-DD or RFC3339"
+		}
+		to = day
+	}
+	from := now.AddDate(0, 0, -(defaultUsageWindowDays - 1)).Format(UsageDayFormat)
+	if fromParam != "" {
+		day, ok := parseUsageDay(fromParam)
+		if !ok {
+			return "", "", "Invalid from: expected YYYY-MM-DD or RFC3339"
+		}
+		from = day
+	}
+	if from > to {
+		return "", "", "from must not be after to"
+	}
+	return from, to, ""
+}
+
+func parseUsageDay(v string) (string, bool) {
+	if t, err := time.Parse(UsageDayFormat, v); err == nil {
+		return t.Format(UsageDayFormat), true
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t.UTC().Format(UsageDayFormat), true
+	}
+	return "", false
+}
+
+// aggregateProjectUsage sums the usage buckets of all sessions, and of the
+// deleted sessions in the usage ledger, that fall within [from, to] and rolls
+// them up per user, per model and per day. Monthly buckets, which hold days past
+// the daily retention, are counted whole when their month overlaps the range.
+// Sessions counts the sessions that still exist.
+func aggregateProjectUsage(items []unstructured.Unstructured, ledger map[string]interface{}, from, to string) types.ProjectUsageResponse {
+	resp := types.ProjectUsageResponse{From: from, To: to}
+	byUser := map[string]types.TokenUsage{}
+	byModel := map[string]types.TokenUsage{}
+	byDay := map[string]types.TokenUsage{}
+
+	// addUsage rolls up the buckets of usage within the range and returns their
+	// total. Buckets without a model split are credited to sessionModel.
+	addUsage := func(usage *types.SessionUsage, sessionModel string) types.TokenUsage {
+		var total types.TokenUsage
+		addBucket := func(key string, bucket types.UsageBucket) {
+			total.Add(bucket.TokenUsage)
+			addRollup(byDay, key, bucket.TokenUsage)
+			if len(bucket.Models) == 0 {
+				addRollup(byModel, sessionModel, bucket.TokenUsage)
+				return
+			}
+			for model, modelUsage := range bucket.Models {
+				addRollup(byModel, model, modelUsage)
+			}
+		}
+		for day, bucket := range usage.Daily {
+			if day >= from && day <= to {
+				addBucket(day, bucket)
+			}
+		}
+		for month, bucket := range usage.Monthly {
+			if month >= from[:len(usageMonthFormat)] && month <= to[:len(usageMonthFormat)] {
+				addBucket(month, bucket)
+			}
+		}
+		return total
+	}
+
+	for _, item := range items {
+		raw, found, _ := unstructured.NestedMap(item.Object, "status", "usage")
+		if !found {
+			continue
+		}
+		usage := ParseSessionUsage(raw)
+		if usage == nil {
+			continue
+		}
+
+		user, _, _ := unstructured.NestedString(item.Object, "spec", "userContext", "userId")
+		if user == "" {
+			user = UnknownUsageKey
+		}
+		// Buckets without a model split are credited to the session's model
+		sessionModel, _, _ := unstructured.NestedString(item.Object, "spec", "llmSettings", "model")
+		if sessionModel == "" {
+			sessionModel = UnknownUsageKey
+		}
+
+		sessionTotal := addUsage(usage, sessionModel)
+		if sessionTotal.IsZero() {
+			continue
+		}
+
+		resp.Sessions++
+		resp.Total.Add(sessionTotal)
+		addRollup(byUser, user, sessionTotal)
+	}
+
+	for user, entry := range ledger {
+		usage := ParseSessionUsage(entry)
+		if usage == nil {
+			continue
+		}
+		// The ledger splits every bucket by model
+		total := addUsage(usage, UnknownUsageKey)
+		if total.IsZero() {
+			continue
+		}
+		resp.Total.Add(total)
+		addRollup(byUser, user, total)
+	}
+
+	resp.ByUser = sortedRollups(byUser)
+	resp.ByModel = sortedRollups(byModel)
+	resp.ByDay = sortedRollups(byDay)
+	// Days read best in chronological order
+	sort.Slice(resp.ByDay, func(i, j int) bool { return resp.ByDay[i].Key < resp.ByDay[j].Key })
+	return resp
+}
+
+func addRollup(m map[string]types.TokenUsage, key string, usage types.TokenUsage) {
+	total := m[key]
+	total.Add(usage)
+	m[key] = total
+}
+
+// sortedRollups orders rollups by cost, then total tokens, descending.
+func sortedRollups(m map[string]types.TokenUsage) []types.UsageRollup {
+	rollups := make([]types.UsageRollup, 0, len(m))
+	for k, u := range m {
+		rollups = append(rollups, types.UsageRollup{Key: k, Usage: u})
+	}
+	sort.Slice(rollups, func(i, j int) bool {
+		a, b := rollups[i].Usage, rollups[j].Usage
+		if a.CostUSD != b.CostUSD {
+			return a.CostUSD > b.CostUSD
+		}
+		at := a.InputTokens + a.OutputTokens + a.CacheReadInputTokens + a.CacheCreationInputTokens
+		bt := b.InputTokens + b.OutputTokens + b.CacheReadInputTokens + b.CacheCreationInputTokens
+		if at != bt {
+			return at > bt
+		}
+		return rollups[i].Key < rollups[j].Key
+	})
+	return rollups
+}
@@ -0,0 +1,197 @@
+//go:build test
+
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ambient-code-backend/tests/config"
+	test_constants "ambient-code-backend/tests/constants"
+	"ambient-code-backend/tests/test_utils"
+	"ambient-code-backend/types"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("Project Usage Handler", Label(test_constants.LabelUnit, test_constants.LabelHandlers, test_constants.LabelSessions), func() {
+	var (
+		httpUtils     *test_utils.HTTPTestUtils
+		k8sUtils      *test_utils.K8sTestUtils
+		testNamespace string
+	)
+
+	BeforeEach(func() {
+		httpUtils = test_utils.NewHTTPTestUtils()
+		k8sUtils = test_utils.NewK8sTestUtils(false, *config.TestNamespace)
+		SetupHandlerDependencies(k8sUtils)
+		testNamespace = "test-project-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	})
+
+	bucket := func(cost float64, input int64, models map[string]interface{}) map[string]interface{} {
+		b := map[string]interface{}{"costUsd": cost, "inputTokens": input, "outputTokens": int64(0), "runs": int64(1)}
+		if models != nil {
+			b["models"] = models
+		}
+		return b
+	}
+	createSession := func(name, user, model string, usage map[string]interface{}) {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "vteam.ambient-code/v1alpha1",
+			"kind":       "AgenticSession",
+			"metadata":   map[string]interface{}{"name": name, "namespace": testNamespace},
+			"spec": map[string]interface{}{
+				"userContext": map[string]interface{}{"userId": user},
+				"llmSettings": map[string]interface{}{"model": model},
+			},
+			"status": map[string]interface{}{"usage": usage},
+		}}
+		_, err := k8sUtils.DynamicClient.Resource(GetAgenticSessionV1Alpha1Resource()).Namespace(testNamespace).Create(context.Background(), obj, v1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+	}
+	getUsage := func(query string) {
+		c := httpUtils.CreateTestGinContext("GET", "/api/projects/"+testNamespace+"/usage"+query, nil)
+		httpUtils.SetAuthHeader("test-token")
+		httpUtils.SetProjectContext(testNamespace)
+		GetProjectUsage(c)
+	}
+
+	Describe("GetProjectUsage", func() {
+		BeforeEach(func() {
+			createSession("split", "alice", "sonnet", map[string]interface{}{
+				"daily": map[string]interface{}{
+					"2026-03-01": bucket(3, 300, map[string]interface{}{
+						"claude-sonnet-4-5": map[string]interface{}{"costUsd": 2.0, "inputTokens": int64(200), "runs": int64(1)},
+						"claude-haiku-4-5":  map[string]interface{}{"costUsd": 1.0, "inputTokens": int64(100), "runs": int64(1)},
+					}),
+					"2026-03-20": bucket(5, 500, nil), // outside the range
+				},
+				"monthly": map[string]interface{}{
+					"2026-02": bucket(7, 700, map[string]interface{}{
+						"claude-opus-4-1": map[string]interface{}{"costUsd": 7.0, "inputTokens": int64(700), "runs": int64(1)},
+					}),
+				},
+			})
+			createSession("legacy", "bob", "opus", map[string]interface{}{
+				"daily": map[string]interface{}{"2026-03-02": bucket(1, 100, nil)},
+			})
+			createSession("idle", "carol", "sonnet", nil)
+		})
+
+		It("Should roll up usage per user, model and day in the range", func() {
+			getUsage("?from=2026-02-15&to=2026-03-10")
+			httpUtils.AssertHTTPStatus(http.StatusOK)
+
+			var response types.ProjectUsageResponse
+			httpUtils.GetResponseJSON(&response)
+			Expect(response.Project).To(Equal(testNamespace))
+			Expect(response.From).To(Equal("2026-02-15"))
+			Expect(response.To).To(Equal("2026-03-10"))
+			Expect(response.Sessions).To(Equal(2))
+			Expect(response.Total.CostUSD).To(BeNumerically("~", 11))
+			Expect(response.Total.InputTokens).To(BeNumerically("==", 1100))
+
+			models := map[string]float64{}
+			for _, r := range response.ByModel {
+				models[r.Key] = r.Usage.CostUSD
+			}
+			Expect(models).To(HaveLen(4))
+			Expect(models["claude-opus-4-1"]).To(BeNumerically("~", 7))
+			Expect(models["claude-sonnet-4-5"]).To(BeNumerically("~", 2))
+			Expect(models["claude-haiku-4-5"]).To(BeNumerically("~", 1))
+			Expect(models["opus"]).To(BeNumerically("~", 1), "buckets without a split go to the session model")
+
+			days := []string{}
+			for _, r := range response.ByDay {
+				days = append(days, r.Key)
+			}
+			Expect(days).To(Equal([]string{"2026-02", "2026-03-01", "2026-03-02"}))
+			Expect(response.ByUser[0].Key).To(Equal("alice"))
+		})
+
+		It("Should count the usage of deleted sessions from the usage ledger", func() {
+			settings := &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "vteam.ambient-code/v1alpha1",
+				"kind":       "ProjectSettings",
+				"metadata":   map[string]interface{}{"name": "projectsettings", "namespace": testNamespace},
+				"status": map[string]interface{}{"usageLedger": map[string]interface{}{
+					"dave": map[string]interface{}{"daily": map[string]interface{}{
+						"2026-03-03": bucket(4, 400, map[string]interface{}{
+							"claude-haiku-4-5": map[string]interface{}{"costUsd": 4.0, "inputTokens": int64(400), "runs": int64(1)},
+						}),
+					}},
+				}},
+			}}
+			_, err := k8sUtils.DynamicClient.Resource(GetProjectSettingsResource()).Namespace(testNamespace).Create(context.Background(), settings, v1.CreateOptions{})
+			Expect(err).NotTo(HaveOccurred())
+
+			getUsage("?from=2026-02-15&to=2026-03-10")
+			httpUtils.AssertHTTPStatus(http.StatusOK)
+
+			var response types.ProjectUsageResponse
+			httpUtils.GetResponseJSON(&response)
+			Expect(response.Sessions).To(Equal(2), "deleted sessions are not counted as sessions")
+			Expect(response.Total.CostUSD).To(BeNumerically("~", 15))
+			users := map[string]float64{}
+			for _, r := range response.ByUser {
+				users[r.Key] = r.Usage.CostUSD
+			}
+			Expect(users["dave"]).To(BeNumerically("~", 4))
+			models := map[string]float64{}
+			for _, r := range response.ByModel {
+				models[r.Key] = r.Usage.CostUSD
+			}
+			Expect(models["claude-haiku-4-5"]).To(BeNumerically("~", 5))
+		})
+
+		It("Should return empty rollups when no usage falls in the range", func() {
+			getUsage("?from=2025-01-01&to=2025-01-31")
+			httpUtils.AssertHTTPStatus(http.StatusOK)
+
+			var response types.ProjectUsageResponse
+			httpUtils.GetResponseJSON(&response)
+			Expect(response.Sessions).To(Equal(0))
+			Expect(response.ByModel).To(BeEmpty())
+		})
+
+		It("Should reject an invalid range", func() {
+			getUsage("?from=2026-03-10&to=2026-03-01")
+			httpUtils.AssertHTTPStatus(http.StatusBadRequest)
+		})
+	})
+
+	Describe("parseUsageRange", func() {
+		now := time.Date(2026, 3, 15, 18, 0, 0, 0, time.UTC)
+
+		It("Should default to the last 30 days", func() {
+			from, to, msg := parseUsageRange("", "", now)
+			Expect(msg).To(BeEmpty())
+			Expect(from).To(Equal("2026-02-14"))
+			Expect(to).To(Equal("2026-03-15"))
+		})
+
+		It("Should accept RFC3339 timestamps as UTC days", func() {
+			from, to, msg := parseUsageRange("2026-03-01T23:30:00-02:00", "2026-03-05T00:00:00Z", now)
+			Expect(msg).To(BeEmpty())
+			Expect(from).To(Equal("2026-03-02"))
+			Expect(to).To(Equal("2026-03-05"))
+		})
+
+		It("Should reject malformed dates", func() {
+			_, _, msg := parseUsageRange("03/01/2026", "", now)
+			Expect(msg).To(ContainSubstring("Invalid from"))
+			_, _, msg = parseUsageRange("", "tomorrow", now)
+			Expect(msg).To(ContainSubstring("Invalid to"))
+		})
+
+		It("Should reject from after to", func() {
+			_, _, msg := parseUsageRange("2026-03-10", "2026-03-01", now)
+			Expect(msg).To(Equal("from must not be after to"))
+		})
+	})
+})
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"ambient-code-shared/webhook"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ListWebhookDeliveries handles GET /api/projects/:projectName/webhooks/deliveries
+// Optional query params: webhook, session (exact match filters) and limit.
+func ListWebhookDeliveries(c *gin.Context) {
+	project := c.GetString("project")
+	reqK8s, _ := GetK8sClientsForRequest(c)
+	if reqK8s == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing token"})
+		c.Abort()
+		return
+	}
+
+	cm, err := reqK8s.CoreV1().ConfigMaps(project).Get(c.Request.Context(), webhook.DeliveryLogConfigMap, v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusOK, gin.H{"items": []webhook.Delivery{}})
+			return
+		}
+		if errors.IsForbidden(err) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions to view webhook deliveries"})
+			return
+		}
+		log.Printf("Failed to get webhook delivery log in project %s: %v", project, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read webhook deliveries"})
+		return
+	}
+
+	var deliveries []webhook.Delivery
+	if raw := cm.Data[webhook.DeliveryLogKey]; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &deliveries); err != nil {
+			log.Printf("Invalid webhook delivery log in project %s: %v", project, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read webhook deliveries"})
+			return
+		}
+	}
+
+	webhookName := c.Query("webhook")
+	session := c.Query("session")
+	limit := 0
+	if v := c.Query("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			limit = n
+		}
+	}
+
+	items := make([]webhook.Delivery, 0, len(deliveries))
+	for _, d := range deliveries {
+		if webhookName != "" && d.Webhook != webhookName {
+			continue
+		}
+		if session != "" && d.Session != session {
+			continue
+		}
+		items = append(items, d)
+		if limit > 0 && len(items) >= limit {
+			break
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
@@ -38,3 +38,12 @@ func GetOpenShiftProjectRequestResource() schema.GroupVersionResource {
 		Resource: "projectrequests",
 	}
 }
+
+// GetScheduledSessionResource returns the GroupVersionResource for ScheduledSession
+func GetScheduledSessionResource() schema.GroupVersionResource {
+	return schema.GroupVersionResource{
+		Group:    "vteam.ambient-code",
+		Version:  "v1alpha1",
+		Resource: "scheduledsessions",
+	}
+}
@@ -0,0 +1,142 @@
+// Package livebus fans the live lines of a session's AG-UI stream out to
+// every client tailing it.  The in-memory bus only reaches clients of this
+// backend replica; the Redis bus also carries lines between replicas, so a
+// client connected to one replica sees runs proxied by another.
+//
+// Lines are not durable: every event is persisted before it is published,
+// and subscribers fill gaps from the event store.
+package livebus
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Bus names accepted in Config.Backend (LIVE_BUS)
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+// Bus delivers published lines to the session's subscribers
+type Bus interface {
+	// Publish sends line to every subscriber of the session.  Subscribers on
+	// this replica receive it before Publish returns.
+	Publish(sessionName, line string)
+	// Subscribe calls fn with every line published for the session until
+	// cancel is called.  fn runs on the publishing goroutine and must not block.
+	Subscribe(sessionName string, fn func(line string)) (cancel func())
+	Close() error
+}
+
+// Config selects and configures a Bus
+type Config struct {
+	Backend  string
+	RedisURL string // redis://[user:password@]host:port[/db]
+}
+
+// ConfigFromEnv reads LIVE_BUS (memory or redis, default memory) and
+// LIVE_BUS_REDIS_URL.
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Backend:  os.Getenv("LIVE_BUS"),
+		RedisURL: os.Getenv("LIVE_BUS_REDIS_URL"),
+	}
+	if cfg.Backend == "" {
+		cfg.Backend = BackendMemory
+	}
+	return cfg
+}
+
+// Open returns the bus selected by cfg
+func Open(cfg Config) (Bus, error) {
+	switch cfg.Backend {
+	case BackendMemory:
+		return NewMemory(), nil
+	case BackendRedis:
+		if cfg.RedisURL == "" {
+			return nil, fmt.Errorf("redis live bus needs LIVE_BUS_REDIS_URL")
+		}
+		opts, err := redis.ParseURL(cfg.RedisURL)
+		if err != nil {
+			return nil, fmt.Errorf("parse LIVE_BUS_REDIS_URL: %w", err)
+		}
+		return NewRedis(redis.NewClient(opts)), nil
+	default:
+		return nil, fmt.Errorf("unknown live bus %q (want %s or %s)", cfg.Backend, BackendMemory, BackendRedis)
+	}
+}
+
+// local is the per-replica fan-out shared by every bus
+type local struct {
+	mu   sync.Mutex
+	subs map[string]map[int]func(string) // sessionName → id → fn
+	next int
+}
+
+// add registers fn and reports whether it is the session's first subscriber
+func (l *local) add(sessionName string, fn func(string)) (id int, first bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.subs == nil {
+		l.subs = make(map[string]map[int]func(string))
+	}
+	subs, ok := l.subs[sessionName]
+	if !ok {
+		subs = make(map[int]func(string))
+		l.subs[sessionName] = subs
+	}
+	id = l.next
+	l.next++
+	subs[id] = fn
+	return id, !ok
+}
+
+// remove unregisters a subscriber and reports whether it was the session's last
+func (l *local) remove(sessionName string, id int) (last bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	subs := l.subs[sessionName]
+	delete(subs, id)
+	if len(subs) == 0 {
+		delete(l.subs, sessionName)
+		return true
+	}
+	return false
+}
+
+func (l *local) deliver(sessionName, line string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, fn := range l.subs[sessionName] {
+		fn(line)
+	}
+}
+
+// Memory is a Bus that only reaches subscribers on this replica
+type Memory struct {
+	local
+}
+
+// NewMemory returns an in-process bus
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// Publish implements Bus
+func (m *Memory) Publish(sessionName, line string) {
+	m.deliver(sessionName, line)
+}
+
+// Subscribe implements Bus
+func (m *Memory) Subscribe(sessionName string, fn func(string)) func() {
+	id, _ := m.add(sessionName, fn)
+	var once sync.Once
+	return func() { once.Do(func() { m.remove(sessionName, id) }) }
+}
+
+// Close implements Bus
+func (m *Memory) Close() error { return nil }
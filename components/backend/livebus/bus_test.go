@@ -0,0 +1,115 @@
+package livebus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// collect subscribes to a session and returns the channel its lines arrive on
+func collect(t *testing.T, bus Bus, sessionName string) (<-chan string, func()) {
+	t.Helper()
+	lines := make(chan string, 16)
+	cancel := bus.Subscribe(sessionName, func(line string) { lines <- line })
+	t.Cleanup(cancel)
+	return lines, cancel
+}
+
+func expectLine(t *testing.T, lines <-chan string, want string) {
+	t.Helper()
+	select {
+	case got := <-lines:
+		if got != want {
+			t.Errorf("received %q, want %q", got, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for %q", want)
+	}
+}
+
+func expectNothing(t *testing.T, lines <-chan string) {
+	t.Helper()
+	select {
+	case got := <-lines:
+		t.Errorf("unexpected line %q", got)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestMemory(t *testing.T) {
+	bus := NewMemory()
+	a, cancelA := collect(t, bus, "s1")
+	b, _ := collect(t, bus, "s1")
+	other, _ := collect(t, bus, "s2")
+
+	bus.Publish("s1", "id: 1\ndata: {}\n\n")
+	expectLine(t, a, "id: 1\ndata: {}\n\n")
+	expectLine(t, b, "id: 1\ndata: {}\n\n")
+	expectNothing(t, other)
+
+	cancelA()
+	cancelA() // cancelling twice is harmless
+	bus.Publish("s1", "data: {}\n\n")
+	expectLine(t, b, "data: {}\n\n")
+	expectNothing(t, a)
+}
+
+func newRedisBus(t *testing.T, server *miniredis.Miniredis) *Redis {
+	t.Helper()
+	bus := NewRedis(redis.NewClient(&redis.Options{Addr: server.Addr()}))
+	t.Cleanup(func() { bus.Close() })
+	return bus
+}
+
+// waitSubscribers waits until n replicas subscribe to the session's channel
+func waitSubscribers(t *testing.T, server *miniredis.Miniredis, sessionName string, n int) {
+	t.Helper()
+	channel := redisChannelPrefix + sessionName
+	deadline := time.Now().Add(2 * time.Second)
+	for server.PubSubNumSub(channel)[channel] != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s has %d subscribers, want %d", channel, server.PubSubNumSub(channel)[channel], n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRedisAcrossReplicas(t *testing.T) {
+	server := miniredis.RunT(t)
+	replicaA, replicaB := newRedisBus(t, server), newRedisBus(t, server)
+
+	onA, _ := collect(t, replicaA, "s1")
+	onB, cancelB := collect(t, replicaB, "s1")
+	otherOnB, _ := collect(t, replicaB, "s2")
+	waitSubscribers(t, server, "s1", 2)
+
+	replicaA.Publish("s1", "id: 1\ndata: {\"type\":\"RUN_STARTED\"}\n\n")
+	expectLine(t, onA, "id: 1\ndata: {\"type\":\"RUN_STARTED\"}\n\n")
+	expectLine(t, onB, "id: 1\ndata: {\"type\":\"RUN_STARTED\"}\n\n")
+	expectNothing(t, otherOnB)
+	expectNothing(t, onA) // the Redis echo of A's own line is ignored
+
+	replicaB.Publish("s1", "id: 2\ndata: {}\n\n")
+	expectLine(t, onA, "id: 2\ndata: {}\n\n")
+	expectLine(t, onB, "id: 2\ndata: {}\n\n")
+
+	// B's last subscriber leaving drops its channel subscription
+	cancelB()
+	waitSubscribers(t, server, "s1", 1)
+}
+
+func TestOpen(t *testing.T) {
+	if bus, err := Open(Config{Backend: BackendMemory}); err != nil {
+		t.Fatal(err)
+	} else if _, ok := bus.(*Memory); !ok {
+		t.Errorf("Open(memory) = %T", bus)
+	}
+	if _, err := Open(Config{Backend: BackendRedis}); err == nil {
+		t.Error("expected an error for a Redis bus without a URL")
+	}
+	if _, err := Open(Config{Backend: "kafka"}); err == nil {
+		t.Error("expected an error for an unknown bus")
+	}
+}
@@ -0,0 +1,135 @@
+package livebus
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisChannelPrefix namespaces the per-session Redis channels
+	redisChannelPrefix = "ambient:agui:live:"
+	// redisQueueSize is how many lines may wait to be sent to Redis before
+	// new ones are dropped; remote subscribers then fill the gap from the store
+	redisQueueSize = 4096
+	// redisReceiveBuffer is how many received lines may wait to be delivered
+	redisReceiveBuffer = 1024
+	redisTimeout       = 5 * time.Second
+)
+
+// Redis is a Bus that carries lines between replicas over Redis pub/sub,
+// one channel per session.  Each replica subscribes to a session's channel
+// while it has local subscribers.  Lines reach local subscribers directly;
+// a replica ignores its own lines when Redis echoes them back.
+type Redis struct {
+	local
+	client *redis.Client
+	pubsub *redis.PubSub
+	origin string // identifies this replica's messages
+
+	subMu sync.Mutex // orders channel (un)subscribes with local add/remove
+
+	queue     chan redisMessage
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+type redisMessage struct {
+	channel, payload string
+}
+
+// NewRedis returns a bus using client.  Close closes the client.
+func NewRedis(client *redis.Client) *Redis {
+	r := &Redis{
+		client: client,
+		pubsub: client.Subscribe(context.Background()),
+		origin: uuid.NewString(),
+		queue:  make(chan redisMessage, redisQueueSize),
+		done:   make(chan struct{}),
+	}
+	go r.send()
+	go r.receive(r.pubsub.Channel(redis.WithChannelSize(redisReceiveBuffer)))
+	return r
+}
+
+// Publish implements Bus.  The line is sent to Redis in the background so a
+// slow or unreachable Redis never holds up the run being streamed.
+func (r *Redis) Publish(sessionName, line string) {
+	r.deliver(sessionName, line)
+	select {
+	case r.queue <- redisMessage{channel: redisChannelPrefix + sessionName, payload: r.origin + "\n" + line}:
+	default:
+		log.Printf("livebus: Redis publish queue full, dropping a line for %s", sessionName)
+	}
+}
+
+// Subscribe implements Bus
+func (r *Redis) Subscribe(sessionName string, fn func(string)) func() {
+	r.subMu.Lock()
+	id, first := r.add(sessionName, fn)
+	if first {
+		// On failure go-redis keeps the channel and subscribes when it reconnects
+		ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+		if err := r.pubsub.Subscribe(ctx, redisChannelPrefix+sessionName); err != nil {
+			log.Printf("livebus: subscribe to %s: %v", sessionName, err)
+		}
+		cancel()
+	}
+	r.subMu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			r.subMu.Lock()
+			defer r.subMu.Unlock()
+			if r.remove(sessionName, id) {
+				ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+				defer cancel()
+				if err := r.pubsub.Unsubscribe(ctx, redisChannelPrefix+sessionName); err != nil {
+					log.Printf("livebus: unsubscribe from %s: %v", sessionName, err)
+				}
+			}
+		})
+	}
+}
+
+func (r *Redis) send() {
+	for {
+		select {
+		case msg := <-r.queue:
+			ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+			if err := r.client.Publish(ctx, msg.channel, msg.payload).Err(); err != nil {
+				log.Printf("livebus: publish to %s: %v", msg.channel, err)
+			}
+			cancel()
+		case <-r.done:
+			return
+		}
+	}
+}
+
+func (r *Redis) receive(messages <-chan *redis.Message) {
+	for msg := range messages {
+		origin, line, ok := strings.Cut(msg.Payload, "\n")
+		if !ok || origin == r.origin {
+			continue
+		}
+		r.deliver(strings.TrimPrefix(msg.Channel, redisChannelPrefix), line)
+	}
+}
+
+// Close implements Bus
+func (r *Redis) Close() error {
+	var err error
+	r.closeOnce.Do(func() {
+		close(r.done)
+		r.pubsub.Close()
+		err = r.client.Close()
+	})
+	return err
+}
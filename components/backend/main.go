@@ -5,11 +5,14 @@ import (
 	"log"
 	"os"
 
+	"ambient-code-backend/eventstore"
 	"ambient-code-backend/featureflags"
 	"ambient-code-backend/git"
 	"ambient-code-backend/github"
 	"ambient-code-backend/handlers"
 	"ambient-code-backend/k8s"
+	"ambient-code-backend/livebus"
+	"ambient-code-backend/metrics"
 	"ambient-code-backend/server"
 	"ambient-code-backend/websocket"
 
@@ -53,6 +56,11 @@ func main() {
 	// Log build information
 	logBuildInfo()
 
+	// Event store migration mode - copies session logs and exits without K8s access
+	if len(os.Args) > 1 && os.Args[1] == "migrate-events" {
+		os.Exit(runMigrateEvents(os.Args[2:]))
+	}
+
 	// Normal server mode - full initialization
 	log.Println("Starting in normal server mode with K8s client initialization")
 
@@ -65,6 +73,14 @@ func main() {
 
 	server.InitConfig()
 
+	// Optional: OpenTelemetry metrics (when OTEL_EXPORTER_OTLP_ENDPOINT is set)
+	shutdownMetrics, err := metrics.InitMetrics(context.Background())
+	if err != nil {
+		log.Printf("Failed to initialize metrics: %v", err)
+	} else {
+		defer shutdownMetrics()
+	}
+
 	// Optional: Unleash feature flags (when UNLEASH_URL and UNLEASH_CLIENT_KEY are set)
 	featureflags.Init()
 
@@ -125,6 +141,19 @@ func main() {
 
 	// Initialize websocket package
 	websocket.StateBaseDir = server.StateBaseDir
+	websocket.CompressSnapshots = os.Getenv("AGUI_SNAPSHOT_COMPRESSION") == "zstd"
+	events, err := eventstore.Open(eventstore.ConfigFromEnv(server.StateBaseDir))
+	if err != nil {
+		log.Fatalf("Failed to open AG-UI event store: %v", err)
+	}
+	defer events.Close()
+	websocket.Events = events
+	live, err := livebus.Open(livebus.ConfigFromEnv())
+	if err != nil {
+		log.Fatalf("Failed to open AG-UI live bus: %v", err)
+	}
+	defer live.Close()
+	websocket.Live = live
 
 	// Normal server mode
 	if err := server.Run(registerRoutes); err != nil {
@@ -0,0 +1,101 @@
+// Package metrics exports backend OpenTelemetry metrics (LLM token usage and cost).
+// Set OTEL_EXPORTER_OTLP_ENDPOINT to enable export; when unset all Record functions are no-ops.
+package metrics
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"ambient-code-backend/types"
+	"ambient-code-shared/telemetry"
+)
+
+var (
+	meter metric.Meter
+
+	// Usage metrics (counters)
+	tokensUsed metric.Int64Counter
+	costUSD    metric.Float64Counter
+	runsTotal  metric.Int64Counter
+)
+
+// InitMetrics initializes OpenTelemetry metrics.
+// Set OTEL_EXPORTER_OTLP_ENDPOINT to configure the collector address.
+// Leave unset or empty to disable metrics export (no-op).
+func InitMetrics(ctx context.Context) (func(), error) {
+	m, shutdown, err := telemetry.InitMeter(ctx, "ambient-code-backend", "ambient-code-backend")
+	if err != nil || m == nil {
+		return shutdown, err
+	}
+	meter = m
+
+	if err := initInstruments(); err != nil {
+		shutdown()
+		return nil, fmt.Errorf("failed to initialize instruments: %w", err)
+	}
+
+	return shutdown, nil
+}
+
+// initInstruments creates all metric instruments
+func initInstruments() error {
+	var err error
+
+	tokensUsed, err = meter.Int64Counter(
+		"ambient.llm.tokens",
+		metric.WithDescription("LLM tokens consumed by session runs, by token type (input, output, cache_read, cache_creation)"),
+		metric.WithUnit("{token}"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create tokensUsed: %w", err)
+	}
+
+	costUSD, err = meter.Float64Counter(
+		"ambient.llm.cost",
+		metric.WithDescription("LLM cost reported by session runs"),
+		metric.WithUnit("USD"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create costUSD: %w", err)
+	}
+
+	runsTotal, err = meter.Int64Counter(
+		"ambient.llm.runs",
+		metric.WithDescription("Session runs with usage reported, counted once per model used in the run"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create runsTotal: %w", err)
+	}
+
+	return nil
+}
+
+// RecordTokenUsage records the usage of a single run by one model
+func RecordTokenUsage(namespace, user, model string, usage types.TokenUsage) {
+	if tokensUsed == nil {
+		return
+	}
+	ctx := context.Background()
+	attrs := []attribute.KeyValue{
+		attribute.String("namespace", namespace),
+		attribute.String("user", user),
+		attribute.String("model", model),
+	}
+	for tokenType, n := range map[string]int64{
+		"input":          usage.InputTokens,
+		"output":         usage.OutputTokens,
+		"cache_read":     usage.CacheReadInputTokens,
+		"cache_creation": usage.CacheCreationInputTokens,
+	} {
+		if n > 0 {
+			tokensUsed.Add(ctx, n, metric.WithAttributes(append(attrs, attribute.String("type", tokenType))...))
+		}
+	}
+	if usage.CostUSD > 0 {
+		costUSD.Add(ctx, usage.CostUSD, metric.WithAttributes(attrs...))
+	}
+	runsTotal.Add(ctx, 1, metric.WithAttributes(attrs...))
+}
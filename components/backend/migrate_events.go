@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"ambient-code-backend/eventstore"
+)
+
+// runMigrateEvents copies every session's AG-UI event log from one store to
+// another, e.g. `main migrate-events -from jsonl -to s3`. Both stores take the
+// rest of their settings from the EVENT_STORE_* environment variables.
+// Sessions that already have events in the destination are skipped, so an
+// interrupted migration can be re-run.
+func runMigrateEvents(args []string) int {
+	flags := flag.NewFlagSet("migrate-events", flag.ContinueOnError)
+	from := flags.String("from", eventstore.BackendJSONL, "source store: jsonl, sqlite or s3")
+	to := flags.String("to", "", "destination store: jsonl, sqlite or s3")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *to == "" || *to == *from {
+		log.Printf("migrate-events: -to must name a store other than -from (%s)", *from)
+		return 2
+	}
+
+	base := eventstore.ConfigFromEnv(getEnvOrDefault("STATE_BASE_DIR", "/workspace"))
+	open := func(backend string) (eventstore.Store, bool) {
+		cfg := base
+		cfg.Backend = backend
+		store, err := eventstore.Open(cfg)
+		if err != nil {
+			log.Printf("migrate-events: open %s store: %v", backend, err)
+			return nil, false
+		}
+		return store, true
+	}
+	src, ok := open(*from)
+	if !ok {
+		return 1
+	}
+	defer src.Close()
+	dst, ok := open(*to)
+	if !ok {
+		return 1
+	}
+	defer dst.Close()
+
+	result, err := eventstore.Copy(context.Background(), src, dst)
+	log.Printf("migrate-events: copied %d events in %d sessions from %s to %s, skipped %d sessions already in %s",
+		result.Events, result.Sessions, *from, *to, result.Skipped, *to)
+	if err != nil {
+		log.Printf("migrate-events: %v", err)
+		return 1
+	}
+	return 0
+}
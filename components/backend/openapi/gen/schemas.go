@@ -0,0 +1,95 @@
+package main
+
+import (
+	"ambient-code-backend/types"
+
+	"ambient-code-shared/webhook"
+)
+
+// schemas lists every component schema of the spec. Each one is reflected from its Go
+// type: json tags name the properties, fields without omitempty are required and doc
+// comments become descriptions.
+var schemas = map[string]interface{}{
+	"AGUIEventLog":                  types.AGUIEventLog{},
+	"AGUIRunMetadata":               types.AGUIRunMetadata{},
+	"Activity":                      types.Activity{},
+	"ActivityDeltaEvent":            types.ActivityDeltaEvent{},
+	"ActivityPatch":                 types.ActivityPatch{},
+	"ActivitySnapshotEvent":         types.ActivitySnapshotEvent{},
+	"AgenticSession":                types.AgenticSession{},
+	"AgenticSessionSpec":            types.AgenticSessionSpec{},
+	"AgenticSessionStatus":          types.AgenticSessionStatus{},
+	"AmbientProject":                types.AmbientProject{},
+	"BaseEvent":                     types.BaseEvent{},
+	"BotAccountRef":                 types.BotAccountRef{},
+	"Branch":                        types.Branch{},
+	"CloneAgenticSessionRequest":    types.CloneAgenticSessionRequest{},
+	"CloneSessionRequest":           types.CloneSessionRequest{},
+	"CommitInfo":                    types.CommitInfo{},
+	"Condition":                     types.Condition{},
+	"CreateAgenticSessionRequest":   types.CreateAgenticSessionRequest{},
+	"CreateProjectRequest":          types.CreateProjectRequest{},
+	"CreateScheduledSessionRequest": types.CreateScheduledSessionRequest{},
+	"ErrorResponse":                 types.ErrorResponse{},
+	"EventChain":                    types.EventChain{},
+	"FeedbackPayload":               types.FeedbackPayload{},
+	"FeedbackTranscriptItem":        types.FeedbackTranscriptItem{},
+	"FileContent":                   types.FileContent{},
+	"ForkSessionRequest":            types.ForkSessionRequest{},
+	"GitConfig":                     types.GitConfig{},
+	"GitLabAPIError":                types.GitLabAPIError{},
+	"GitLabBranch":                  types.GitLabBranch{},
+	"GitLabCommit":                  types.GitLabCommit{},
+	"GitLabConnection":              types.GitLabConnection{},
+	"GitLabTreeEntry":               types.GitLabTreeEntry{},
+	"GitRepository":                 types.GitRepository{},
+	"LLMSettings":                   types.LLMSettings{},
+	"Message":                       types.Message{},
+	"MessagesSnapshotEvent":         types.MessagesSnapshotEvent{},
+	"MetaEvent":                     types.MetaEvent{},
+	"PaginatedResponse":             types.PaginatedResponse{},
+	"PaginationParams":              types.PaginationParams{},
+	"ParsedGitLabRepo":              types.ParsedGitLabRepo{},
+	"Paths":                         types.Paths{},
+	"ProjectUsageResponse":          types.ProjectUsageResponse{},
+	"RawEvent":                      types.RawEvent{},
+	"ReconciledRepo":                types.ReconciledRepo{},
+	"ReconciledWorkflow":            types.ReconciledWorkflow{},
+	"ResourceOverrides":             types.ResourceOverrides{},
+	"RunAgentInput":                 types.RunAgentInput{},
+	"RunAgentOutput":                types.RunAgentOutput{},
+	"RunErrorEvent":                 types.RunErrorEvent{},
+	"RunFinishedEvent":              types.RunFinishedEvent{},
+	"RunStartedEvent":               types.RunStartedEvent{},
+	"ScheduledSession":              types.ScheduledSession{},
+	"ScheduledSessionSpec":          types.ScheduledSessionSpec{},
+	"ScheduledSessionStatus":        types.ScheduledSessionStatus{},
+	"ScheduledSessionTemplate":      types.ScheduledSessionTemplate{},
+	"SearchHit":                     types.SearchHit{},
+	"SessionNext":                   types.SessionNext{},
+	"SessionState":                  types.SessionState{},
+	"SessionUsage":                  types.SessionUsage{},
+	"SimpleRepo":                    types.SimpleRepo{},
+	"StateDeltaEvent":               types.StateDeltaEvent{},
+	"StatePatch":                    types.StatePatch{},
+	"StateSnapshotEvent":            types.StateSnapshotEvent{},
+	"StepFinishedEvent":             types.StepFinishedEvent{},
+	"StepStartedEvent":              types.StepStartedEvent{},
+	"TextMessageContentEvent":       types.TextMessageContentEvent{},
+	"TextMessageEndEvent":           types.TextMessageEndEvent{},
+	"TextMessageStartEvent":         types.TextMessageStartEvent{},
+	"TokenUsage":                    types.TokenUsage{},
+	"ToolCall":                      types.ToolCall{},
+	"ToolCallArgsEvent":             types.ToolCallArgsEvent{},
+	"ToolCallEndEvent":              types.ToolCallEndEvent{},
+	"ToolCallStartEvent":            types.ToolCallStartEvent{},
+	"ToolDefinition":                types.ToolDefinition{},
+	"TreeEntry":                     types.TreeEntry{},
+	"UpdateAgenticSessionRequest":   types.UpdateAgenticSessionRequest{},
+	"UpdateScheduledSessionRequest": types.UpdateScheduledSessionRequest{},
+	"UsageBucket":                   types.UsageBucket{},
+	"UsageRollup":                   types.UsageRollup{},
+	"UserContext":                   types.UserContext{},
+	"WorkflowSelection":             types.WorkflowSelection{},
+	"WebhookDelivery":               webhook.Delivery{},
+}
@@ -0,0 +1,27 @@
+// Package openapi embeds and serves the OpenAPI 3 specification of the backend API.
+//
+// openapi.json is generated: operations are written by hand in paths.json and the
+// component schemas are reflected from the Go types listed in gen/schemas.go. Run
+// go generate ./openapi after changing either. paths.json must describe every route
+// registered in routes.go; the route coverage test in the main package fails when a
+// route is missing.
+package openapi
+
+//go:generate go run ./gen
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Spec is the OpenAPI 3 document served at GET /openapi.json
+//
+//go:embed openapi.json
+var Spec []byte
+
+// Handler serves the OpenAPI specification
+func Handler(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", Spec)
+}
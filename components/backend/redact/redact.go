@@ -0,0 +1,143 @@
+// Package redact masks credentials in AG-UI events before they are
+// persisted or exported.
+//
+// A Redactor combines the built-in credential formats with a project's
+// own patterns and the literal values of the secrets mounted into the
+// session's runner.  It rewrites every string in an event, wherever it
+// sits in the JSON tree, and reports how many matches it masked.
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Placeholder replaces every redacted match
+const Placeholder = "[REDACTED]"
+
+// MinSecretLength is the shortest secret value that is redacted literally.
+// Shorter values ("true", "us-east-1") would mask ordinary text.
+const MinSecretLength = 8
+
+// builtinPatterns are credential formats redacted in every project.  When
+// a pattern has a capture group only the group is masked, so the name in
+// "aws_secret_access_key=..." stays readable.
+var builtinPatterns = []*regexp.Regexp{
+	// GitHub personal, OAuth, user-to-server, server-to-server (app
+	// installation) and refresh tokens, and fine-grained PATs
+	regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,255}\b`),
+	regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{22,255}\b`),
+	// Legacy GitHub App installation tokens
+	regexp.MustCompile(`\bv1\.[0-9a-f]{40}\b`),
+	// GitLab personal, deploy, runner, trigger and CI job tokens
+	regexp.MustCompile(`\bgl(?:pat|dt|rt|ptt|cbt|soat)-[A-Za-z0-9_\-]{20,}`),
+	// AWS access key IDs and secret access keys
+	regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	regexp.MustCompile(`(?i)aws_secret_access_key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})`),
+	// JSON Web Tokens
+	regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`),
+	// Anthropic API keys
+	regexp.MustCompile(`\bsk-ant-[A-Za-z0-9_\-]{20,}`),
+}
+
+// Redactor masks credentials in strings and decoded JSON values.  It is
+// safe for concurrent use.
+type Redactor struct {
+	patterns []*regexp.Regexp
+	secrets  *strings.Replacer // nil when there are no secret values
+}
+
+// Default redacts the built-in credential formats only.
+var Default = &Redactor{patterns: builtinPatterns}
+
+// New returns a Redactor for the built-in formats plus the given regular
+// expressions and literal secret values.  Secret values shorter than
+// MinSecretLength are ignored.
+func New(patterns []string, secretValues []string) (*Redactor, error) {
+	r := &Redactor{patterns: append([]*regexp.Regexp{}, builtinPatterns...)}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+
+	// Longest first, so a secret containing another is masked whole
+	var values []string
+	seen := map[string]bool{}
+	for _, v := range secretValues {
+		v = strings.TrimSpace(v)
+		if len(v) >= MinSecretLength && !seen[v] {
+			seen[v] = true
+			values = append(values, v)
+		}
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	if len(values) > 0 {
+		pairs := make([]string, 0, 2*len(values))
+		for _, v := range values {
+			pairs = append(pairs, v, Placeholder)
+		}
+		r.secrets = strings.NewReplacer(pairs...)
+	}
+	return r, nil
+}
+
+// String returns s with credentials masked and the number of matches.
+func (r *Redactor) String(s string) (string, int) {
+	count := 0
+	if r.secrets != nil {
+		if replaced := r.secrets.Replace(s); replaced != s {
+			count += strings.Count(replaced, Placeholder) - strings.Count(s, Placeholder)
+			s = replaced
+		}
+	}
+	for _, re := range r.patterns {
+		s = re.ReplaceAllStringFunc(s, func(match string) string {
+			count++
+			if re.NumSubexp() == 0 {
+				return Placeholder
+			}
+			sub := re.FindStringSubmatchIndex(match)
+			if len(sub) < 4 || sub[2] < 0 {
+				return Placeholder
+			}
+			return match[:sub[2]] + Placeholder + match[sub[3]:]
+		})
+	}
+	return s, count
+}
+
+// Value masks credentials in every string of a decoded JSON value (maps
+// and slices are rewritten in place) and returns it with the number of
+// matches.
+func (r *Redactor) Value(v interface{}) (interface{}, int) {
+	switch val := v.(type) {
+	case string:
+		return r.String(val)
+	case map[string]interface{}:
+		total := 0
+		for k, item := range val {
+			redacted, n := r.Value(item)
+			if n > 0 {
+				val[k] = redacted
+				total += n
+			}
+		}
+		return val, total
+	case []interface{}:
+		total := 0
+		for i, item := range val {
+			redacted, n := r.Value(item)
+			if n > 0 {
+				val[i] = redacted
+				total += n
+			}
+		}
+		return val, total
+	}
+	return v, 0
+}
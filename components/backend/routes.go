@@ -2,6 +2,7 @@ package main
 
 import (
 	"ambient-code-backend/handlers"
+	"ambient-code-backend/openapi"
 	"ambient-code-backend/websocket"
 
 	"github.com/gin-gonic/gin"
@@ -67,6 +68,8 @@ func registerRoutes(r *gin.Engine) {
 			projectGroup.POST("/agentic-sessions/:sessionName/agui/run", websocket.HandleAGUIRunProxy)
 			projectGroup.POST("/agentic-sessions/:sessionName/agui/interrupt", websocket.HandleAGUIInterrupt)
 			projectGroup.POST("/agentic-sessions/:sessionName/agui/feedback", websocket.HandleAGUIFeedback)
+			// GET  /agui/ws → WebSocket carrying the event stream and run/interrupt/feedback requests
+			projectGroup.GET("/agentic-sessions/:sessionName/agui/ws", websocket.HandleAGUIWebSocket)
 
 			// Runner capabilities endpoint
 			projectGroup.GET("/agentic-sessions/:sessionName/agui/capabilities", websocket.HandleCapabilities)
@@ -82,6 +85,39 @@ func registerRoutes(r *gin.Engine) {
 
 			// Session export
 			projectGroup.GET("/agentic-sessions/:sessionName/export", websocket.HandleExportSession)
+			projectGroup.GET("/agentic-sessions/:sessionName/verify", websocket.HandleVerifySession)
+			// Event log deletion (called by the operator's session garbage collector)
+			projectGroup.DELETE("/agentic-sessions/:sessionName/events", websocket.HandleDeleteSessionEvents)
+			// Session import (restores an export as a stopped session)
+			projectGroup.POST("/agentic-sessions/import", websocket.HandleImportSession)
+			// Session fork (new session continuing from a point in the log)
+			projectGroup.POST("/agentic-sessions/:sessionName/fork", websocket.HandleForkSession)
+
+			// Full-text search over session transcripts
+			projectGroup.GET("/search", websocket.HandleSearchProject)
+
+			// Conversation history folded from the AG-UI event log
+			projectGroup.GET("/agentic-sessions/:sessionName/messages", websocket.HandleListSessionMessages)
+
+			// Agent state and activities as of an event or run
+			projectGroup.GET("/agentic-sessions/:sessionName/state", websocket.HandleGetSessionState)
+
+			// Run history indexed from the AG-UI event log
+			projectGroup.GET("/agentic-sessions/:sessionName/runs", websocket.HandleListSessionRuns)
+			projectGroup.GET("/agentic-sessions/:sessionName/runs/:runId", websocket.HandleGetSessionRun)
+
+			// Scheduled (recurring) sessions - reconciled by the operator's ScheduledSession controller
+			projectGroup.GET("/scheduled-sessions", handlers.ListScheduledSessions)
+			projectGroup.POST("/scheduled-sessions", handlers.CreateScheduledSession)
+			projectGroup.GET("/scheduled-sessions/:scheduleName", handlers.GetScheduledSession)
+			projectGroup.PUT("/scheduled-sessions/:scheduleName", handlers.UpdateScheduledSession)
+			projectGroup.DELETE("/scheduled-sessions/:scheduleName", handlers.DeleteScheduledSession)
+
+			// Webhook delivery log (deliveries are made by the operator on session phase changes)
+			projectGroup.GET("/webhooks/deliveries", handlers.ListWebhookDeliveries)
+
+			// Token usage and cost rollups (accumulated from RUN_FINISHED events)
+			projectGroup.GET("/usage", handlers.GetProjectUsage)
 
 			projectGroup.GET("/permissions", handlers.ListProjectPermissions)
 			projectGroup.POST("/permissions", handlers.AddProjectPermission)
@@ -156,6 +192,9 @@ func registerRoutes(r *gin.Engine) {
 	// Health check endpoint
 	r.GET("/health", handlers.Health)
 
+	// OpenAPI specification (public, no auth required)
+	r.GET("/openapi.json", openapi.Handler)
+
 	// Generic OAuth2 callback endpoint (outside /api for MCP compatibility)
 	r.GET("/oauth2callback", handlers.HandleOAuth2Callback)
 
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"ambient-code-backend/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ginParam matches gin path parameters (:name) and wildcards (*name)
+var ginParam = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+// TestOpenAPICoversRoutes fails when a route registered in routes.go is not
+// described in openapi/openapi.json.
+func TestOpenAPICoversRoutes(t *testing.T) {
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(openapi.Spec, &spec); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	registerRoutes(r)
+
+	for _, route := range r.Routes() {
+		path := ginParam.ReplaceAllString(route.Path, "{$1}")
+		if _, ok := spec.Paths[path][strings.ToLower(route.Method)]; !ok {
+			t.Errorf("route %s %s is missing from openapi/openapi.json (as %s)", route.Method, route.Path, path)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+// Package search is an in-memory full-text index of session transcripts.
+//
+// An Index holds the documents of one project: the text of each message
+// and the name and arguments of each tool call, keyed by session and
+// message or tool call ID.  Documents are tokenized into lower-case words;
+// a query matches the documents that contain all of its words, and each
+// hit carries a snippet of the document with the matches highlighted.
+package search
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Document kinds
+const (
+	KindMessage  = "message"
+	KindToolCall = "tool_call"
+)
+
+// MaxTextLength caps the text indexed per document; tool arguments can
+// hold whole files.
+const MaxTextLength = 32 << 10
+
+// snippetRadius is the number of bytes of context kept on each side of the
+// first match in a snippet
+const snippetRadius = 80
+
+// Document is one indexed message or tool call
+type Document struct {
+	Session   string
+	ID        string // message ID, or tool call ID for tool calls
+	Kind      string
+	RunID     string
+	Role      string // messages only
+	ToolName  string // tool calls only
+	Seq       int64  // event that started the message or call
+	Timestamp string
+	Text      string
+}
+
+// indexed is the text of the document that queries match: its text, and
+// the tool name of a tool call
+func (d *Document) indexed() string {
+	if d.ToolName == "" {
+		return d.Text
+	}
+	return d.ToolName + "\n" + d.Text
+}
+
+// Hit is a document matching a query
+type Hit struct {
+	Document
+	Score   int    // occurrences of the query's words
+	Snippet string // HTML-escaped, matches wrapped in <mark>
+}
+
+type docKey struct{ session, id string }
+
+// Index is the index of one project.  It is safe for concurrent use.
+type Index struct {
+	mu       sync.RWMutex
+	docs     map[docKey]*Document
+	postings map[string]map[docKey]int // word → document → occurrences
+	size     int                       // bytes of indexed text
+}
+
+// New returns an empty index
+func New() *Index {
+	return &Index{
+		docs:     make(map[docKey]*Document),
+		postings: make(map[string]map[docKey]int),
+	}
+}
+
+// Put adds doc, replacing the document with the same session and ID
+func (ix *Index) Put(doc Document) {
+	if len(doc.Text) > MaxTextLength {
+		doc.Text = truncate(doc.Text, MaxTextLength)
+	}
+	key := docKey{doc.Session, doc.ID}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(key)
+	ix.docs[key] = &doc
+	ix.size += len(doc.indexed())
+	for word, n := range countWords(doc.indexed()) {
+		if ix.postings[word] == nil {
+			ix.postings[word] = make(map[docKey]int)
+		}
+		ix.postings[word][key] = n
+	}
+}
+
+// Get returns the document with the session and ID
+func (ix *Index) Get(session, id string) (Document, bool) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	doc, ok := ix.docs[docKey{session, id}]
+	if !ok {
+		return Document{}, false
+	}
+	return *doc, true
+}
+
+// Delete removes the document with the session and ID
+func (ix *Index) Delete(session, id string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(docKey{session, id})
+}
+
+// DeleteSession removes every document of session
+func (ix *Index) DeleteSession(session string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	for key := range ix.docs {
+		if key.session == session {
+			ix.remove(key)
+		}
+	}
+}
+
+func (ix *Index) remove(key docKey) {
+	doc, ok := ix.docs[key]
+	if !ok {
+		return
+	}
+	for word := range countWords(doc.indexed()) {
+		delete(ix.postings[word], key)
+		if len(ix.postings[word]) == 0 {
+			delete(ix.postings, word)
+		}
+	}
+	delete(ix.docs, key)
+	ix.size -= len(doc.indexed())
+}
+
+// Size returns the number of bytes of text indexed, a measure of the
+// memory the index holds
+func (ix *Index) Size() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.size
+}
+
+// Search returns the documents containing every word of query, in sessions
+// for which allow returns true (nil allows all), best first: by score,
+// then most recent first.
+func (ix *Index) Search(query string, allow func(session string) bool) []Hit {
+	words := Words(query)
+	if len(words) == 0 {
+		return nil
+	}
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	// Walk the rarest word's postings and check the others
+	sort.Slice(words, func(i, j int) bool { return len(ix.postings[words[i]]) < len(ix.postings[words[j]]) })
+	var hits []Hit
+	for key, n := range ix.postings[words[0]] {
+		if allow != nil && !allow(key.session) {
+			continue
+		}
+		score := n
+		for _, word := range words[1:] {
+			m := ix.postings[word][key]
+			if m == 0 {
+				score = 0
+				break
+			}
+			score += m
+		}
+		if score == 0 {
+			continue
+		}
+		doc := ix.docs[key]
+		hits = append(hits, Hit{Document: *doc, Score: score, Snippet: Snippet(doc.indexed(), words)})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		a, b := hits[i], hits[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Timestamp != b.Timestamp {
+			return a.Timestamp > b.Timestamp
+		}
+		if a.Session != b.Session {
+			return a.Session < b.Session
+		}
+		return a.Seq > b.Seq
+	})
+	return hits
+}
+
+// Words returns the distinct lower-case words of s: runs of letters and
+// digits, of at least two characters.
+func Words(s string) []string {
+	counts := countWords(s)
+	words := make([]string, 0, len(counts))
+	for word := range counts {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
+}
+
+func countWords(s string) map[string]int {
+	counts := make(map[string]int)
+	for _, span := range wordSpans(s) {
+		counts[strings.ToLower(s[span[0]:span[1]])]++
+	}
+	return counts
+}
+
+// wordSpans returns the byte offsets of the words of s
+func wordSpans(s string) [][2]int {
+	var spans [][2]int
+	start := -1
+	for i, r := range s {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case isWord && start < 0:
+			start = i
+		case !isWord && start >= 0:
+			if utf8.RuneCountInString(s[start:i]) >= 2 {
+				spans = append(spans, [2]int{start, i})
+			}
+			start = -1
+		}
+	}
+	if start >= 0 && utf8.RuneCountInString(s[start:]) >= 2 {
+		spans = append(spans, [2]int{start, len(s)})
+	}
+	return spans
+}
+
+// Snippet returns the part of text around the first of words it contains,
+// HTML-escaped, with every occurrence of the words in it wrapped in
+// <mark></mark>.  Whitespace is collapsed to single spaces.
+func Snippet(text string, words []string) string {
+	want := make(map[string]bool, len(words))
+	for _, word := range words {
+		want[word] = true
+	}
+	var matches [][2]int
+	for _, span := range wordSpans(text) {
+		if want[strings.ToLower(text[span[0]:span[1]])] {
+			matches = append(matches, span)
+		}
+	}
+	if len(matches) == 0 {
+		return html.EscapeString(collapseSpace(truncate(text, 2*snippetRadius)))
+	}
+
+	from, to := matches[0][0]-snippetRadius, matches[0][1]+snippetRadius
+	if from < 0 {
+		from = 0
+	}
+	if to > len(text) {
+		to = len(text)
+	}
+	for from > 0 && !utf8.RuneStart(text[from]) {
+		from--
+	}
+	for to < len(text) && !utf8.RuneStart(text[to]) {
+		to++
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := from
+	for _, m := range matches {
+		if m[0] < from {
+			continue
+		}
+		if m[1] > to {
+			break
+		}
+		b.WriteString(html.EscapeString(collapseSpace(text[pos:m[0]])))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[m[0]:m[1]]))
+		b.WriteString("</mark>")
+		pos = m[1]
+	}
+	b.WriteString(html.EscapeString(collapseSpace(text[pos:to])))
+	if to < len(text) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// collapseSpace replaces each run of whitespace with one space
+func collapseSpace(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			if !space {
+				b.WriteByte(' ')
+			}
+			space = true
+			continue
+		}
+		space = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// truncate cuts s to at most n bytes, on a rune boundary
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
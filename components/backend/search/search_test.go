@@ -0,0 +1,86 @@
+package search
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSearchMatchesAllWords(t *testing.T) {
+	ix := New()
+	ix.Put(Document{Session: "s1", ID: "m1", Kind: KindMessage, Text: "We debugged the Kafka consumer lag today"})
+	ix.Put(Document{Session: "s1", ID: "m2", Kind: KindMessage, Text: "The consumer is fine"})
+	ix.Put(Document{Session: "s2", ID: "t1", Kind: KindToolCall, ToolName: "Grep", Text: `{"pattern":"kafka.Consumer","path":"internal/"}`})
+	ix.Put(Document{Session: "s3", ID: "m1", Kind: KindMessage, Text: "kafka consumer kafka"})
+
+	hits := ix.Search("Kafka consumer", nil)
+	var got []string
+	for _, hit := range hits {
+		got = append(got, hit.Session+"/"+hit.ID)
+	}
+	if strings.Join(got, ",") != "s3/m1,s1/m1,s2/t1" {
+		t.Errorf("Search() = %v, want s3/m1 (score 3) first, without s1/m2", got)
+	}
+
+	if hits := ix.Search("grep", nil); len(hits) != 1 || hits[0].ID != "t1" {
+		t.Errorf("Search(tool name) = %v", hits)
+	}
+	if hits := ix.Search("kafka", func(session string) bool { return session == "s2" }); len(hits) != 1 || hits[0].Session != "s2" {
+		t.Errorf("Search() with allow = %v", hits)
+	}
+	if hits := ix.Search("a !", nil); hits != nil {
+		t.Errorf("Search() without words = %v", hits)
+	}
+}
+
+func TestPutReplacesAndDeleteRemoves(t *testing.T) {
+	ix := New()
+	ix.Put(Document{Session: "s", ID: "m", Text: "first draft"})
+	ix.Put(Document{Session: "s", ID: "m", Text: "final answer"})
+	if hits := ix.Search("draft", nil); len(hits) != 0 {
+		t.Errorf("replaced text still matches: %v", hits)
+	}
+	if hits := ix.Search("answer", nil); len(hits) != 1 {
+		t.Errorf("new text does not match: %v", hits)
+	}
+
+	ix.Put(Document{Session: "s", ID: "m2", Text: "another answer"})
+	ix.Put(Document{Session: "other", ID: "m", Text: "answer"})
+	ix.DeleteSession("s")
+	if hits := ix.Search("answer", nil); len(hits) != 1 || hits[0].Session != "other" {
+		t.Errorf("after DeleteSession: %v", hits)
+	}
+	if len(ix.postings["final"]) != 0 || len(ix.postings["another"]) != 0 {
+		t.Errorf("postings left behind: %v", ix.postings)
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	text := strings.Repeat("lorem ipsum ", 20) + "the <Kafka>\n\n consumer stalled " + strings.Repeat("dolor sit ", 20)
+	got := Snippet(text, []string{"kafka", "consumer"})
+	if !strings.Contains(got, "the &lt;<mark>Kafka</mark>&gt; <mark>consumer</mark> stalled") {
+		t.Errorf("Snippet() = %q", got)
+	}
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") {
+		t.Errorf("Snippet() of a long text is not elided: %q", got)
+	}
+	if got := Snippet("short text", []string{"short"}); got != "<mark>short</mark> text" {
+		t.Errorf("Snippet() = %q", got)
+	}
+	if got := Words("Kafka-consumer, a kafka_2 ümlaut"); strings.Join(got, " ") != "consumer kafka ümlaut" {
+		t.Errorf("Words() = %v", got)
+	}
+}
+
+func TestSize(t *testing.T) {
+	ix := New()
+	ix.Put(Document{Session: "s1", ID: "m1", Text: "hello"})
+	ix.Put(Document{Session: "s1", ID: "t1", ToolName: "Bash", Text: "ls"})
+	ix.Put(Document{Session: "s1", ID: "m1", Text: "hello world"})
+	if got := ix.Size(); got != len("hello world")+len("Bash\nls") {
+		t.Errorf("Size() = %d", got)
+	}
+	ix.DeleteSession("s1")
+	if got := ix.Size(); got != 0 {
+		t.Errorf("Size() after DeleteSession = %d, want 0", got)
+	}
+}
@@ -231,6 +231,7 @@ func getCustomListKinds() map[schema.GroupVersionResource]string {
 	return map[schema.GroupVersionResource]string{
 		k8s.GetAgenticSessionV1Alpha1Resource(): "AgenticSessionList",
 		k8s.GetProjectSettingsResource():        "ProjectSettingsList",
+		k8s.GetScheduledSessionResource():       "ScheduledSessionList",
 	}
 }
 
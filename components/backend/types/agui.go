@@ -43,9 +43,10 @@ const (
 	EventTypeTextMessageEnd     = "TEXT_MESSAGE_END"
 
 	// Tool call events (streaming)
-	EventTypeToolCallStart = "TOOL_CALL_START"
-	EventTypeToolCallArgs  = "TOOL_CALL_ARGS"
-	EventTypeToolCallEnd   = "TOOL_CALL_END"
+	EventTypeToolCallStart  = "TOOL_CALL_START"
+	EventTypeToolCallArgs   = "TOOL_CALL_ARGS"
+	EventTypeToolCallEnd    = "TOOL_CALL_END"
+	EventTypeToolCallResult = "TOOL_CALL_RESULT"
 
 	// State management events
 	EventTypeStateSnapshot = "STATE_SNAPSHOT"
@@ -87,6 +88,7 @@ type BaseEvent struct {
 	ThreadID  string `json:"threadId"`
 	RunID     string `json:"runId"`
 	Timestamp int64  `json:"timestamp,omitempty"` // Epoch milliseconds (AG-UI spec)
+	Seq       int64  `json:"seq,omitempty"`       // Per-session sequence number, assigned when persisted; also the SSE id
 	// Optional fields
 	MessageID   string `json:"messageId,omitempty"`
 	ParentRunID string `json:"parentRunId,omitempty"`
@@ -230,7 +232,7 @@ type ToolCallEndEvent struct {
 // StateSnapshotEvent provides complete state for hydration
 type StateSnapshotEvent struct {
 	BaseEvent
-	State map[string]interface{} `json:"state"`
+	Snapshot map[string]interface{} `json:"snapshot"`
 }
 
 // StateDeltaEvent provides incremental state updates
@@ -350,14 +352,65 @@ type AGUIEventLog struct {
 
 // AGUIRunMetadata contains metadata about a run for indexing
 type AGUIRunMetadata struct {
-	ThreadID     string `json:"threadId"`
-	RunID        string `json:"runId"`
-	ParentRunID  string `json:"parentRunId,omitempty"`
-	SessionName  string `json:"sessionName"`
-	ProjectName  string `json:"projectName"`
-	StartedAt    string `json:"startedAt"`
-	FinishedAt   string `json:"finishedAt,omitempty"`
-	Status       string `json:"status"` // "running", "completed", "error"
-	EventCount   int    `json:"eventCount"`
-	RestartCount int    `json:"restartCount,omitempty"`
+	ThreadID      string   `json:"threadId"`
+	RunID         string   `json:"runId"`
+	ParentRunID   string   `json:"parentRunId,omitempty"`
+	SessionName   string   `json:"sessionName"`
+	ProjectName   string   `json:"projectName"`
+	StartedAt     string   `json:"startedAt"`
+	FinishedAt    string   `json:"finishedAt,omitempty"`
+	DurationMs    int64    `json:"durationMs,omitempty"`
+	Status        string   `json:"status"`          // "running", "completed", "error", "interrupted"
+	Error         string   `json:"error,omitempty"` // RUN_ERROR message
+	EventCount    int      `json:"eventCount"`
+	StartSeq      int64    `json:"startSeq,omitempty"` // seq of RUN_STARTED
+	EndSeq        int64    `json:"endSeq,omitempty"`   // seq of the run's last event
+	MessageCount  int      `json:"messageCount"`
+	ToolCallCount int      `json:"toolCallCount"`
+	ToolsUsed     []string `json:"toolsUsed,omitempty"` // distinct tool names, in order of first use
+	RestartCount  int      `json:"restartCount,omitempty"`
+}
+
+// SessionState is a session's agent state and activities as of one point
+// in its event log, rebuilt from STATE_* and ACTIVITY_* events
+type SessionState struct {
+	Seq        int64                  `json:"seq"`             // last event applied
+	RunID      string                 `json:"runId,omitempty"` // set when requested by run
+	State      map[string]interface{} `json:"state"`
+	Activities []Activity             `json:"activities"`
+}
+
+// EventChain is the result of verifying the hash chain of a session's
+// event log.  Each event's "hash" is the SHA-256 of the previous event's
+// hash and the event itself, so an edited, inserted or removed event
+// breaks the chain from that point.
+type EventChain struct {
+	Valid     bool   `json:"valid"`
+	Events    int    `json:"events"`              // events checked
+	Genesis   int64  `json:"genesis,omitempty"`   // seq the log was recorded to chain from
+	Unchained int    `json:"unchained,omitempty"` // leading events written before chaining
+	HeadSeq   int64  `json:"headSeq,omitempty"`   // last chained event
+	HeadHash  string `json:"headHash,omitempty"`
+	BrokenAt  int64  `json:"brokenAt,omitempty"` // seq of the first event that does not verify
+	Error     string `json:"error,omitempty"`
+	// Unverified lists the events declared changed after they were stored
+	// whose content no longer matches their hash: the chain vouches for
+	// their position, not for what they say
+	Unverified []int64 `json:"unverified,omitempty"`
+}
+
+// SearchHit is a message or tool call of a session transcript matching a
+// search query
+type SearchHit struct {
+	SessionName string `json:"sessionName"`
+	RunID       string `json:"runId,omitempty"`
+	Kind        string `json:"kind"`                 // "message" or "tool_call"
+	MessageID   string `json:"messageId,omitempty"`  // messages
+	Role        string `json:"role,omitempty"`       // messages
+	ToolCallID  string `json:"toolCallId,omitempty"` // tool calls
+	ToolName    string `json:"toolName,omitempty"`   // tool calls
+	Seq         int64  `json:"seq,omitempty"`
+	Timestamp   string `json:"timestamp,omitempty"`
+	Snippet     string `json:"snippet"` // HTML-escaped, matches wrapped in <mark>
+	Score       int    `json:"score"`
 }
@@ -3,6 +3,11 @@ package types
 
 // Common types used across the application
 
+// ErrorResponse is the body of every error response
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
 type GitRepository struct {
 	URL      string       `json:"url"`
 	Branch   *string      `json:"branch,omitempty"`
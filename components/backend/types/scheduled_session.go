@@ -0,0 +1,62 @@
+package types
+
+// ScheduledSession represents a cron-driven template that the operator uses to
+// create AgenticSessions on a schedule.
+type ScheduledSession struct {
+	APIVersion string                  `json:"apiVersion"`
+	Kind       string                  `json:"kind"`
+	Metadata   map[string]interface{}  `json:"metadata"`
+	Spec       ScheduledSessionSpec    `json:"spec"`
+	Status     *ScheduledSessionStatus `json:"status,omitempty"`
+}
+
+type ScheduledSessionSpec struct {
+	Schedule                       string                   `json:"schedule"`
+	TimeZone                       string                   `json:"timeZone,omitempty"`
+	Suspend                        bool                     `json:"suspend"`
+	ConcurrencyPolicy              string                   `json:"concurrencyPolicy,omitempty"`
+	StartingDeadlineSeconds        *int64                   `json:"startingDeadlineSeconds,omitempty"`
+	SuccessfulSessionsHistoryLimit *int                     `json:"successfulSessionsHistoryLimit,omitempty"`
+	FailedSessionsHistoryLimit     *int                     `json:"failedSessionsHistoryLimit,omitempty"`
+	Template                       ScheduledSessionTemplate `json:"template"`
+}
+
+// ScheduledSessionTemplate is stamped onto every AgenticSession the schedule creates
+type ScheduledSessionTemplate struct {
+	Labels      map[string]string  `json:"labels,omitempty"`
+	Annotations map[string]string  `json:"annotations,omitempty"`
+	Spec        AgenticSessionSpec `json:"spec"`
+}
+
+type ScheduledSessionStatus struct {
+	ObservedGeneration int64       `json:"observedGeneration,omitempty"`
+	Active             []string    `json:"active,omitempty"`
+	LastScheduleTime   *string     `json:"lastScheduleTime,omitempty"`
+	LastSuccessfulTime *string     `json:"lastSuccessfulTime,omitempty"`
+	NextScheduleTime   *string     `json:"nextScheduleTime,omitempty"`
+	Conditions         []Condition `json:"conditions,omitempty"`
+}
+
+type CreateScheduledSessionRequest struct {
+	// Name is optional; a timestamp-based name is generated when empty
+	Name                           string                      `json:"name,omitempty"`
+	Schedule                       string                      `json:"schedule" binding:"required"`
+	TimeZone                       string                      `json:"timeZone,omitempty"`
+	Suspend                        *bool                       `json:"suspend,omitempty"`
+	ConcurrencyPolicy              string                      `json:"concurrencyPolicy,omitempty"`
+	StartingDeadlineSeconds        *int64                      `json:"startingDeadlineSeconds,omitempty"`
+	SuccessfulSessionsHistoryLimit *int                        `json:"successfulSessionsHistoryLimit,omitempty"`
+	FailedSessionsHistoryLimit     *int                        `json:"failedSessionsHistoryLimit,omitempty"`
+	Template                       CreateAgenticSessionRequest `json:"template"`
+}
+
+type UpdateScheduledSessionRequest struct {
+	Schedule                       *string                      `json:"schedule,omitempty"`
+	TimeZone                       *string                      `json:"timeZone,omitempty"`
+	Suspend                        *bool                        `json:"suspend,omitempty"`
+	ConcurrencyPolicy              *string                      `json:"concurrencyPolicy,omitempty"`
+	StartingDeadlineSeconds        *int64                       `json:"startingDeadlineSeconds,omitempty"`
+	SuccessfulSessionsHistoryLimit *int                         `json:"successfulSessionsHistoryLimit,omitempty"`
+	FailedSessionsHistoryLimit     *int                         `json:"failedSessionsHistoryLimit,omitempty"`
+	Template                       *CreateAgenticSessionRequest `json:"template,omitempty"`
+}
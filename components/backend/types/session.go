@@ -19,6 +19,7 @@ type AgenticSessionSpec struct {
 	LLMSettings          LLMSettings        `json:"llmSettings"`
 	Timeout              int                `json:"timeout"`
 	InactivityTimeout    *int               `json:"inactivityTimeout,omitempty"`
+	Priority             int                `json:"priority,omitempty"`
 	UserContext          *UserContext       `json:"userContext,omitempty"`
 	BotAccount           *BotAccountRef     `json:"botAccount,omitempty"`
 	ResourceOverrides    *ResourceOverrides `json:"resourceOverrides,omitempty"`
@@ -28,6 +29,25 @@ type AgenticSessionSpec struct {
 	Repos []SimpleRepo `json:"repos,omitempty"`
 	// Active workflow for dynamic workflow switching
 	ActiveWorkflow *WorkflowSelection `json:"activeWorkflow,omitempty"`
+	// Follow-up session the operator starts when this one completes
+	Next *SessionNext `json:"next,omitempty"`
+	// Seconds after reaching Completed or Failed before the operator deletes the session
+	TTLSecondsAfterFinished *int64 `json:"ttlSecondsAfterFinished,omitempty"`
+}
+
+// SessionNext declares a follow-up session created automatically when the current one
+// reaches Completed. Nesting Next builds multi-step pipelines (plan -> implement -> review).
+type SessionNext struct {
+	InitialPrompt string       `json:"initialPrompt"`
+	DisplayName   string       `json:"displayName,omitempty"`
+	LLMSettings   *LLMSettings `json:"llmSettings,omitempty"`
+	Timeout       *int         `json:"timeout,omitempty"`
+	Interactive   *bool        `json:"interactive,omitempty"`
+	// CarryRepos defaults to true: repos are carried over on the branch the session ended on
+	CarryRepos *bool `json:"carryRepos,omitempty"`
+	// Artifacts are paths relative to the artifacts directory copied into the follow-up's workspace
+	Artifacts []string     `json:"artifacts,omitempty"`
+	Next      *SessionNext `json:"next,omitempty"`
 }
 
 // SimpleRepo represents a simplified repository configuration
@@ -48,6 +68,7 @@ type AgenticSessionStatus struct {
 	ReconciledWorkflow *ReconciledWorkflow `json:"reconciledWorkflow,omitempty"`
 	SDKSessionID       string              `json:"sdkSessionId,omitempty"`
 	SDKRestartCount    int                 `json:"sdkRestartCount,omitempty"`
+	Usage              *SessionUsage       `json:"usage,omitempty"`
 	Conditions         []Condition         `json:"conditions,omitempty"`
 }
 
@@ -58,12 +79,17 @@ type CreateAgenticSessionRequest struct {
 	Timeout         *int         `json:"timeout,omitempty"`
 	Interactive     *bool        `json:"interactive,omitempty"`
 	ParentSessionID string       `json:"parent_session_id,omitempty"`
+	// Priority orders admission when the project's concurrency quota queues sessions
+	Priority *int `json:"priority,omitempty"`
+	// TTLSecondsAfterFinished deletes the session that long after it completes or fails
+	TTLSecondsAfterFinished *int64 `json:"ttlSecondsAfterFinished,omitempty"`
 	// Multi-repo support
 	Repos                []SimpleRepo      `json:"repos,omitempty"`
 	UserContext          *UserContext      `json:"userContext,omitempty"`
 	EnvironmentVariables map[string]string `json:"environmentVariables,omitempty"`
 	Labels               map[string]string `json:"labels,omitempty"`
 	Annotations          map[string]string `json:"annotations,omitempty"`
+	Next                 *SessionNext      `json:"next,omitempty"`
 }
 
 type CloneSessionRequest struct {
@@ -71,6 +97,14 @@ type CloneSessionRequest struct {
 	NewSessionName string `json:"newSessionName" binding:"required"`
 }
 
+// ForkSessionRequest names the point of the source session's history a fork
+// starts from: the end of run RunID, or event Seq.  Exactly one is set.
+type ForkSessionRequest struct {
+	RunID          string `json:"runId,omitempty"`
+	Seq            int64  `json:"seq,omitempty"`
+	NewSessionName string `json:"newSessionName,omitempty"`
+}
+
 type UpdateAgenticSessionRequest struct {
 	InitialPrompt *string      `json:"initialPrompt,omitempty"`
 	DisplayName   *string      `json:"displayName,omitempty"`
@@ -0,0 +1,64 @@
+package types
+
+// TokenUsage is an accumulated count of LLM tokens and cost
+type TokenUsage struct {
+	InputTokens              int64   `json:"inputTokens"`
+	OutputTokens             int64   `json:"outputTokens"`
+	CacheReadInputTokens     int64   `json:"cacheReadInputTokens"`
+	CacheCreationInputTokens int64   `json:"cacheCreationInputTokens"`
+	CostUSD                  float64 `json:"costUsd"`
+	Runs                     int64   `json:"runs"`
+}
+
+// Add accumulates other into u
+func (u *TokenUsage) Add(other TokenUsage) {
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.CacheReadInputTokens += other.CacheReadInputTokens
+	u.CacheCreationInputTokens += other.CacheCreationInputTokens
+	u.CostUSD += other.CostUSD
+	u.Runs += other.Runs
+}
+
+// IsZero reports whether no tokens or cost were recorded
+func (u TokenUsage) IsZero() bool {
+	return u.InputTokens == 0 && u.OutputTokens == 0 && u.CacheReadInputTokens == 0 &&
+		u.CacheCreationInputTokens == 0 && u.CostUSD == 0
+}
+
+// UsageBucket is the usage for one day or month, with the share of each model.
+type UsageBucket struct {
+	TokenUsage
+	Models map[string]TokenUsage `json:"models,omitempty"`
+}
+
+// SessionUsage is stored in AgenticSession status.usage. Daily buckets (keyed by UTC date,
+// YYYY-MM-DD) allow time-range rollups without keeping a record per run. Only the most
+// recent days are kept; older buckets are folded into Monthly (keyed YYYY-MM) so the
+// status stays bounded for long-lived sessions.
+type SessionUsage struct {
+	TokenUsage
+	Models      map[string]TokenUsage  `json:"models,omitempty"`
+	Daily       map[string]UsageBucket `json:"daily,omitempty"`
+	Monthly     map[string]UsageBucket `json:"monthly,omitempty"`
+	LastUpdated string                 `json:"lastUpdated,omitempty"`
+}
+
+// UsageRollup is a usage total for one user, model or day. Day keys are YYYY-MM-DD,
+// except for usage older than the daily retention, which is reported per month (YYYY-MM).
+type UsageRollup struct {
+	Key   string     `json:"key"`
+	Usage TokenUsage `json:"usage"`
+}
+
+// ProjectUsageResponse is returned by GET /api/projects/:projectName/usage
+type ProjectUsageResponse struct {
+	Project  string        `json:"project"`
+	From     string        `json:"from"`
+	To       string        `json:"to"`
+	Total    TokenUsage    `json:"total"`
+	Sessions int           `json:"sessions"`
+	ByUser   []UsageRollup `json:"byUser"`
+	ByModel  []UsageRollup `json:"byModel"`
+	ByDay    []UsageRollup `json:"byDay"`
+}
@@ -12,6 +12,7 @@
 package websocket
 
 import (
+	"ambient-code-backend/eventstore"
 	"ambient-code-backend/handlers"
 	"ambient-code-backend/types"
 	"bufio"
@@ -22,6 +23,7 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -55,9 +57,20 @@ var lastActivityUpdateTimes sync.Map
 
 // sessionProjectMap maps sessionName → projectName so that persistStreamedEvent
 // (which only receives sessionID) can look up the project for activity tracking.
-// Populated by HandleAGUIRunProxy on each run request.
+// Populated by HandleAGUIRunProxy on each run request, and by rememberSession.
+// Like sessionRedactors it only covers sessions this replica has served.
 var sessionProjectMap sync.Map
 
+// rememberSession records the project of a session whose events this replica
+// writes outside a run it proxies, and loads the session's redactor if this
+// replica has none, so the event is redacted and indexed like the run's.
+func rememberSession(projectName, sessionName string) {
+	sessionProjectMap.Store(sessionName, projectName)
+	if _, ok := sessionRedactors.Load(sessionName); !ok {
+		refreshSessionRedactor(projectName, sessionName)
+	}
+}
+
 // HandleAGUIEvents serves the AG-UI event stream over SSE.  Clients
 // (typically EventSource) connect here to receive all events for a
 // session — both persisted history and live events from active runs.
@@ -66,6 +79,9 @@ var sessionProjectMap sync.Map
 //
 //	POST /agui/run  → starts a run, returns JSON metadata immediately
 //	GET  /agui/events → SSE stream of all thread events (past + future)
+//
+// Every event carries its per-session sequence number as the SSE id, so
+// a client reconnecting with Last-Event-ID only receives what it missed.
 func HandleAGUIEvents(c *gin.Context) {
 	projectName := c.Param("projectName")
 	sessionName := c.Param("sessionName")
@@ -95,40 +111,11 @@ func HandleAGUIEvents(c *gin.Context) {
 	// Subscribe to live broadcast pipe BEFORE loading persisted events.
 	// This ordering prevents a race where events published between
 	// loadEvents() and subscribeLive() would be missed by the client.
-	liveCh, cleanup := subscribeLive(sessionName)
+	live, cleanup := subscribeLive(sessionName)
 	defer cleanup()
 
-	events := loadEvents(sessionName)
-
-	if len(events) > 0 {
-		// Check if the last run is finished.
-		runFinished := false
-		if last := events[len(events)-1]; last != nil {
-			if t, _ := last["type"].(string); t == types.EventTypeRunFinished {
-				runFinished = true
-			}
-		}
-
-		if runFinished {
-			// Finished runs get compacted replay (fast, small).
-			compacted := compactStreamingEvents(events)
-			log.Printf("AGUI Events: %d raw → %d compacted events for %s (finished)", len(events), len(compacted), sessionName)
-			for _, evt := range compacted {
-				writeSSEEvent(c.Writer, evt)
-			}
-		} else {
-			// Active run — send raw events to preserve streaming structure.
-			log.Printf("AGUI Events: replaying %d raw events for %s (running)", len(events), sessionName)
-			for _, evt := range events {
-				writeSSEEvent(c.Writer, evt)
-			}
-		}
-		c.Writer.Flush()
-	}
-
-	// Drain live events buffered during replay — they are already
-	// covered by the persisted events we just sent.
-	drainLiveChannel(liveCh)
+	stream := &replayStream{out: sseWriter{c.Writer}}
+	stream.replay(sessionName, parseLastEventID(c))
 
 	// Tail live events until client disconnects.
 	// Send SSE comments as keepalive every 15s to prevent proxies
@@ -142,12 +129,11 @@ func HandleAGUIEvents(c *gin.Context) {
 		case <-clientGone:
 			log.Printf("AGUI Events: client disconnected for %s", sessionName)
 			return
-		case line, ok := <-liveCh:
+		case line, ok := <-live.C:
 			if !ok {
 				return
 			}
-			fmt.Fprint(c.Writer, line)
-			c.Writer.Flush()
+			stream.forward(sessionName, live, line)
 		case <-heartbeat.C:
 			// SSE comment — ignored by EventSource but keeps connection alive
 			fmt.Fprint(c.Writer, ": heartbeat\n\n")
@@ -156,6 +142,160 @@ func HandleAGUIEvents(c *gin.Context) {
 	}
 }
 
+// eventWriter is the transport half of a replayStream.
+type eventWriter interface {
+	// writeEvent sends one event, tagged with id when it is positive.
+	writeEvent(event map[string]interface{}, id int64)
+	// writeLine sends a frame from the live pipe.
+	writeLine(line string)
+}
+
+// sseWriter writes to an SSE response; live frames are already SSE.
+type sseWriter struct {
+	w gin.ResponseWriter
+}
+
+func (s sseWriter) writeEvent(event map[string]interface{}, id int64) {
+	writeSSEEvent(s.w, event, id)
+}
+
+func (s sseWriter) writeLine(line string) {
+	fmt.Fprint(s.w, line)
+	s.w.Flush()
+}
+
+// replayStream writes persisted and live events to one client and
+// tracks the last id it sent, so live lines already covered by a replay
+// are skipped and ids never go backwards.
+type replayStream struct {
+	out    eventWriter
+	lastID int64
+}
+
+// replay sends the session's history: everything after lastEventID when
+// the client is resuming, otherwise the whole log.  Call it after
+// subscribing to the live pipe, so no event falls between the two.
+func (r *replayStream) replay(sessionName string, lastEventID int64) {
+	history := loadReplayHistory(sessionName)
+
+	// An id beyond the end of the log belongs to a log that no longer
+	// exists — fall back to a full replay.
+	if lastEventID > history.lastSeq() {
+		lastEventID = 0
+	}
+
+	if lastEventID > 0 {
+		// Reconnect — send only what the client missed, raw, so the
+		// ids it resumes from are never ahead of what it received.
+		r.lastID = lastEventID
+		missed := history.after(sessionName, lastEventID)
+		log.Printf("AGUI Events: resuming %s after event %d (%d missed)", sessionName, lastEventID, len(missed))
+		r.write(missed)
+	} else if !history.empty() {
+		// Check if the last run is finished.
+		runFinished := false
+		if last := history.last(); last != nil {
+			if t, _ := last["type"].(string); t == types.EventTypeRunFinished {
+				runFinished = true
+			}
+		}
+
+		if runFinished {
+			// Finished runs get compacted replay (fast, small).
+			compacted := history.compacted()
+			log.Printf("AGUI Events: %d snapshot + %d raw → %d compacted events for %s (finished)", len(history.snapshot), len(history.tail), len(compacted), sessionName)
+			r.write(compacted)
+		} else {
+			// Active run — send the raw events after the snapshot of
+			// finished runs to preserve streaming structure.
+			log.Printf("AGUI Events: replaying %d snapshot + %d raw events for %s (running)", len(history.snapshot), len(history.tail), sessionName)
+			r.write(history.snapshot)
+			r.write(history.tail)
+		}
+	}
+	if len(history.tail) >= snapshotMinEvents {
+		requestSnapshot(sessionName)
+	}
+}
+
+// write sends persisted events.  Compacted replays may emit an event
+// after one with a higher seq, so each event carries the highest seq
+// sent so far.
+func (r *replayStream) write(events []map[string]interface{}) {
+	for _, evt := range events {
+		if seq := eventstore.Seq(evt); seq > r.lastID {
+			r.lastID = seq
+		}
+		r.out.writeEvent(evt, r.lastID)
+	}
+}
+
+// forward sends a line received from the live subscription, first
+// catching up from the store if the subscription dropped lines.
+func (r *replayStream) forward(sessionName string, live *liveSubscription, line string) {
+	if live.overflowed() {
+		// The client fell more than liveBufferSize lines behind and
+		// some were dropped — tell it, then catch up from the store.
+		log.Printf("AGUI Events: live buffer overflowed for %s, resyncing after event %d", sessionName, r.lastID)
+		r.out.writeEvent(streamResyncEvent(sessionName, r.lastID), 0)
+		r.write(loadEventsAfter(sessionName, r.lastID))
+	}
+	r.writeLive(sessionName, line)
+}
+
+// writeLive forwards a line from the live pipe.  Lines at or below
+// lastID were already replayed; a line that skips ahead means events
+// were persisted but published out of order (e.g. feedback racing a
+// run), so the gap is filled from the store first.
+func (r *replayStream) writeLive(sessionName, line string) {
+	if id := sseFrameID(line); id > 0 {
+		if id <= r.lastID {
+			return
+		}
+		if id > r.lastID+1 {
+			for _, evt := range loadEventsAfter(sessionName, r.lastID) {
+				if eventstore.Seq(evt) >= id {
+					break
+				}
+				r.lastID = eventstore.Seq(evt)
+				r.out.writeEvent(evt, r.lastID)
+			}
+		}
+		r.lastID = id
+	}
+	r.out.writeLine(line)
+}
+
+// parseLastEventID returns the id the client last received, from the
+// Last-Event-ID header EventSource sends on reconnect or, for clients
+// that cannot set headers, the lastEventId query parameter.
+func parseLastEventID(c *gin.Context) int64 {
+	value := c.GetHeader("Last-Event-ID")
+	if value == "" {
+		value = c.Query("lastEventId")
+	}
+	id, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || id < 0 {
+		return 0
+	}
+	return id
+}
+
+// streamResyncEvent tells a client that live events were dropped and the
+// ones after lastEventID are being resent from the store.  It is not
+// persisted.
+func streamResyncEvent(threadID string, lastEventID int64) map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "RAW",
+		"threadId": threadID,
+		"event": map[string]interface{}{
+			"type":        "stream_resync",
+			"reason":      "gap detected",
+			"lastEventId": lastEventID,
+		},
+	}
+}
+
 // HandleAGUIRunProxy accepts an AG-UI run request, forwards it to the
 // runner pod in a background goroutine, and returns JSON metadata
 // immediately.  Events are persisted and broadcast to GET /agui/events
@@ -185,6 +325,13 @@ func HandleAGUIRunProxy(c *gin.Context) {
 		return
 	}
 
+	status, resp := startRun(projectName, sessionName, input)
+	c.JSON(status, resp)
+}
+
+// startRun forwards a run request to the runner in the background and
+// returns the response for the client: the run and thread IDs.
+func startRun(projectName, sessionName string, input types.RunAgentInput) (int, gin.H) {
 	// Generate or use provided IDs
 	threadID := input.ThreadID
 	if threadID == "" {
@@ -207,6 +354,8 @@ func HandleAGUIRunProxy(c *gin.Context) {
 
 	// Store project→session mapping for activity tracking in persistStreamedEvent
 	sessionProjectMap.Store(sessionName, projectName)
+	// Pick up the project's current redaction patterns and runner secrets
+	refreshSessionRedactor(projectName, sessionName)
 
 	// Parse messages for display name generation and hidden metadata
 	var minimalMsgs []types.Message
@@ -233,8 +382,7 @@ func HandleAGUIRunProxy(c *gin.Context) {
 	// ── Forward to runner in background, return JSON immediately ──
 	bodyBytes, err := json.Marshal(input)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to serialize input"})
-		return
+		return http.StatusInternalServerError, gin.H{"error": "Failed to serialize input"}
 	}
 
 	runnerURL := getRunnerEndpoint(projectName, sessionName)
@@ -243,10 +391,10 @@ func HandleAGUIRunProxy(c *gin.Context) {
 	go proxyRunnerStream(runnerURL, bodyBytes, sessionName, runID, threadID)
 
 	// Return metadata immediately — events arrive via GET /agui/events
-	c.JSON(http.StatusOK, gin.H{
+	return http.StatusOK, gin.H{
 		"runId":    runID,
 		"threadId": threadID,
-	})
+	}
 }
 
 // proxyRunnerStream connects to the runner's SSE endpoint, reads events,
@@ -271,6 +419,8 @@ func proxyRunnerStream(runnerURL string, bodyBytes []byte, sessionName, runID, t
 	}
 
 	// Pipe SSE from runner: persist each event and broadcast to subscribers
+	var usage runUsageTracker
+	deltas := newDeltaRedactor()
 	reader := bufio.NewReader(resp.Body)
 	for {
 		line, err := reader.ReadString('\n')
@@ -282,18 +432,39 @@ func proxyRunnerStream(runnerURL string, bodyBytes []byte, sessionName, runID, t
 		}
 
 		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue // frames are re-terminated when published
+		}
 
-		// Persist every data event to JSONL
+		// Persist every data event to JSONL and publish it with its seq
 		if strings.HasPrefix(trimmed, "data: ") {
 			jsonData := strings.TrimPrefix(trimmed, "data: ")
-			persistStreamedEvent(sessionName, runID, threadID, jsonData)
+			usage.observe(jsonData)
+			if event := parseStreamedEvent(runID, threadID, jsonData); event != nil {
+				for _, evt := range deltas.next(event) {
+					publishEvent(sessionName, persistStreamedEvent(sessionName, evt), evt)
+				}
+				continue
+			}
 		}
 
-		// Publish raw SSE line to all GET /agui/events subscribers
-		publishLine(sessionName, line)
+		// Anything else (comments, unparseable data) goes out as-is
+		publishLine(sessionName, trimmed+"\n\n")
+	}
+	// Text still held back when the runner goes away
+	for _, evt := range deltas.flush() {
+		publishEvent(sessionName, persistStreamedEvent(sessionName, evt), evt)
 	}
 
 	log.Printf("AGUI Proxy: run %s stream ended", truncID(runID))
+	requestSnapshot(sessionName)
+
+	// Accumulate the run's token usage on the session CR
+	if runUsage, byModel := usage.total(); !runUsage.IsZero() {
+		if projectName, ok := sessionProjectMap.Load(sessionName); ok {
+			recordRunUsage(projectName.(string), sessionName, runUsage, byModel)
+		}
+	}
 }
 
 // publishAndPersistErrorEvents generates RUN_STARTED + RUN_ERROR events,
@@ -306,9 +477,7 @@ func publishAndPersistErrorEvents(sessionName, runID, threadID, message string)
 		"threadId": threadID,
 		"runId":    runID,
 	}
-	persistEvent(sessionName, startEvt)
-	startData, _ := json.Marshal(startEvt)
-	publishLine(sessionName, fmt.Sprintf("data: %s\n\n", startData))
+	publishEvent(sessionName, persistEvent(sessionName, startEvt), startEvt)
 
 	// RUN_ERROR
 	errEvt := map[string]interface{}{
@@ -317,9 +486,7 @@ func publishAndPersistErrorEvents(sessionName, runID, threadID, message string)
 		"threadId": threadID,
 		"runId":    runID,
 	}
-	persistEvent(sessionName, errEvt)
-	errData, _ := json.Marshal(errEvt)
-	publishLine(sessionName, fmt.Sprintf("data: %s\n\n", errData))
+	publishEvent(sessionName, persistEvent(sessionName, errEvt), errEvt)
 }
 
 // ─── Hidden message helpers ──────────────────────────────────────────
@@ -351,22 +518,20 @@ func emitHiddenMessageMetadata(sessionName, runID, threadID, messageID string) {
 			"hidden":    true,
 		},
 	}
-	persistEvent(sessionName, evt)
-	data, _ := json.Marshal(evt)
-	publishLine(sessionName, fmt.Sprintf("data: %s\n\n", data))
+	publishEvent(sessionName, persistEvent(sessionName, evt), evt)
 }
 
-// persistStreamedEvent parses a raw JSON event, ensures IDs, and
-// appends it to the event log.  No in-memory state, no broadcasting.
+// parseStreamedEvent parses a raw JSON event from the runner and ensures
+// its IDs.  It returns nil if jsonData is not a JSON object.
 //
 // NOTE: We intentionally do NOT inject timestamps.  The AG-UI spec
 // defines timestamp as z.number().optional() (epoch ms).  If the
 // runner omits it, the field stays absent — the proxy should not
 // invent fields the source didn't emit.
-func persistStreamedEvent(sessionID, runID, threadID, jsonData string) {
+func parseStreamedEvent(runID, threadID, jsonData string) map[string]interface{} {
 	var event map[string]interface{}
-	if err := json.Unmarshal([]byte(jsonData), &event); err != nil {
-		return
+	if err := json.Unmarshal([]byte(jsonData), &event); err != nil || event == nil {
+		return nil
 	}
 
 	// Ensure required fields (threadId + runId are needed for compaction)
@@ -376,8 +541,14 @@ func persistStreamedEvent(sessionID, runID, threadID, jsonData string) {
 	if event["runId"] == nil || event["runId"] == "" {
 		event["runId"] = runID
 	}
+	return event
+}
 
-	persistEvent(sessionID, event)
+// persistStreamedEvent appends an event from the runner's stream to the
+// event log and returns its sequence number.  No broadcasting — the
+// caller publishes.
+func persistStreamedEvent(sessionID string, event map[string]interface{}) int64 {
+	seq := persistEvent(sessionID, event)
 
 	// Update lastActivityTime on CR for activity events (debounced).
 	// Extract event type to check; projectName is derived from the
@@ -388,6 +559,7 @@ func persistStreamedEvent(sessionID, runID, threadID, jsonData string) {
 			updateLastActivityTime(projectName.(string), sessionID, eventType == types.EventTypeRunStarted)
 		}
 	}
+	return seq
 }
 
 // ─── POST /agui/interrupt ────────────────────────────────────────────
@@ -409,30 +581,33 @@ func HandleAGUIInterrupt(c *gin.Context) {
 		return
 	}
 
+	status, resp := sendInterrupt(projectName, sessionName)
+	c.JSON(status, resp)
+}
+
+// sendInterrupt asks the runner to interrupt the current run.
+func sendInterrupt(projectName, sessionName string) (int, gin.H) {
 	runnerURL := getRunnerEndpoint(projectName, sessionName)
 	interruptURL := strings.TrimSuffix(runnerURL, "/") + "/interrupt"
 
 	req, err := http.NewRequest("POST", interruptURL, bytes.NewReader([]byte("{}")))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		return http.StatusInternalServerError, gin.H{"error": err.Error()}
 	}
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
 	if err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
-		return
+		return http.StatusBadGateway, gin.H{"error": err.Error()}
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		c.JSON(resp.StatusCode, gin.H{"error": string(body)})
-		return
+		return resp.StatusCode, gin.H{"error": string(body)}
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Interrupt signal sent"})
+	return http.StatusOK, gin.H{"message": "Interrupt signal sent"}
 }
 
 // ─── POST /agui/feedback ─────────────────────────────────────────────
@@ -466,10 +641,16 @@ func HandleAGUIFeedback(c *gin.Context) {
 		return
 	}
 
+	status, resp := submitFeedback(projectName, sessionName, metaEvent)
+	c.JSON(status, resp)
+}
+
+// submitFeedback forwards a META feedback event to the runner and
+// persists and broadcasts the RAW event it returns.
+func submitFeedback(projectName, sessionName string, metaEvent map[string]interface{}) (int, gin.H) {
 	eventType, _ := metaEvent["type"].(string)
 	if eventType != types.EventTypeMeta {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Expected META event type"})
-		return
+		return http.StatusBadRequest, gin.H{"error": "Expected META event type"}
 	}
 
 	// Forward to runner — it sends to Langfuse and returns a RAW event
@@ -479,40 +660,38 @@ func HandleAGUIFeedback(c *gin.Context) {
 	bodyBytes, _ := json.Marshal(metaEvent)
 	req, err := http.NewRequest("POST", feedbackURL, bytes.NewReader(bodyBytes))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create request"})
-		return
+		return http.StatusInternalServerError, gin.H{"error": "Failed to create request"}
 	}
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
 	if err != nil {
-		c.JSON(http.StatusAccepted, gin.H{"error": "Runner unavailable — feedback not recorded", "status": "failed"})
-		return
+		return http.StatusAccepted, gin.H{"error": "Runner unavailable — feedback not recorded", "status": "failed"}
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		log.Printf("AGUI Feedback: runner returned %d for %s: %s", resp.StatusCode, sessionName, string(body))
-		c.JSON(resp.StatusCode, gin.H{"error": "Runner rejected feedback", "status": "failed"})
-		return
+		return resp.StatusCode, gin.H{"error": "Runner rejected feedback", "status": "failed"}
 	}
 
-	// Runner returned a RAW event — persist it directly (no run wrapping needed).
+	// Runner returned a RAW event — persist and broadcast it directly (no run wrapping needed).
 	var rawEvent map[string]interface{}
 	if err := json.NewDecoder(resp.Body).Decode(&rawEvent); err != nil {
 		log.Printf("AGUI Feedback: failed to decode runner response for %s: %v", sessionName, err)
-		c.JSON(http.StatusOK, gin.H{"message": "Feedback sent but not persisted", "status": "sent"})
-		return
+		return http.StatusOK, gin.H{"message": "Feedback sent but not persisted", "status": "sent"}
 	}
 
+	// The run may have been proxied by another replica
+	rememberSession(projectName, sessionName)
 	go func() {
 		threadID := sessionName
 		rawEvent["threadId"] = threadID
-		persistEvent(sessionName, rawEvent)
+		publishEvent(sessionName, persistEvent(sessionName, rawEvent), rawEvent)
 	}()
 
-	c.JSON(http.StatusOK, gin.H{"message": "Feedback submitted", "status": "sent"})
+	return http.StatusOK, gin.H{"message": "Feedback submitted", "status": "sent"}
 }
 
 // ─── GET /agui/capabilities ──────────────────────────────────────────
@@ -671,20 +850,6 @@ func getRunnerEndpoint(projectName, sessionName string) string {
 	return fmt.Sprintf("http://session-%s.%s.svc.cluster.local:8001/", sessionName, projectName)
 }
 
-// drainLiveChannel discards any buffered lines already in the channel.
-// Called after replaying persisted events to skip duplicates that were
-// published to the live pipe while the replay was in progress.
-func drainLiveChannel(ch <-chan string) {
-	for {
-		select {
-		case <-ch:
-			// discard — already replayed from persisted events
-		default:
-			return // buffer is empty
-		}
-	}
-}
-
 // truncID returns the first 8 chars of an ID for logging, or the
 // full string if shorter.
 func truncID(id string) string {
@@ -1,6 +1,7 @@
 package websocket
 
 import (
+	"fmt"
 	"testing"
 
 	"ambient-code-backend/types"
@@ -53,3 +54,61 @@ func TestIsActivityEvent(t *testing.T) {
 		})
 	}
 }
+
+// benchmarkReplayLog persists a long session: runs runs of deltas deltas each.
+func benchmarkReplayLog(b *testing.B, runs, deltas int) string {
+	b.Helper()
+	useTempStateDir(b)
+	session := "bench-session"
+	for i := 0; i < runs; i++ {
+		appendRun(session, fmt.Sprintf("r%d", i), deltas, true)
+	}
+	return session
+}
+
+// BenchmarkReplay measures what HandleAGUIEvents does on connect for a
+// finished session: load the log and compact it.
+func BenchmarkReplay(b *testing.B) {
+	for _, bc := range []struct {
+		name     string
+		snapshot bool
+		compress bool
+	}{
+		{"full", false, false},
+		{"snapshot", true, false},
+		{"snapshot-zstd", true, true},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			session := benchmarkReplayLog(b, 200, 100)
+			CompressSnapshots = bc.compress
+			defer func() { CompressSnapshots = false }()
+			if bc.snapshot {
+				if err := buildSnapshot(session, 1); err != nil {
+					b.Fatal(err)
+				}
+				// A run since the snapshot, as after a typical reconnect
+				appendRun(session, "latest", 100, true)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				history := loadReplayHistory(session)
+				if len(history.compacted()) == 0 {
+					b.Fatal("empty replay")
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkBuildSnapshot measures a background compaction of a long log.
+func BenchmarkBuildSnapshot(b *testing.B) {
+	session := benchmarkReplayLog(b, 200, 100)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dropSnapshot(session)
+		if err := buildSnapshot(session, 1); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+// agui_snapshot.go — Materialized replay snapshots.
+//
+// A snapshot is the compacted replay of a session's log up to the end of
+// its last finished run, plus where that prefix ends in the log: its last
+// seq and, for stores read by byte offset (JSONL), the offset after it.
+// Connect handlers start from the snapshot and only read and compact the
+// events after it, so reconnects no longer slow down as a session grows.
+//
+// Snapshots are rebuilt in the background after runs end.  They are a
+// cache: a missing, unreadable or stale snapshot means a full replay.
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"ambient-code-backend/eventstore"
+	"ambient-code-backend/types"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	snapshotFileName = "agui-snapshot.json"
+	snapshotVersion  = 1
+
+	// snapshotMinEvents is how many events must follow the current
+	// snapshot before rebuilding it is worth the write.
+	snapshotMinEvents = 1000
+)
+
+// CompressSnapshots writes snapshots zstd-compressed (agui-snapshot.json.zst).
+// Set at startup from AGUI_SNAPSHOT_COMPRESSION=zstd.  Both forms are read.
+var CompressSnapshots bool
+
+// replaySnapshot is the on-disk form of a snapshot.
+type replaySnapshot struct {
+	Version int                      `json:"version"`
+	Seq     int64                    `json:"seq"`              // last event covered
+	Offset  int64                    `json:"offset,omitempty"` // log offset after Seq (OffsetLoader stores only)
+	Events  []map[string]interface{} `json:"events"`
+}
+
+var (
+	snapshotEncoder = sync.OnceValue(func() *zstd.Encoder {
+		enc, _ := zstd.NewWriter(nil)
+		return enc
+	})
+	snapshotDecoder = sync.OnceValue(func() *zstd.Decoder {
+		dec, _ := zstd.NewReader(nil)
+		return dec
+	})
+)
+
+func snapshotPath(sessionID string, compressed bool) string {
+	path := filepath.Join(StateBaseDir, "sessions", sessionID, snapshotFileName)
+	if compressed {
+		path += ".zst"
+	}
+	return path
+}
+
+// readSnapshot returns the session's snapshot, or nil if it has none
+// that this version can use.
+func readSnapshot(sessionID string) *replaySnapshot {
+	for _, compressed := range []bool{true, false} {
+		data, err := os.ReadFile(snapshotPath(sessionID, compressed))
+		if err != nil {
+			continue
+		}
+		if compressed {
+			if data, err = snapshotDecoder().DecodeAll(data, nil); err != nil {
+				log.Printf("AGUI Snapshot: failed to decompress snapshot of %s: %v", sessionID, err)
+				return nil
+			}
+		}
+		var snap replaySnapshot
+		if err := json.Unmarshal(data, &snap); err != nil || snap.Version != snapshotVersion {
+			return nil
+		}
+		return &snap
+	}
+	return nil
+}
+
+// writeSnapshot atomically replaces the session's snapshot.
+func writeSnapshot(sessionID string, snap *replaySnapshot) error {
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	if CompressSnapshots {
+		data = snapshotEncoder().EncodeAll(data, nil)
+	}
+	path := snapshotPath(sessionID, CompressSnapshots)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	// Drop the other form so a stale one is never read
+	os.Remove(snapshotPath(sessionID, !CompressSnapshots))
+	return nil
+}
+
+// dropSnapshot deletes the session's snapshot.  Anything that rewrites a
+// log (rather than appending to it) must call this.
+func dropSnapshot(sessionID string) {
+	for _, compressed := range []bool{true, false} {
+		if err := os.Remove(snapshotPath(sessionID, compressed)); err != nil && !os.IsNotExist(err) {
+			log.Printf("AGUI Snapshot: failed to remove snapshot of %s: %v", sessionID, err)
+		}
+	}
+}
+
+// loadAfterSnapshot reads the events after snap and returns them with the
+// offset the log was read up to (0 for stores without offsets).
+func loadAfterSnapshot(sessionID string, snap *replaySnapshot) ([]map[string]interface{}, int64, error) {
+	ctx := context.Background()
+	if loader, ok := Events.(eventstore.OffsetLoader); ok {
+		if snap.Seq > 0 && snap.Offset == 0 {
+			return nil, 0, eventstore.ErrStaleOffset // written for another store
+		}
+		return loader.LoadFromOffset(ctx, sessionID, snap.Offset, snap.Seq)
+	}
+	if snap.Offset > 0 {
+		return nil, 0, eventstore.ErrStaleOffset // written for another store
+	}
+	events, err := Events.Load(ctx, sessionID, snap.Seq)
+	return events, 0, err
+}
+
+// ─── Replay ──────────────────────────────────────────────────────────
+
+// replayHistory is a session's log as a connect handler replays it: the
+// compacted snapshot of finished runs, if there is one, followed by the
+// raw events after it.
+type replayHistory struct {
+	snapshot    []map[string]interface{}
+	snapshotSeq int64
+	tail        []map[string]interface{}
+}
+
+// loadReplayHistory reads a session's log, starting from its snapshot
+// when it has a usable one.
+func loadReplayHistory(sessionID string) replayHistory {
+	if snap := readSnapshot(sessionID); snap != nil {
+		tail, _, err := loadAfterSnapshot(sessionID, snap)
+		if err == nil {
+			return replayHistory{snapshot: snap.Events, snapshotSeq: snap.Seq, tail: tail}
+		}
+		log.Printf("AGUI Snapshot: ignoring snapshot of %s: %v", sessionID, err)
+		if errors.Is(err, eventstore.ErrStaleOffset) {
+			dropSnapshot(sessionID)
+		}
+	}
+	return replayHistory{tail: loadEvents(sessionID)}
+}
+
+func (h replayHistory) empty() bool {
+	return len(h.snapshot) == 0 && len(h.tail) == 0
+}
+
+// last returns the last event of the log, or nil if it is empty.
+func (h replayHistory) last() map[string]interface{} {
+	if len(h.tail) > 0 {
+		return h.tail[len(h.tail)-1]
+	}
+	if len(h.snapshot) > 0 {
+		return h.snapshot[len(h.snapshot)-1]
+	}
+	return nil
+}
+
+// lastSeq returns the sequence number of the last event of the log.
+func (h replayHistory) lastSeq() int64 {
+	if len(h.tail) > 0 {
+		return eventstore.Seq(h.tail[len(h.tail)-1])
+	}
+	return h.snapshotSeq
+}
+
+// after returns the raw events with a sequence number greater than seq.
+// Events inside the snapshot are only available compacted there, so they
+// are read from the store.
+func (h replayHistory) after(sessionID string, seq int64) []map[string]interface{} {
+	if seq >= h.snapshotSeq {
+		return eventsAfter(h.tail, seq)
+	}
+	return loadEventsAfter(sessionID, seq)
+}
+
+// compacted returns the whole log compacted.
+func (h replayHistory) compacted() []map[string]interface{} {
+	tail := compactStreamingEvents(h.tail)
+	events := make([]map[string]interface{}, 0, len(h.snapshot)+len(tail))
+	return append(append(events, h.snapshot...), tail...)
+}
+
+// ─── Background compaction ───────────────────────────────────────────
+
+var (
+	snapshotQueue   = make(chan string, 64)
+	snapshotPending sync.Map // sessionID → struct{} while queued
+	snapshotWorker  sync.Once
+)
+
+// requestSnapshot asks the background compactor to bring the session's
+// snapshot up to date.  It never blocks; when the queue is full the
+// request is dropped and a later one catches up.
+func requestSnapshot(sessionID string) {
+	snapshotWorker.Do(func() { go runSnapshotWorker() })
+	if _, queued := snapshotPending.LoadOrStore(sessionID, struct{}{}); queued {
+		return
+	}
+	select {
+	case snapshotQueue <- sessionID:
+	default:
+		snapshotPending.Delete(sessionID)
+	}
+}
+
+func runSnapshotWorker() {
+	for sessionID := range snapshotQueue {
+		snapshotPending.Delete(sessionID)
+		if err := buildSnapshot(sessionID, snapshotMinEvents); err != nil {
+			log.Printf("AGUI Snapshot: failed to snapshot %s: %v", sessionID, err)
+		}
+	}
+}
+
+// buildSnapshot extends the session's snapshot with the events after it,
+// once at least minEvents have accumulated.  Only whole runs are
+// compacted, so nothing is written while a run is in progress.
+func buildSnapshot(sessionID string, minEvents int) error {
+	prev := readSnapshot(sessionID)
+	if prev == nil {
+		prev = &replaySnapshot{}
+	}
+	tail, offset, err := loadAfterSnapshot(sessionID, prev)
+	if errors.Is(err, eventstore.ErrStaleOffset) {
+		prev = &replaySnapshot{}
+		tail, offset, err = loadAfterSnapshot(sessionID, prev)
+	}
+	if err != nil {
+		return fmt.Errorf("read log: %w", err)
+	}
+	if len(tail) < minEvents || !runsFinished(tail) {
+		return nil
+	}
+
+	compacted := compactStreamingEvents(tail)
+	events := make([]map[string]interface{}, 0, len(prev.Events)+len(compacted))
+	events = append(append(events, prev.Events...), compacted...)
+	snap := &replaySnapshot{
+		Version: snapshotVersion,
+		Seq:     eventstore.Seq(tail[len(tail)-1]),
+		Offset:  offset,
+		Events:  events,
+	}
+	if err := writeSnapshot(sessionID, snap); err != nil {
+		return err
+	}
+	log.Printf("AGUI Snapshot: %s snapshotted up to event %d (%d events)", sessionID, snap.Seq, len(events))
+	return nil
+}
+
+// runsFinished reports whether every run started in events has ended, so
+// no message or tool call stream is left open.
+func runsFinished(events []map[string]interface{}) bool {
+	for i := len(events) - 1; i >= 0; i-- {
+		switch events[i]["type"] {
+		case types.EventTypeRunFinished, types.EventTypeRunError:
+			return true
+		case types.EventTypeRunStarted:
+			return false
+		}
+	}
+	return true
+}
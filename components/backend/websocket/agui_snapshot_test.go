@@ -0,0 +1,117 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"ambient-code-backend/eventstore"
+	"ambient-code-backend/types"
+)
+
+// appendRun persists one run streaming a message in deltas pieces.  An
+// unfinished run stops after its deltas.
+func appendRun(session, runID string, deltas int, finished bool) {
+	msgID := runID + "-msg"
+	persistEvent(session, map[string]interface{}{"type": types.EventTypeRunStarted, "runId": runID})
+	persistEvent(session, map[string]interface{}{"type": types.EventTypeTextMessageStart, "messageId": msgID, "role": "assistant"})
+	for i := 0; i < deltas; i++ {
+		persistEvent(session, map[string]interface{}{"type": types.EventTypeTextMessageContent, "messageId": msgID, "delta": fmt.Sprintf("word%d ", i)})
+	}
+	if !finished {
+		return
+	}
+	persistEvent(session, map[string]interface{}{"type": types.EventTypeTextMessageEnd, "messageId": msgID})
+	persistEvent(session, map[string]interface{}{"type": types.EventTypeRunFinished, "runId": runID})
+}
+
+func mustJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestSnapshotReplayMatchesFullCompaction(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		t.Run(fmt.Sprintf("compress=%v", compress), func(t *testing.T) {
+			useTempStateDir(t)
+			CompressSnapshots = compress
+			t.Cleanup(func() { CompressSnapshots = false })
+			session := "snap-session"
+
+			appendRun(session, "r1", 5, true)
+			appendRun(session, "r2", 5, true)
+			if err := buildSnapshot(session, 1); err != nil {
+				t.Fatalf("buildSnapshot() error = %v", err)
+			}
+			snap := readSnapshot(session)
+			if snap == nil || snap.Seq != 18 {
+				t.Fatalf("snapshot = %+v, want one up to event 18", snap)
+			}
+			if _, err := os.Stat(snapshotPath(session, compress)); err != nil {
+				t.Errorf("snapshot not written in the expected form: %v", err)
+			}
+
+			appendRun(session, "r3", 3, true)
+			history := loadReplayHistory(session)
+			if history.snapshotSeq != 18 || len(history.tail) != 7 {
+				t.Fatalf("history has snapshot up to %d and %d tail events", history.snapshotSeq, len(history.tail))
+			}
+			if got, want := mustJSON(t, history.compacted()), mustJSON(t, compactStreamingEvents(loadEvents(session))); got != want {
+				t.Errorf("snapshot replay differs from full compaction\n got %s\nwant %s", got, want)
+			}
+			if history.lastSeq() != 25 {
+				t.Errorf("lastSeq() = %d, want 25", history.lastSeq())
+			}
+
+			// Resuming inside the snapshot reads the raw events from the store
+			if missed := history.after(session, 16); len(missed) != 9 || eventstore.Seq(missed[0]) != 17 {
+				t.Errorf("after(16) returned %d events starting at %v", len(missed), missed)
+			}
+			if missed := history.after(session, 24); len(missed) != 1 || eventstore.Seq(missed[0]) != 25 {
+				t.Errorf("after(24) = %v", missed)
+			}
+		})
+	}
+}
+
+func TestBuildSnapshotSkipsActiveRun(t *testing.T) {
+	useTempStateDir(t)
+	session := "active-session"
+	appendRun(session, "r1", 3, true)
+	appendRun(session, "r2", 3, false)
+
+	if err := buildSnapshot(session, 1); err != nil {
+		t.Fatal(err)
+	}
+	if snap := readSnapshot(session); snap != nil {
+		t.Errorf("snapshot written mid-run: %+v", snap)
+	}
+}
+
+func TestStaleSnapshotIsDropped(t *testing.T) {
+	useTempStateDir(t)
+	session := "stale-session"
+	appendRun(session, "r1", 3, true)
+	if err := buildSnapshot(session, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// The log is replaced by a shorter one
+	logPath := filepath.Join(StateBaseDir, "sessions", session, "agui-events.jsonl")
+	if err := os.WriteFile(logPath, []byte(`{"type":"RUN_STARTED"}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	history := loadReplayHistory(session)
+	if history.snapshot != nil || len(history.tail) != 1 {
+		t.Errorf("history = %+v, want the full log without snapshot", history)
+	}
+	if readSnapshot(session) != nil {
+		t.Error("stale snapshot was not removed")
+	}
+}
@@ -2,7 +2,7 @@
 //
 // agui_store.go — Event persistence, compaction, and replay.
 //
-// Write path:  append every event to agui-events.jsonl.
+// Write path:  append every event to the session's log in Events.
 // Read path:   load + compact events for reconnect replay.
 // Compaction:  Go port of @ag-ui/client compactEvents — concatenates
 //
@@ -10,188 +10,138 @@
 package websocket
 
 import (
+	"ambient-code-backend/eventstore"
+	"ambient-code-backend/livebus"
 	"ambient-code-backend/types"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
-	"os"
-	"sync"
+	"strconv"
+	"strings"
 	"sync/atomic"
 	"time"
 )
 
-// ─── Write mutex eviction ────────────────────────────────────────────
-// writeMutexes entries are evicted after writeMutexEvictAge of inactivity
-// to prevent unbounded sync.Map growth on long-running backends.
-
-const writeMutexEvictAge = 30 * time.Minute
-
-func init() {
-	go func() {
-		ticker := time.NewTicker(10 * time.Minute)
-		for range ticker.C {
-			evictStaleWriteMutexes()
-		}
-	}()
-}
-
-// evictStaleWriteMutexes removes write mutex entries that haven't been
-// used within writeMutexEvictAge.
-func evictStaleWriteMutexes() {
-	threshold := time.Now().Add(-writeMutexEvictAge).Unix()
-	writeMutexes.Range(func(key, value interface{}) bool {
-		entry := value.(*writeMutexEntry)
-		if atomic.LoadInt64(&entry.lastUsed) < threshold {
-			writeMutexes.Delete(key)
-		}
-		return true
-	})
-}
-
 // StateBaseDir is the root directory for session state persistence.
 // Set from the STATE_BASE_DIR env var (default "/workspace") at startup.
 var StateBaseDir string
 
+// Events stores the AG-UI event log of every session.  Set at startup
+// from the EVENT_STORE configuration (see package eventstore).
+var Events eventstore.Store
+
 // ─── Live event pipe (multi-client broadcast) ───────────────────────
 // The run handler pipes raw SSE lines to ALL connect handlers tailing
-// the same session.  Zero latency — same as the direct run() path.
+// the same session, on every backend replica, through Live.  Zero
+// latency on the replica proxying the run — same as the direct run() path.
 
-type sessionBroadcast struct {
-	mu   sync.Mutex
-	subs map[int]chan string
-	next int
-}
+// Live carries live SSE lines to subscribers.  Set at startup from the
+// LIVE_BUS configuration (see package livebus); in-process by default.
+var Live livebus.Bus = livebus.NewMemory()
 
-var liveBroadcasts sync.Map // sessionName → *sessionBroadcast
+// liveBufferSize is how many lines a slow subscriber may fall behind
+// before lines are dropped and it has to resync from the store.
+const liveBufferSize = 256
 
-func getBroadcast(sessionName string) *sessionBroadcast {
-	val, _ := liveBroadcasts.LoadOrStore(sessionName, &sessionBroadcast{
-		subs: make(map[int]chan string),
-	})
-	return val.(*sessionBroadcast)
+// liveSubscription is one connect handler's view of the live pipe.
+type liveSubscription struct {
+	C       chan string
+	dropped atomic.Bool // set when a line could not be delivered
 }
 
-// publishLine sends a raw SSE line to ALL connect handlers tailing this session.
-func publishLine(sessionName, line string) {
-	b := getBroadcast(sessionName)
-	b.mu.Lock()
-	defer b.mu.Unlock()
-	for _, ch := range b.subs {
-		select {
-		case ch <- line:
-		default: // slow client — drop (it's persisted to JSONL)
-		}
-	}
-}
-
-// subscribeLive creates a channel to receive live SSE lines for a session.
-// Multiple clients can subscribe to the same session simultaneously.
-func subscribeLive(sessionName string) (<-chan string, func()) {
-	b := getBroadcast(sessionName)
-	ch := make(chan string, 256)
-
-	b.mu.Lock()
-	id := b.next
-	b.next++
-	b.subs[id] = ch
-	b.mu.Unlock()
-
-	return ch, func() {
-		b.mu.Lock()
-		delete(b.subs, id)
-		b.mu.Unlock()
-	}
-}
-
-// ─── Write path ──────────────────────────────────────────────────────
-
-// writeMutexEntry wraps a per-session mutex with a last-used timestamp
-// for eviction of idle entries.
-type writeMutexEntry struct {
-	mu       sync.Mutex
-	lastUsed int64 // unix seconds, updated atomically
+// overflowed reports (and clears) whether lines were dropped since the
+// last call.
+func (s *liveSubscription) overflowed() bool {
+	return s.dropped.Swap(false)
 }
 
-// writeMutexes serialises JSONL appends per session, preventing
-// interleaved writes from concurrent goroutines (e.g. run handler +
-// feedback handler writing to the same session file simultaneously).
-var writeMutexes sync.Map // sessionID → *writeMutexEntry
-
-func getWriteMutex(sessionID string) *sync.Mutex {
-	now := time.Now().Unix()
-	val, _ := writeMutexes.LoadOrStore(sessionID, &writeMutexEntry{lastUsed: now})
-	entry := val.(*writeMutexEntry)
-	atomic.StoreInt64(&entry.lastUsed, now)
-	return &entry.mu
+// publishLine sends a raw SSE line to ALL connect handlers tailing this session.
+func publishLine(sessionName, line string) {
+	Live.Publish(sessionName, line)
 }
 
-// persistEvent appends a single AG-UI event to the session's JSONL log.
-// Writes are serialised per-session via a mutex to prevent interleaving.
-func persistEvent(sessionID string, event map[string]interface{}) {
-	dir := fmt.Sprintf("%s/sessions/%s", StateBaseDir, sessionID)
-	path := dir + "/agui-events.jsonl"
-	_ = ensureDir(dir)
-
+// publishEvent broadcasts a persisted event, tagged with its sequence
+// number so subscribers can resume and deduplicate.
+func publishEvent(sessionName string, seq int64, event map[string]interface{}) {
 	data, err := json.Marshal(event)
 	if err != nil {
-		log.Printf("AGUI Store: failed to marshal event: %v", err)
+		log.Printf("AGUI Store: failed to marshal event for broadcast: %v", err)
 		return
 	}
+	publishLine(sessionName, sseFrame(seq, data))
+}
 
-	mu := getWriteMutex(sessionID)
-	mu.Lock()
-	defer mu.Unlock()
+// subscribeLive creates a subscription to receive live SSE lines for a
+// session.  Multiple clients can subscribe to the same session simultaneously.
+func subscribeLive(sessionName string) (*liveSubscription, func()) {
+	sub := &liveSubscription{C: make(chan string, liveBufferSize)}
+	cancel := Live.Subscribe(sessionName, func(line string) {
+		select {
+		case sub.C <- line:
+		default: // slow client — drop (it's persisted) and flag a resync
+			sub.dropped.Store(true)
+		}
+	})
+	return sub, cancel
+}
 
-	f, err := openFileAppend(path)
-	if err != nil {
-		log.Printf("AGUI Store: failed to open event log: %v", err)
-		return
-	}
-	defer f.Close()
+// ─── Write path ──────────────────────────────────────────────────────
 
-	if _, err := f.Write(append(data, '\n')); err != nil {
-		log.Printf("AGUI Store: failed to write event: %v", err)
+// persistEvent appends a single AG-UI event to the session's log in
+// Events and returns the sequence number it was assigned, or 0 if it
+// could not be written.  Sequence numbers start at 1 per session and
+// are recorded in the event's "seq" field.  Credentials are redacted
+// first, in place, so the caller publishes the redacted event.  Written
+// events are added to the project's search index (see search.go).
+func persistEvent(sessionID string, event map[string]interface{}) int64 {
+	redactEvent(sessionRedactor(sessionID), event)
+	seq, err := Events.Append(context.Background(), sessionID, event)
+	if err != nil {
+		log.Printf("AGUI Store: failed to persist event for %s: %v", sessionID, err)
+		return 0
 	}
+	indexPersistedEvent(sessionID, event)
+	return seq
 }
 
 // ─── Read path ───────────────────────────────────────────────────────
 
-// loadEvents reads all AG-UI events for a session from the JSONL log.
-// Automatically triggers legacy migration if the log doesn't exist but
-// a pre-AG-UI messages.jsonl file does.
+// loadEvents reads all AG-UI events for a session from Events.
+// Automatically triggers legacy migration if the session has no events
+// but a pre-AG-UI messages.jsonl file exists.
 func loadEvents(sessionID string) []map[string]interface{} {
-	path := fmt.Sprintf("%s/sessions/%s/agui-events.jsonl", StateBaseDir, sessionID)
+	events := loadEventsAfter(sessionID, 0)
+	if len(events) > 0 {
+		return events
+	}
+	// Attempt legacy migration (messages.jsonl → AG-UI events)
+	if err := MigrateLegacySessionToAGUI(sessionID); err != nil {
+		log.Printf("AGUI Store: legacy migration failed for %s: %v", sessionID, err)
+	}
+	return loadEventsAfter(sessionID, 0)
+}
 
-	data, err := os.ReadFile(path)
+// loadEventsAfter reads the session's events with a sequence number
+// greater than seq.
+func loadEventsAfter(sessionID string, seq int64) []map[string]interface{} {
+	events, err := Events.Load(context.Background(), sessionID, seq)
 	if err != nil {
-		if os.IsNotExist(err) {
-			// Attempt legacy migration (messages.jsonl → agui-events.jsonl)
-			if mErr := MigrateLegacySessionToAGUI(sessionID); mErr != nil {
-				log.Printf("AGUI Store: legacy migration failed for %s: %v", sessionID, mErr)
-			}
-			// Retry after migration
-			data, err = os.ReadFile(path)
-			if err != nil {
-				return nil
-			}
-		} else {
-			log.Printf("AGUI Store: failed to read event log for %s: %v", sessionID, err)
-			return nil
-		}
+		log.Printf("AGUI Store: failed to read events for %s: %v", sessionID, err)
+		return nil
 	}
+	return events
+}
 
-	events := make([]map[string]interface{}, 0, 64)
-	for _, line := range splitLines(data) {
-		if len(line) == 0 {
-			continue
-		}
-		var evt map[string]interface{}
-		if err := json.Unmarshal(line, &evt); err == nil {
-			events = append(events, evt)
+// eventsAfter returns the events with a sequence number greater than seq.
+func eventsAfter(events []map[string]interface{}, seq int64) []map[string]interface{} {
+	for i, evt := range events {
+		if eventstore.Seq(evt) > seq {
+			return events[i:]
 		}
 	}
-	return events
+	return nil
 }
 
 // ─── Compaction ──────────────────────────────────────────────────────
@@ -202,6 +152,7 @@ func loadEvents(sessionID string) []map[string]interface{} {
 type pendingText struct {
 	start       map[string]interface{}
 	deltas      []string
+	lastSeq     int64 // seq of the last delta, carried by the combined event
 	end         map[string]interface{}
 	otherEvents []map[string]interface{}
 }
@@ -209,6 +160,7 @@ type pendingText struct {
 type pendingTool struct {
 	start       map[string]interface{}
 	deltas      []string
+	lastSeq     int64 // seq of the last delta, carried by the combined event
 	end         map[string]interface{}
 	otherEvents []map[string]interface{}
 }
@@ -255,11 +207,15 @@ func compactStreamingEvents(events []map[string]interface{}) []map[string]interf
 			for _, d := range p.deltas {
 				combined += d
 			}
-			compacted = append(compacted, map[string]interface{}{
+			combinedEvt := map[string]interface{}{
 				"type":      types.EventTypeTextMessageContent,
 				"messageId": id,
 				"delta":     combined,
-			})
+			}
+			if p.lastSeq > 0 {
+				combinedEvt["seq"] = p.lastSeq
+			}
+			compacted = append(compacted, combinedEvt)
 		}
 		if p.end != nil {
 			compacted = append(compacted, p.end)
@@ -281,11 +237,15 @@ func compactStreamingEvents(events []map[string]interface{}) []map[string]interf
 			for _, d := range p.deltas {
 				combined += d
 			}
-			compacted = append(compacted, map[string]interface{}{
+			combinedEvt := map[string]interface{}{
 				"type":       types.EventTypeToolCallArgs,
 				"toolCallId": id,
 				"delta":      combined,
-			})
+			}
+			if p.lastSeq > 0 {
+				combinedEvt["seq"] = p.lastSeq
+			}
+			compacted = append(compacted, combinedEvt)
 		}
 		if p.end != nil {
 			compacted = append(compacted, p.end)
@@ -306,7 +266,9 @@ func compactStreamingEvents(events []map[string]interface{}) []map[string]interf
 		case types.EventTypeTextMessageContent:
 			if id, _ := evt["messageId"].(string); id != "" {
 				delta, _ := evt["delta"].(string)
-				getText(id).deltas = append(getText(id).deltas, delta)
+				p := getText(id)
+				p.deltas = append(p.deltas, delta)
+				p.lastSeq = eventstore.Seq(evt)
 			} else {
 				compacted = append(compacted, evt)
 			}
@@ -326,7 +288,9 @@ func compactStreamingEvents(events []map[string]interface{}) []map[string]interf
 		case types.EventTypeToolCallArgs:
 			if id, _ := evt["toolCallId"].(string); id != "" {
 				delta, _ := evt["delta"].(string)
-				getTool(id).deltas = append(getTool(id).deltas, delta)
+				p := getTool(id)
+				p.deltas = append(p.deltas, delta)
+				p.lastSeq = eventstore.Seq(evt)
 			} else {
 				compacted = append(compacted, evt)
 			}
@@ -413,9 +377,10 @@ func sanitizeEventTimestamp(evt map[string]interface{}) {
 
 // ─── SSE helpers ─────────────────────────────────────────────────────
 
-// writeSSEEvent marshals an event and writes it in SSE data: format.
+// writeSSEEvent marshals an event and writes it in SSE data: format,
+// preceded by an id: line when id is positive.
 // If the event is a map, timestamps are sanitized to epoch ms first.
-func writeSSEEvent(w http.ResponseWriter, event interface{}) {
+func writeSSEEvent(w http.ResponseWriter, event interface{}, id int64) {
 	// Sanitize timestamps on map events (replayed from store)
 	if m, ok := event.(map[string]interface{}); ok {
 		sanitizeEventTimestamp(m)
@@ -425,22 +390,34 @@ func writeSSEEvent(w http.ResponseWriter, event interface{}) {
 		log.Printf("AGUI Store: failed to marshal SSE event: %v", err)
 		return
 	}
-	fmt.Fprintf(w, "data: %s\n\n", data)
+	fmt.Fprint(w, sseFrame(id, data))
 	if f, ok := w.(http.Flusher); ok {
 		f.Flush()
 	}
 }
 
-// ─── File helpers ────────────────────────────────────────────────────
-
-func ensureDir(path string) error {
-	return os.MkdirAll(path, 0755)
+// sseFrame formats one SSE event.  The id: field lets EventSource send
+// Last-Event-ID when it reconnects.
+func sseFrame(id int64, data []byte) string {
+	if id > 0 {
+		return fmt.Sprintf("id: %d\ndata: %s\n\n", id, data)
+	}
+	return fmt.Sprintf("data: %s\n\n", data)
 }
 
-func openFileAppend(path string) (*os.File, error) {
-	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+// sseFrameID returns the id: of a frame built by sseFrame, or 0 if it
+// has none.
+func sseFrameID(frame string) int64 {
+	if !strings.HasPrefix(frame, "id: ") {
+		return 0
+	}
+	idLine, _, _ := strings.Cut(strings.TrimPrefix(frame, "id: "), "\n")
+	id, _ := strconv.ParseInt(idLine, 10, 64)
+	return id
 }
 
+// ─── File helpers ────────────────────────────────────────────────────
+
 func splitLines(data []byte) [][]byte {
 	var lines [][]byte
 	start := 0
@@ -0,0 +1,223 @@
+package websocket
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ambient-code-backend/eventstore"
+	"ambient-code-backend/redact"
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// useTempStateDir points StateBaseDir and a JSONL event store at a fresh
+// directory for one test
+func useTempStateDir(t testing.TB) {
+	t.Helper()
+	prevDir, prevEvents := StateBaseDir, Events
+	StateBaseDir = t.TempDir()
+	Events = eventstore.NewJSONL(StateBaseDir)
+	t.Cleanup(func() { StateBaseDir, Events = prevDir, prevEvents })
+}
+
+func TestPersistEventAssignsSequenceNumbers(t *testing.T) {
+	useTempStateDir(t)
+	session := "seq-session"
+
+	// A log written before sequence numbers existed
+	dir := filepath.Join(StateBaseDir, "sessions", session)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "agui-events.jsonl"), []byte(`{"type":"RUN_STARTED"}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := persistEvent(session, map[string]interface{}{"type": "STEP_STARTED"}); got != 2 {
+		t.Errorf("persistEvent() = %d, want 2", got)
+	}
+	// A restarted backend picks up where the log ends
+	Events = eventstore.NewJSONL(StateBaseDir)
+	if got := persistEvent(session, map[string]interface{}{"type": "RUN_FINISHED"}); got != 3 {
+		t.Errorf("persistEvent() after restart = %d, want 3", got)
+	}
+
+	events := loadEvents(session)
+	for i, evt := range events {
+		if got := eventstore.Seq(evt); got != int64(i+1) {
+			t.Errorf("event %d has seq %d, want %d", i, got, i+1)
+		}
+	}
+	if len(events) != 3 {
+		t.Fatalf("loadEvents() returned %d events, want 3", len(events))
+	}
+	if after := eventsAfter(events, 2); len(after) != 1 || after[0]["type"] != "RUN_FINISHED" {
+		t.Errorf("eventsAfter(2) = %v", after)
+	}
+}
+
+func TestPersistEventRedactsCredentials(t *testing.T) {
+	useTempStateDir(t)
+	session := "redact-session"
+	r, err := redact.New([]string{`ticket-[0-9]+`}, []string{"runner-secret-value"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionRedactors.Store(session, r)
+	t.Cleanup(func() { sessionRedactors.Delete(session) })
+
+	event := map[string]interface{}{
+		"type":       types.EventTypeToolCallResult,
+		"toolCallId": "t1",
+		"content":    "GITHUB_TOKEN=ghp_" + strings.Repeat("x", 36) + " KEY=runner-secret-value ticket-42",
+	}
+	persistEvent(session, event)
+
+	stored := loadEvents(session)
+	if len(stored) != 1 {
+		t.Fatalf("loadEvents() returned %d events, want 1", len(stored))
+	}
+	if got := stored[0]["content"]; got != "GITHUB_TOKEN=[REDACTED] KEY=[REDACTED] [REDACTED]" {
+		t.Errorf("stored content = %q", got)
+	}
+	if got := stored[0]["redactions"]; got != float64(3) {
+		t.Errorf("redactions = %v, want 3", got)
+	}
+	// The caller publishes the same, redacted, event
+	if event["content"] != stored[0]["content"] {
+		t.Errorf("published content = %q", event["content"])
+	}
+}
+
+func TestStreamRedactsCredentialsSplitAcrossDeltas(t *testing.T) {
+	useTempStateDir(t)
+	session := "split-session"
+	token := "ghp_" + strings.Repeat("x", 36)
+
+	deltas := newDeltaRedactor()
+	var published []map[string]interface{}
+	for _, evt := range []map[string]interface{}{
+		{"type": types.EventTypeTextMessageStart, "messageId": "m1"},
+		{"type": types.EventTypeTextMessageContent, "messageId": "m1", "delta": "export GITHUB_TOKEN=" + token[:10]},
+		{"type": types.EventTypeTextMessageContent, "messageId": "m1", "delta": token[10:] + " && git"},
+		{"type": types.EventTypeTextMessageContent, "messageId": "m1", "delta": " push"},
+		{"type": types.EventTypeTextMessageEnd, "messageId": "m1"},
+		{"type": types.EventTypeToolCallStart, "toolCallId": "t1"},
+		{"type": types.EventTypeToolCallArgs, "toolCallId": "t1", "delta": `{"token":"` + token[:20]},
+		{"type": types.EventTypeToolCallArgs, "toolCallId": "t1", "delta": token[20:] + `"}`},
+		{"type": types.EventTypeRunFinished},
+	} {
+		for _, out := range deltas.next(evt) {
+			persistEvent(session, out)
+			published = append(published, out)
+		}
+	}
+
+	stored := loadEvents(session)
+	if len(stored) != len(published) {
+		t.Fatalf("stored %d events, published %d", len(stored), len(published))
+	}
+	var text, args string
+	for i, evt := range stored {
+		if strings.Contains(fmt.Sprint(evt), token[:10]) || strings.Contains(fmt.Sprint(published[i]), token[:10]) {
+			t.Errorf("event %d leaks the token: %v", i, evt)
+		}
+		switch evt["type"] {
+		case types.EventTypeTextMessageContent:
+			text += evt["delta"].(string)
+		case types.EventTypeToolCallArgs:
+			args += evt["delta"].(string)
+		}
+	}
+	if text != "export GITHUB_TOKEN=[REDACTED] && git push" {
+		t.Errorf("stored text = %q", text)
+	}
+	if args != `{"token":"[REDACTED]"}` {
+		t.Errorf("stored tool arguments = %q", args)
+	}
+	if last := stored[len(stored)-1]["type"]; last != types.EventTypeRunFinished {
+		t.Errorf("last event = %v, want RUN_FINISHED", last)
+	}
+}
+
+func TestCompactionKeepsLastDeltaSeq(t *testing.T) {
+	events := []map[string]interface{}{
+		{"type": types.EventTypeTextMessageStart, "messageId": "m1", "seq": int64(1)},
+		{"type": types.EventTypeTextMessageContent, "messageId": "m1", "delta": "Hel", "seq": int64(2)},
+		{"type": types.EventTypeTextMessageContent, "messageId": "m1", "delta": "lo", "seq": int64(3)},
+		{"type": types.EventTypeTextMessageEnd, "messageId": "m1", "seq": int64(4)},
+	}
+	compacted := compactStreamingEvents(events)
+	if len(compacted) != 3 {
+		t.Fatalf("compactStreamingEvents() returned %d events, want 3", len(compacted))
+	}
+	if compacted[1]["delta"] != "Hello" || eventstore.Seq(compacted[1]) != 3 {
+		t.Errorf("combined delta = %v, want delta Hello with seq 3", compacted[1])
+	}
+}
+
+func TestPublishLineFlagsOverflow(t *testing.T) {
+	sub, cleanup := subscribeLive("overflow-session")
+	defer cleanup()
+
+	for i := 0; i < liveBufferSize; i++ {
+		publishLine("overflow-session", "data: {}\n\n")
+	}
+	if sub.overflowed() {
+		t.Fatal("a full buffer without drops should not count as overflow")
+	}
+	publishLine("overflow-session", "data: {}\n\n")
+	if !sub.overflowed() {
+		t.Error("expected overflow after a dropped line")
+	}
+	if sub.overflowed() {
+		t.Error("overflowed() should clear the flag")
+	}
+}
+
+func TestReplayStreamWriteLive(t *testing.T) {
+	useTempStateDir(t)
+	session := "live-session"
+	for i := 0; i < 4; i++ {
+		persistEvent(session, map[string]interface{}{"type": "CUSTOM", "name": fmt.Sprintf("e%d", i+1)})
+	}
+
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	replay := &replayStream{out: sseWriter{ctx.Writer}, lastID: 1}
+
+	replay.writeLive(session, sseFrame(1, []byte(`{"name":"e1"}`))) // already sent
+	replay.writeLive(session, sseFrame(4, []byte(`{"name":"e4"}`))) // 2 and 3 come from the store
+	replay.writeLive(session, ": comment\n\n")
+
+	body := w.Body.String()
+	var ids []string
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(line, "id: ") {
+			ids = append(ids, strings.TrimPrefix(line, "id: "))
+		}
+	}
+	if got := strings.Join(ids, ","); got != "2,3,4" {
+		t.Errorf("sent ids %s, want 2,3,4\n%s", got, body)
+	}
+	if !strings.HasSuffix(body, ": comment\n\n") {
+		t.Error("lines without an id should be forwarded")
+	}
+	if replay.lastID != 4 {
+		t.Errorf("lastID = %d, want 4", replay.lastID)
+	}
+}
+
+func TestSSEFrameID(t *testing.T) {
+	if got := sseFrameID(sseFrame(42, []byte(`{}`))); got != 42 {
+		t.Errorf("sseFrameID() = %d, want 42", got)
+	}
+	if got := sseFrameID(sseFrame(0, []byte(`{}`))); got != 0 {
+		t.Errorf("sseFrameID() without id = %d, want 0", got)
+	}
+}
@@ -0,0 +1,264 @@
+// agui_ws.go — AG-UI over a single WebSocket.
+//
+// GET /agui/ws upgrades to a WebSocket that carries both directions of
+// the SSE + POST endpoints, for clients that want one connection:
+//
+//	server → client  {"type":"event","seq":12,"event":{...AG-UI event...}}
+//	client → server  {"type":"run","requestId":"1","input":{...RunAgentInput...}}
+//	                 {"type":"interrupt","requestId":"2"}
+//	                 {"type":"feedback","requestId":"3","event":{...META event...}}
+//	server → client  {"type":"response","requestId":"1","status":200,"body":{...}}
+//
+// Events follow the same replay semantics as GET /agui/events: history
+// first (from after ?lastEventId= when resuming), then live events.
+// Responses carry the status and body the POST endpoints would return.
+package websocket
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"ambient-code-backend/handlers"
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// wsPingInterval is how often the server pings; a client that has not
+	// answered within wsPongWait is disconnected.
+	wsPingInterval = 15 * time.Second
+	wsPongWait     = 45 * time.Second
+	wsWriteWait    = 10 * time.Second
+	// wsMaxMessageSize bounds client messages; run inputs carry the
+	// conversation so far.
+	wsMaxMessageSize = 16 << 20
+)
+
+// Client request types
+const (
+	wsRequestRun       = "run"
+	wsRequestInterrupt = "interrupt"
+	wsRequestFeedback  = "feedback"
+)
+
+// wsRequest is a message from the client.
+type wsRequest struct {
+	Type      string                 `json:"type"`
+	RequestID string                 `json:"requestId,omitempty"`
+	Input     json.RawMessage        `json:"input,omitempty"`
+	Event     map[string]interface{} `json:"event,omitempty"`
+}
+
+// wsMessage is a message to the client: an event or a response.
+type wsMessage struct {
+	Type      string      `json:"type"`
+	Seq       int64       `json:"seq,omitempty"`
+	Event     interface{} `json:"event,omitempty"`
+	RequestID string      `json:"requestId,omitempty"`
+	Status    int         `json:"status,omitempty"`
+	Body      interface{} `json:"body,omitempty"`
+}
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+}
+
+// HandleAGUIWebSocket serves the AG-UI event stream and accepts run,
+// interrupt and feedback requests over one WebSocket.  Connecting needs
+// read access to the session; each request is checked for update access
+// like the corresponding POST endpoint.
+func HandleAGUIWebSocket(c *gin.Context) {
+	projectName := c.Param("projectName")
+	sessionName := c.Param("sessionName")
+
+	// SECURITY: Authenticate + RBAC (read access)
+	reqK8s, _ := handlers.GetK8sClientsForRequest(c)
+	if reqK8s == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing token"})
+		c.Abort()
+		return
+	}
+	if !checkAccess(reqK8s, projectName, sessionName, "get") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		c.Abort()
+		return
+	}
+
+	conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("AGUI WebSocket: upgrade failed for %s/%s: %v", projectName, sessionName, err)
+		return // the upgrader has written the error response
+	}
+	log.Printf("AGUI WebSocket: client connected for %s/%s", projectName, sessionName)
+
+	ws := &wsSession{
+		conn:        conn,
+		projectName: projectName,
+		sessionName: sessionName,
+		canUpdate: func() bool {
+			return checkAccess(reqK8s, projectName, sessionName, "update")
+		},
+	}
+	ws.serve(parseLastEventID(c))
+	log.Printf("AGUI WebSocket: client disconnected for %s", sessionName)
+}
+
+// wsSession is one WebSocket client of a session.
+type wsSession struct {
+	conn        *websocket.Conn
+	projectName string
+	sessionName string
+	canUpdate   func() bool // RBAC check for requests
+
+	writeMu sync.Mutex // gorilla/websocket allows one writer at a time
+}
+
+// serve replays history, then forwards live events and answers requests
+// until the connection closes.
+func (ws *wsSession) serve(lastEventID int64) {
+	defer ws.conn.Close()
+
+	// Subscribe BEFORE replaying so nothing falls between the two.
+	live, cleanup := subscribeLive(ws.sessionName)
+	defer cleanup()
+
+	requests := make(chan wsRequest)
+	closed, done := make(chan struct{}), make(chan struct{})
+	defer close(done)
+	go ws.read(requests, closed, done)
+
+	stream := &replayStream{out: ws}
+	stream.replay(ws.sessionName, lastEventID)
+
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case req := <-requests:
+			// Requests wait on the runner; keep forwarding events meanwhile
+			go ws.handle(req)
+		case line, ok := <-live.C:
+			if !ok {
+				return
+			}
+			stream.forward(ws.sessionName, live, line)
+		case <-ping.C:
+			if err := ws.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// read decodes client messages until the connection fails or the client
+// stops answering pings, then closes closed.  done is closed when serve
+// returns.
+func (ws *wsSession) read(requests chan<- wsRequest, closed chan<- struct{}, done <-chan struct{}) {
+	defer close(closed)
+	ws.conn.SetReadLimit(wsMaxMessageSize)
+	ws.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	ws.conn.SetPongHandler(func(string) error {
+		return ws.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+	for {
+		_, data, err := ws.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Printf("AGUI WebSocket: read error for %s: %v", ws.sessionName, err)
+			}
+			return
+		}
+		var req wsRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			ws.respond(req, http.StatusBadRequest, gin.H{"error": "invalid message: " + err.Error()})
+			continue
+		}
+		ws.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		select {
+		case requests <- req:
+		case <-done:
+			return
+		}
+	}
+}
+
+// handle performs one client request and sends its response.
+func (ws *wsSession) handle(req wsRequest) {
+	switch req.Type {
+	case wsRequestRun, wsRequestInterrupt, wsRequestFeedback:
+	default:
+		ws.respond(req, http.StatusBadRequest, gin.H{"error": "unknown request type " + req.Type})
+		return
+	}
+	if !ws.canUpdate() {
+		ws.respond(req, http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	switch req.Type {
+	case wsRequestRun:
+		var input types.RunAgentInput
+		if err := json.Unmarshal(req.Input, &input); err != nil {
+			ws.respond(req, http.StatusBadRequest, gin.H{"error": "invalid input: " + err.Error()})
+			return
+		}
+		status, body := startRun(ws.projectName, ws.sessionName, input)
+		ws.respond(req, status, body)
+	case wsRequestInterrupt:
+		status, body := sendInterrupt(ws.projectName, ws.sessionName)
+		ws.respond(req, status, body)
+	case wsRequestFeedback:
+		if req.Event == nil {
+			ws.respond(req, http.StatusBadRequest, gin.H{"error": "invalid feedback event: missing event"})
+			return
+		}
+		status, body := submitFeedback(ws.projectName, ws.sessionName, req.Event)
+		ws.respond(req, status, body)
+	}
+}
+
+func (ws *wsSession) respond(req wsRequest, status int, body interface{}) {
+	ws.send(wsMessage{Type: "response", RequestID: req.RequestID, Status: status, Body: body})
+}
+
+// send writes one message.  A failed write closes the connection, which
+// ends serve through read.
+func (ws *wsSession) send(msg wsMessage) {
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+	ws.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	if err := ws.conn.WriteJSON(msg); err != nil {
+		log.Printf("AGUI WebSocket: write failed for %s: %v", ws.sessionName, err)
+		ws.conn.Close()
+	}
+}
+
+// writeEvent implements eventWriter.
+func (ws *wsSession) writeEvent(event map[string]interface{}, id int64) {
+	sanitizeEventTimestamp(event)
+	ws.send(wsMessage{Type: "event", Seq: id, Event: event})
+}
+
+// writeLine implements eventWriter.  Live lines are SSE frames; their
+// data is sent as the event and comments are dropped.
+func (ws *wsSession) writeLine(line string) {
+	var data string
+	for _, field := range strings.Split(line, "\n") {
+		if strings.HasPrefix(field, "data: ") {
+			data = strings.TrimPrefix(field, "data: ")
+		}
+	}
+	if data == "" || !json.Valid([]byte(data)) {
+		return
+	}
+	ws.send(wsMessage{Type: "event", Seq: sseFrameID(line), Event: json.RawMessage(data)})
+}
@@ -0,0 +1,92 @@
+package websocket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialTestSession serves a wsSession for session behind a test server and
+// connects a client to it.
+func dialTestSession(t *testing.T, session string, lastEventID int64, canUpdate bool) *websocket.Conn {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := wsUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		ws := &wsSession{conn: conn, projectName: "project", sessionName: session, canUpdate: func() bool { return canUpdate }}
+		ws.serve(lastEventID)
+	}))
+	t.Cleanup(server.Close)
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func readMessage(t *testing.T, conn *websocket.Conn) map[string]interface{} {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var msg map[string]interface{}
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return msg
+}
+
+func TestWebSocketReplaysThenStreamsLive(t *testing.T) {
+	useTempStateDir(t)
+	session := "ws-session"
+	for _, eventType := range []string{"RUN_STARTED", "RUN_FINISHED", "RAW"} {
+		persistEvent(session, map[string]interface{}{"type": eventType})
+	}
+
+	// Resuming after event 1 replays 2 and 3
+	client := dialTestSession(t, session, 1, false)
+	for _, want := range []float64{2, 3} {
+		msg := readMessage(t, client)
+		if msg["type"] != "event" || msg["seq"] != want {
+			t.Fatalf("message = %v, want event %v", msg, want)
+		}
+	}
+
+	// Live events arrive as they are published; SSE comments are dropped
+	publishLine(session, ": heartbeat\n\n")
+	event := map[string]interface{}{"type": "RUN_STARTED", "runId": "r2"}
+	publishEvent(session, persistEvent(session, event), event)
+	msg := readMessage(t, client)
+	if got, _ := msg["event"].(map[string]interface{}); msg["seq"] != float64(4) || got["runId"] != "r2" {
+		t.Errorf("live message = %v", msg)
+	}
+}
+
+func TestWebSocketRequests(t *testing.T) {
+	useTempStateDir(t)
+	client := dialTestSession(t, "ws-requests", 0, false)
+
+	for _, tc := range []struct {
+		request string
+		status  float64
+	}{
+		{`{"type":"run","requestId":"a","input":{}}`, http.StatusForbidden},
+		{`{"type":"shutdown","requestId":"b"}`, http.StatusBadRequest},
+		{`not json`, http.StatusBadRequest},
+	} {
+		if err := client.WriteMessage(websocket.TextMessage, []byte(tc.request)); err != nil {
+			t.Fatal(err)
+		}
+		msg := readMessage(t, client)
+		if msg["type"] != "response" || msg["status"] != tc.status {
+			t.Errorf("response to %s = %v, want status %v", tc.request, msg, tc.status)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"ambient-code-backend/handlers"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// HandleDeleteSessionEvents deletes the session's event log from Events,
+// with its snapshots and legacy messages.  The operator calls it before it
+// garbage-collects a session, so logs are deleted whatever the store.
+// DELETE /api/projects/:projectName/agentic-sessions/:sessionName/events
+//
+// The caller must be allowed to delete the session.  Logs are keyed by
+// session name alone, so the log of a name that another project also has
+// a session by is kept (409).
+func HandleDeleteSessionEvents(c *gin.Context) {
+	projectName := c.Param("projectName")
+	sessionName := c.Param("sessionName")
+
+	reqK8s, _ := handlers.GetK8sClientsForRequest(c)
+	if reqK8s == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing token"})
+		c.Abort()
+		return
+	}
+	if !checkAccess(reqK8s, projectName, sessionName, "delete") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		c.Abort()
+		return
+	}
+	if !isValidSessionName(sessionName) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session name"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	shared, err := sessionNameShared(ctx, projectName, sessionName)
+	if err != nil {
+		log.Printf("Delete events: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check for other sessions"})
+		return
+	}
+	if shared != "" {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("project %s also has a session named %s; its event log is kept", shared, sessionName)})
+		return
+	}
+
+	if err := deleteSessionLog(ctx, sessionName); err != nil {
+		log.Printf("Delete events: failed to delete the log of %s/%s: %v", projectName, sessionName, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete session events"})
+		return
+	}
+	log.Printf("Delete events: deleted the event log of %s/%s", projectName, sessionName)
+	c.Status(http.StatusNoContent)
+}
+
+// sessionNameShared returns a project other than projectName that has a
+// session named sessionName, or "" if there is none.
+func sessionNameShared(ctx context.Context, projectName, sessionName string) (string, error) {
+	if handlers.DynamicClient == nil {
+		return "", fmt.Errorf("no backend client to list sessions")
+	}
+	list, err := handlers.DynamicClient.Resource(handlers.GetAgenticSessionV1Alpha1Resource()).List(ctx, metav1.ListOptions{
+		FieldSelector: "metadata.name=" + sessionName,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list sessions named %s: %w", sessionName, err)
+	}
+	for _, item := range list.Items {
+		if item.GetName() == sessionName && item.GetNamespace() != projectName {
+			return item.GetNamespace(), nil
+		}
+	}
+	return "", nil
+}
+
+// deleteSessionLog removes the session's log from Events, its directory
+// under StateBaseDir (snapshots and legacy messages), and what this
+// replica holds of it in memory.
+func deleteSessionLog(ctx context.Context, sessionName string) error {
+	if err := Events.Delete(ctx, sessionName); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(filepath.Join(StateBaseDir, "sessions", sessionName)); err != nil {
+		return err
+	}
+	forgetSearchSession(sessionName)
+	sessionRedactors.Delete(sessionName)
+	return nil
+}
@@ -0,0 +1,57 @@
+package websocket
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"ambient-code-backend/eventstore"
+)
+
+func TestDeleteSessionLog(t *testing.T) {
+	useTempStateDir(t)
+	searchIndexes.Delete("delete-proj")
+	t.Cleanup(func() { searchIndexes.Delete("delete-proj") })
+	ctx := context.Background()
+
+	for _, evt := range []map[string]interface{}{
+		{"type": "TEXT_MESSAGE_START", "messageId": "m1", "role": "user"},
+		{"type": "TEXT_MESSAGE_CONTENT", "messageId": "m1", "delta": "doomed message"},
+		{"type": "TEXT_MESSAGE_END", "messageId": "m1"},
+	} {
+		persistEvent("doomed", evt)
+	}
+	persistEvent("kept", map[string]interface{}{"type": "RUN_STARTED"})
+	if err := writeSnapshot("doomed", &replaySnapshot{Seq: 3}); err != nil {
+		t.Fatal(err)
+	}
+	ps := projectSearchIndex("delete-proj")
+	if err := ps.indexer("doomed", true).load(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := deleteSessionLog(ctx, "doomed"); err != nil {
+		t.Fatalf("deleteSessionLog() error = %v", err)
+	}
+	if events := loadEvents("doomed"); len(events) != 0 {
+		t.Errorf("log after delete = %v", events)
+	}
+	if _, err := os.Stat(filepath.Join(StateBaseDir, "sessions", "doomed")); !os.IsNotExist(err) {
+		t.Errorf("session directory left behind: %v", err)
+	}
+	if hits := ps.index.Search("doomed", nil); len(hits) != 0 || ps.indexer("doomed", false) != nil {
+		t.Errorf("deleted session still indexed: %+v", hits)
+	}
+	if events := loadEvents("kept"); len(events) != 1 {
+		t.Errorf("other session's log = %v", events)
+	}
+
+	// A new session by the same name starts a new log
+	if seq := persistEvent("doomed", map[string]interface{}{"type": "RUN_STARTED"}); seq != 1 {
+		t.Errorf("first event of the new log has seq %d, want 1", seq)
+	}
+	if chain := eventstore.VerifyChain(loadEvents("doomed"), 1); !chain.Valid {
+		t.Errorf("VerifyChain() of the new log = %+v", chain)
+	}
+}
@@ -9,28 +9,55 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
+	"ambient-code-backend/eventstore"
 	"ambient-code-backend/handlers"
 
 	"github.com/gin-gonic/gin"
 	authv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// exportSchemaVersion is the version of ExportResponse written by this
+// backend.  Exports from before versioning have none (0) and read the same.
+const exportSchemaVersion = 1
+
 // ExportResponse contains the exported session data
 type ExportResponse struct {
+	SchemaVersion  int             `json:"schemaVersion"`
 	SessionID      string          `json:"sessionId"`
 	ProjectName    string          `json:"projectName"`
 	ExportDate     string          `json:"exportDate"`
 	AGUIEvents     json.RawMessage `json:"aguiEvents"`
 	LegacyMessages json.RawMessage `json:"legacyMessages,omitempty"`
 	HasLegacy      bool            `json:"hasLegacy"`
+	// SessionSpec is the AgenticSession's spec, used to recreate the
+	// session on import.  Omitted when the session no longer exists.
+	SessionSpec map[string]interface{} `json:"sessionSpec,omitempty"`
+	// ChainHead is the hash of the last chained event as stored, so the
+	// export can be verified offline by recomputing the chain over
+	// AGUIEvents (see eventstore.EventHash)
+	ChainHead    string `json:"chainHead,omitempty"`
+	ChainHeadSeq int64  `json:"chainHeadSeq,omitempty"`
+	// ChainGenesis is the seq the log was recorded to chain from, or 0 for
+	// a session created before chaining existed
+	ChainGenesis int64 `json:"chainGenesis,omitempty"`
+	// RedactedOnExport lists the events changed by redaction patterns added
+	// after they were stored.  Their own hashes no longer match; the chain
+	// still links them to their neighbours.
+	RedactedOnExport []int64 `json:"redactedOnExport,omitempty"`
 }
 
 // HandleExportSession exports session chat data as JSON
 // GET /api/projects/:projectName/agentic-sessions/:sessionName/export
+//
+// ?format=markdown, html or jsonl exports a readable transcript of the
+// AG-UI events instead (see transcript.go).  ?throughSeq= ends it at an
+// event, like the log of a fork when it was taken.
 func HandleExportSession(c *gin.Context) {
 	projectName := c.Param("projectName")
 	sessionName := c.Param("sessionName")
@@ -38,7 +65,7 @@ func HandleExportSession(c *gin.Context) {
 	log.Printf("Export: Exporting session %s/%s", projectName, sessionName)
 
 	// SECURITY: Authenticate user and get user-scoped K8s client
-	reqK8s, _ := handlers.GetK8sClientsForRequest(c)
+	reqK8s, reqDyn := handlers.GetK8sClientsForRequest(c)
 	if reqK8s == nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing token"})
 		c.Abort()
@@ -73,6 +100,25 @@ func HandleExportSession(c *gin.Context) {
 		return
 	}
 
+	var transcript transcriptRenderer
+	var transcriptType, transcriptExt string
+	if format := c.Query("format"); format != "" && format != "json" {
+		var ok bool
+		if transcript, transcriptType, transcriptExt, ok = newTranscriptRenderer(format); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown format %q (want json, markdown, html or jsonl)", format)})
+			return
+		}
+	}
+	var throughSeq int64
+	if v := c.Query("throughSeq"); v != "" {
+		seq, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || seq <= 0 || transcript == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "throughSeq must be a positive event seq, with a transcript format"})
+			return
+		}
+		throughSeq = seq
+	}
+
 	// Build paths safely using filepath.Join and validate they're within StateBaseDir
 	baseDir := filepath.Clean(StateBaseDir)
 	sessionDir := filepath.Join(baseDir, "sessions", sessionName)
@@ -85,35 +131,92 @@ func HandleExportSession(c *gin.Context) {
 		return
 	}
 
-	aguiEventsPath := filepath.Join(sessionDir, "agui-events.jsonl")
+	if transcript != nil {
+		// The log is read from the store run by run, not loaded whole
+		found, err := hasEvents(ctx, sessionName)
+		if err != nil {
+			log.Printf("Export: Error reading AG-UI events: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read session events"})
+			return
+		}
+		if _, err := os.Stat(sessionDir); !found && os.IsNotExist(err) {
+			log.Printf("Export: No events or session directory for %s", sessionName)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			return
+		}
+
+		// Redacted again, like the JSON export
+		events := storeEvents(ctx, sessionName, refreshSessionRedactor(projectName, sessionName))
+		if throughSeq > 0 {
+			events = eventsThrough(events, throughSeq)
+		}
+		c.Header("Content-Type", transcriptType)
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s-transcript.%s\"", sessionName, transcriptExt))
+		c.Status(http.StatusOK)
+		if err := writeTranscript(c.Writer, transcript, projectName, sessionName, time.Now().UTC().Format(time.RFC3339), events); err != nil {
+			log.Printf("Export: failed to write transcript of %s: %v", sessionName, err)
+			return
+		}
+		log.Printf("Export: Successfully exported transcript of session %s (%s)", sessionName, transcriptExt)
+		return
+	}
+
 	legacyMigratedPath := filepath.Join(sessionDir, "messages.jsonl.migrated")
 	legacyOriginalPath := filepath.Join(sessionDir, "messages.jsonl")
 
-	// Check if session directory exists
-	if _, err := os.Stat(sessionDir); os.IsNotExist(err) {
-		log.Printf("Export: Session directory not found: %s", sessionDir)
+	// Read AG-UI events
+	aguiData, err := Events.Load(ctx, sessionName, 0)
+	if err != nil {
+		log.Printf("Export: Error reading AG-UI events: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read session events"})
+		return
+	}
+
+	// A session with no events in the store may still have a state
+	// directory (e.g. legacy messages only)
+	if _, err := os.Stat(sessionDir); len(aguiData) == 0 && os.IsNotExist(err) {
+		log.Printf("Export: No events or session directory for %s", sessionName)
 		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
 		return
 	}
 
 	response := ExportResponse{
-		SessionID:   sessionName,
-		ProjectName: projectName,
-		ExportDate:  time.Now().UTC().Format(time.RFC3339),
-		HasLegacy:   false,
+		SchemaVersion: exportSchemaVersion,
+		SessionID:     sessionName,
+		ProjectName:   projectName,
+		ExportDate:    time.Now().UTC().Format(time.RFC3339),
+		HasLegacy:     false,
+	}
+	if reqDyn != nil {
+		item, err := reqDyn.Resource(handlers.GetAgenticSessionV1Alpha1Resource()).Namespace(projectName).Get(ctx, sessionName, metav1.GetOptions{})
+		if err == nil {
+			response.SessionSpec, _ = item.Object["spec"].(map[string]interface{})
+			response.ChainGenesis = chainGenesis(item)
+		} else if !errors.IsNotFound(err) {
+			log.Printf("Export: Warning - failed to read session %s/%s: %v", projectName, sessionName, err)
+		}
 	}
 
-	// Read AG-UI events
-	aguiData, err := readJSONLFile(aguiEventsPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// No AG-UI events yet - return empty array
-			response.AGUIEvents = json.RawMessage("[]")
-		} else {
-			log.Printf("Export: Error reading AG-UI events: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read session events"})
-			return
+	// The head is taken from the log as stored; a broken chain is still
+	// exported, and shows up when the export is verified
+	chain := eventstore.VerifyChain(aguiData, response.ChainGenesis)
+	if !chain.Valid {
+		log.Printf("Export: hash chain of %s is broken at event %d: %s", sessionName, chain.BrokenAt, chain.Error)
+	}
+	response.ChainHead, response.ChainHeadSeq = eventstore.ChainHead(aguiData)
+
+	// Redact again: the project's patterns may have changed since the
+	// events were written
+	redactor := refreshSessionRedactor(projectName, sessionName)
+	for _, event := range aguiData {
+		if redactEvent(redactor, event) > 0 {
+			response.RedactedOnExport = append(response.RedactedOnExport, eventstore.Seq(event))
 		}
+	}
+
+	if len(aguiData) == 0 {
+		// No AG-UI events yet - return empty array
+		response.AGUIEvents = json.RawMessage("[]")
 	} else {
 		// Pretty-print the events array
 		prettyJSON, err := json.MarshalIndent(aguiData, "", "  ")
@@ -140,6 +243,9 @@ func HandleExportSession(c *gin.Context) {
 		if err != nil {
 			log.Printf("Export: Warning - failed to read legacy messages: %v", err)
 		} else {
+			for _, msg := range legacyData {
+				redactEvent(redactor, msg)
+			}
 			prettyJSON, err := json.MarshalIndent(legacyData, "", "  ")
 			if err != nil {
 				log.Printf("Export: Warning - failed to format legacy messages: %v", err)
@@ -222,3 +328,18 @@ func readJSONLFile(path string) ([]map[string]interface{}, error) {
 
 	return events, nil
 }
+
+// errFoundEvent stops the scan of hasEvents
+var errFoundEvent = fmt.Errorf("found an event")
+
+// hasEvents reports whether the session's log has an event, without
+// reading the rest of it.
+func hasEvents(ctx context.Context, sessionName string) (bool, error) {
+	err := eventstore.Scan(ctx, Events, sessionName, 0, func(map[string]interface{}) error {
+		return errFoundEvent
+	})
+	if err == errFoundEvent {
+		return true, nil
+	}
+	return false, err
+}
@@ -0,0 +1,257 @@
+// fork.go — new sessions that continue another session from a point in
+// its history.
+//
+// CloneSession copies a session's spec; a fork also copies its event log
+// up to a run or event, so the conversation can be retried from before it
+// went wrong.  The fork's runner starts with no Claude state of its own;
+// FORK_CONTEXT_SEQ tells it to read the copied conversation back as a
+// transcript (export ?throughSeq=) and give it to the model as context.
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"ambient-code-backend/eventstore"
+	"ambient-code-backend/handlers"
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	parentSessionAnnotation = "vteam.ambient-code/parent-session-id"
+	forkedFromAnnotation    = "ambient-code.io/forked-from"
+
+	// forkContextEnv is the seq of the last event copied into the fork's
+	// log, which the runner reads back as the conversation so far
+	forkContextEnv = "FORK_CONTEXT_SEQ"
+)
+
+// HandleForkSession creates a session whose event log is the source
+// session's log up to the end of a run (runId) or up to an event (seq).
+// POST /api/projects/:projectName/agentic-sessions/:sessionName/fork
+//
+// The fork is named newSessionName, or "<session>-fork", with a number
+// appended if that is taken.  It keeps the source's spec, but is
+// interactive, runs as the caller and has no initial prompt: the
+// conversation continues from the fork point with the user's next message.
+func HandleForkSession(c *gin.Context) {
+	projectName := c.Param("projectName")
+	sessionName := c.Param("sessionName")
+
+	reqK8s, reqDyn := handlers.GetK8sClientsForRequest(c)
+	if reqK8s == nil || reqDyn == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing token"})
+		c.Abort()
+		return
+	}
+	if !checkAccess(reqK8s, projectName, sessionName, "get") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		c.Abort()
+		return
+	}
+	if !isValidSessionName(sessionName) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session name"})
+		return
+	}
+
+	var req types.ForkSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if (req.RunID == "") == (req.Seq <= 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "exactly one of runId or seq (> 0) is required"})
+		return
+	}
+	name := strings.TrimSpace(req.NewSessionName)
+	if name == "" {
+		name = sessionName
+	}
+	if !isValidSessionName(name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid new session name"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	gvr := handlers.GetAgenticSessionV1Alpha1Resource()
+	source, err := reqDyn.Resource(gvr).Namespace(projectName).Get(ctx, sessionName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			return
+		}
+		log.Printf("Fork: failed to get session %s/%s: %v", projectName, sessionName, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get session"})
+		return
+	}
+
+	events, err := Events.Load(ctx, sessionName, 0)
+	if err != nil {
+		log.Printf("Fork: failed to read events of %s: %v", sessionName, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read session events"})
+		return
+	}
+	prefix, forkSeq, err := forkPrefix(events, req.RunID, req.Seq)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Without newSessionName, name is the source's, which is taken
+	name, err = freeSessionName(ctx, reqDyn, projectName, name, "-fork")
+	if err != nil {
+		log.Printf("Fork: %v", err)
+		if errors.IsForbidden(err) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Created by the caller, so RBAC decides whether they may create sessions here
+	created, err := reqDyn.Resource(gvr).Namespace(projectName).Create(ctx, forkedSession(c, source, name, forkSeq, int64(len(prefix))), metav1.CreateOptions{})
+	if err != nil {
+		log.Printf("Fork: failed to create session %s/%s: %v", projectName, name, err)
+		if errors.IsForbidden(err) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+		return
+	}
+
+	dropSnapshot(name)
+	refreshSessionRedactor(projectName, name)
+	if err := appendRenamedEvents(name, sessionName, prefix); err != nil {
+		log.Printf("Fork: failed to write the log of %s/%s: %v", projectName, name, err)
+		if delErr := reqDyn.Resource(gvr).Namespace(projectName).Delete(context.Background(), name, metav1.DeleteOptions{}); delErr != nil {
+			log.Printf("Fork: failed to remove session %s/%s after a failed fork: %v", projectName, name, delErr)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write session events"})
+		return
+	}
+
+	log.Printf("Fork: forked %s/%s at event %d as %s (%d events)", projectName, sessionName, forkSeq, name, len(prefix))
+	c.JSON(http.StatusCreated, gin.H{
+		"sessionName": name,
+		"forkedAt":    forkSeq,
+		"events":      len(prefix),
+		"session":     created.Object,
+	})
+}
+
+// forkPrefix returns the events up to the end of run runID, or up to and
+// including event seq, and the seq of the last.  A prefix that ends inside
+// a run is closed with a RUN_ERROR, so the fork does not show a run that
+// never finishes.
+func forkPrefix(events []map[string]interface{}, runID string, seq int64) ([]map[string]interface{}, int64, error) {
+	end := int64(-1)
+	if runID != "" {
+		for _, run := range indexRuns("", "", events) {
+			if run.RunID == runID {
+				end = run.EndSeq
+			}
+		}
+		if end < 0 {
+			return nil, 0, fmt.Errorf("run %s not found", runID)
+		}
+	} else {
+		for _, evt := range events {
+			if eventstore.Seq(evt) == seq {
+				end = seq
+				break
+			}
+		}
+		if end < 0 {
+			return nil, 0, fmt.Errorf("event %d not found", seq)
+		}
+	}
+
+	var prefix []map[string]interface{}
+	var open map[string]interface{} // RUN_STARTED of the run the prefix ends in
+	for _, evt := range events {
+		if eventstore.Seq(evt) > end {
+			break
+		}
+		switch evt["type"] {
+		case types.EventTypeRunStarted:
+			open = evt
+		case types.EventTypeRunFinished, types.EventTypeRunError:
+			open = nil
+		}
+		prefix = append(prefix, evt)
+	}
+	if open != nil {
+		closing := map[string]interface{}{
+			"type":    types.EventTypeRunError,
+			"message": fmt.Sprintf("Session forked at event %d, before this run finished", end),
+		}
+		for _, key := range []string{"threadId", "runId"} {
+			if v, ok := open[key]; ok {
+				closing[key] = v
+			}
+		}
+		prefix = append(prefix, closing)
+	}
+	return prefix, end, nil
+}
+
+// forkedSession builds the AgenticSession of a fork of source taken at
+// event seq, whose log holds the copied events 1 to copied.  It runs as the
+// caller, not as the source's owner.
+func forkedSession(c *gin.Context, source *unstructured.Unstructured, name string, seq, copied int64) *unstructured.Unstructured {
+	spec := map[string]interface{}{}
+	if src, ok := source.Object["spec"].(map[string]interface{}); ok {
+		spec = runtime.DeepCopyJSON(src)
+	}
+	// The conversation so far is in the log; do not run the prompt again
+	delete(spec, "initialPrompt")
+	spec["interactive"] = true
+	spec["project"] = source.GetNamespace()
+	if userContext := handlers.CallerUserContext(c); userContext != nil {
+		spec["userContext"] = userContext
+	} else {
+		delete(spec, "userContext")
+	}
+	if dn, _ := spec["displayName"].(string); strings.TrimSpace(dn) != "" {
+		spec["displayName"] = fmt.Sprintf("%s (Fork)", dn)
+	} else {
+		spec["displayName"] = fmt.Sprintf("%s (Fork)", source.GetName())
+	}
+	envVars, _ := spec["environmentVariables"].(map[string]interface{})
+	if envVars == nil {
+		envVars = map[string]interface{}{}
+	}
+	envVars[forkContextEnv] = strconv.FormatInt(copied, 10)
+	spec["environmentVariables"] = envVars
+
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "vteam.ambient-code/v1alpha1",
+		"kind":       "AgenticSession",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": source.GetNamespace(),
+			"annotations": map[string]interface{}{
+				parentSessionAnnotation: source.GetName(),
+				forkedFromAnnotation:    fmt.Sprintf("%s@%d", source.GetName(), seq),
+				// The copied events are rechained from the first
+				handlers.EventChainGenesisAnnotation: "1",
+			},
+		},
+		"spec": spec,
+		"status": map[string]interface{}{
+			"phase": "Pending",
+		},
+	}}
+}
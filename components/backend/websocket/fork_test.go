@@ -0,0 +1,100 @@
+package websocket
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"ambient-code-backend/eventstore"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestForkPrefix(t *testing.T) {
+	events := []map[string]interface{}{
+		{"type": "RUN_STARTED", "runId": "r1", "threadId": "s", "seq": float64(1)},
+		{"type": "TEXT_MESSAGE_START", "messageId": "m1", "seq": float64(2)},
+		{"type": "RUN_FINISHED", "runId": "r1", "seq": float64(3)},
+		{"type": "RUN_STARTED", "runId": "r2", "threadId": "s", "seq": float64(4)},
+		{"type": "TEXT_MESSAGE_START", "messageId": "m2", "seq": float64(5)},
+		{"type": "RUN_FINISHED", "runId": "r2", "seq": float64(6)},
+	}
+
+	prefix, end, err := forkPrefix(events, "r1", 0)
+	if err != nil || end != 3 || len(prefix) != 3 {
+		t.Fatalf("forkPrefix(r1) = %d events, end %d, %v; want 3, 3, nil", len(prefix), end, err)
+	}
+
+	// Mid-run: the run is closed in the fork
+	prefix, end, err = forkPrefix(events, "", 5)
+	if err != nil || end != 5 || len(prefix) != 6 {
+		t.Fatalf("forkPrefix(seq 5) = %d events, end %d, %v; want 6, 5, nil", len(prefix), end, err)
+	}
+	if last := prefix[5]; last["type"] != "RUN_ERROR" || last["runId"] != "r2" || last["threadId"] != "s" {
+		t.Errorf("closing event = %v", last)
+	}
+
+	if _, _, err := forkPrefix(events, "r9", 0); err == nil {
+		t.Error("forkPrefix(unknown run): want error")
+	}
+	if _, _, err := forkPrefix(events, "", 7); err == nil {
+		t.Error("forkPrefix(unknown seq): want error")
+	}
+}
+
+func TestForkWritesRenamedPrefix(t *testing.T) {
+	useTempStateDir(t)
+	for _, typ := range []string{"RUN_STARTED", "RUN_FINISHED", "RUN_STARTED", "RUN_FINISHED"} {
+		persistEvent("src", map[string]interface{}{"type": typ, "threadId": "src"})
+	}
+	prefix, _, err := forkPrefix(loadEvents("src"), "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := appendRenamedEvents("src-fork", "src", prefix); err != nil {
+		t.Fatal(err)
+	}
+	forked := loadEvents("src-fork")
+	if len(forked) != 2 || forked[1]["threadId"] != "src-fork" {
+		t.Fatalf("fork log = %v", forked)
+	}
+	if chain := eventstore.VerifyChain(forked, 1); !chain.Valid {
+		t.Errorf("VerifyChain() of the fork = %+v", chain)
+	}
+	if src := loadEvents("src"); len(src) != 4 || src[0]["threadId"] != "src" {
+		t.Errorf("source log changed: %v", src)
+	}
+}
+
+func TestForkedSession(t *testing.T) {
+	source := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "src", "namespace": "proj"},
+		"spec": map[string]interface{}{
+			"displayName":          "Fix the bug",
+			"initialPrompt":        "fix it",
+			"interactive":          false,
+			"environmentVariables": map[string]interface{}{"FOO": "bar"},
+			"userContext":          map[string]interface{}{"userId": "owner", "displayName": "Owner"},
+		},
+	}}
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("userID", "forker")
+	obj := forkedSession(c, source, "src-fork", 12, 9)
+
+	spec := obj.Object["spec"].(map[string]interface{})
+	env := spec["environmentVariables"].(map[string]interface{})
+	if spec["initialPrompt"] != nil || spec["interactive"] != true || spec["displayName"] != "Fix the bug (Fork)" || env["FOO"] != "bar" || env[forkContextEnv] != "9" {
+		t.Errorf("spec = %v", spec)
+	}
+	// The fork runs as whoever forked it
+	if userContext := spec["userContext"].(map[string]interface{}); userContext["userId"] != "forker" || userContext["displayName"] != "" {
+		t.Errorf("userContext = %v", userContext)
+	}
+	if obj.GetAnnotations()[parentSessionAnnotation] != "src" || obj.GetAnnotations()[forkedFromAnnotation] != "src@12" || chainGenesis(obj) != 1 {
+		t.Errorf("annotations = %v", obj.GetAnnotations())
+	}
+	// The source is not modified
+	if _, ok := source.Object["spec"].(map[string]interface{})["environmentVariables"].(map[string]interface{})[forkContextEnv]; ok {
+		t.Error("source spec modified")
+	}
+}
@@ -0,0 +1,316 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"ambient-code-backend/eventstore"
+	"ambient-code-backend/handlers"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	// importMaxBytes bounds the size of an uploaded export
+	importMaxBytes = 512 << 20
+
+	// importedUnverifiedAnnotation lists the seqs of imported events whose
+	// content the export's chain could not vouch for (see validateExport).
+	// The import rechains them, so /verify reports them from here.
+	importedUnverifiedAnnotation = "ambient-code.io/imported-unverified-events"
+)
+
+// importedUnverified returns the seqs listed in the session's
+// importedUnverifiedAnnotation.
+func importedUnverified(session *unstructured.Unstructured) []int64 {
+	var seqs []int64
+	for _, s := range strings.Split(session.GetAnnotations()[importedUnverifiedAnnotation], ",") {
+		if seq, err := strconv.ParseInt(s, 10, 64); err == nil {
+			seqs = append(seqs, seq)
+		}
+	}
+	return seqs
+}
+
+// HandleImportSession restores an export (see HandleExportSession) as a new
+// session of the project.  The AgenticSession is recreated stopped, so it
+// can be reviewed or resumed, and the event log is written to Events.
+// POST /api/projects/:projectName/agentic-sessions/import
+//
+// The session keeps its exported name unless ?name= is given; if that name
+// is taken, in the project or in the event store, "-imported" (and a
+// number) is appended.  Events are renumbered and rechained on import;
+// their thread ID follows the new name.
+func HandleImportSession(c *gin.Context) {
+	projectName := c.Param("projectName")
+
+	reqK8s, reqDyn := handlers.GetK8sClientsForRequest(c)
+	if reqK8s == nil || reqDyn == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing token"})
+		c.Abort()
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, importMaxBytes)
+	var export ExportResponse
+	if err := c.ShouldBindJSON(&export); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid export: %v", err)})
+		return
+	}
+	events, unverified, err := validateExport(&export)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	name := strings.TrimSpace(c.Query("name"))
+	if name == "" {
+		name = export.SessionID
+	}
+	if !isValidSessionName(name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session name"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	gvr := handlers.GetAgenticSessionV1Alpha1Resource()
+	name, err = freeSessionName(ctx, reqDyn, projectName, name, "-imported")
+	if err != nil {
+		log.Printf("Import: %v", err)
+		if errors.IsForbidden(err) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := importedSession(c, projectName, name, &export, unverified)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid export: %v", err)})
+		return
+	}
+
+	// Created by the caller, so RBAC decides whether they may import here
+	created, err := reqDyn.Resource(gvr).Namespace(projectName).Create(ctx, session, metav1.CreateOptions{})
+	if err != nil {
+		log.Printf("Import: failed to create session %s/%s: %v", projectName, name, err)
+		if errors.IsForbidden(err) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+		return
+	}
+
+	if err := writeImportedLog(projectName, name, export.SessionID, events, &export); err != nil {
+		log.Printf("Import: failed to write the log of %s/%s: %v", projectName, name, err)
+		if delErr := reqDyn.Resource(gvr).Namespace(projectName).Delete(context.Background(), name, metav1.DeleteOptions{}); delErr != nil {
+			log.Printf("Import: failed to remove session %s/%s after a failed import: %v", projectName, name, delErr)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write session events"})
+		return
+	}
+
+	log.Printf("Import: imported %s/%s as %s/%s (%d events)", export.ProjectName, export.SessionID, projectName, name, len(events))
+	c.JSON(http.StatusCreated, gin.H{
+		"sessionName":      name,
+		"events":           len(events),
+		"unverifiedEvents": unverified,
+		"session":          created.Object,
+	})
+}
+
+// validateExport checks the export's version and, when its events are
+// chained, their chain and head, and returns its events.  It also returns
+// the new seqs (see importedSeqs) of the events that redactedOnExport
+// lists and that no longer match their hashes: nothing vouches for their
+// content, as redactedOnExport is part of the uploaded file.
+func validateExport(export *ExportResponse) ([]map[string]interface{}, []int64, error) {
+	if export.SchemaVersion < 0 || export.SchemaVersion > exportSchemaVersion {
+		return nil, nil, fmt.Errorf("unsupported export schema version %d (this backend reads up to %d)", export.SchemaVersion, exportSchemaVersion)
+	}
+	if !isValidSessionName(export.SessionID) {
+		return nil, nil, fmt.Errorf("invalid export: invalid sessionId %q", export.SessionID)
+	}
+	var events []map[string]interface{}
+	if len(export.AGUIEvents) > 0 {
+		if err := json.Unmarshal(export.AGUIEvents, &events); err != nil {
+			return nil, nil, fmt.Errorf("invalid export: aguiEvents: %v", err)
+		}
+	}
+
+	changed := make(map[int64]bool, len(export.RedactedOnExport))
+	for _, seq := range export.RedactedOnExport {
+		changed[seq] = true
+	}
+	chain := eventstore.VerifyChainExcept(events, export.ChainGenesis, changed)
+	if !chain.Valid {
+		return nil, nil, fmt.Errorf("export failed verification: event %d: %s", chain.BrokenAt, chain.Error)
+	}
+	if export.ChainHead != "" {
+		if head, seq := eventstore.ChainHead(events); head != export.ChainHead || seq != export.ChainHeadSeq {
+			return nil, nil, fmt.Errorf("export failed verification: its events do not end at chainHead (event %d)", export.ChainHeadSeq)
+		}
+	}
+	return events, importedSeqs(events, chain.Unverified), nil
+}
+
+// importedSeqs maps seqs of an export's events to the seqs the events get
+// in the imported log, which numbers them from 1 in order.
+func importedSeqs(events []map[string]interface{}, seqs []int64) []int64 {
+	wanted := make(map[int64]bool, len(seqs))
+	for _, seq := range seqs {
+		wanted[seq] = true
+	}
+	var out []int64
+	for i, event := range events {
+		if wanted[eventstore.Seq(event)] {
+			out = append(out, int64(i+1))
+		}
+	}
+	return out
+}
+
+// freeSessionName returns name, or name with suffix (and a number), such
+// that neither the project nor the event store has a session by that name.
+// The store is shared by all projects, and may still hold the log of a
+// session whose namespace was deleted.
+func freeSessionName(ctx context.Context, dyn dynamic.Interface, projectName, name, suffix string) (string, error) {
+	gvr := handlers.GetAgenticSessionV1Alpha1Resource()
+	candidate := name
+	for i := 1; i <= 50; i++ {
+		_, err := dyn.Resource(gvr).Namespace(projectName).Get(ctx, candidate, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			existing, loadErr := Events.Load(ctx, candidate, 0)
+			if loadErr != nil {
+				return "", fmt.Errorf("failed to check the event store for %s: %w", candidate, loadErr)
+			}
+			if len(existing) == 0 {
+				return candidate, nil
+			}
+		} else if err != nil {
+			return "", fmt.Errorf("failed to check for session %s/%s: %w", projectName, candidate, err)
+		}
+
+		next := suffix
+		if i > 1 {
+			next = fmt.Sprintf("%s-%d", suffix, i)
+		}
+		base := name
+		if len(base)+len(next) > 63 {
+			base = strings.TrimRight(base[:63-len(next)], "-")
+		}
+		candidate = base + next
+	}
+	return "", fmt.Errorf("no free name for session %s in project %s", name, projectName)
+}
+
+// importedSession builds the AgenticSession for an import.  Its spec is
+// rebuilt from the exported one as a create request (see
+// handlers.ImportedSessionSpec), so it runs as the caller.  The
+// desired-phase annotation makes the operator move it straight to Stopped
+// without starting a runner.
+func importedSession(c *gin.Context, projectName, name string, export *ExportResponse, unverified []int64) (*unstructured.Unstructured, error) {
+	spec, err := handlers.ImportedSessionSpec(c, projectName, name, export.SessionSpec)
+	if err != nil {
+		return nil, err
+	}
+	if dn, _ := spec["displayName"].(string); strings.TrimSpace(dn) == "" {
+		spec["displayName"] = fmt.Sprintf("%s (imported)", export.SessionID)
+	}
+	// The exported prompt already ran; do not send it again on resume
+	delete(spec, "initialPrompt")
+
+	annotations := map[string]interface{}{
+		"ambient-code.io/desired-phase": "Stopped",
+		"ambient-code.io/imported-from": export.ProjectName + "/" + export.SessionID,
+		// The imported events are rechained from the first
+		handlers.EventChainGenesisAnnotation: "1",
+	}
+	if len(unverified) > 0 {
+		seqs := make([]string, len(unverified))
+		for i, seq := range unverified {
+			seqs[i] = strconv.FormatInt(seq, 10)
+		}
+		annotations[importedUnverifiedAnnotation] = strings.Join(seqs, ",")
+	}
+
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "vteam.ambient-code/v1alpha1",
+		"kind":       "AgenticSession",
+		"metadata": map[string]interface{}{
+			"name":        name,
+			"namespace":   projectName,
+			"annotations": annotations,
+		},
+		"spec": spec,
+		"status": map[string]interface{}{
+			"phase": "Pending",
+		},
+	}}, nil
+}
+
+// appendRenamedEvents appends events taken from the log of session oldName
+// to the log of session name.  They are renumbered and rechained, and
+// thread IDs that named the old session name the new one.
+func appendRenamedEvents(name, oldName string, events []map[string]interface{}) error {
+	for _, event := range events {
+		delete(event, "seq")
+		delete(event, "hash")
+		if event["threadId"] == oldName {
+			event["threadId"] = name
+		}
+		if persistEvent(name, event) == 0 {
+			return fmt.Errorf("failed to persist event %v", event["type"])
+		}
+	}
+	return nil
+}
+
+// writeImportedLog appends the exported events to the new session's log,
+// renumbered and rechained, with thread IDs that named the old session
+// renamed, and restores the legacy messages next to it.
+func writeImportedLog(projectName, name, oldName string, events []map[string]interface{}, export *ExportResponse) error {
+	// Nothing cached for this name may outlive the import
+	dropSnapshot(name)
+	refreshSessionRedactor(projectName, name)
+
+	if err := appendRenamedEvents(name, oldName, events); err != nil {
+		return err
+	}
+
+	if !export.HasLegacy || len(export.LegacyMessages) == 0 {
+		return nil
+	}
+	var legacy []map[string]interface{}
+	if err := json.Unmarshal(export.LegacyMessages, &legacy); err != nil {
+		return fmt.Errorf("legacyMessages: %w", err)
+	}
+	var b strings.Builder
+	for _, msg := range legacy {
+		line, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	dir := filepath.Join(StateBaseDir, "sessions", name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	// .migrated: the events above already include them
+	return os.WriteFile(filepath.Join(dir, "messages.jsonl.migrated"), []byte(b.String()), 0644)
+}
@@ -0,0 +1,143 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"ambient-code-backend/eventstore"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportOf builds the export of a session the way HandleExportSession does
+func exportOf(t *testing.T, session string) *ExportResponse {
+	t.Helper()
+	events := loadEvents(session)
+	data, err := json.Marshal(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, headSeq := eventstore.ChainHead(events)
+	return &ExportResponse{
+		SchemaVersion: exportSchemaVersion,
+		SessionID:     session,
+		ProjectName:   "source",
+		AGUIEvents:    data,
+		ChainHead:     head,
+		ChainHeadSeq:  headSeq,
+	}
+}
+
+func TestValidateExport(t *testing.T) {
+	useTempStateDir(t)
+	for _, typ := range []string{"RUN_STARTED", "TEXT_MESSAGE_START", "RUN_FINISHED"} {
+		persistEvent("exported", map[string]interface{}{"type": typ, "threadId": "exported", "runId": "r1"})
+	}
+
+	if events, unverified, err := validateExport(exportOf(t, "exported")); err != nil || len(events) != 3 || len(unverified) != 0 {
+		t.Fatalf("validateExport() = %d events, %v, %v; want 3, none, nil", len(events), unverified, err)
+	}
+
+	future := exportOf(t, "exported")
+	future.SchemaVersion = exportSchemaVersion + 1
+	if _, _, err := validateExport(future); err == nil {
+		t.Error("validateExport() of a newer schema version: want error")
+	}
+
+	// An event edited after export no longer verifies...
+	tampered := exportOf(t, "exported")
+	var events []map[string]interface{}
+	_ = json.Unmarshal(tampered.AGUIEvents, &events)
+	events[1]["type"] = "TEXT_MESSAGE_END"
+	tampered.AGUIEvents, _ = json.Marshal(events)
+	if _, _, err := validateExport(tampered); err == nil {
+		t.Error("validateExport() of an edited export: want error")
+	}
+	// ...and one the export says it redacted is accepted, but as unverified
+	tampered.RedactedOnExport = []int64{2, 3}
+	if _, unverified, err := validateExport(tampered); err != nil || len(unverified) != 1 || unverified[0] != 2 {
+		t.Errorf("validateExport() of an export-redacted event = %v, %v; want [2], nil", unverified, err)
+	}
+
+	truncated := exportOf(t, "exported")
+	_ = json.Unmarshal(truncated.AGUIEvents, &events)
+	truncated.AGUIEvents, _ = json.Marshal(events[:2])
+	if _, _, err := validateExport(truncated); err == nil {
+		t.Error("validateExport() of a truncated export: want error")
+	}
+}
+
+func TestWriteImportedLogRenamesAndRechains(t *testing.T) {
+	useTempStateDir(t)
+	for _, typ := range []string{"RUN_STARTED", "RUN_FINISHED"} {
+		persistEvent("original", map[string]interface{}{"type": typ, "threadId": "original", "runId": "r1"})
+	}
+	persistEvent("other", map[string]interface{}{"type": "RUN_STARTED"})
+	export := exportOf(t, "original")
+	events, _, err := validateExport(export)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeImportedLog("target", "original-imported", "original", events, export); err != nil {
+		t.Fatalf("writeImportedLog() error = %v", err)
+	}
+	imported := loadEvents("original-imported")
+	if len(imported) != 2 {
+		t.Fatalf("imported %d events, want 2", len(imported))
+	}
+	for i, evt := range imported {
+		if evt["threadId"] != "original-imported" || eventstore.Seq(evt) != int64(i+1) {
+			t.Errorf("event %d = %v", i, evt)
+		}
+	}
+	if chain := eventstore.VerifyChain(imported, 1); !chain.Valid || chain.Unchained != 0 {
+		t.Errorf("VerifyChain() of the import = %+v", chain)
+	}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("userID", "importer")
+	obj, err := importedSession(c, "target", "original-imported", &ExportResponse{
+		SessionID:   "original",
+		ProjectName: "source",
+		SessionSpec: map[string]interface{}{
+			"project":        "source",
+			"initialPrompt":  "hi",
+			"timeout":        float64(600),
+			"userContext":    map[string]interface{}{"userId": "victim", "displayName": "Victim", "groups": []interface{}{"admins"}},
+			"serviceAccount": "cluster-admin",
+		},
+	}, []int64{2, 5})
+	if err != nil {
+		t.Fatalf("importedSession() error = %v", err)
+	}
+	spec := obj.Object["spec"].(map[string]interface{})
+	if spec["project"] != "target" || spec["initialPrompt"] != nil || spec["timeout"] != 600 || spec["serviceAccount"] != nil {
+		t.Errorf("spec = %v", spec)
+	}
+	// The session runs as the importer, whatever the export claims
+	userContext := spec["userContext"].(map[string]interface{})
+	if userContext["userId"] != "importer" || userContext["displayName"] != "" || len(userContext["groups"].([]string)) != 0 {
+		t.Errorf("userContext = %v", userContext)
+	}
+	annotations := obj.Object["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})
+	if annotations["ambient-code.io/desired-phase"] != "Stopped" || annotations["ambient-code.io/imported-from"] != "source/original" {
+		t.Errorf("annotations = %v", annotations)
+	}
+	if got := importedUnverified(obj); len(got) != 2 || got[0] != 2 || got[1] != 5 {
+		t.Errorf("importedUnverified() = %v, want [2 5]", got)
+	}
+}
+
+func TestImportedSessionRejectsInvalidSpec(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("userID", "importer")
+	_, err := importedSession(c, "target", "s", &ExportResponse{
+		SessionID:   "s",
+		SessionSpec: map[string]interface{}{"environmentVariables": map[string]interface{}{"A": float64(1)}},
+	}, nil)
+	if err == nil {
+		t.Error("importedSession() accepted a non-string environment variable")
+	}
+}
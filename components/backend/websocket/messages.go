@@ -0,0 +1,441 @@
+// messages.go — conversation history folded from the AG-UI event log.
+//
+// Clients that only want the conversation get it as types.Message
+// objects instead of replaying every event: streamed TEXT_MESSAGE_* and
+// TOOL_CALL_* events are folded into messages, and MESSAGES_SNAPSHOT
+// events (which the runner sends at the end of each run) replace the
+// messages they contain.
+package websocket
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"ambient-code-backend/handlers"
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleListSessionMessages returns the session's conversation, oldest
+// first, without messages marked hidden.
+// GET /api/projects/:projectName/agentic-sessions/:sessionName/messages
+//
+// Query parameters: limit and continue (from the previous page's
+// "continue"), and since/before (RFC 3339) to select messages by time.
+func HandleListSessionMessages(c *gin.Context) {
+	projectName := c.Param("projectName")
+	sessionName := c.Param("sessionName")
+
+	reqK8s, _ := handlers.GetK8sClientsForRequest(c)
+	if reqK8s == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing token"})
+		c.Abort()
+		return
+	}
+	if !checkAccess(reqK8s, projectName, sessionName, "get") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		c.Abort()
+		return
+	}
+	if !isValidSessionName(sessionName) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session name"})
+		return
+	}
+
+	var params types.PaginationParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pagination parameters"})
+		return
+	}
+	types.NormalizePaginationParams(&params)
+	since, err := parseTimeQuery(c, "since")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	before, err := parseTimeQuery(c, "before")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	history := loadReplayHistory(sessionName)
+	folder := newMessageFolder()
+	folder.fold(history.snapshot)
+	folder.fold(history.tail)
+	messages := filterMessagesByTime(folder.visible(), since, before)
+
+	page, err := pageMessages(messages, params.Continue, params.Limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("Messages: %d of %d messages for %s/%s", len(page.Items.([]types.Message)), page.TotalCount, projectName, sessionName)
+	c.JSON(http.StatusOK, page)
+}
+
+// parseTimeQuery parses an optional RFC 3339 query parameter.
+func parseTimeQuery(c *gin.Context, name string) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: expected an RFC 3339 timestamp", name)
+	}
+	return &t, nil
+}
+
+// filterMessagesByTime keeps messages at or after since and before
+// before.  Messages without a timestamp cannot be placed and are dropped
+// when either bound is given.
+func filterMessagesByTime(messages []types.Message, since, before *time.Time) []types.Message {
+	if since == nil && before == nil {
+		return messages
+	}
+	filtered := make([]types.Message, 0, len(messages))
+	for _, msg := range messages {
+		t, err := time.Parse(time.RFC3339Nano, msg.Timestamp)
+		if err != nil {
+			continue
+		}
+		if (since != nil && t.Before(*since)) || (before != nil && !t.Before(*before)) {
+			continue
+		}
+		filtered = append(filtered, msg)
+	}
+	return filtered
+}
+
+// pageMessages returns up to limit messages after the one named by the
+// continue token.  Tokens name a message rather than a position, so pages
+// stay consistent while the conversation grows.
+func pageMessages(messages []types.Message, continueToken string, limit int) (types.PaginatedResponse, error) {
+	start := 0
+	if continueToken != "" {
+		id, err := base64.RawURLEncoding.DecodeString(continueToken)
+		if err != nil {
+			return types.PaginatedResponse{}, fmt.Errorf("invalid continue token")
+		}
+		start = -1
+		for i, msg := range messages {
+			if msg.ID == string(id) {
+				start = i + 1
+				break
+			}
+		}
+		if start < 0 {
+			return types.PaginatedResponse{}, fmt.Errorf("invalid continue token")
+		}
+	}
+	end := start + limit
+	if end > len(messages) {
+		end = len(messages)
+	}
+
+	page := types.PaginatedResponse{
+		Items:      append([]types.Message{}, messages[start:end]...),
+		TotalCount: len(messages),
+		Limit:      limit,
+		HasMore:    end < len(messages),
+	}
+	if page.HasMore {
+		page.Continue = base64.RawURLEncoding.EncodeToString([]byte(messages[end-1].ID))
+	}
+	return page, nil
+}
+
+// ─── Folding ─────────────────────────────────────────────────────────
+
+// messageFolder rebuilds messages from events, in the order they first
+// appear.
+type messageFolder struct {
+	messages []*types.Message
+	byID     map[string]*types.Message
+	toolMsg  map[string]*types.Message // toolCallID → assistant message holding the call
+	hidden   map[string]bool           // from message_metadata RAW events
+}
+
+func newMessageFolder() *messageFolder {
+	return &messageFolder{
+		byID:    make(map[string]*types.Message),
+		toolMsg: make(map[string]*types.Message),
+		hidden:  make(map[string]bool),
+	}
+}
+
+// message returns the message with id, adding it if it is new.
+func (f *messageFolder) message(id, role string, timestamp string) *types.Message {
+	if msg, ok := f.byID[id]; ok {
+		return msg
+	}
+	msg := &types.Message{ID: id, Role: role, Timestamp: timestamp}
+	f.messages = append(f.messages, msg)
+	f.byID[id] = msg
+	return msg
+}
+
+// toolCall returns the call with id, or nil if no message holds it.
+func (f *messageFolder) toolCall(id string) *types.ToolCall {
+	msg := f.toolMsg[id]
+	if msg == nil {
+		return nil
+	}
+	for i := range msg.ToolCalls {
+		if msg.ToolCalls[i].ID == id {
+			return &msg.ToolCalls[i]
+		}
+	}
+	return nil
+}
+
+// lastAssistant returns the most recent assistant message, or nil.
+func (f *messageFolder) lastAssistant() *types.Message {
+	for i := len(f.messages) - 1; i >= 0; i-- {
+		if f.messages[i].Role == types.RoleAssistant {
+			return f.messages[i]
+		}
+	}
+	return nil
+}
+
+func (f *messageFolder) fold(events []map[string]interface{}) {
+	for _, evt := range events {
+		f.add(evt)
+	}
+}
+
+// add folds the next event of the log.
+func (f *messageFolder) add(evt map[string]interface{}) {
+	eventType, _ := evt["type"].(string)
+	ts := eventTimestamp(evt)
+	switch eventType {
+	case types.EventTypeTextMessageStart:
+		if id, _ := evt["messageId"].(string); id != "" {
+			role, _ := evt["role"].(string)
+			if role == "" {
+				role = types.RoleAssistant
+			}
+			f.message(id, role, ts)
+		}
+	case types.EventTypeTextMessageContent:
+		if id, _ := evt["messageId"].(string); id != "" {
+			delta, _ := evt["delta"].(string)
+			f.message(id, types.RoleAssistant, ts).Content += delta
+		}
+	case types.EventTypeToolCallStart:
+		f.startToolCall(evt, ts)
+	case types.EventTypeToolCallArgs:
+		id, _ := evt["toolCallId"].(string)
+		if tc := f.toolCall(id); tc != nil {
+			delta, _ := evt["delta"].(string)
+			tc.Args += delta
+		}
+	case types.EventTypeToolCallEnd:
+		id, _ := evt["toolCallId"].(string)
+		if tc := f.toolCall(id); tc != nil && tc.Status == "running" {
+			tc.Status = "completed"
+		}
+	case types.EventTypeToolCallResult:
+		f.toolResult(evt, ts)
+	case types.EventTypeMessagesSnapshot:
+		raw, _ := evt["messages"].([]interface{})
+		for _, item := range raw {
+			if m, ok := item.(map[string]interface{}); ok {
+				f.upsert(snapshotMessage(m), ts)
+			}
+		}
+	case types.EventTypeRaw:
+		if inner, _ := evt["event"].(map[string]interface{}); inner != nil && inner["type"] == "message_metadata" {
+			id, _ := inner["messageId"].(string)
+			if hidden, _ := inner["hidden"].(bool); hidden && id != "" {
+				f.hidden[id] = true
+			}
+		}
+	}
+}
+
+// startToolCall attaches a new call to the assistant message it belongs
+// to: its parentMessageId, else the latest assistant message.
+func (f *messageFolder) startToolCall(evt map[string]interface{}, ts string) {
+	id, _ := evt["toolCallId"].(string)
+	if id == "" || f.toolMsg[id] != nil {
+		return
+	}
+	name, _ := evt["toolCallName"].(string)
+	parentTool, _ := evt["parent_tool_call_id"].(string)
+
+	var owner *types.Message
+	if parentID, _ := evt["parentMessageId"].(string); parentID != "" {
+		owner = f.message(parentID, types.RoleAssistant, ts)
+	} else if owner = f.lastAssistant(); owner == nil {
+		owner = f.message(id, types.RoleAssistant, ts)
+	}
+	owner.ToolCalls = append(owner.ToolCalls, types.ToolCall{
+		ID:              id,
+		Name:            name,
+		Type:            "function",
+		Status:          "running",
+		ParentToolUseID: parentTool,
+	})
+	f.toolMsg[id] = owner
+}
+
+// toolResult records a TOOL_CALL_RESULT on its call and as a tool message.
+func (f *messageFolder) toolResult(evt map[string]interface{}, ts string) {
+	toolCallID, _ := evt["toolCallId"].(string)
+	content, _ := evt["content"].(string)
+	if tc := f.toolCall(toolCallID); tc != nil {
+		tc.Result = content
+		if tc.Status != "error" {
+			tc.Status = "completed"
+		}
+	}
+	id, _ := evt["messageId"].(string)
+	if id == "" {
+		id = toolCallID + "-result"
+	}
+	msg := f.message(id, types.RoleTool, ts)
+	msg.ToolCallID = toolCallID
+	msg.Content = content
+}
+
+// upsert applies a message from a MESSAGES_SNAPSHOT.  The snapshot is
+// authoritative for content; what only streaming knows (timestamps, call
+// results and status) is kept.
+func (f *messageFolder) upsert(snap types.Message, ts string) {
+	if snap.ID == "" {
+		return
+	}
+	msg := f.message(snap.ID, snap.Role, ts)
+	if snap.Timestamp == "" {
+		snap.Timestamp = msg.Timestamp
+	}
+	for i := range snap.ToolCalls {
+		tc := &snap.ToolCalls[i]
+		if prev := f.toolCall(tc.ID); prev != nil {
+			if tc.Result == "" {
+				tc.Result = prev.Result
+			}
+			if tc.Status == "" {
+				tc.Status = prev.Status
+			}
+			if tc.ParentToolUseID == "" {
+				tc.ParentToolUseID = prev.ParentToolUseID
+			}
+		}
+	}
+	*msg = snap
+	for _, tc := range msg.ToolCalls {
+		f.toolMsg[tc.ID] = msg
+	}
+	if msg.Role == types.RoleTool && msg.ToolCallID != "" {
+		if tc := f.toolCall(msg.ToolCallID); tc != nil && tc.Result == "" {
+			tc.Result = msg.Content
+			tc.Status = "completed"
+		}
+	}
+}
+
+// visible returns the folded messages without hidden ones.
+func (f *messageFolder) visible() []types.Message {
+	messages := make([]types.Message, 0, len(f.messages))
+	for _, msg := range f.messages {
+		if f.hidden[msg.ID] || isMessageHidden(msg.Metadata) {
+			continue
+		}
+		messages = append(messages, *msg)
+	}
+	return messages
+}
+
+// snapshotMessage converts a MESSAGES_SNAPSHOT entry.  Tool calls use
+// the AG-UI nested form {id, type, function: {name, arguments}}; content
+// may be a string or a list of parts.
+func snapshotMessage(m map[string]interface{}) types.Message {
+	msg := types.Message{Metadata: m["metadata"]}
+	msg.ID, _ = m["id"].(string)
+	msg.Role, _ = m["role"].(string)
+	msg.Name, _ = m["name"].(string)
+	msg.Content = messageContent(m["content"])
+	if msg.ToolCallID, _ = m["toolCallId"].(string); msg.ToolCallID == "" {
+		msg.ToolCallID, _ = m["tool_call_id"].(string)
+	}
+	switch ts := m["timestamp"].(type) {
+	case string:
+		msg.Timestamp = ts
+	case float64:
+		msg.Timestamp = formatEventTime(int64(ts))
+	}
+
+	calls, _ := m["toolCalls"].([]interface{})
+	if calls == nil {
+		calls, _ = m["tool_calls"].([]interface{})
+	}
+	for _, item := range calls {
+		raw, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		tc := types.ToolCall{}
+		tc.ID, _ = raw["id"].(string)
+		tc.Type, _ = raw["type"].(string)
+		tc.ParentToolUseID, _ = raw["parentToolUseId"].(string)
+		tc.Result, _ = raw["result"].(string)
+		tc.Status, _ = raw["status"].(string)
+		if fn, ok := raw["function"].(map[string]interface{}); ok {
+			tc.Name, _ = fn["name"].(string)
+			tc.Args, _ = fn["arguments"].(string)
+		} else {
+			tc.Name, _ = raw["name"].(string)
+			tc.Args, _ = raw["args"].(string)
+		}
+		msg.ToolCalls = append(msg.ToolCalls, tc)
+	}
+	return msg
+}
+
+// messageContent flattens string or [{type: text, text}] content.
+func messageContent(content interface{}) string {
+	switch v := content.(type) {
+	case string:
+		return v
+	case []interface{}:
+		var parts []string
+		for _, item := range v {
+			if part, ok := item.(map[string]interface{}); ok {
+				if text, ok := part["text"].(string); ok {
+					parts = append(parts, text)
+				}
+			}
+		}
+		return strings.Join(parts, "")
+	}
+	return ""
+}
+
+// eventTimestamp returns the event's timestamp as RFC 3339, or "".
+func eventTimestamp(evt map[string]interface{}) string {
+	switch ts := evt["timestamp"].(type) {
+	case float64:
+		return formatEventTime(int64(ts))
+	case int64:
+		return formatEventTime(ts)
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+			return t.UTC().Format(time.RFC3339Nano)
+		}
+	}
+	return ""
+}
+
+// formatEventTime formats an AG-UI epoch-millisecond timestamp.
+func formatEventTime(ms int64) string {
+	return time.UnixMilli(ms).UTC().Format(time.RFC3339Nano)
+}
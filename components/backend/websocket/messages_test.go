@@ -0,0 +1,155 @@
+package websocket
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"ambient-code-backend/types"
+)
+
+func TestFoldMessagesFromStreamingEvents(t *testing.T) {
+	f := newMessageFolder()
+	f.fold([]map[string]interface{}{
+		{"type": types.EventTypeTextMessageStart, "messageId": "u1", "role": "user", "timestamp": float64(1700000000000)},
+		{"type": types.EventTypeTextMessageContent, "messageId": "u1", "delta": "list files"},
+		{"type": types.EventTypeTextMessageStart, "messageId": "a1", "role": "assistant"},
+		{"type": types.EventTypeTextMessageContent, "messageId": "a1", "delta": "Sure, "},
+		{"type": types.EventTypeTextMessageContent, "messageId": "a1", "delta": "running ls."},
+		{"type": types.EventTypeToolCallStart, "toolCallId": "t1", "toolCallName": "Bash", "parentMessageId": "a1"},
+		{"type": types.EventTypeToolCallArgs, "toolCallId": "t1", "delta": `{"command":`},
+		{"type": types.EventTypeToolCallArgs, "toolCallId": "t1", "delta": `"ls"}`},
+		{"type": types.EventTypeToolCallEnd, "toolCallId": "t1"},
+		{"type": types.EventTypeToolCallResult, "toolCallId": "t1", "messageId": "t1-result", "content": "main.go"},
+	})
+	msgs := f.visible()
+
+	if len(msgs) != 3 {
+		t.Fatalf("got %d messages, want 3: %+v", len(msgs), msgs)
+	}
+	if msgs[0].Role != types.RoleUser || msgs[0].Content != "list files" || msgs[0].Timestamp != "2023-11-14T22:13:20Z" {
+		t.Errorf("user message = %+v", msgs[0])
+	}
+	if msgs[1].Content != "Sure, running ls." || len(msgs[1].ToolCalls) != 1 {
+		t.Fatalf("assistant message = %+v", msgs[1])
+	}
+	tc := msgs[1].ToolCalls[0]
+	if tc.Name != "Bash" || tc.Args != `{"command":"ls"}` || tc.Status != "completed" || tc.Result != "main.go" {
+		t.Errorf("tool call = %+v", tc)
+	}
+	if msgs[2].Role != types.RoleTool || msgs[2].ToolCallID != "t1" || msgs[2].Content != "main.go" {
+		t.Errorf("tool message = %+v", msgs[2])
+	}
+}
+
+func TestFoldMessagesSnapshotReplacesStreamedContent(t *testing.T) {
+	f := newMessageFolder()
+	f.fold([]map[string]interface{}{
+		{"type": types.EventTypeTextMessageStart, "messageId": "a1", "role": "assistant", "timestamp": float64(1700000000000)},
+		{"type": types.EventTypeTextMessageContent, "messageId": "a1", "delta": "partial"},
+		{"type": types.EventTypeToolCallStart, "toolCallId": "t1", "toolCallName": "Read", "parentMessageId": "a1"},
+		{"type": types.EventTypeToolCallResult, "toolCallId": "t1", "content": "file body"},
+		{"type": types.EventTypeMessagesSnapshot, "messages": []interface{}{
+			map[string]interface{}{"id": "u0", "role": "user", "content": []interface{}{
+				map[string]interface{}{"type": "text", "text": "read it"},
+			}},
+			map[string]interface{}{"id": "a1", "role": "assistant", "content": "full answer", "toolCalls": []interface{}{
+				map[string]interface{}{"id": "t1", "type": "function", "function": map[string]interface{}{"name": "Read", "arguments": `{"path":"x"}`}},
+			}},
+			map[string]interface{}{"id": "t1-result", "role": "tool", "tool_call_id": "t1", "content": "file body"},
+		}},
+	})
+	msgs := f.visible()
+
+	if len(msgs) != 3 {
+		t.Fatalf("got %d messages, want 3: %+v", len(msgs), msgs)
+	}
+	// Streamed messages keep their position; new ones are appended
+	if msgs[0].ID != "a1" || msgs[1].ID != "t1-result" || msgs[2].ID != "u0" {
+		t.Errorf("order = %s, %s, %s", msgs[0].ID, msgs[1].ID, msgs[2].ID)
+	}
+	a1 := msgs[0]
+	if a1.Content != "full answer" || a1.Timestamp != "2023-11-14T22:13:20Z" {
+		t.Errorf("assistant message = %+v", a1)
+	}
+	if len(a1.ToolCalls) != 1 || a1.ToolCalls[0].Args != `{"path":"x"}` || a1.ToolCalls[0].Result != "file body" || a1.ToolCalls[0].Status != "completed" {
+		t.Errorf("tool calls = %+v", a1.ToolCalls)
+	}
+	if msgs[1].ToolCallID != "t1" {
+		t.Errorf("tool message = %+v", msgs[1])
+	}
+	if msgs[2].Content != "read it" {
+		t.Errorf("user message = %+v", msgs[2])
+	}
+}
+
+func TestFoldMessagesSkipsHidden(t *testing.T) {
+	f := newMessageFolder()
+	f.fold([]map[string]interface{}{
+		{"type": types.EventTypeMessagesSnapshot, "messages": []interface{}{
+			map[string]interface{}{"id": "m1", "role": "user", "content": "auto prompt", "metadata": map[string]interface{}{"hidden": true}},
+			map[string]interface{}{"id": "m2", "role": "user", "content": "hidden later"},
+			map[string]interface{}{"id": "m3", "role": "assistant", "content": "visible"},
+		}},
+		{"type": types.EventTypeRaw, "event": map[string]interface{}{"type": "message_metadata", "messageId": "m2", "hidden": true}},
+	})
+	msgs := f.visible()
+	if len(msgs) != 1 || msgs[0].ID != "m3" {
+		t.Errorf("visible = %+v, want only m3", msgs)
+	}
+}
+
+func TestFilterMessagesByTime(t *testing.T) {
+	msgs := []types.Message{
+		{ID: "a", Timestamp: "2024-01-01T00:00:00Z"},
+		{ID: "b", Timestamp: "2024-01-02T00:00:00Z"},
+		{ID: "c"},
+		{ID: "d", Timestamp: "2024-01-03T00:00:00Z"},
+	}
+	since := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	before := time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)
+
+	if got := filterMessagesByTime(msgs, nil, nil); len(got) != 4 {
+		t.Errorf("no bounds: got %d messages, want 4", len(got))
+	}
+	got := filterMessagesByTime(msgs, &since, &before)
+	if len(got) != 1 || got[0].ID != "b" {
+		t.Errorf("since/before: got %+v, want only b", got)
+	}
+}
+
+func TestPageMessages(t *testing.T) {
+	var msgs []types.Message
+	for i := 0; i < 5; i++ {
+		msgs = append(msgs, types.Message{ID: fmt.Sprintf("m%d", i)})
+	}
+
+	var ids []string
+	token := ""
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("pagination did not terminate")
+		}
+		page, err := pageMessages(msgs, token, 2)
+		if err != nil {
+			t.Fatalf("pageMessages() error = %v", err)
+		}
+		if page.TotalCount != 5 {
+			t.Errorf("TotalCount = %d, want 5", page.TotalCount)
+		}
+		for _, msg := range page.Items.([]types.Message) {
+			ids = append(ids, msg.ID)
+		}
+		if !page.HasMore {
+			break
+		}
+		token = page.Continue
+	}
+	if fmt.Sprint(ids) != "[m0 m1 m2 m3 m4]" {
+		t.Errorf("paged ids = %v", ids)
+	}
+
+	if _, err := pageMessages(msgs, "bm9wZQ", 2); err == nil {
+		t.Error("unknown continue token: want error")
+	}
+}
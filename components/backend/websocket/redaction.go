@@ -0,0 +1,223 @@
+// redaction.go — credential redaction for persisted and exported events.
+//
+// Every event is passed through the session's redactor before it is
+// persisted (and so before it is published), and exports pass the log
+// through it again so patterns added since the events were written apply
+// too.  The number of matches masked in an event is recorded on it as
+// "redactions".  Streamed text and tool-call arguments are held back up
+// to a word boundary first (see deltaRedactor), so a credential split
+// across deltas is redacted whole.
+package websocket
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"ambient-code-backend/handlers"
+	"ambient-code-backend/redact"
+	"ambient-code-backend/types"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// defaultRunnerSecretsName is used when ProjectSettings does not name
+	// the runner secret (see spec.runnerSecretsName)
+	defaultRunnerSecretsName = "ambient-runner-secrets"
+	// integrationSecretsName is injected into every runner when present
+	integrationSecretsName = "ambient-non-vertex-integrations"
+
+	// maxHeldDelta bounds the text held back from a stream that has no
+	// whitespace; past it all but the last heldDeltaTail bytes go out
+	maxHeldDelta  = 16 << 10
+	heldDeltaTail = 1 << 10
+)
+
+// sessionRedactors maps sessionName → *redact.Redactor, refreshed when a
+// run starts.  Sessions without one use redact.Default.
+var sessionRedactors sync.Map
+
+// sessionRedactor returns the redactor for the session.
+func sessionRedactor(sessionName string) *redact.Redactor {
+	if r, ok := sessionRedactors.Load(sessionName); ok {
+		return r.(*redact.Redactor)
+	}
+	return redact.Default
+}
+
+// refreshSessionRedactor rebuilds the session's redactor from the
+// project's redaction patterns and the values of the secrets mounted into
+// its runner.  What cannot be read is logged and left out; the built-in
+// formats always apply.
+func refreshSessionRedactor(projectName, sessionName string) *redact.Redactor {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	patterns, runnerSecrets := projectRedactionSettings(ctx, projectName)
+	var values []string
+	if handlers.K8sClient != nil {
+		for _, name := range []string{runnerSecrets, integrationSecretsName} {
+			secret, err := handlers.K8sClient.CoreV1().Secrets(projectName).Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				if !errors.IsNotFound(err) {
+					log.Printf("Redaction: failed to read secret %s/%s: %v", projectName, name, err)
+				}
+				continue
+			}
+			for _, v := range secret.Data {
+				values = append(values, string(v))
+			}
+		}
+	}
+
+	r, err := redact.New(patterns, values)
+	if err != nil {
+		log.Printf("Redaction: ignoring patterns of project %s: %v", projectName, err)
+		r, _ = redact.New(nil, values)
+	}
+	sessionRedactors.Store(sessionName, r)
+	return r
+}
+
+// projectRedactionSettings reads spec.redaction.patterns and
+// spec.runnerSecretsName from the project's ProjectSettings.
+func projectRedactionSettings(ctx context.Context, projectName string) (patterns []string, runnerSecrets string) {
+	runnerSecrets = defaultRunnerSecretsName
+	if handlers.DynamicClient == nil {
+		return nil, runnerSecrets
+	}
+	obj, err := handlers.DynamicClient.Resource(handlers.GetProjectSettingsResource()).Namespace(projectName).Get(ctx, "projectsettings", metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Printf("Redaction: failed to read project settings of %s: %v", projectName, err)
+		}
+		return nil, runnerSecrets
+	}
+	patterns, _, _ = unstructured.NestedStringSlice(obj.Object, "spec", "redaction", "patterns")
+	if name, _, _ := unstructured.NestedString(obj.Object, "spec", "runnerSecretsName"); name != "" {
+		runnerSecrets = name
+	}
+	return patterns, runnerSecrets
+}
+
+// redactEvent masks credentials in event in place, adds the number of
+// matches to its "redactions" count and returns it.
+func redactEvent(r *redact.Redactor, event map[string]interface{}) int {
+	_, n := r.Value(event)
+	if n > 0 {
+		prev, _ := event["redactions"].(float64)
+		if p, ok := event["redactions"].(int); ok {
+			prev = float64(p)
+		}
+		event["redactions"] = int(prev) + n
+	}
+	return n
+}
+
+// deltaRedactor holds back the text of TEXT_MESSAGE_CONTENT and
+// TOOL_CALL_ARGS deltas after their last whitespace, and releases it with
+// the next delta of the same message or tool call, so the redactor sees
+// every word whole.  Whatever is held is released before the stream's end
+// event, before the run's final event, and by flush.  One is used per
+// proxied run stream.
+type deltaRedactor struct {
+	held  map[string]map[string]interface{} // stream → delta event carrying the held text
+	order []string
+}
+
+func newDeltaRedactor() *deltaRedactor {
+	return &deltaRedactor{held: make(map[string]map[string]interface{})}
+}
+
+// deltaStream returns the message or tool call that event streams or
+// ends, and whether it is a delta.
+func deltaStream(event map[string]interface{}) (stream string, delta bool) {
+	switch event["type"] {
+	case types.EventTypeTextMessageContent, types.EventTypeTextMessageEnd:
+		id, _ := event["messageId"].(string)
+		return "message:" + id, event["type"] == types.EventTypeTextMessageContent
+	case types.EventTypeToolCallArgs, types.EventTypeToolCallEnd:
+		id, _ := event["toolCallId"].(string)
+		return "tool:" + id, event["type"] == types.EventTypeToolCallArgs
+	}
+	return "", false
+}
+
+// next returns the events to persist, in order, for an event from the
+// runner: none while a delta is held back entirely.
+func (d *deltaRedactor) next(event map[string]interface{}) []map[string]interface{} {
+	stream, delta := deltaStream(event)
+	if delta {
+		return d.hold(stream, event)
+	}
+	var out []map[string]interface{}
+	switch {
+	case stream != "":
+		out = d.release(stream)
+	case event["type"] == types.EventTypeRunFinished, event["type"] == types.EventTypeRunError:
+		out = d.flush()
+	}
+	return append(out, event)
+}
+
+// hold appends event's delta to the stream's held text and returns a
+// delta event with the text up to its last whitespace, if there is any.
+func (d *deltaRedactor) hold(stream string, event map[string]interface{}) []map[string]interface{} {
+	text, _ := event["delta"].(string)
+	if prev, ok := d.held[stream]; ok {
+		held, _ := prev["delta"].(string)
+		text = held + text
+	} else {
+		d.order = append(d.order, stream)
+	}
+
+	cut := strings.LastIndexAny(text, " \t\r\n") + 1
+	if cut == 0 && len(text) > maxHeldDelta {
+		cut = len(text) - heldDeltaTail
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+	}
+
+	rest := make(map[string]interface{}, len(event))
+	for k, v := range event {
+		rest[k] = v
+	}
+	rest["delta"] = text[cut:]
+	if cut == len(text) {
+		delete(d.held, stream)
+	} else {
+		d.held[stream] = rest
+	}
+	if cut == 0 {
+		return nil
+	}
+	event["delta"] = text[:cut]
+	return []map[string]interface{}{event}
+}
+
+// release returns the stream's held text as a delta event, if any.
+func (d *deltaRedactor) release(stream string) []map[string]interface{} {
+	held, ok := d.held[stream]
+	if !ok {
+		return nil
+	}
+	delete(d.held, stream)
+	return []map[string]interface{}{held}
+}
+
+// flush releases the held text of every stream.
+func (d *deltaRedactor) flush() []map[string]interface{} {
+	var out []map[string]interface{}
+	for _, stream := range d.order {
+		out = append(out, d.release(stream)...)
+	}
+	d.order = nil
+	return out
+}
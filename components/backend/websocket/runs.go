@@ -0,0 +1,214 @@
+// runs.go — run history indexed from the AG-UI event log.
+//
+// A session is a sequence of runs, each delimited by RUN_STARTED and
+// RUN_FINISHED or RUN_ERROR.  The run endpoints summarize them so a failed
+// turn can be found without reading the raw log.
+package websocket
+
+import (
+	"net/http"
+	"time"
+
+	"ambient-code-backend/eventstore"
+	"ambient-code-backend/handlers"
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Run statuses
+const (
+	runStatusRunning     = "running"
+	runStatusCompleted   = "completed"
+	runStatusError       = "error"
+	runStatusInterrupted = "interrupted" // a later run started before this one ended
+)
+
+// HandleListSessionRuns lists the session's runs, oldest first.
+// GET /api/projects/:projectName/agentic-sessions/:sessionName/runs
+func HandleListSessionRuns(c *gin.Context) {
+	projectName := c.Param("projectName")
+	sessionName := c.Param("sessionName")
+	if !authorizeRunsRequest(c, projectName, sessionName) {
+		return
+	}
+
+	var params types.PaginationParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pagination parameters"})
+		return
+	}
+	types.NormalizePaginationParams(&params)
+
+	runs := indexRuns(projectName, sessionName, loadEvents(sessionName))
+	start := params.Offset
+	if start > len(runs) {
+		start = len(runs)
+	}
+	end := start + params.Limit
+	if end > len(runs) {
+		end = len(runs)
+	}
+	page := types.PaginatedResponse{
+		Items:      runs[start:end],
+		TotalCount: len(runs),
+		Limit:      params.Limit,
+		Offset:     params.Offset,
+		HasMore:    end < len(runs),
+	}
+	if page.HasMore {
+		page.NextOffset = &end
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// HandleGetSessionRun returns one run of the session.
+// GET /api/projects/:projectName/agentic-sessions/:sessionName/runs/:runId
+func HandleGetSessionRun(c *gin.Context) {
+	projectName := c.Param("projectName")
+	sessionName := c.Param("sessionName")
+	runID := c.Param("runId")
+	if !authorizeRunsRequest(c, projectName, sessionName) {
+		return
+	}
+
+	for _, run := range indexRuns(projectName, sessionName, loadEvents(sessionName)) {
+		if run.RunID == runID {
+			c.JSON(http.StatusOK, run)
+			return
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "Run not found"})
+}
+
+// authorizeRunsRequest checks read access to the session and writes the
+// error response when it is denied.
+func authorizeRunsRequest(c *gin.Context, projectName, sessionName string) bool {
+	reqK8s, _ := handlers.GetK8sClientsForRequest(c)
+	if reqK8s == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing token"})
+		c.Abort()
+		return false
+	}
+	if !checkAccess(reqK8s, projectName, sessionName, "get") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		c.Abort()
+		return false
+	}
+	if !isValidSessionName(sessionName) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session name"})
+		return false
+	}
+	return true
+}
+
+// indexRuns summarizes each run in events.  Events outside any run (such
+// as feedback) are not counted.
+func indexRuns(projectName, sessionName string, events []map[string]interface{}) []types.AGUIRunMetadata {
+	ix := newRunIndexer(projectName, sessionName)
+	for _, evt := range events {
+		ix.add(evt)
+	}
+	return ix.runs
+}
+
+// runIndexer is indexRuns for a log read one event at a time.  A run's
+// summary in runs is final once the run has ended, or the next one started.
+type runIndexer struct {
+	projectName, sessionName string
+
+	runs     []types.AGUIRunMetadata
+	current  int // index in runs of the run in progress, or -1
+	started  time.Time
+	lastSeen time.Time
+	messages map[string]bool
+	tools    map[string]bool
+}
+
+func newRunIndexer(projectName, sessionName string) *runIndexer {
+	return &runIndexer{projectName: projectName, sessionName: sessionName, runs: []types.AGUIRunMetadata{}, current: -1}
+}
+
+func (ix *runIndexer) finish(status string, ended time.Time) {
+	run := &ix.runs[ix.current]
+	run.Status = status
+	if !ended.IsZero() {
+		run.FinishedAt = ended.UTC().Format(types.AGUIMetadataTimestampFormat)
+		if !ix.started.IsZero() {
+			run.DurationMs = ended.Sub(ix.started).Milliseconds()
+		}
+	}
+	ix.current = -1
+}
+
+// add indexes the next event of the log.
+func (ix *runIndexer) add(evt map[string]interface{}) {
+	eventType, _ := evt["type"].(string)
+	ts := eventTime(evt)
+
+	if eventType == types.EventTypeRunStarted {
+		if ix.current >= 0 {
+			ix.finish(runStatusInterrupted, ix.lastSeen)
+		}
+		run := types.AGUIRunMetadata{
+			SessionName: ix.sessionName,
+			ProjectName: ix.projectName,
+			Status:      runStatusRunning,
+		}
+		run.RunID, _ = evt["runId"].(string)
+		run.ThreadID, _ = evt["threadId"].(string)
+		run.ParentRunID, _ = evt["parentRunId"].(string)
+		run.StartSeq = eventstore.Seq(evt)
+		if ix.started = ts; !ts.IsZero() {
+			run.StartedAt = ts.UTC().Format(types.AGUIMetadataTimestampFormat)
+		}
+		ix.runs = append(ix.runs, run)
+		ix.current = len(ix.runs) - 1
+		ix.lastSeen = ts
+		ix.messages, ix.tools = map[string]bool{}, map[string]bool{}
+	}
+	if ix.current < 0 {
+		return
+	}
+
+	current := &ix.runs[ix.current]
+	current.EventCount++
+	if seq := eventstore.Seq(evt); seq > 0 {
+		current.EndSeq = seq
+	}
+	if !ts.IsZero() {
+		ix.lastSeen = ts
+	}
+
+	switch eventType {
+	case types.EventTypeTextMessageStart:
+		if id, _ := evt["messageId"].(string); id != "" && !ix.messages[id] {
+			ix.messages[id] = true
+			current.MessageCount++
+		}
+	case types.EventTypeToolCallStart:
+		current.ToolCallCount++
+		if name, _ := evt["toolCallName"].(string); name != "" && !ix.tools[name] {
+			ix.tools[name] = true
+			current.ToolsUsed = append(current.ToolsUsed, name)
+		}
+	case types.EventTypeRunFinished:
+		ix.finish(runStatusCompleted, ix.lastSeen)
+	case types.EventTypeRunError:
+		// AG-UI names the field "message"; older events used "error"
+		if current.Error, _ = evt["message"].(string); current.Error == "" {
+			current.Error, _ = evt["error"].(string)
+		}
+		ix.finish(runStatusError, ix.lastSeen)
+	}
+}
+
+// eventTime returns the event's timestamp, or the zero time if it has none.
+func eventTime(evt map[string]interface{}) time.Time {
+	ts := eventTimestamp(evt)
+	if ts == "" {
+		return time.Time{}
+	}
+	t, _ := time.Parse(time.RFC3339Nano, ts)
+	return t
+}
@@ -0,0 +1,54 @@
+package websocket
+
+import (
+	"fmt"
+	"testing"
+
+	"ambient-code-backend/types"
+)
+
+func TestIndexRuns(t *testing.T) {
+	ms := func(sec int) float64 { return float64(1700000000000 + sec*1000) }
+	events := []map[string]interface{}{
+		{"type": types.EventTypeMeta, "metaType": "thumbs_up"}, // outside any run
+		{"type": types.EventTypeRunStarted, "runId": "r1", "threadId": "t", "seq": int64(1), "timestamp": ms(0)},
+		{"type": types.EventTypeTextMessageStart, "messageId": "m1", "seq": int64(2)},
+		{"type": types.EventTypeToolCallStart, "toolCallId": "c1", "toolCallName": "Bash", "seq": int64(3)},
+		{"type": types.EventTypeToolCallStart, "toolCallId": "c2", "toolCallName": "Read", "seq": int64(4)},
+		{"type": types.EventTypeToolCallStart, "toolCallId": "c3", "toolCallName": "Bash", "seq": int64(5)},
+		{"type": types.EventTypeRunFinished, "runId": "r1", "seq": int64(6), "timestamp": ms(12)},
+		{"type": types.EventTypeRunStarted, "runId": "r2", "seq": int64(7), "timestamp": ms(20)},
+		{"type": types.EventTypeTextMessageStart, "messageId": "m2", "seq": int64(8), "timestamp": ms(21)},
+		{"type": types.EventTypeRunStarted, "runId": "r3", "parentRunId": "r2", "seq": int64(9), "timestamp": ms(30)},
+		{"type": types.EventTypeRunError, "runId": "r3", "message": "runner crashed", "seq": int64(10), "timestamp": ms(35)},
+		{"type": types.EventTypeRunStarted, "runId": "r4", "seq": int64(11)},
+	}
+	runs := indexRuns("proj", "sess", events)
+	if len(runs) != 4 {
+		t.Fatalf("got %d runs, want 4", len(runs))
+	}
+
+	r1 := runs[0]
+	if r1.Status != runStatusCompleted || r1.StartedAt != "2023-11-14T22:13:20Z" || r1.FinishedAt != "2023-11-14T22:13:32Z" || r1.DurationMs != 12000 {
+		t.Errorf("r1 timing = %+v", r1)
+	}
+	if r1.EventCount != 6 || r1.StartSeq != 1 || r1.EndSeq != 6 || r1.MessageCount != 1 || r1.ToolCallCount != 3 {
+		t.Errorf("r1 counts = %+v", r1)
+	}
+	if fmt.Sprint(r1.ToolsUsed) != "[Bash Read]" {
+		t.Errorf("r1 tools = %v", r1.ToolsUsed)
+	}
+	if r1.ProjectName != "proj" || r1.SessionName != "sess" || r1.ThreadID != "t" {
+		t.Errorf("r1 identity = %+v", r1)
+	}
+
+	if r2 := runs[1]; r2.Status != runStatusInterrupted || r2.DurationMs != 1000 || r2.EndSeq != 8 {
+		t.Errorf("r2 = %+v", r2)
+	}
+	if r3 := runs[2]; r3.Status != runStatusError || r3.Error != "runner crashed" || r3.ParentRunID != "r2" || r3.DurationMs != 5000 {
+		t.Errorf("r3 = %+v", r3)
+	}
+	if r4 := runs[3]; r4.Status != runStatusRunning || r4.StartedAt != "" || r4.FinishedAt != "" {
+		t.Errorf("r4 = %+v", r4)
+	}
+}
@@ -0,0 +1,482 @@
+// search.go — full-text search over the transcripts of a project's
+// sessions.
+//
+// Each project has a search.Index of the text of its sessions' messages
+// and the names and arguments of their tool calls, held in this process.
+// A session's log is indexed the first time the project is searched, and
+// each search first reads the events stored after the last one indexed,
+// so events written by other replicas are found too; in between,
+// persistEvent indexes this replica's.  Messages and tool calls are
+// indexed when they end, and MESSAGES_SNAPSHOT events replace the text of
+// the messages they contain.
+//
+// The indexes together hold at most searchIndexMaxBytes of text: after a
+// search, the indexes of the projects searched least recently are dropped
+// until they fit, and rebuilt from the store when next searched.
+package websocket
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"ambient-code-backend/eventstore"
+	"ambient-code-backend/handlers"
+	"ambient-code-backend/search"
+	"ambient-code-backend/types"
+
+	"github.com/gin-gonic/gin"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// searchIndexMaxBytes bounds the text held by all projects' indexes
+var searchIndexMaxBytes = 256 << 20
+
+// projectSearch is the index of one project and the indexing state of its
+// sessions
+type projectSearch struct {
+	index    *search.Index
+	mu       sync.Mutex
+	sessions map[string]*sessionIndexer
+	lastUsed atomic.Int64 // searchClock when last searched
+}
+
+// searchIndexes maps projectName → *projectSearch
+var searchIndexes sync.Map
+
+// searchClock orders searches, for evictSearchIndexes
+var searchClock atomic.Int64
+
+func projectSearchIndex(projectName string) *projectSearch {
+	ps, _ := searchIndexes.LoadOrStore(projectName, &projectSearch{
+		index:    search.New(),
+		sessions: make(map[string]*sessionIndexer),
+	})
+	return ps.(*projectSearch)
+}
+
+// indexer returns the session's indexer, adding it if create is set
+func (ps *projectSearch) indexer(sessionName string, create bool) *sessionIndexer {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	si := ps.sessions[sessionName]
+	if si == nil && create {
+		si = &sessionIndexer{
+			index:   ps.index,
+			session: sessionName,
+			pending: make(map[string]*search.Document),
+			hidden:  make(map[string]bool),
+		}
+		ps.sessions[sessionName] = si
+	}
+	return si
+}
+
+// evictSearchIndexes drops the indexes of the projects searched least
+// recently, other than keep, until the rest hold at most
+// searchIndexMaxBytes of text.
+func evictSearchIndexes(keep string) {
+	type entry struct {
+		name     string
+		size     int
+		lastUsed int64
+	}
+	var entries []entry
+	total := 0
+	searchIndexes.Range(func(key, value interface{}) bool {
+		ps := value.(*projectSearch)
+		e := entry{name: key.(string), size: ps.index.Size(), lastUsed: ps.lastUsed.Load()}
+		total += e.size
+		if e.name != keep {
+			entries = append(entries, e)
+		}
+		return true
+	})
+	sort.Slice(entries, func(i, j int) bool { return entries[i].lastUsed < entries[j].lastUsed })
+	for _, e := range entries {
+		if total <= searchIndexMaxBytes {
+			return
+		}
+		searchIndexes.Delete(e.name)
+		total -= e.size
+		log.Printf("Search: dropped the index of project %s (%d bytes) to stay within %d bytes", e.name, e.size, searchIndexMaxBytes)
+	}
+}
+
+// prune forgets the sessions not in names (deleted sessions)
+func (ps *projectSearch) prune(names map[string]bool) {
+	ps.mu.Lock()
+	var gone []string
+	for name := range ps.sessions {
+		if !names[name] {
+			gone = append(gone, name)
+			delete(ps.sessions, name)
+		}
+	}
+	ps.mu.Unlock()
+	for _, name := range gone {
+		ps.index.DeleteSession(name)
+	}
+}
+
+// forgetSearchSession drops what every project's index holds of a session
+// whose log was deleted, so a new log by that name is indexed afresh.
+func forgetSearchSession(sessionName string) {
+	searchIndexes.Range(func(_, value interface{}) bool {
+		ps := value.(*projectSearch)
+		ps.mu.Lock()
+		_, ok := ps.sessions[sessionName]
+		delete(ps.sessions, sessionName)
+		ps.mu.Unlock()
+		if ok {
+			ps.index.DeleteSession(sessionName)
+		}
+		return true
+	})
+}
+
+// sessionIndexer turns one session's events into documents
+type sessionIndexer struct {
+	index   *search.Index
+	session string
+
+	mu      sync.Mutex
+	loaded  bool  // the log has been read; persistEvent adds this replica's events
+	lastSeq int64 // last event indexed
+	runID   string
+	pending map[string]*search.Document // messages and tool calls not ended yet, by ID
+	hidden  map[string]bool             // message IDs hidden by message_metadata
+
+	// Where load stopped reading, for stores that are OffsetLoaders, and
+	// the seq of the last event before it
+	offset, offsetSeq int64
+}
+
+// load indexes the events of the session's log after the last one
+// indexed, which may have been written by another replica.
+func (si *sessionIndexer) load(ctx context.Context) error {
+	si.mu.Lock()
+	defer si.mu.Unlock()
+	err := si.readNew(ctx)
+	if errors.Is(err, eventstore.ErrStaleOffset) {
+		// The log was replaced; index it again
+		si.reset()
+		err = si.readNew(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	// A run in progress: index what it has streamed so far, and the rest
+	// when it ends
+	for _, doc := range si.pending {
+		if !si.hidden[doc.ID] && strings.TrimSpace(doc.Text+doc.ToolName) != "" {
+			si.index.Put(*doc)
+		}
+	}
+	si.loaded = true
+	return nil
+}
+
+// readNew applies the events stored after those already read; si.mu is
+// held.  Stores that are OffsetLoaders are read from where the last read
+// stopped.
+func (si *sessionIndexer) readNew(ctx context.Context) error {
+	if loader, ok := Events.(eventstore.OffsetLoader); ok {
+		events, offset, err := loader.LoadFromOffset(ctx, si.session, si.offset, si.offsetSeq)
+		if err != nil {
+			return err
+		}
+		for _, evt := range events {
+			si.apply(evt)
+			si.offsetSeq = eventstore.Seq(evt)
+		}
+		si.offset = offset
+		return nil
+	}
+	return eventstore.Scan(ctx, Events, si.session, si.lastSeq, func(evt map[string]interface{}) error {
+		si.apply(evt)
+		return nil
+	})
+}
+
+// reset forgets what was indexed of the session; si.mu is held
+func (si *sessionIndexer) reset() {
+	si.index.DeleteSession(si.session)
+	si.loaded = false
+	si.lastSeq, si.offset, si.offsetSeq = 0, 0, 0
+	si.runID = ""
+	si.pending = make(map[string]*search.Document)
+	si.hidden = make(map[string]bool)
+}
+
+// add indexes an event persistEvent has just written.  Events of a log
+// that has not been loaded are left to load, and so are those that follow
+// events another replica wrote that load has not read yet.
+func (si *sessionIndexer) add(evt map[string]interface{}) {
+	si.mu.Lock()
+	defer si.mu.Unlock()
+	if seq := eventstore.Seq(evt); si.loaded && (seq == 0 || seq == si.lastSeq+1) {
+		si.apply(evt)
+	}
+}
+
+// apply indexes evt; si.mu is held
+func (si *sessionIndexer) apply(evt map[string]interface{}) {
+	seq := eventstore.Seq(evt)
+	if seq > 0 {
+		if seq <= si.lastSeq {
+			return
+		}
+		si.lastSeq = seq
+	}
+	ts := eventTimestamp(evt)
+
+	switch evt["type"] {
+	case types.EventTypeRunStarted:
+		si.flushPending()
+		si.runID, _ = evt["runId"].(string)
+	case types.EventTypeRunFinished, types.EventTypeRunError:
+		si.flushPending()
+	case types.EventTypeTextMessageStart:
+		if id, _ := evt["messageId"].(string); id != "" {
+			role, _ := evt["role"].(string)
+			if role == "" {
+				role = types.RoleAssistant
+			}
+			si.pending[id] = si.newDoc(id, search.KindMessage, seq, ts)
+			si.pending[id].Role = role
+		}
+	case types.EventTypeTextMessageContent:
+		if id, _ := evt["messageId"].(string); id != "" {
+			delta, _ := evt["delta"].(string)
+			si.pendingDoc(id, search.KindMessage, seq, ts).Text += delta
+		}
+	case types.EventTypeTextMessageEnd:
+		if id, _ := evt["messageId"].(string); id != "" {
+			si.flush(id)
+		}
+	case types.EventTypeToolCallStart:
+		if id, _ := evt["toolCallId"].(string); id != "" {
+			doc := si.newDoc(id, search.KindToolCall, seq, ts)
+			doc.ToolName, _ = evt["toolCallName"].(string)
+			si.pending[id] = doc
+		}
+	case types.EventTypeToolCallArgs:
+		if id, _ := evt["toolCallId"].(string); id != "" {
+			delta, _ := evt["delta"].(string)
+			si.pendingDoc(id, search.KindToolCall, seq, ts).Text += delta
+		}
+	case types.EventTypeToolCallEnd:
+		if id, _ := evt["toolCallId"].(string); id != "" {
+			si.flush(id)
+		}
+	case types.EventTypeMessagesSnapshot:
+		raw, _ := evt["messages"].([]interface{})
+		for _, item := range raw {
+			if m, ok := item.(map[string]interface{}); ok {
+				si.applySnapshotMessage(snapshotMessage(m), seq, ts)
+			}
+		}
+	case types.EventTypeRaw:
+		if inner, _ := evt["event"].(map[string]interface{}); inner != nil && inner["type"] == "message_metadata" {
+			id, _ := inner["messageId"].(string)
+			if hidden, _ := inner["hidden"].(bool); hidden && id != "" {
+				si.hidden[id] = true
+				delete(si.pending, id)
+				si.index.Delete(si.session, id)
+			}
+		}
+	}
+}
+
+// applySnapshotMessage replaces the text of a message, and of its tool
+// calls, with the snapshot's.  Where they were first seen is kept.
+func (si *sessionIndexer) applySnapshotMessage(msg types.Message, seq int64, ts string) {
+	if msg.ID == "" || msg.Role == types.RoleTool || si.hidden[msg.ID] || isMessageHidden(msg.Metadata) {
+		return
+	}
+	if msg.Content != "" {
+		doc := si.existingDoc(msg.ID, search.KindMessage, seq, ts)
+		doc.Role, doc.Text = msg.Role, msg.Content
+		if msg.Timestamp != "" && doc.Timestamp == "" {
+			doc.Timestamp = msg.Timestamp
+		}
+		delete(si.pending, msg.ID)
+		si.index.Put(*doc)
+	}
+	for _, tc := range msg.ToolCalls {
+		if tc.ID == "" {
+			continue
+		}
+		doc := si.existingDoc(tc.ID, search.KindToolCall, seq, ts)
+		doc.ToolName, doc.Text = tc.Name, tc.Args
+		delete(si.pending, tc.ID)
+		si.index.Put(*doc)
+	}
+}
+
+func (si *sessionIndexer) newDoc(id, kind string, seq int64, ts string) *search.Document {
+	return &search.Document{Session: si.session, ID: id, Kind: kind, RunID: si.runID, Seq: seq, Timestamp: ts}
+}
+
+// pendingDoc returns the pending document with id, starting one if its
+// start event is missing
+func (si *sessionIndexer) pendingDoc(id, kind string, seq int64, ts string) *search.Document {
+	doc := si.pending[id]
+	if doc == nil {
+		doc = si.newDoc(id, kind, seq, ts)
+		if kind == search.KindMessage {
+			doc.Role = types.RoleAssistant
+		}
+		si.pending[id] = doc
+	}
+	return doc
+}
+
+// existingDoc returns the pending or indexed document with id, or a new one
+func (si *sessionIndexer) existingDoc(id, kind string, seq int64, ts string) *search.Document {
+	if doc := si.pending[id]; doc != nil {
+		return doc
+	}
+	if doc, ok := si.index.Get(si.session, id); ok {
+		return &doc
+	}
+	return si.newDoc(id, kind, seq, ts)
+}
+
+// flush indexes the pending document with id
+func (si *sessionIndexer) flush(id string) {
+	doc := si.pending[id]
+	delete(si.pending, id)
+	if doc == nil || si.hidden[id] || strings.TrimSpace(doc.Text+doc.ToolName) == "" {
+		return
+	}
+	si.index.Put(*doc)
+}
+
+// flushPending indexes what an ended (or cut short) run left pending
+func (si *sessionIndexer) flushPending() {
+	for id := range si.pending {
+		si.flush(id)
+	}
+}
+
+// indexPersistedEvent is called by persistEvent for each event written.
+// Sessions whose project is not known here are indexed from their log when
+// their project is searched.
+func indexPersistedEvent(sessionName string, event map[string]interface{}) {
+	projectName, ok := sessionProjectMap.Load(sessionName)
+	if !ok {
+		return
+	}
+	if si := projectSearchIndex(projectName.(string)).indexer(sessionName, false); si != nil {
+		si.add(event)
+	}
+}
+
+// HandleSearchProject searches the transcripts of the project's sessions.
+// GET /api/projects/:projectName/search?q=
+//
+// Every word of q must appear in a message or tool call for it to match.
+// Only sessions the caller can list are searched, each after indexing the
+// events stored since it was last indexed.  Results are paginated with
+// limit and offset.
+func HandleSearchProject(c *gin.Context) {
+	projectName := c.Param("projectName")
+	query := strings.TrimSpace(c.Query("q"))
+	if len(search.Words(query)) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q must contain a word of at least two characters"})
+		return
+	}
+
+	_, reqDyn := handlers.GetK8sClientsForRequest(c)
+	if reqDyn == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing token"})
+		c.Abort()
+		return
+	}
+
+	var params types.PaginationParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pagination parameters"})
+		return
+	}
+	types.NormalizePaginationParams(&params)
+
+	// The caller's own list of sessions is what they may search
+	ctx := c.Request.Context()
+	list, err := reqDyn.Resource(handlers.GetAgenticSessionV1Alpha1Resource()).Namespace(projectName).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		if k8serrors.IsForbidden(err) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+			return
+		}
+		log.Printf("Search: failed to list sessions in %s: %v", projectName, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list sessions"})
+		return
+	}
+
+	ps := projectSearchIndex(projectName)
+	ps.lastUsed.Store(searchClock.Add(1))
+	allowed := make(map[string]bool, len(list.Items))
+	for _, item := range list.Items {
+		name := item.GetName()
+		allowed[name] = true
+		if err := ps.indexer(name, true).load(ctx); err != nil {
+			log.Printf("Search: failed to index %s/%s: %v", projectName, name, err)
+		}
+	}
+	ps.prune(allowed)
+
+	hits := ps.index.Search(query, func(session string) bool { return allowed[session] })
+	evictSearchIndexes(projectName)
+	start := params.Offset
+	if start > len(hits) {
+		start = len(hits)
+	}
+	end := start + params.Limit
+	if end > len(hits) {
+		end = len(hits)
+	}
+	items := make([]types.SearchHit, 0, end-start)
+	for _, hit := range hits[start:end] {
+		items = append(items, searchHit(hit))
+	}
+	page := types.PaginatedResponse{
+		Items:      items,
+		TotalCount: len(hits),
+		Limit:      params.Limit,
+		Offset:     params.Offset,
+		HasMore:    end < len(hits),
+	}
+	if page.HasMore {
+		page.NextOffset = &end
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+func searchHit(hit search.Hit) types.SearchHit {
+	out := types.SearchHit{
+		SessionName: hit.Session,
+		RunID:       hit.RunID,
+		Kind:        hit.Kind,
+		Role:        hit.Role,
+		ToolName:    hit.ToolName,
+		Seq:         hit.Seq,
+		Timestamp:   hit.Timestamp,
+		Snippet:     hit.Snippet,
+		Score:       hit.Score,
+	}
+	if hit.Kind == search.KindToolCall {
+		out.ToolCallID = hit.ID
+	} else {
+		out.MessageID = hit.ID
+	}
+	return out
+}
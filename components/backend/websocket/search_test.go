@@ -0,0 +1,146 @@
+package websocket
+
+import (
+	"context"
+	"testing"
+
+	"ambient-code-backend/search"
+)
+
+func TestSearchIndexLoadsLogThenFollowsPersistEvent(t *testing.T) {
+	useTempStateDir(t)
+	searchIndexes.Delete("search-proj")
+	sessionProjectMap.Store("searched", "search-proj")
+	t.Cleanup(func() {
+		sessionProjectMap.Delete("searched")
+		searchIndexes.Delete("search-proj")
+	})
+
+	for _, evt := range []map[string]interface{}{
+		{"type": "RUN_STARTED", "runId": "r1"},
+		{"type": "TEXT_MESSAGE_START", "messageId": "u1", "role": "user"},
+		{"type": "TEXT_MESSAGE_CONTENT", "messageId": "u1", "delta": "Why is the Kafka "},
+		{"type": "TEXT_MESSAGE_CONTENT", "messageId": "u1", "delta": "consumer lagging?"},
+		{"type": "TEXT_MESSAGE_END", "messageId": "u1"},
+		{"type": "TOOL_CALL_START", "toolCallId": "t1", "toolCallName": "Grep"},
+		{"type": "TOOL_CALL_ARGS", "toolCallId": "t1", "delta": `{"pattern":"rebalance"}`},
+		{"type": "TOOL_CALL_END", "toolCallId": "t1"},
+		{"type": "RUN_FINISHED", "runId": "r1"},
+	} {
+		persistEvent("searched", evt)
+	}
+
+	ps := projectSearchIndex("search-proj")
+	if hits := ps.index.Search("kafka", nil); len(hits) != 0 {
+		t.Fatalf("events indexed before the log was loaded: %v", hits)
+	}
+	if err := ps.indexer("searched", true).load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	hits := ps.index.Search("kafka consumer", nil)
+	if len(hits) != 1 || hits[0].ID != "u1" || hits[0].RunID != "r1" || hits[0].Role != "user" {
+		t.Fatalf("Search(kafka consumer) = %+v", hits)
+	}
+	if hits := ps.index.Search("rebalance", nil); len(hits) != 1 || hits[0].ToolName != "Grep" {
+		t.Errorf("Search(rebalance) = %+v", hits)
+	}
+
+	// From now on persistEvent indexes, and snapshots replace text
+	for _, evt := range []map[string]interface{}{
+		{"type": "RUN_STARTED", "runId": "r2"},
+		{"type": "TEXT_MESSAGE_START", "messageId": "a2", "role": "assistant"},
+		{"type": "TEXT_MESSAGE_CONTENT", "messageId": "a2", "delta": "Raise max.poll.interval"},
+		{"type": "TEXT_MESSAGE_END", "messageId": "a2"},
+		{"type": "MESSAGES_SNAPSHOT", "messages": []interface{}{
+			map[string]interface{}{"id": "u1", "role": "user", "content": "Why is the consumer slow?"},
+		}},
+		{"type": "RAW", "event": map[string]interface{}{"type": "message_metadata", "messageId": "a2", "hidden": true}},
+		{"type": "RUN_FINISHED", "runId": "r2"},
+	} {
+		persistEvent("searched", evt)
+	}
+	if hits := ps.index.Search("kafka", nil); len(hits) != 0 {
+		t.Errorf("snapshot did not replace the message text: %+v", hits)
+	}
+	if hits := ps.index.Search("slow", nil); len(hits) != 1 || hits[0].RunID != "r1" {
+		t.Errorf("Search(slow) = %+v", hits)
+	}
+	if hits := ps.index.Search("poll", nil); len(hits) != 0 {
+		t.Errorf("hidden message is searchable: %+v", hits)
+	}
+
+	ps.prune(map[string]bool{})
+	if hits := ps.index.Search("rebalance", nil); len(hits) != 0 {
+		t.Errorf("pruned session is searchable: %+v", hits)
+	}
+}
+
+func TestSearchIndexReadsEventsOfOtherReplicas(t *testing.T) {
+	useTempStateDir(t)
+	searchIndexes.Delete("replicas-proj")
+	sessionProjectMap.Store("replicated", "replicas-proj")
+	t.Cleanup(func() {
+		sessionProjectMap.Delete("replicated")
+		searchIndexes.Delete("replicas-proj")
+	})
+	ctx := context.Background()
+	message := func(id, text string) []map[string]interface{} {
+		return []map[string]interface{}{
+			{"type": "TEXT_MESSAGE_START", "messageId": id, "role": "user"},
+			{"type": "TEXT_MESSAGE_CONTENT", "messageId": id, "delta": text},
+			{"type": "TEXT_MESSAGE_END", "messageId": id},
+		}
+	}
+
+	ps := projectSearchIndex("replicas-proj")
+	for _, evt := range message("m1", "first replica") {
+		persistEvent("replicated", evt)
+	}
+	if err := ps.indexer("replicated", true).load(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Written by another replica: this one's persistEvent never sees them,
+	// and its own next event must wait for them
+	for _, evt := range message("m2", "second replica") {
+		if _, err := Events.Append(ctx, "replicated", evt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, evt := range message("m3", "third message") {
+		persistEvent("replicated", evt)
+	}
+	if hits := ps.index.Search("third", nil); len(hits) != 0 {
+		t.Errorf("indexed an event past the replica's: %+v", hits)
+	}
+
+	if err := ps.indexer("replicated", false).load(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if hits := ps.index.Search("replica", nil); len(hits) != 2 {
+		t.Errorf("Search(replica) = %+v, want both replicas' messages", hits)
+	}
+	if hits := ps.index.Search("third", nil); len(hits) != 1 {
+		t.Errorf("Search(third) = %+v", hits)
+	}
+}
+
+func TestEvictSearchIndexes(t *testing.T) {
+	defer func(max int) { searchIndexMaxBytes = max }(searchIndexMaxBytes)
+	searchIndexMaxBytes = 45
+	for i, name := range []string{"evict-old", "evict-new", "evict-searched"} {
+		ps := projectSearchIndex(name)
+		ps.index.Put(search.Document{Session: "s", ID: "m", Text: "twenty bytes of text"})
+		ps.lastUsed.Store(int64(i + 1))
+		t.Cleanup(func() { searchIndexes.Delete(name) })
+	}
+
+	// The project just searched stays even if it were the oldest
+	projectSearchIndex("evict-searched").lastUsed.Store(0)
+	evictSearchIndexes("evict-searched")
+	for name, want := range map[string]bool{"evict-old": false, "evict-new": true, "evict-searched": true} {
+		if _, ok := searchIndexes.Load(name); ok != want {
+			t.Errorf("index of %s kept = %v, want %v", name, ok, want)
+		}
+	}
+}
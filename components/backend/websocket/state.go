@@ -0,0 +1,228 @@
+// state.go — point-in-time agent state rebuilt from the AG-UI event log.
+//
+// The runner publishes its shared state as STATE_SNAPSHOT events (the
+// document in "snapshot") followed by STATE_DELTA events carrying RFC 6902
+// JSON Patch operations in "delta", and its activity list as the platform's
+// array-based ACTIVITY_SNAPSHOT ("activities") and ACTIVITY_DELTA ("delta"
+// of add/update/remove patches) events, as the frontend reads them.  Replaying
+// them up to an event answers "what was the state after run 3?".
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"ambient-code-backend/eventstore"
+	"ambient-code-backend/handlers"
+	"ambient-code-backend/types"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/gin-gonic/gin"
+)
+
+// HandleGetSessionState returns the session's state and activities.
+// GET /api/projects/:projectName/agentic-sessions/:sessionName/state
+//
+// With ?seq=N the events up to and including N are replayed; with
+// ?runId=X, the events up to the end of that run.  Without either, the
+// whole log is.
+func HandleGetSessionState(c *gin.Context) {
+	projectName := c.Param("projectName")
+	sessionName := c.Param("sessionName")
+
+	reqK8s, _ := handlers.GetK8sClientsForRequest(c)
+	if reqK8s == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing token"})
+		c.Abort()
+		return
+	}
+	if !checkAccess(reqK8s, projectName, sessionName, "get") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		c.Abort()
+		return
+	}
+	if !isValidSessionName(sessionName) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session name"})
+		return
+	}
+
+	seqParam, runID := c.Query("seq"), c.Query("runId")
+	if seqParam != "" && runID != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "seq and runId are mutually exclusive"})
+		return
+	}
+
+	events := loadEvents(sessionName)
+	switch {
+	case seqParam != "":
+		seq, err := strconv.ParseInt(seqParam, 10, 64)
+		if err != nil || seq < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid seq: expected a non-negative integer"})
+			return
+		}
+		events = eventsThroughSeq(events, seq)
+	case runID != "":
+		var ok bool
+		if events, ok = eventsThroughRun(events, runID); !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("run %q not found", runID)})
+			return
+		}
+	}
+
+	state := replayState(sessionName, events)
+	state.RunID = runID
+	c.JSON(http.StatusOK, state)
+}
+
+// eventsThroughSeq returns the prefix of events up to and including seq.
+func eventsThroughSeq(events []map[string]interface{}, seq int64) []map[string]interface{} {
+	for i, evt := range events {
+		if eventstore.Seq(evt) > seq {
+			return events[:i]
+		}
+	}
+	return events
+}
+
+// eventsThroughRun returns the prefix of events ending with runID's
+// RUN_FINISHED or RUN_ERROR.  A run that never ended extends to the next
+// run's start.  ok is false if the log has no such run.
+func eventsThroughRun(events []map[string]interface{}, runID string) ([]map[string]interface{}, bool) {
+	started := -1
+	for i, evt := range events {
+		eventType, _ := evt["type"].(string)
+		if started < 0 {
+			if eventType == types.EventTypeRunStarted && evt["runId"] == runID {
+				started = i
+			}
+			continue
+		}
+		switch eventType {
+		case types.EventTypeRunFinished, types.EventTypeRunError:
+			if evt["runId"] == runID {
+				return events[:i+1], true
+			}
+		case types.EventTypeRunStarted:
+			return events[:i], true
+		}
+	}
+	return events, started >= 0
+}
+
+// replayState applies the state and activity events in events, in order.
+// A delta that does not apply to the state it follows is skipped.
+func replayState(sessionName string, events []map[string]interface{}) types.SessionState {
+	result := types.SessionState{
+		State:      map[string]interface{}{},
+		Activities: []types.Activity{},
+	}
+	for _, evt := range events {
+		if seq := eventstore.Seq(evt); seq > 0 {
+			result.Seq = seq
+		}
+		switch evt["type"] {
+		case types.EventTypeStateSnapshot:
+			// The runner sends "snapshot"; "state" is accepted from older writers
+			snapshot, ok := evt["snapshot"]
+			if !ok {
+				snapshot = evt["state"]
+			}
+			state := map[string]interface{}{}
+			if err := remarshal(snapshot, &state); err != nil || state == nil {
+				log.Printf("State: skipping unreadable STATE_SNAPSHOT %d of %s: %v", result.Seq, sessionName, err)
+				continue
+			}
+			result.State = state
+		case types.EventTypeStateDelta:
+			state, err := applyStateDelta(result.State, evt["delta"])
+			if err != nil {
+				log.Printf("State: skipping STATE_DELTA %d of %s: %v", result.Seq, sessionName, err)
+				continue
+			}
+			result.State = state
+		case types.EventTypeActivitySnapshot:
+			activities := []types.Activity{}
+			if err := remarshal(evt["activities"], &activities); err != nil {
+				log.Printf("State: skipping unreadable ACTIVITY_SNAPSHOT %d of %s: %v", result.Seq, sessionName, err)
+				continue
+			}
+			if activities == nil {
+				activities = []types.Activity{}
+			}
+			result.Activities = activities
+		case types.EventTypeActivityDelta:
+			var delta []types.ActivityPatch
+			if err := remarshal(evt["delta"], &delta); err != nil {
+				log.Printf("State: skipping unreadable ACTIVITY_DELTA %d of %s: %v", result.Seq, sessionName, err)
+				continue
+			}
+			result.Activities = applyActivityDelta(result.Activities, delta)
+		}
+	}
+	return result
+}
+
+// applyStateDelta applies a JSON Patch to state.  The patch is applied as
+// a whole: if any operation fails, state is returned unchanged.
+func applyStateDelta(state map[string]interface{}, delta interface{}) (map[string]interface{}, error) {
+	ops, err := json.Marshal(delta)
+	if err != nil {
+		return state, err
+	}
+	patch, err := jsonpatch.DecodePatch(ops)
+	if err != nil {
+		return state, err
+	}
+	doc, err := json.Marshal(state)
+	if err != nil {
+		return state, err
+	}
+	patched, err := patch.Apply(doc)
+	if err != nil {
+		return state, err
+	}
+	next := map[string]interface{}{}
+	if err := json.Unmarshal(patched, &next); err != nil {
+		return state, fmt.Errorf("patched state is not an object: %w", err)
+	}
+	return next, nil
+}
+
+// applyActivityDelta applies add/update/remove operations keyed by
+// activity ID, as the frontend does.
+func applyActivityDelta(activities []types.Activity, delta []types.ActivityPatch) []types.Activity {
+	for _, patch := range delta {
+		idx := -1
+		for i := range activities {
+			if activities[i].ID == patch.Activity.ID {
+				idx = i
+				break
+			}
+		}
+		switch patch.Op {
+		case "add":
+			activities = append(activities, patch.Activity)
+		case "update":
+			if idx >= 0 {
+				activities[idx] = patch.Activity
+			}
+		case "remove":
+			if idx >= 0 {
+				activities = append(activities[:idx], activities[idx+1:]...)
+			}
+		}
+	}
+	return activities
+}
+
+// remarshal converts a decoded JSON value into dst.
+func remarshal(src, dst interface{}) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
@@ -0,0 +1,89 @@
+package websocket
+
+import (
+	"testing"
+
+	"ambient-code-backend/types"
+)
+
+func stateTestLog() []map[string]interface{} {
+	return []map[string]interface{}{
+		{"type": types.EventTypeRunStarted, "runId": "r1", "seq": int64(1)},
+		{"type": types.EventTypeStateSnapshot, "seq": int64(2), "snapshot": map[string]interface{}{"phase": "plan", "files": []interface{}{"a.go"}}},
+		{"type": types.EventTypeActivitySnapshot, "seq": int64(3), "activities": []interface{}{
+			map[string]interface{}{"id": "act1", "type": "task", "status": "running"},
+		}},
+		{"type": types.EventTypeRunFinished, "runId": "r1", "seq": int64(4)},
+		{"type": types.EventTypeRunStarted, "runId": "r2", "seq": int64(5)},
+		{"type": types.EventTypeStateDelta, "seq": int64(6), "delta": []interface{}{
+			map[string]interface{}{"op": "replace", "path": "/phase", "value": "build"},
+			map[string]interface{}{"op": "add", "path": "/files/-", "value": "b.go"},
+		}},
+		{"type": types.EventTypeActivityDelta, "seq": int64(7), "delta": []interface{}{
+			map[string]interface{}{"op": "update", "activity": map[string]interface{}{"id": "act1", "type": "task", "status": "completed"}},
+			map[string]interface{}{"op": "add", "activity": map[string]interface{}{"id": "act2", "type": "task"}},
+		}},
+		// Fails as a whole: the second operation has no target
+		{"type": types.EventTypeStateDelta, "seq": int64(8), "delta": []interface{}{
+			map[string]interface{}{"op": "replace", "path": "/phase", "value": "broken"},
+			map[string]interface{}{"op": "remove", "path": "/missing"},
+		}},
+		{"type": types.EventTypeRunFinished, "runId": "r2", "seq": int64(9)},
+		{"type": types.EventTypeRunStarted, "runId": "r3", "seq": int64(10)},
+		{"type": types.EventTypeStateDelta, "seq": int64(11), "delta": []interface{}{
+			map[string]interface{}{"op": "remove", "path": "/files"},
+		}},
+	}
+}
+
+func TestReplayStateThroughRun(t *testing.T) {
+	events, ok := eventsThroughRun(stateTestLog(), "r1")
+	if !ok {
+		t.Fatal("run r1 not found")
+	}
+	got := replayState("s", events)
+	if got.Seq != 4 || got.State["phase"] != "plan" || len(got.Activities) != 1 || got.Activities[0].Status != "running" {
+		t.Errorf("after r1 = %+v", got)
+	}
+
+	events, _ = eventsThroughRun(stateTestLog(), "r2")
+	got = replayState("s", events)
+	if got.Seq != 9 || got.State["phase"] != "build" {
+		t.Errorf("after r2 state = %+v (seq %d)", got.State, got.Seq)
+	}
+	if files, _ := got.State["files"].([]interface{}); len(files) != 2 || files[1] != "b.go" {
+		t.Errorf("after r2 files = %v", got.State["files"])
+	}
+	if len(got.Activities) != 2 || got.Activities[0].Status != "completed" || got.Activities[1].ID != "act2" {
+		t.Errorf("after r2 activities = %+v", got.Activities)
+	}
+
+	// r3 never finished: it runs to the end of the log
+	events, _ = eventsThroughRun(stateTestLog(), "r3")
+	if got = replayState("s", events); got.Seq != 11 || got.State["files"] != nil {
+		t.Errorf("after r3 = %+v", got)
+	}
+
+	if _, ok := eventsThroughRun(stateTestLog(), "nope"); ok {
+		t.Error("unknown run: want not found")
+	}
+}
+
+func TestReplayStateReadsLegacySnapshotField(t *testing.T) {
+	got := replayState("s", []map[string]interface{}{
+		{"type": types.EventTypeStateSnapshot, "seq": int64(1), "state": map[string]interface{}{"phase": "plan"}},
+	})
+	if got.State["phase"] != "plan" {
+		t.Errorf("state = %v", got.State)
+	}
+}
+
+func TestReplayStateThroughSeq(t *testing.T) {
+	got := replayState("s", eventsThroughSeq(stateTestLog(), 6))
+	if got.Seq != 6 || got.State["phase"] != "build" || len(got.Activities) != 1 {
+		t.Errorf("through 6 = %+v", got)
+	}
+	if got = replayState("s", eventsThroughSeq(stateTestLog(), 0)); got.Seq != 0 || len(got.State) != 0 || got.Activities == nil {
+		t.Errorf("through 0 = %+v, want empty state and activities", got)
+	}
+}
@@ -0,0 +1,518 @@
+// transcript.go — readable renderings of a session's event log for export.
+//
+// ?format=markdown|html|jsonl on the export endpoint renders the log as a
+// transcript instead of raw events: the conversation of each run
+// under a run header, tool calls (collapsible in Markdown and HTML) with
+// their arguments and results, and feedback next to the message it rates.
+// Output is written and flushed run by run.
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"strings"
+
+	"ambient-code-backend/eventstore"
+	"ambient-code-backend/redact"
+	"ambient-code-backend/types"
+)
+
+// Transcript formats accepted by ?format= on the export endpoint
+const (
+	transcriptFormatMarkdown = "markdown"
+	transcriptFormatHTML     = "html"
+	transcriptFormatJSONL    = "jsonl"
+)
+
+// transcriptFeedback is a META feedback event on the conversation
+type transcriptFeedback struct {
+	MessageID string `json:"messageId,omitempty"`
+	Type      string `json:"type"` // thumbs_up or thumbs_down
+	UserID    string `json:"userId,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	Comment   string `json:"comment,omitempty"`
+	Timestamp string `json:"timestamp,omitempty"`
+}
+
+// transcriptRun is one run of a transcript, and one line of the JSONL format
+type transcriptRun struct {
+	types.AGUIRunMetadata
+	Messages []types.Message      `json:"messages"`
+	Feedback []transcriptFeedback `json:"feedback,omitempty"`
+}
+
+// transcriptRenderer writes one transcript format.  Feedback not tied to a
+// message shown in a run is passed to end.
+type transcriptRenderer interface {
+	begin(w io.Writer, projectName, sessionName, exportDate string) error
+	run(w io.Writer, run *transcriptRun) error
+	end(w io.Writer, feedback []transcriptFeedback) error
+}
+
+// newTranscriptRenderer returns the renderer for format, its content type
+// and file extension, or ok=false for an unknown format.
+func newTranscriptRenderer(format string) (r transcriptRenderer, contentType, ext string, ok bool) {
+	switch format {
+	case transcriptFormatMarkdown, "md":
+		return markdownTranscript{}, "text/markdown; charset=utf-8", "md", true
+	case transcriptFormatHTML:
+		return htmlTranscript{}, "text/html; charset=utf-8", "html", true
+	case transcriptFormatJSONL:
+		return jsonlTranscript{}, "application/x-ndjson", "jsonl", true
+	}
+	return nil, "", "", false
+}
+
+// eventSource reads a session's log, calling fn with each event, oldest
+// first, and stopping at the first error fn returns.  A transcript reads
+// it twice.
+type eventSource func(fn func(event map[string]interface{}) error) error
+
+// storeEvents reads the session's log from Events a few events at a time,
+// passing each event through redactor.
+func storeEvents(ctx context.Context, sessionName string, redactor *redact.Redactor) eventSource {
+	return func(fn func(event map[string]interface{}) error) error {
+		return eventstore.Scan(ctx, Events, sessionName, 0, func(event map[string]interface{}) error {
+			redactEvent(redactor, event)
+			return fn(event)
+		})
+	}
+}
+
+// errThroughSeq ends a read of eventsThrough early
+var errThroughSeq = errors.New("past the last event read")
+
+// eventsThrough reads events up to and including event seq.
+func eventsThrough(events eventSource, seq int64) eventSource {
+	return func(fn func(event map[string]interface{}) error) error {
+		err := events(func(event map[string]interface{}) error {
+			if eventstore.Seq(event) > seq {
+				return errThroughSeq
+			}
+			return fn(event)
+		})
+		if err == errThroughSeq {
+			return nil
+		}
+		return err
+	}
+}
+
+// writeTranscript renders the log read from events with r, flushing w
+// after each run if it can be flushed.  Only the feedback is kept from a
+// first pass; the second folds the log as it is read and writes each run's
+// new messages when the next run starts.
+func writeTranscript(w io.Writer, r transcriptRenderer, projectName, sessionName, exportDate string, events eventSource) error {
+	flush := func() {
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+
+	// Feedback is shown with the message it rates, which comes first
+	feedback := make(map[string][]transcriptFeedback)
+	var allFeedback []transcriptFeedback
+	err := events(func(evt map[string]interface{}) error {
+		if fb, ok := feedbackOf(evt); ok {
+			feedback[fb.MessageID] = append(feedback[fb.MessageID], fb)
+			allFeedback = append(allFeedback, fb)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := r.begin(w, projectName, sessionName, exportDate); err != nil {
+		return err
+	}
+	runs := newRunIndexer(projectName, sessionName)
+	folder := newMessageFolder()
+	next := 0 // index in folder.messages of the first message not written yet
+
+	// Events before the first RUN_STARTED (legacy or imported history)
+	// form a run of their own, without metadata
+	runIndex := -1
+	emit := func() error {
+		run := &transcriptRun{}
+		if runIndex >= 0 {
+			run.AGUIRunMetadata = runs.runs[runIndex]
+		}
+		for ; next < len(folder.messages); next++ {
+			msg := folder.messages[next]
+			if folder.hidden[msg.ID] || isMessageHidden(msg.Metadata) {
+				continue
+			}
+			run.Messages = append(run.Messages, *msg)
+			run.Feedback = append(run.Feedback, feedback[msg.ID]...)
+			delete(feedback, msg.ID)
+		}
+		if len(run.Messages) == 0 && run.RunID == "" {
+			return nil
+		}
+		if err := r.run(w, run); err != nil {
+			return err
+		}
+		flush()
+		return nil
+	}
+
+	err = events(func(evt map[string]interface{}) error {
+		runs.add(evt)
+		if evt["type"] == types.EventTypeRunStarted {
+			if err := emit(); err != nil {
+				return err
+			}
+			runIndex++
+		}
+		folder.add(evt)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := emit(); err != nil {
+		return err
+	}
+
+	var rest []transcriptFeedback
+	for _, fb := range allFeedback {
+		if _, left := feedback[fb.MessageID]; left {
+			rest = append(rest, fb)
+		}
+	}
+	return r.end(w, rest)
+}
+
+// feedbackOf converts a META feedback event
+func feedbackOf(evt map[string]interface{}) (transcriptFeedback, bool) {
+	if evt["type"] != types.EventTypeMeta {
+		return transcriptFeedback{}, false
+	}
+	fb := transcriptFeedback{Timestamp: eventTimestamp(evt)}
+	fb.Type, _ = evt["metaType"].(string)
+	payload, _ := evt["payload"].(map[string]interface{})
+	fb.MessageID, _ = payload["messageId"].(string)
+	fb.UserID, _ = payload["userId"].(string)
+	fb.Reason, _ = payload["reason"].(string)
+	fb.Comment, _ = payload["comment"].(string)
+	return fb, fb.Type != ""
+}
+
+// feedbackLabel describes a feedback type for people
+func feedbackLabel(fb transcriptFeedback) string {
+	switch fb.Type {
+	case "thumbs_up":
+		return "👍 Helpful"
+	case "thumbs_down":
+		return "👎 Not helpful"
+	}
+	return fb.Type
+}
+
+// feedbackDetail is the reason, comment and author of feedback
+func feedbackDetail(fb transcriptFeedback) string {
+	var parts []string
+	if fb.Reason != "" {
+		parts = append(parts, fb.Reason)
+	}
+	if fb.Comment != "" {
+		parts = append(parts, fmt.Sprintf("%q", fb.Comment))
+	}
+	if fb.UserID != "" {
+		parts = append(parts, "by "+fb.UserID)
+	}
+	if fb.Timestamp != "" {
+		parts = append(parts, fb.Timestamp)
+	}
+	return strings.Join(parts, " · ")
+}
+
+// roleLabel names a message's author in a transcript
+func roleLabel(role string) string {
+	switch role {
+	case types.RoleUser:
+		return "User"
+	case types.RoleAssistant:
+		return "Assistant"
+	case types.RoleSystem, types.RoleDeveloper:
+		return "System"
+	case types.RoleTool:
+		return "Tool"
+	}
+	return role
+}
+
+// runTitle is the heading of a run
+func runTitle(run *transcriptRun) string {
+	if run.RunID == "" {
+		return "Earlier conversation"
+	}
+	return "Run " + run.RunID
+}
+
+// runSummary is the line under a run's heading
+func runSummary(run *transcriptRun) string {
+	if run.RunID == "" {
+		return ""
+	}
+	parts := []string{run.Status}
+	if run.StartedAt != "" {
+		parts = append(parts, "started "+run.StartedAt)
+	}
+	if run.FinishedAt != "" {
+		parts = append(parts, "finished "+run.FinishedAt)
+	}
+	if run.DurationMs > 0 {
+		parts = append(parts, fmt.Sprintf("%.1fs", float64(run.DurationMs)/1000))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// prettyArgs indents tool arguments that are JSON
+func prettyArgs(args string) string {
+	var v interface{}
+	if err := json.Unmarshal([]byte(args), &v); err != nil {
+		return args
+	}
+	pretty, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return args
+	}
+	return string(pretty)
+}
+
+// shownInline reports whether a message is rendered on its own rather
+// than as the result of a tool call shown with its call
+func shownInline(msg types.Message, calls map[string]bool) bool {
+	return msg.Role != types.RoleTool || !calls[msg.ToolCallID]
+}
+
+// toolCallIDs returns the IDs of the tool calls in messages
+func toolCallIDs(messages []types.Message) map[string]bool {
+	ids := make(map[string]bool)
+	for _, msg := range messages {
+		for _, tc := range msg.ToolCalls {
+			ids[tc.ID] = true
+		}
+	}
+	return ids
+}
+
+// ─── Markdown ────────────────────────────────────────────
+
+type markdownTranscript struct{}
+
+func (markdownTranscript) begin(w io.Writer, projectName, sessionName, exportDate string) error {
+	_, err := fmt.Fprintf(w, "# Session %s\n\nProject `%s` · exported %s\n", sessionName, projectName, exportDate)
+	return err
+}
+
+func (markdownTranscript) run(w io.Writer, run *transcriptRun) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n---\n\n## %s\n\n", runTitle(run))
+	if summary := runSummary(run); summary != "" {
+		fmt.Fprintf(&b, "_%s_\n\n", summary)
+	}
+	feedback := make(map[string][]transcriptFeedback)
+	for _, fb := range run.Feedback {
+		feedback[fb.MessageID] = append(feedback[fb.MessageID], fb)
+	}
+	calls := toolCallIDs(run.Messages)
+	for _, msg := range run.Messages {
+		if !shownInline(msg, calls) {
+			continue
+		}
+		fmt.Fprintf(&b, "### %s", roleLabel(msg.Role))
+		if msg.Timestamp != "" {
+			fmt.Fprintf(&b, " · %s", msg.Timestamp)
+		}
+		b.WriteString("\n\n")
+		if msg.Content != "" {
+			b.WriteString(msg.Content)
+			b.WriteString("\n\n")
+		}
+		for _, tc := range msg.ToolCalls {
+			fmt.Fprintf(&b, "<details>\n<summary>Tool call: <code>%s</code> (%s)</summary>\n\n", html.EscapeString(tc.Name), html.EscapeString(tc.Status))
+			if tc.Args != "" {
+				b.WriteString("Arguments:\n\n")
+				writeFence(&b, "json", prettyArgs(tc.Args))
+			}
+			if tc.Result != "" {
+				b.WriteString("Result:\n\n")
+				writeFence(&b, "", tc.Result)
+			}
+			if tc.Error != "" {
+				fmt.Fprintf(&b, "Error: %s\n\n", tc.Error)
+			}
+			b.WriteString("</details>\n\n")
+		}
+		for _, fb := range feedback[msg.ID] {
+			fmt.Fprintf(&b, "> **Feedback:** %s", feedbackLabel(fb))
+			if detail := feedbackDetail(fb); detail != "" {
+				fmt.Fprintf(&b, " — %s", detail)
+			}
+			b.WriteString("\n\n")
+		}
+	}
+	if run.Error != "" {
+		fmt.Fprintf(&b, "**Run failed:** %s\n", run.Error)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (markdownTranscript) end(w io.Writer, feedback []transcriptFeedback) error {
+	if len(feedback) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	b.WriteString("\n---\n\n## Feedback\n\n")
+	for _, fb := range feedback {
+		fmt.Fprintf(&b, "- %s", feedbackLabel(fb))
+		if detail := feedbackDetail(fb); detail != "" {
+			fmt.Fprintf(&b, " — %s", detail)
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeFence writes text as a fenced code block whose fence is longer
+// than any run of backticks in it
+func writeFence(b *strings.Builder, lang, text string) {
+	longest, run := 0, 0
+	for _, ch := range text {
+		if ch == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	fmt.Fprintf(b, "%s%s\n%s\n%s\n\n", fence, lang, strings.TrimRight(text, "\n"), fence)
+}
+
+// ─── HTML ────────────────────────────────────────────────
+
+type htmlTranscript struct{}
+
+const transcriptStyle = `body{font-family:system-ui,sans-serif;max-width:60rem;margin:2rem auto;padding:0 1rem;color:#1f2328}
+section.run{border-top:1px solid #d0d7de;margin-top:2rem}
+.meta{color:#59636e;font-size:.875rem}
+.message{margin:1rem 0}.message h3{margin:0 0 .25rem;font-size:1rem}
+.user{background:#f6f8fa;border-radius:6px;padding:.5rem 1rem}
+.content{white-space:pre-wrap}
+details{border:1px solid #d0d7de;border-radius:6px;padding:.25rem .75rem;margin:.5rem 0}
+pre{background:#f6f8fa;padding:.5rem;overflow-x:auto;white-space:pre-wrap}
+.feedback{border-left:3px solid #d0d7de;padding-left:.75rem;color:#59636e}
+.error{color:#d1242f}`
+
+func (htmlTranscript) begin(w io.Writer, projectName, sessionName, exportDate string) error {
+	_, err := fmt.Fprintf(w, "<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n<title>Session %s</title>\n<style>\n%s\n</style>\n</head>\n<body>\n<h1>Session %s</h1>\n<p class=\"meta\">Project <code>%s</code> · exported %s</p>\n",
+		html.EscapeString(sessionName), transcriptStyle, html.EscapeString(sessionName), html.EscapeString(projectName), html.EscapeString(exportDate))
+	return err
+}
+
+func (htmlTranscript) run(w io.Writer, run *transcriptRun) error {
+	esc := html.EscapeString
+	var b strings.Builder
+	fmt.Fprintf(&b, "<section class=\"run\">\n<h2>%s</h2>\n", esc(runTitle(run)))
+	if summary := runSummary(run); summary != "" {
+		fmt.Fprintf(&b, "<p class=\"meta\">%s</p>\n", esc(summary))
+	}
+	feedback := make(map[string][]transcriptFeedback)
+	for _, fb := range run.Feedback {
+		feedback[fb.MessageID] = append(feedback[fb.MessageID], fb)
+	}
+	calls := toolCallIDs(run.Messages)
+	for _, msg := range run.Messages {
+		if !shownInline(msg, calls) {
+			continue
+		}
+		fmt.Fprintf(&b, "<div class=\"message %s\">\n<h3>%s", esc(msg.Role), esc(roleLabel(msg.Role)))
+		if msg.Timestamp != "" {
+			fmt.Fprintf(&b, " <time class=\"meta\" datetime=\"%s\">%s</time>", esc(msg.Timestamp), esc(msg.Timestamp))
+		}
+		b.WriteString("</h3>\n")
+		if msg.Content != "" {
+			fmt.Fprintf(&b, "<div class=\"content\">%s</div>\n", esc(msg.Content))
+		}
+		for _, tc := range msg.ToolCalls {
+			fmt.Fprintf(&b, "<details>\n<summary>Tool call: <code>%s</code> <span class=\"meta\">(%s)</span></summary>\n", esc(tc.Name), esc(tc.Status))
+			if tc.Args != "" {
+				fmt.Fprintf(&b, "<p>Arguments:</p>\n<pre><code>%s</code></pre>\n", esc(prettyArgs(tc.Args)))
+			}
+			if tc.Result != "" {
+				fmt.Fprintf(&b, "<p>Result:</p>\n<pre><code>%s</code></pre>\n", esc(tc.Result))
+			}
+			if tc.Error != "" {
+				fmt.Fprintf(&b, "<p class=\"error\">Error: %s</p>\n", esc(tc.Error))
+			}
+			b.WriteString("</details>\n")
+		}
+		for _, fb := range feedback[msg.ID] {
+			fmt.Fprintf(&b, "<p class=\"feedback\"><strong>Feedback:</strong> %s", esc(feedbackLabel(fb)))
+			if detail := feedbackDetail(fb); detail != "" {
+				fmt.Fprintf(&b, " — %s", esc(detail))
+			}
+			b.WriteString("</p>\n")
+		}
+		b.WriteString("</div>\n")
+	}
+	if run.Error != "" {
+		fmt.Fprintf(&b, "<p class=\"error\"><strong>Run failed:</strong> %s</p>\n", esc(run.Error))
+	}
+	b.WriteString("</section>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (htmlTranscript) end(w io.Writer, feedback []transcriptFeedback) error {
+	var b strings.Builder
+	if len(feedback) > 0 {
+		b.WriteString("<section class=\"run\">\n<h2>Feedback</h2>\n<ul>\n")
+		for _, fb := range feedback {
+			fmt.Fprintf(&b, "<li>%s", html.EscapeString(feedbackLabel(fb)))
+			if detail := feedbackDetail(fb); detail != "" {
+				fmt.Fprintf(&b, " — %s", html.EscapeString(detail))
+			}
+			b.WriteString("</li>\n")
+		}
+		b.WriteString("</ul>\n</section>\n")
+	}
+	b.WriteString("</body>\n</html>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ─── JSONL ───────────────────────────────────────────────
+
+// jsonlTranscript writes one transcriptRun per line; feedback not tied to
+// a shown message goes on a last line of its own.
+type jsonlTranscript struct{}
+
+func (jsonlTranscript) begin(io.Writer, string, string, string) error { return nil }
+
+func (jsonlTranscript) run(w io.Writer, run *transcriptRun) error {
+	if run.Messages == nil {
+		run.Messages = []types.Message{}
+	}
+	return json.NewEncoder(w).Encode(run)
+}
+
+func (jsonlTranscript) end(w io.Writer, feedback []transcriptFeedback) error {
+	if len(feedback) == 0 {
+		return nil
+	}
+	return json.NewEncoder(w).Encode(map[string]interface{}{"feedback": feedback})
+}
@@ -0,0 +1,148 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"ambient-code-backend/redact"
+)
+
+// sliceEvents reads a log held in memory
+func sliceEvents(events []map[string]interface{}) eventSource {
+	return func(fn func(event map[string]interface{}) error) error {
+		for _, event := range events {
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// transcriptEvents is a two-run session: a tool call in the first run, a
+// failed second run, and feedback on the first answer
+func transcriptEvents() []map[string]interface{} {
+	return []map[string]interface{}{
+		{"type": "RUN_STARTED", "runId": "r1", "threadId": "s", "seq": float64(1), "timestamp": float64(1700000000000)},
+		{"type": "TEXT_MESSAGE_START", "messageId": "u1", "role": "user", "seq": float64(2)},
+		{"type": "TEXT_MESSAGE_CONTENT", "messageId": "u1", "delta": "List <files>", "seq": float64(3)},
+		{"type": "TEXT_MESSAGE_END", "messageId": "u1", "seq": float64(4)},
+		{"type": "TEXT_MESSAGE_START", "messageId": "a1", "role": "assistant", "seq": float64(5)},
+		{"type": "TEXT_MESSAGE_CONTENT", "messageId": "a1", "delta": "Sure, ", "seq": float64(6)},
+		{"type": "TEXT_MESSAGE_CONTENT", "messageId": "a1", "delta": "running ls.", "seq": float64(7)},
+		{"type": "TEXT_MESSAGE_END", "messageId": "a1", "seq": float64(8)},
+		{"type": "TOOL_CALL_START", "toolCallId": "t1", "toolCallName": "Bash", "parentMessageId": "a1", "seq": float64(9)},
+		{"type": "TOOL_CALL_ARGS", "toolCallId": "t1", "delta": `{"command":`, "seq": float64(10)},
+		{"type": "TOOL_CALL_ARGS", "toolCallId": "t1", "delta": `"ls"}`, "seq": float64(11)},
+		{"type": "TOOL_CALL_END", "toolCallId": "t1", "seq": float64(12)},
+		{"type": "TOOL_CALL_RESULT", "toolCallId": "t1", "content": "a.go\n```\nb.go", "seq": float64(13)},
+		{"type": "RUN_FINISHED", "runId": "r1", "seq": float64(14), "timestamp": float64(1700000002500)},
+		{"type": "META", "metaType": "thumbs_up", "payload": map[string]interface{}{"messageId": "a1", "userId": "alice", "comment": "nice"}, "seq": float64(15)},
+		{"type": "RUN_STARTED", "runId": "r2", "threadId": "s", "seq": float64(16)},
+		{"type": "RUN_ERROR", "message": "runner crashed", "seq": float64(17)},
+		{"type": "META", "metaType": "thumbs_down", "payload": map[string]interface{}{"reason": "slow"}, "seq": float64(18)},
+	}
+}
+
+func TestMarkdownTranscript(t *testing.T) {
+	var buf bytes.Buffer
+	r, _, _, _ := newTranscriptRenderer("markdown")
+	if err := writeTranscript(&buf, r, "proj", "s", "2024-01-01T00:00:00Z", sliceEvents(transcriptEvents())); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"# Session s",
+		"## Run r1",
+		"_completed · started 2023-11-14T22:13:20Z · finished 2023-11-14T22:13:22Z · 2.5s_",
+		"### User",
+		"List <files>",
+		"Sure, running ls.",
+		"<summary>Tool call: <code>Bash</code> (completed)</summary>",
+		"````\na.go\n```\nb.go\n````",
+		"> **Feedback:** 👍 Helpful — \"nice\" · by alice",
+		"## Run r2",
+		"**Run failed:** runner crashed",
+		"## Feedback\n\n- 👎 Not helpful — slow",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("transcript is missing %q:\n%s", want, out)
+		}
+	}
+	// The result is shown with its call, not as a message of its own
+	if strings.Contains(out, "### Tool") {
+		t.Errorf("tool result rendered as a message:\n%s", out)
+	}
+}
+
+func TestHTMLTranscriptEscapes(t *testing.T) {
+	var buf bytes.Buffer
+	r, _, _, _ := newTranscriptRenderer("html")
+	if err := writeTranscript(&buf, r, "proj", "s", "now", sliceEvents(transcriptEvents())); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "List &lt;files&gt;") || strings.Contains(out, "<files>") {
+		t.Errorf("message content not escaped:\n%s", out)
+	}
+	if !strings.HasSuffix(out, "</body>\n</html>\n") || strings.Count(out, "<details>") != 1 {
+		t.Errorf("unexpected document:\n%s", out)
+	}
+}
+
+func TestJSONLTranscriptWritesOneLinePerRun(t *testing.T) {
+	var buf bytes.Buffer
+	r, _, _, _ := newTranscriptRenderer("jsonl")
+	if err := writeTranscript(&buf, r, "proj", "s", "now", sliceEvents(transcriptEvents())); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 2 runs and leftover feedback:\n%s", len(lines), buf.String())
+	}
+	var run transcriptRun
+	if err := json.Unmarshal([]byte(lines[0]), &run); err != nil {
+		t.Fatal(err)
+	}
+	if run.RunID != "r1" || len(run.Messages) != 3 || len(run.Feedback) != 1 || run.Messages[1].ToolCalls[0].Args != `{"command":"ls"}` {
+		t.Errorf("first run = %+v", run)
+	}
+	if _, _, _, ok := newTranscriptRenderer("pdf"); ok {
+		t.Error("newTranscriptRenderer(pdf): want unknown")
+	}
+}
+
+func TestTranscriptFromStore(t *testing.T) {
+	useTempStateDir(t)
+	for _, evt := range transcriptEvents() {
+		delete(evt, "seq")
+		persistEvent("stored", evt)
+	}
+	r, _, _, _ := newTranscriptRenderer("jsonl")
+	var fromStore, fromMemory bytes.Buffer
+	if err := writeTranscript(&fromStore, r, "proj", "s", "now", storeEvents(context.Background(), "stored", redact.Default)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeTranscript(&fromMemory, r, "proj", "s", "now", sliceEvents(loadEvents("stored"))); err != nil {
+		t.Fatal(err)
+	}
+	if fromStore.String() != fromMemory.String() || strings.Count(fromStore.String(), "\n") != 3 {
+		t.Errorf("transcript from the store:\n%s\nwant:\n%s", fromStore.String(), fromMemory.String())
+	}
+}
+
+func TestTranscriptThroughSeq(t *testing.T) {
+	var buf bytes.Buffer
+	r, _, _, _ := newTranscriptRenderer("jsonl")
+	if err := writeTranscript(&buf, r, "proj", "s", "now", eventsThrough(sliceEvents(transcriptEvents()), 15)); err != nil {
+		t.Fatal(err)
+	}
+	// The first run and its feedback; nothing of the second
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], `"runId":"r1"`) || !strings.Contains(lines[0], "nice") {
+		t.Errorf("transcript through event 15:\n%s", buf.String())
+	}
+}
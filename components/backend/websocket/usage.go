@@ -0,0 +1,368 @@
+// usage.go — token usage and cost accounting for proxied runs.
+//
+// The runner reports usage in two places: RAW events relaying SDK messages
+// (per assistant message) and RUN_FINISHED.result (the SDK's ResultMessage,
+// which totals the whole run). RUN_FINISHED is authoritative; the RAW sum is
+// only used when a run ends without a result (e.g. the stream was cut).
+package websocket
+
+import (
+	"ambient-code-backend/handlers"
+	"ambient-code-backend/metrics"
+	"ambient-code-backend/types"
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/retry"
+)
+
+// usageDailyRetentionDays is how many UTC days keep their own bucket in status.usage.daily;
+// older days are rolled up per month into status.usage.monthly.
+const usageDailyRetentionDays = 90
+
+// runUsageTracker accumulates the usage reported by a single run's event stream,
+// split by the model that consumed it. Usage that names no model is kept under "".
+type runUsageTracker struct {
+	rawUsage     types.TokenUsage
+	rawByModel   map[string]types.TokenUsage
+	finalUsage   types.TokenUsage
+	finalByModel map[string]types.TokenUsage
+	hasFinished  bool
+}
+
+// observe inspects one SSE data payload for usage information.
+func (t *runUsageTracker) observe(jsonData string) {
+	var event map[string]interface{}
+	if err := json.Unmarshal([]byte(jsonData), &event); err != nil {
+		return
+	}
+
+	eventType, _ := event["type"].(string)
+	switch eventType {
+	case types.EventTypeRunFinished:
+		result, ok := event["result"].(map[string]interface{})
+		if !ok {
+			return
+		}
+		usage := parseUsageMap(result["usage"])
+		usage.CostUSD = numberField(result, "total_cost_usd", "totalCostUsd")
+		byModel := parseModelUsage(result["modelUsage"], result["model_usage"])
+		// modelUsage also covers subagent and helper models, which usage may leave out
+		var modelTotal types.TokenUsage
+		for _, u := range byModel {
+			modelTotal.Add(u)
+		}
+		usage = maxUsage(usage, modelTotal)
+		if !usage.IsZero() {
+			t.finalUsage = usage
+			t.finalByModel = byModel
+			t.hasFinished = true
+		}
+	case types.EventTypeRaw:
+		raw, ok := event["event"].(map[string]interface{})
+		if !ok {
+			return
+		}
+		usage := parseUsageMap(raw["usage"])
+		model, _ := raw["model"].(string)
+		if msg, ok := raw["message"].(map[string]interface{}); ok {
+			if usage.IsZero() {
+				usage = parseUsageMap(msg["usage"])
+			}
+			if m, ok := msg["model"].(string); ok && m != "" {
+				model = m
+			}
+		}
+		if usage.IsZero() {
+			return
+		}
+		t.rawUsage.Add(usage)
+		if t.rawByModel == nil {
+			t.rawByModel = make(map[string]types.TokenUsage)
+		}
+		modelUsage := t.rawByModel[model]
+		modelUsage.Add(usage)
+		t.rawByModel[model] = modelUsage
+	}
+}
+
+// total returns the usage for the run and its split by model, each counted as one
+// run when non-zero. The split always adds up to the total: usage that cannot be
+// attributed to a model is returned under "".
+func (t *runUsageTracker) total() (types.TokenUsage, map[string]types.TokenUsage) {
+	usage, byModel := t.rawUsage, t.rawByModel
+	if t.hasFinished {
+		usage, byModel = t.finalUsage, t.finalByModel
+		// Without a per-model breakdown, credit the run to the only model seen streaming
+		if len(byModel) == 0 && len(t.rawByModel) == 1 {
+			for model := range t.rawByModel {
+				byModel = map[string]types.TokenUsage{model: usage}
+			}
+		}
+	}
+	if usage.IsZero() {
+		return usage, nil
+	}
+	usage.Runs = 1
+
+	split := make(map[string]types.TokenUsage, len(byModel)+1)
+	var attributed types.TokenUsage
+	for model, u := range byModel {
+		if u.IsZero() {
+			continue
+		}
+		u.Runs = 1
+		split[model] = u
+		attributed.Add(u)
+	}
+	if rest := subtractUsage(usage, attributed); !rest.IsZero() {
+		rest.Add(split[""])
+		rest.Runs = 1
+		split[""] = rest
+	}
+	return usage, split
+}
+
+// parseModelUsage reads the SDK's per-model breakdown (model name to usage, with the
+// cost under costUSD), from the first of candidates that is present.
+func parseModelUsage(candidates ...interface{}) map[string]types.TokenUsage {
+	for _, v := range candidates {
+		m, ok := v.(map[string]interface{})
+		if !ok || len(m) == 0 {
+			continue
+		}
+		byModel := make(map[string]types.TokenUsage, len(m))
+		for model, raw := range m {
+			fields, ok := raw.(map[string]interface{})
+			if !ok || model == "" {
+				continue
+			}
+			usage := parseUsageMap(fields)
+			usage.CostUSD = numberField(fields, "costUSD", "costUsd", "cost_usd")
+			if !usage.IsZero() {
+				byModel[model] = usage
+			}
+		}
+		return byModel
+	}
+	return nil
+}
+
+// maxUsage returns the field-wise maximum of a and b.
+func maxUsage(a, b types.TokenUsage) types.TokenUsage {
+	return types.TokenUsage{
+		InputTokens:              max(a.InputTokens, b.InputTokens),
+		OutputTokens:             max(a.OutputTokens, b.OutputTokens),
+		CacheReadInputTokens:     max(a.CacheReadInputTokens, b.CacheReadInputTokens),
+		CacheCreationInputTokens: max(a.CacheCreationInputTokens, b.CacheCreationInputTokens),
+		CostUSD:                  max(a.CostUSD, b.CostUSD),
+		Runs:                     max(a.Runs, b.Runs),
+	}
+}
+
+// subtractUsage returns a minus b, clamping each field at zero. Cost differences
+// below a millionth of a dollar are treated as rounding.
+func subtractUsage(a, b types.TokenUsage) types.TokenUsage {
+	rest := types.TokenUsage{
+		InputTokens:              max(a.InputTokens-b.InputTokens, 0),
+		OutputTokens:             max(a.OutputTokens-b.OutputTokens, 0),
+		CacheReadInputTokens:     max(a.CacheReadInputTokens-b.CacheReadInputTokens, 0),
+		CacheCreationInputTokens: max(a.CacheCreationInputTokens-b.CacheCreationInputTokens, 0),
+	}
+	if cost := a.CostUSD - b.CostUSD; cost > 1e-6 {
+		rest.CostUSD = cost
+	}
+	return rest
+}
+
+// parseUsageMap reads Anthropic-style usage counters, accepting both
+// snake_case (SDK) and camelCase keys.
+func parseUsageMap(v interface{}) types.TokenUsage {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return types.TokenUsage{}
+	}
+	return types.TokenUsage{
+		InputTokens:              int64(numberField(m, "input_tokens", "inputTokens")),
+		OutputTokens:             int64(numberField(m, "output_tokens", "outputTokens")),
+		CacheReadInputTokens:     int64(numberField(m, "cache_read_input_tokens", "cacheReadInputTokens")),
+		CacheCreationInputTokens: int64(numberField(m, "cache_creation_input_tokens", "cacheCreationInputTokens")),
+	}
+}
+
+func numberField(m map[string]interface{}, keys ...string) float64 {
+	for _, k := range keys {
+		if n, ok := m[k].(float64); ok && n > 0 {
+			return n
+		}
+	}
+	return 0
+}
+
+// recordRunUsage adds a run's usage to the session's status.usage (totals, the split by
+// model and the bucket for the current UTC day) and exports it as OTel counters.
+// Usage that the run did not attribute to a model is credited to the session's model.
+func recordRunUsage(projectName, sessionName string, usage types.TokenUsage, byModel map[string]types.TokenUsage) {
+	if handlers.DynamicClient == nil {
+		log.Printf("Usage tracking: DynamicClient is nil, skipping update for %s/%s", projectName, sessionName)
+		return
+	}
+
+	gvr := handlers.GetAgenticSessionV1Alpha1Resource()
+	ctx, cancel := context.WithTimeout(context.Background(), activityUpdateTimeout)
+	defer cancel()
+
+	now := time.Now().UTC()
+	var userID string
+	var attributed map[string]types.TokenUsage
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		obj, err := handlers.DynamicClient.Resource(gvr).Namespace(projectName).Get(ctx, sessionName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		userID, _, _ = unstructured.NestedString(obj.Object, "spec", "userContext", "userId")
+		model, _, _ := unstructured.NestedString(obj.Object, "spec", "llmSettings", "model")
+		attributed = attributeUsage(byModel, model)
+
+		status, _, _ := unstructured.NestedMap(obj.Object, "status")
+		if status == nil {
+			status = make(map[string]any)
+		}
+		current := handlers.ParseSessionUsage(status["usage"])
+		if current == nil {
+			current = &types.SessionUsage{}
+		}
+		addSessionUsage(current, usage, attributed, now)
+
+		encoded, err := usageToMap(current)
+		if err != nil {
+			return err
+		}
+		status["usage"] = encoded
+		if err := unstructured.SetNestedField(obj.Object, status, "status"); err != nil {
+			return err
+		}
+		_, err = handlers.DynamicClient.Resource(gvr).Namespace(projectName).UpdateStatus(ctx, obj, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		log.Printf("Usage tracking: failed to update usage for %s/%s: %v", projectName, sessionName, err)
+		return
+	}
+
+	for model, modelUsage := range attributed {
+		metrics.RecordTokenUsage(projectName, userID, model, modelUsage)
+	}
+}
+
+// attributeUsage credits the unattributed share of a run ("") to the session's
+// configured model, or to handlers.UnknownUsageKey when none is set.
+func attributeUsage(byModel map[string]types.TokenUsage, sessionModel string) map[string]types.TokenUsage {
+	if sessionModel == "" {
+		sessionModel = handlers.UnknownUsageKey
+	}
+	out := make(map[string]types.TokenUsage, len(byModel))
+	for model, usage := range byModel {
+		if model == "" {
+			model = sessionModel
+		}
+		merged := out[model]
+		merged.Add(usage)
+		// A model credited twice is still one run
+		merged.Runs = min(merged.Runs, 1)
+		out[model] = merged
+	}
+	return out
+}
+
+// addSessionUsage folds a run's usage into the session totals, the per-model totals
+// and the day bucket for now, then rolls day buckets older than the retention window
+// into monthly buckets.
+func addSessionUsage(current *types.SessionUsage, usage types.TokenUsage, byModel map[string]types.TokenUsage, now time.Time) {
+	current.TokenUsage.Add(usage)
+	if current.Models == nil {
+		current.Models = make(map[string]types.TokenUsage)
+	}
+	for model, modelUsage := range byModel {
+		total := current.Models[model]
+		total.Add(modelUsage)
+		current.Models[model] = total
+	}
+
+	if current.Daily == nil {
+		current.Daily = make(map[string]types.UsageBucket)
+	}
+	day := now.Format(handlers.UsageDayFormat)
+	current.Daily[day] = addToBucket(current.Daily[day], usage, byModel)
+	rollUpDailyUsage(current, now)
+	current.LastUpdated = now.Format(time.RFC3339)
+}
+
+// rollUpDailyUsage moves day buckets older than usageDailyRetentionDays into the
+// bucket for their month.
+func rollUpDailyUsage(current *types.SessionUsage, now time.Time) {
+	cutoff := now.AddDate(0, 0, -(usageDailyRetentionDays - 1)).Format(handlers.UsageDayFormat)
+	for day, bucket := range current.Daily {
+		if day >= cutoff {
+			continue
+		}
+		if current.Monthly == nil {
+			current.Monthly = make(map[string]types.UsageBucket)
+		}
+		month := day[:len("2006-01")]
+		current.Monthly[month] = addToBucket(current.Monthly[month], bucket.TokenUsage, bucket.Models)
+		delete(current.Daily, day)
+	}
+}
+
+func addToBucket(bucket types.UsageBucket, usage types.TokenUsage, byModel map[string]types.TokenUsage) types.UsageBucket {
+	bucket.TokenUsage.Add(usage)
+	if len(byModel) == 0 {
+		return bucket
+	}
+	if bucket.Models == nil {
+		bucket.Models = make(map[string]types.TokenUsage, len(byModel))
+	}
+	for model, modelUsage := range byModel {
+		total := bucket.Models[model]
+		total.Add(modelUsage)
+		bucket.Models[model] = total
+	}
+	return bucket
+}
+
+// usageToMap converts SessionUsage to the unstructured form stored on the CR.
+func usageToMap(usage *types.SessionUsage) (map[string]interface{}, error) {
+	b, err := json.Marshal(usage)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return normalizeNumbers(m).(map[string]interface{}), nil
+}
+
+// normalizeNumbers converts whole float64 values produced by json.Unmarshal to
+// int64 so unstructured.SetNestedField accepts them as integers.
+func normalizeNumbers(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = normalizeNumbers(item)
+		}
+		return val
+	case float64:
+		if val == float64(int64(val)) {
+			return int64(val)
+		}
+		return val
+	default:
+		return v
+	}
+}
@@ -0,0 +1,168 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	"ambient-code-backend/types"
+)
+
+func TestRunUsageTracker(t *testing.T) {
+	t.Run("RUN_FINISHED result is authoritative", func(t *testing.T) {
+		var tracker runUsageTracker
+		tracker.observe(`{"type":"RAW","event":{"type":"assistant","message":{"usage":{"input_tokens":5,"output_tokens":7}}}}`)
+		tracker.observe(`{"type":"RUN_FINISHED","result":{"total_cost_usd":0.25,"usage":{"input_tokens":100,"output_tokens":40,"cache_read_input_tokens":900,"cache_creation_input_tokens":20}}}`)
+
+		got, byModel := tracker.total()
+		want := types.TokenUsage{InputTokens: 100, OutputTokens: 40, CacheReadInputTokens: 900, CacheCreationInputTokens: 20, CostUSD: 0.25, Runs: 1}
+		if got != want {
+			t.Errorf("total() = %+v, want %+v", got, want)
+		}
+		if len(byModel) != 1 || byModel[""] != want {
+			t.Errorf("expected the run to be unattributed, got %+v", byModel)
+		}
+	})
+
+	t.Run("RUN_FINISHED modelUsage splits the run", func(t *testing.T) {
+		var tracker runUsageTracker
+		tracker.observe(`{"type":"RUN_FINISHED","result":{"total_cost_usd":0.30,"usage":{"input_tokens":100,"output_tokens":40},` +
+			`"modelUsage":{"claude-sonnet-4-5":{"inputTokens":100,"outputTokens":40,"costUSD":0.25},"claude-haiku-4-5":{"inputTokens":50,"outputTokens":10,"costUSD":0.04}}}}`)
+
+		got, byModel := tracker.total()
+		want := types.TokenUsage{InputTokens: 150, OutputTokens: 50, CostUSD: 0.30, Runs: 1}
+		if got != want {
+			t.Errorf("total() = %+v, want %+v (modelUsage includes the helper model)", got, want)
+		}
+		if sonnet := byModel["claude-sonnet-4-5"]; sonnet.InputTokens != 100 || sonnet.CostUSD != 0.25 || sonnet.Runs != 1 {
+			t.Errorf("unexpected sonnet share %+v", sonnet)
+		}
+		if haiku := byModel["claude-haiku-4-5"]; haiku.InputTokens != 50 || haiku.CostUSD != 0.04 {
+			t.Errorf("unexpected haiku share %+v", haiku)
+		}
+		// The cost not covered by modelUsage is left for the session model
+		if rest := byModel[""]; rest.InputTokens != 0 || rest.CostUSD < 0.0099 || rest.CostUSD > 0.0101 {
+			t.Errorf("unexpected unattributed share %+v", rest)
+		}
+	})
+
+	t.Run("RAW usage summed per model without RUN_FINISHED", func(t *testing.T) {
+		var tracker runUsageTracker
+		tracker.observe(`{"type":"RAW","event":{"model":"claude-opus-4-1","usage":{"input_tokens":5,"output_tokens":7}}}`)
+		tracker.observe(`{"type":"RAW","event":{"message":{"model":"claude-haiku-4-5","usage":{"inputTokens":3,"outputTokens":1}}}}`)
+		tracker.observe(`{"type":"TEXT_MESSAGE_CONTENT","delta":"hi"}`)
+		tracker.observe(`not json`)
+
+		got, byModel := tracker.total()
+		want := types.TokenUsage{InputTokens: 8, OutputTokens: 8, Runs: 1}
+		if got != want {
+			t.Errorf("total() = %+v, want %+v", got, want)
+		}
+		if len(byModel) != 2 || byModel["claude-opus-4-1"].InputTokens != 5 || byModel["claude-haiku-4-5"].InputTokens != 3 {
+			t.Errorf("unexpected split %+v", byModel)
+		}
+	})
+
+	t.Run("single streamed model is credited with the final usage", func(t *testing.T) {
+		var tracker runUsageTracker
+		tracker.observe(`{"type":"RAW","event":{"message":{"model":"claude-opus-4-1","usage":{"input_tokens":5}}}}`)
+		tracker.observe(`{"type":"RUN_FINISHED","result":{"usage":{"input_tokens":100}}}`)
+
+		_, byModel := tracker.total()
+		if len(byModel) != 1 || byModel["claude-opus-4-1"].InputTokens != 100 {
+			t.Errorf("unexpected split %+v", byModel)
+		}
+	})
+
+	t.Run("no usage", func(t *testing.T) {
+		var tracker runUsageTracker
+		tracker.observe(`{"type":"RUN_FINISHED","threadId":"t","runId":"r"}`)
+		if got, byModel := tracker.total(); !got.IsZero() || got.Runs != 0 || byModel != nil {
+			t.Errorf("expected zero usage, got %+v %+v", got, byModel)
+		}
+	})
+}
+
+func TestAttributeUsage(t *testing.T) {
+	byModel := map[string]types.TokenUsage{
+		"":                  {InputTokens: 1, Runs: 1},
+		"claude-sonnet-4-5": {InputTokens: 10, Runs: 1},
+		"claude-haiku-4-5":  {InputTokens: 5, Runs: 1},
+	}
+
+	got := attributeUsage(byModel, "claude-sonnet-4-5")
+	if s := got["claude-sonnet-4-5"]; s.InputTokens != 11 || s.Runs != 1 {
+		t.Errorf("unattributed usage should merge into the session model as one run, got %+v", s)
+	}
+	if _, ok := got[""]; ok {
+		t.Error("empty model key left in the result")
+	}
+
+	if got := attributeUsage(map[string]types.TokenUsage{"": {InputTokens: 1}}, ""); got["unknown"].InputTokens != 1 {
+		t.Errorf("expected unknown model, got %+v", got)
+	}
+}
+
+func TestAddSessionUsage(t *testing.T) {
+	day1 := time.Date(2026, 3, 1, 23, 59, 0, 0, time.UTC)
+	day2 := day1.Add(2 * time.Minute)
+	run := types.TokenUsage{InputTokens: 10, OutputTokens: 5, CostUSD: 0.5, Runs: 1}
+
+	usage := &types.SessionUsage{}
+	addSessionUsage(usage, run, map[string]types.TokenUsage{"claude-sonnet-4-5": run}, day1)
+	addSessionUsage(usage, run, map[string]types.TokenUsage{"claude-opus-4-1": run}, day2)
+
+	if usage.InputTokens != 20 || usage.Runs != 2 || usage.CostUSD != 1.0 {
+		t.Errorf("unexpected totals: %+v", usage.TokenUsage)
+	}
+	if usage.Models["claude-sonnet-4-5"].Runs != 1 || usage.Models["claude-opus-4-1"].Runs != 1 {
+		t.Errorf("each run should be credited to its own model: %+v", usage.Models)
+	}
+	if len(usage.Daily) != 2 || usage.Daily["2026-03-01"].Runs != 1 || usage.Daily["2026-03-02"].Models["claude-opus-4-1"].InputTokens != 10 {
+		t.Errorf("unexpected daily buckets: %+v", usage.Daily)
+	}
+
+	m, err := usageToMap(usage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m["inputTokens"].(int64); !ok {
+		t.Errorf("inputTokens should be stored as int64, got %T", m["inputTokens"])
+	}
+}
+
+func TestAddSessionUsage_RollsUpOldDays(t *testing.T) {
+	run := types.TokenUsage{InputTokens: 10, Runs: 1}
+	byModel := map[string]types.TokenUsage{"claude-sonnet-4-5": run}
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	usage := &types.SessionUsage{}
+	for i := 0; i < 120; i++ {
+		addSessionUsage(usage, run, byModel, start.AddDate(0, 0, i))
+	}
+
+	if len(usage.Daily) != usageDailyRetentionDays {
+		t.Errorf("kept %d daily buckets, want %d", len(usage.Daily), usageDailyRetentionDays)
+	}
+	// 2026-01-01 + 119 days is 2026-04-30, so the daily window starts on 2026-01-31
+	if _, ok := usage.Daily["2026-01-30"]; ok {
+		t.Error("2026-01-30 should have been rolled up")
+	}
+	if _, ok := usage.Daily["2026-01-31"]; !ok {
+		t.Error("2026-01-31 should still be a daily bucket")
+	}
+	jan := usage.Monthly["2026-01"]
+	if jan.Runs != 30 || jan.Models["claude-sonnet-4-5"].InputTokens != 300 {
+		t.Errorf("unexpected January rollup %+v", jan)
+	}
+
+	var sum types.TokenUsage
+	for _, b := range usage.Daily {
+		sum.Add(b.TokenUsage)
+	}
+	for _, b := range usage.Monthly {
+		sum.Add(b.TokenUsage)
+	}
+	if sum != usage.TokenUsage {
+		t.Errorf("buckets sum to %+v, totals are %+v", sum, usage.TokenUsage)
+	}
+}
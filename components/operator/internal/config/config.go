@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/dynamic"
@@ -29,6 +30,26 @@ type Config struct {
 	S3Endpoint             string
 	S3Bucket               string
 	PodFSGroup             *int64
+	// BackendAPIURL is the base URL of the backend API, ending in /api
+	BackendAPIURL string
+}
+
+// restConfig is the configuration the clients were created with
+var restConfig *rest.Config
+
+// BearerToken returns the token the operator authenticates with, read again from its
+// file so a rotated service account token is picked up. It is empty when the clients
+// were not initialized or do not use a token.
+func BearerToken() string {
+	if restConfig == nil {
+		return ""
+	}
+	if restConfig.BearerTokenFile != "" {
+		if data, err := os.ReadFile(restConfig.BearerTokenFile); err == nil {
+			return strings.TrimSpace(string(data))
+		}
+	}
+	return restConfig.BearerToken
 }
 
 // InitK8sClients initializes the Kubernetes clients
@@ -53,6 +74,7 @@ func InitK8sClients() error {
 	// Default is QPS=5, Burst=10 which is too low for operators handling concurrent sessions
 	config.QPS = 100
 	config.Burst = 200
+	restConfig = config
 
 	// Create standard Kubernetes client
 	K8sClient, err = kubernetes.NewForConfig(config)
@@ -130,5 +152,6 @@ func LoadConfig() *Config {
 		S3Endpoint:             s3Endpoint,
 		S3Bucket:               s3Bucket,
 		PodFSGroup:             podFSGroup,
+		BackendAPIURL:          fmt.Sprintf("http://backend-service.%s.svc.cluster.local:8080/api", backendNamespace),
 	}
 }
@@ -17,15 +17,18 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"ambient-code-operator/internal/config"
 	"ambient-code-operator/internal/handlers"
 	optypes "ambient-code-operator/internal/types"
+	"ambient-code-shared/budget"
 )
 
 // AgenticSessionReconciler reconciles AgenticSession resources.
@@ -78,12 +81,29 @@ func (r *AgenticSessionReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		return ctrl.Result{}, fmt.Errorf("failed to get AgenticSession: %w", err)
 	}
 
+	// A deleted session only waits for its usage to reach the project's ledger
+	if session.GetDeletionTimestamp() != nil {
+		return r.reconcileDeleted(ctx, session)
+	}
+
 	// Check if namespace is managed
 	if !r.isNamespaceManaged(ctx, session.GetNamespace()) {
 		logger.V(2).Info("Skipping unmanaged namespace", "namespace", session.GetNamespace())
 		return ctrl.Result{}, nil
 	}
 
+	// Hold deletion until the session's usage is in the ledger (see reconcileDeleted)
+	if !controllerutil.ContainsFinalizer(session, budget.UsageFinalizer) {
+		patch := client.MergeFromWithOptions(session.DeepCopy(), client.MergeFromWithOptimisticLock{})
+		controllerutil.AddFinalizer(session, budget.UsageFinalizer)
+		if err := r.Patch(ctx, session, patch); err != nil {
+			if errors.IsNotFound(err) {
+				return ctrl.Result{}, nil
+			}
+			return ctrl.Result{}, fmt.Errorf("failed to add the usage finalizer: %w", err)
+		}
+	}
+
 	// Get current phase
 	status, _, _ := unstructured.NestedMap(session.Object, "status")
 	phase := ""
@@ -105,7 +125,7 @@ func (r *AgenticSessionReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	var err error
 
 	switch phase {
-	case "", "Pending":
+	case "", "Pending", "Queued":
 		result, err = r.reconcilePending(ctx, session)
 	case "Creating":
 		result, err = r.reconcileCreating(ctx, session)
@@ -129,7 +149,13 @@ func (r *AgenticSessionReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 			// Requeue to process the Pending phase
 			return ctrl.Result{Requeue: true}, nil
 		}
-		// No restart requested - terminal phases, no action needed
+		// No restart requested - start the follow-up declared in spec.next, if any
+		if phase == "Completed" {
+			if err := handlers.StartNextSession(ctx, session); err != nil {
+				logger.Error(err, "Failed to start next session in chain")
+				return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+			}
+		}
 		result, err = ctrl.Result{}, nil
 	default:
 		logger.Info("Unknown phase", "phase", phase)
@@ -156,8 +182,33 @@ func (r *AgenticSessionReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	return result, nil
 }
 
+// reconcileDeleted adds the usage of a session being deleted to its project's usage
+// ledger, then removes budget.UsageFinalizer so the deletion can complete. The patch
+// is not conditional on the session's resourceVersion: a conflict would record the
+// usage twice on retry.
+func (r *AgenticSessionReconciler) reconcileDeleted(ctx context.Context, session *unstructured.Unstructured) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(session, budget.UsageFinalizer) {
+		return ctrl.Result{}, nil
+	}
+	if err := handlers.RecordDeletedSessionUsage(ctx, session); err != nil {
+		return ctrl.Result{}, err
+	}
+	patch := client.MergeFrom(session.DeepCopy())
+	controllerutil.RemoveFinalizer(session, budget.UsageFinalizer)
+	if err := r.Patch(ctx, session, patch); err != nil && !errors.IsNotFound(err) {
+		return ctrl.Result{}, fmt.Errorf("failed to remove the usage finalizer: %w", err)
+	}
+	return ctrl.Result{}, nil
+}
+
 // isNamespaceManaged checks if the namespace has the managed label
 func (r *AgenticSessionReconciler) isNamespaceManaged(ctx context.Context, namespace string) bool {
+	return namespaceIsManaged(ctx, r.Client, namespace)
+}
+
+// namespaceIsManaged checks if the namespace has the ambient-code.io/managed label.
+// Shared by the AgenticSession and ScheduledSession controllers.
+func namespaceIsManaged(ctx context.Context, c client.Reader, namespace string) bool {
 	ns := &unstructured.Unstructured{}
 	ns.SetGroupVersionKind(schema.GroupVersionKind{
 		Group:   "",
@@ -165,7 +216,7 @@ func (r *AgenticSessionReconciler) isNamespaceManaged(ctx context.Context, names
 		Kind:    "Namespace",
 	})
 
-	if err := r.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+	if err := c.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
 		return false
 	}
 
@@ -197,12 +248,6 @@ func (r *AgenticSessionReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	}
 
 	// Watch AgenticSessions with predicates to filter unnecessary events
-	agenticSessionGVK := schema.GroupVersionKind{
-		Group:   "vteam.ambient-code",
-		Version: "v1alpha1",
-		Kind:    "AgenticSession",
-	}
-
 	u := &unstructured.Unstructured{}
 	u.SetGroupVersionKind(agenticSessionGVK)
 
@@ -217,6 +262,10 @@ func (r *AgenticSessionReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			if e.ObjectNew.GetGeneration() != e.ObjectOld.GetGeneration() {
 				return true
 			}
+			// Process deletion, which waits for the usage finalizer
+			if e.ObjectNew.GetDeletionTimestamp() != nil && e.ObjectOld.GetDeletionTimestamp() == nil {
+				return true
+			}
 			// Process if annotations changed (desired-phase, etc.)
 			oldAnns := e.ObjectOld.GetAnnotations()
 			newAnns := e.ObjectNew.GetAnnotations()
@@ -244,6 +293,39 @@ func (r *AgenticSessionReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		return fmt.Errorf("unable to watch AgenticSessions: %w", err)
 	}
 
+	// Re-check Queued sessions as soon as a slot may have been released or the project's
+	// quota changed, instead of having every Queued session poll for a free slot
+	queuedSessions := handler.TypedEnqueueRequestsFromMapFunc(r.queuedSessionsInNamespace)
+	if err := c.Watch(
+		source.Kind(mgr.GetCache(), u, queuedSessions, predicate.TypedFuncs[*unstructured.Unstructured]{
+			CreateFunc: func(e event.TypedCreateEvent[*unstructured.Unstructured]) bool { return false },
+			UpdateFunc: func(e event.TypedUpdateEvent[*unstructured.Unstructured]) bool {
+				return releasesAdmissionSlot(sessionPhase(e.ObjectOld), sessionPhase(e.ObjectNew))
+			},
+			DeleteFunc: func(e event.TypedDeleteEvent[*unstructured.Unstructured]) bool {
+				return activeSessionPhases[sessionPhase(e.Object)]
+			},
+			GenericFunc: func(e event.TypedGenericEvent[*unstructured.Unstructured]) bool { return false },
+		}),
+	); err != nil {
+		return fmt.Errorf("unable to watch AgenticSession slot releases: %w", err)
+	}
+
+	ps := &unstructured.Unstructured{}
+	ps.SetGroupVersionKind(projectSettingsGVK)
+	if err := c.Watch(
+		source.Kind(mgr.GetCache(), ps, queuedSessions, predicate.TypedFuncs[*unstructured.Unstructured]{
+			CreateFunc: func(e event.TypedCreateEvent[*unstructured.Unstructured]) bool { return false },
+			UpdateFunc: func(e event.TypedUpdateEvent[*unstructured.Unstructured]) bool {
+				return e.ObjectNew.GetGeneration() != e.ObjectOld.GetGeneration()
+			},
+			DeleteFunc:  func(e event.TypedDeleteEvent[*unstructured.Unstructured]) bool { return true },
+			GenericFunc: func(e event.TypedGenericEvent[*unstructured.Unstructured]) bool { return false },
+		}),
+	); err != nil {
+		return fmt.Errorf("unable to watch ProjectSettings: %w", err)
+	}
+
 	// Watch Pods and trigger reconcile on parent AgenticSession
 	// This eliminates polling delays - we react immediately when pod status changes
 	podHandler := handler.TypedEnqueueRequestForOwner[*corev1.Pod](
@@ -286,6 +368,40 @@ func GetGVR() schema.GroupVersionResource {
 	return optypes.GetAgenticSessionResource()
 }
 
+// activeSessionPhases are the phases that count against the project's concurrency quota
+var activeSessionPhases = map[string]bool{"Creating": true, "Running": true, "Stopping": true}
+
+func sessionPhase(session *unstructured.Unstructured) string {
+	phase, _, _ := unstructured.NestedString(session.Object, "status", "phase")
+	return phase
+}
+
+// releasesAdmissionSlot reports whether a phase change frees a concurrency slot.
+func releasesAdmissionSlot(oldPhase, newPhase string) bool {
+	return activeSessionPhases[oldPhase] && !activeSessionPhases[newPhase]
+}
+
+// queuedSessionsInNamespace maps an event to reconcile requests for every Queued session in
+// the object's namespace so they re-run admission.
+func (r *AgenticSessionReconciler) queuedSessionsInNamespace(ctx context.Context, obj *unstructured.Unstructured) []reconcile.Request {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(agenticSessionGVK.GroupVersion().WithKind("AgenticSessionList"))
+	if err := r.List(ctx, list, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list queued sessions", "namespace", obj.GetNamespace())
+		return nil
+	}
+	var requests []reconcile.Request
+	for i := range list.Items {
+		if sessionPhase(&list.Items[i]) == "Queued" {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+				Namespace: list.Items[i].GetNamespace(),
+				Name:      list.Items[i].GetName(),
+			}})
+		}
+	}
+	return requests
+}
+
 // mapsEqual compares two string maps for equality
 func mapsEqual(a, b map[string]string) bool {
 	if len(a) != len(b) {
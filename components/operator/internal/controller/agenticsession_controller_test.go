@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"ambient-code-shared/budget"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func newTestSession(namespace, name, phase string) *unstructured.Unstructured {
+	s := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec":   map[string]interface{}{},
+		"status": map[string]interface{}{"phase": phase},
+	}}
+	s.SetGroupVersionKind(agenticSessionGVK)
+	s.SetNamespace(namespace)
+	s.SetName(name)
+	return s
+}
+
+func TestReleasesAdmissionSlot(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{"Running", "Completed", true},
+		{"Creating", "Failed", true},
+		{"Stopping", "Stopped", true},
+		{"Running", "Stopping", false},
+		{"Pending", "Creating", false},
+		{"Queued", "Pending", false},
+		{"Completed", "Pending", false},
+	}
+	for _, tt := range tests {
+		if got := releasesAdmissionSlot(tt.from, tt.to); got != tt.want {
+			t.Errorf("releasesAdmissionSlot(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestQueuedSessionsInNamespace(t *testing.T) {
+	finished := newTestSession(testScheduleNamespace, "finished", "Completed")
+	r := &AgenticSessionReconciler{Client: newTestClient(t,
+		finished,
+		newTestSession(testScheduleNamespace, "queued-a", "Queued"),
+		newTestSession(testScheduleNamespace, "queued-b", "Queued"),
+		newTestSession(testScheduleNamespace, "running", "Running"),
+		newTestSession("other-project", "queued-elsewhere", "Queued"),
+	)}
+
+	requests := r.queuedSessionsInNamespace(context.Background(), finished)
+
+	got := map[string]bool{}
+	for _, req := range requests {
+		if req.Namespace != testScheduleNamespace {
+			t.Errorf("unexpected request for namespace %s", req.Namespace)
+		}
+		got[req.Name] = true
+	}
+	if len(got) != 2 || !got["queued-a"] || !got["queued-b"] {
+		t.Errorf("expected requests for queued-a and queued-b, got %v", requests)
+	}
+}
+
+func TestReconcileDeletedRemovesUsageFinalizer(t *testing.T) {
+	session := newTestSession(testScheduleNamespace, "deleted", "Completed")
+	session.SetFinalizers([]string{budget.UsageFinalizer})
+	now := metav1.Now()
+	session.SetDeletionTimestamp(&now)
+	r := &AgenticSessionReconciler{Client: newTestClient(t, session)}
+
+	if _, err := r.reconcileDeleted(context.Background(), session); err != nil {
+		t.Fatalf("reconcileDeleted() error = %v", err)
+	}
+	got := newTestSession(testScheduleNamespace, "deleted", "")
+	err := r.Get(context.Background(), types.NamespacedName{Namespace: testScheduleNamespace, Name: "deleted"}, got)
+	if !errors.IsNotFound(err) {
+		t.Errorf("expected the session to be gone once its finalizer is removed, got %v (finalizers %v)", err, got.GetFinalizers())
+	}
+}
@@ -0,0 +1,202 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed five-field cron expression
+// (minute hour day-of-month month day-of-week). Each field is stored as a
+// bitmask of permitted values.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// domStar/dowStar record whether the day fields were unrestricted. As in
+	// Vixie cron, when both day fields are restricted a time matches if
+	// EITHER of them matches.
+	domStar, dowStar bool
+}
+
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	cronMinute = cronField{name: "minute", min: 0, max: 59}
+	cronHour   = cronField{name: "hour", min: 0, max: 23}
+	cronDom    = cronField{name: "day-of-month", min: 1, max: 31}
+	cronMonth  = cronField{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// Day-of-week accepts 0-7 where both 0 and 7 are Sunday.
+	cronDow = cronField{name: "day-of-week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+
+	cronDescriptors = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+)
+
+// maxCronSearchYears bounds Next() for expressions that can never fire (e.g. "0 0 30 2 *").
+const maxCronSearchYears = 5
+
+// parseCronSchedule parses a standard five-field cron expression or one of the
+// @yearly/@monthly/@weekly/@daily/@hourly descriptors.
+func parseCronSchedule(spec string) (*cronSchedule, error) {
+	spec = strings.TrimSpace(spec)
+	if expanded, ok := cronDescriptors[strings.ToLower(spec)]; ok {
+		spec = expanded
+	}
+	if strings.HasPrefix(spec, "@") {
+		return nil, fmt.Errorf("unsupported schedule descriptor %q", spec)
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields (minute hour day-of-month month day-of-week), got %d", len(fields))
+	}
+
+	s := &cronSchedule{}
+	var err error
+	if s.minute, err = parseCronField(fields[0], cronMinute); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseCronField(fields[1], cronHour); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseCronField(fields[2], cronDom); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseCronField(fields[3], cronMonth); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseCronField(fields[4], cronDow); err != nil {
+		return nil, err
+	}
+	// Fold Sunday=7 onto Sunday=0
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+		s.dow &^= 1 << 7
+	}
+	s.domStar = fields[2] == "*" || fields[2] == "?"
+	s.dowStar = fields[4] == "*" || fields[4] == "?"
+	return s, nil
+}
+
+// parseCronField parses a comma-separated list of values, ranges (a-b) and
+// steps (*/n, a-b/n, a/n) into a bitmask.
+func parseCronField(expr string, f cronField) (uint64, error) {
+	var mask uint64
+	for _, part := range strings.Split(expr, ",") {
+		if part == "" {
+			return 0, fmt.Errorf("empty value in %s field %q", f.name, expr)
+		}
+
+		rangePart, step := part, 1
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			rangePart = part[:idx]
+			n, err := strconv.Atoi(part[idx+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %s field %q", f.name, part)
+			}
+			step = n
+		}
+
+		var lo, hi int
+		switch {
+		case rangePart == "*" || rangePart == "?":
+			lo, hi = f.min, f.max
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if lo, err = parseCronValue(bounds[0], f); err != nil {
+				return 0, err
+			}
+			if hi, err = parseCronValue(bounds[1], f); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range in %s field %q", f.name, part)
+			}
+		default:
+			v, err := parseCronValue(rangePart, f)
+			if err != nil {
+				return 0, err
+			}
+			lo, hi = v, v
+			// "a/n" means "starting at a, every n"
+			if step > 1 {
+				hi = f.max
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			mask |= 1 << uint(v)
+		}
+	}
+	return mask, nil
+}
+
+func parseCronValue(s string, f cronField) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q in %s field", s, f.name)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("%s value %d out of range [%d-%d]", f.name, v, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Next returns the first activation time strictly after t, evaluated in t's
+// location. It returns the zero time if the schedule never fires.
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxCronSearchYears, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronSchedule_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"@every 5m",
+	} {
+		if _, err := parseCronSchedule(spec); err == nil {
+			t.Errorf("parseCronSchedule(%q) expected error", spec)
+		}
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	base := time.Date(2026, 1, 15, 10, 30, 0, 0, time.UTC) // Thursday
+
+	tests := []struct {
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"*/15 * * * *", base, time.Date(2026, 1, 15, 10, 45, 0, 0, time.UTC)},
+		{"0 2 * * *", base, time.Date(2026, 1, 16, 2, 0, 0, 0, time.UTC)},
+		{"@daily", base, time.Date(2026, 1, 16, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", base, time.Date(2026, 1, 15, 11, 0, 0, 0, time.UTC)},
+		{"0 9 * * mon-fri", time.Date(2026, 1, 16, 10, 0, 0, 0, time.UTC), time.Date(2026, 1, 19, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", base, time.Date(2026, 1, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 feb *", base, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: either matches (the 20th or any Monday)
+		{"0 0 20 * 1", base, time.Date(2026, 1, 19, 0, 0, 0, 0, time.UTC)},
+		// Strictly after: an exact match is skipped
+		{"30 10 * * *", base, time.Date(2026, 1, 16, 10, 30, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		sched, err := parseCronSchedule(tt.spec)
+		if err != nil {
+			t.Fatalf("parseCronSchedule(%q): %v", tt.spec, err)
+		}
+		if got := sched.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("%q.Next(%s) = %s, want %s", tt.spec, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestCronScheduleNext_NeverFires(t *testing.T) {
+	sched, err := parseCronSchedule("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := sched.Next(time.Now()); !got.IsZero() {
+		t.Errorf("expected zero time for impossible schedule, got %s", got)
+	}
+}
+
+func TestMostRecentScheduleTime(t *testing.T) {
+	sched, _ := parseCronSchedule("0 * * * *")
+	now := time.Date(2026, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	got := mostRecentScheduleTime(sched, now.Add(-3*time.Hour), now)
+	if want := time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("mostRecentScheduleTime = %s, want %s", got, want)
+	}
+
+	if got := mostRecentScheduleTime(sched, now.Add(-10*time.Minute), now); !got.IsZero() {
+		t.Errorf("expected no due run, got %s", got)
+	}
+}
+
+func TestScheduledSessionName(t *testing.T) {
+	ts := time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)
+	if got, want := scheduledSessionName("nightly", ts), "nightly-29474520"; got != want {
+		t.Errorf("scheduledSessionName = %q, want %q", got, want)
+	}
+	long := "a-very-long-scheduled-session-name-that-exceeds-the-limit"
+	if got := scheduledSessionName(long, ts); len(got) > maxScheduledNameLen+9 {
+		t.Errorf("scheduledSessionName too long: %q", got)
+	}
+}
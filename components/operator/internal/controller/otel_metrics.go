@@ -4,21 +4,15 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
-	"time"
 
-	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
 	"go.opentelemetry.io/otel/metric"
-	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
-	"go.opentelemetry.io/otel/sdk/resource"
-	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"ambient-code-operator/internal/config"
+	"ambient-code-shared/telemetry"
 )
 
 var (
@@ -32,10 +26,11 @@ var (
 	imagePullDuration      metric.Float64Histogram
 
 	// Session lifecycle metrics (counters)
-	sessionPhaseTransitions metric.Int64Counter
-	sessionsCompleted       metric.Int64Counter
-	sessionsByUser          metric.Int64Counter
-	sessionsByProject       metric.Int64Counter
+	sessionPhaseTransitions  metric.Int64Counter
+	sessionsCompleted        metric.Int64Counter
+	sessionsByUser           metric.Int64Counter
+	sessionsByProject        metric.Int64Counter
+	sessionsGarbageCollected metric.Int64Counter
 
 	// Error metrics (counters)
 	reconcileRetries   metric.Int64Counter
@@ -49,65 +44,19 @@ var (
 // Set OTEL_EXPORTER_OTLP_ENDPOINT to configure the collector address.
 // Leave unset or empty to disable metrics export (no-op).
 func InitMetrics(ctx context.Context) (func(), error) {
-	// Get OTLP endpoint from environment; skip if not configured
-	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
-	if endpoint == "" {
-		log.Println("OTEL_EXPORTER_OTLP_ENDPOINT not set, metrics export disabled")
-		return func() {}, nil
+	m, shutdown, err := telemetry.InitMeter(ctx, "agentic-operator", "ambient-code-operator")
+	if err != nil || m == nil {
+		return shutdown, err
 	}
-
-	// Create resource with service information
-	res, err := resource.New(ctx,
-		resource.WithAttributes(
-			semconv.ServiceName("agentic-operator"),
-			semconv.ServiceVersion(os.Getenv("VERSION")),
-			attribute.String("deployment.environment", os.Getenv("DEPLOYMENT_ENV")),
-		),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create resource: %w", err)
-	}
-
-	// Create OTLP exporter
-	exporter, err := otlpmetricgrpc.New(ctx,
-		otlpmetricgrpc.WithEndpoint(endpoint),
-		otlpmetricgrpc.WithInsecure(), // Use TLS in production
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
-	}
-
-	// Create meter provider with periodic reader
-	meterProvider := sdkmetric.NewMeterProvider(
-		sdkmetric.WithResource(res),
-		sdkmetric.WithReader(
-			sdkmetric.NewPeriodicReader(exporter,
-				sdkmetric.WithInterval(30*time.Second),
-			),
-		),
-	)
-
-	// Set global meter provider
-	otel.SetMeterProvider(meterProvider)
-
-	// Get meter for this package
-	meter = meterProvider.Meter("ambient-code-operator")
+	meter = m
 
 	// Initialize metrics
 	if err := initInstruments(); err != nil {
+		shutdown()
 		return nil, fmt.Errorf("failed to initialize instruments: %w", err)
 	}
 
-	log.Println("OpenTelemetry metrics initialized, exporting to:", endpoint)
-
-	// Return shutdown function
-	return func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		if err := meterProvider.Shutdown(ctx); err != nil {
-			log.Printf("Error shutting down meter provider: %v", err)
-		}
-	}, nil
+	return shutdown, nil
 }
 
 // initInstruments creates all metric instruments
@@ -204,6 +153,15 @@ func initInstruments() error {
 		return fmt.Errorf("failed to create sessionsByProject: %w", err)
 	}
 
+	// Sessions deleted by the garbage collector
+	sessionsGarbageCollected, err = meter.Int64Counter(
+		"ambient.sessions.garbage_collected",
+		metric.WithDescription("Total finished sessions deleted after their ttlSecondsAfterFinished expired"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create sessionsGarbageCollected: %w", err)
+	}
+
 	// === COUNTERS (Error metrics) ===
 
 	// Reconcile retries
@@ -299,6 +257,24 @@ func initGauges() error {
 		return fmt.Errorf("failed to create sessions.pending gauge: %w", err)
 	}
 
+	// Queued sessions gauge (held back by project concurrency quotas)
+	_, err = meter.Int64ObservableGauge(
+		"ambient.sessions.queued",
+		metric.WithDescription("Number of sessions waiting for a project concurrency slot"),
+		metric.WithInt64Callback(func(ctx context.Context, o metric.Int64Observer) error {
+			counts := countSessionsByPhase(ctx, "Queued")
+			for ns, count := range counts {
+				o.Observe(count, metric.WithAttributes(
+					attribute.String("namespace", ns),
+				))
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create sessions.queued gauge: %w", err)
+	}
+
 	// S3 storage bytes gauge
 	_, err = meter.Int64ObservableGauge(
 		"ambient.s3.storage.bytes",
@@ -433,6 +409,19 @@ func RecordSessionCreatedByProject(namespace string) {
 		metric.WithAttributes(attribute.String("namespace", namespace)))
 }
 
+// RecordSessionGarbageCollected is called by the session garbage collector, which runs
+// whether or not metrics export is enabled.
+func RecordSessionGarbageCollected(namespace, finalPhase string) {
+	if sessionsGarbageCollected == nil {
+		return
+	}
+	sessionsGarbageCollected.Add(context.Background(), 1,
+		metric.WithAttributes(
+			attribute.String("namespace", namespace),
+			attribute.String("final_phase", finalPhase),
+		))
+}
+
 // === Error counters ===
 
 func RecordReconcileRetry(namespace, phase string) {
@@ -2,6 +2,7 @@ package controller
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
 	"strings"
 	"time"
@@ -16,6 +17,10 @@ import (
 	"ambient-code-operator/internal/handlers"
 )
 
+// queuedSessionRecheckInterval is the safety-net recheck for Queued sessions. Slot releases
+// and ProjectSettings changes re-run admission immediately (see SetupWithManager).
+const queuedSessionRecheckInterval = 5 * time.Minute
+
 // recordPhaseTransition records a phase transition
 func recordPhaseTransition(namespace, fromPhase, toPhase string) {
 	if fromPhase == "" {
@@ -154,10 +159,17 @@ func (r *AgenticSessionReconciler) reconcilePending(ctx context.Context, session
 	name := session.GetName()
 	namespace := session.GetNamespace()
 
-	logger.Info("Processing Pending session", "name", name, "namespace", namespace)
+	phase, _, _ := unstructured.NestedString(session.Object, "status", "phase")
+	if phase == "" {
+		phase = "Pending"
+	}
+
+	logger.Info("Processing Pending session", "name", name, "namespace", namespace, "phase", phase)
 
-	// Record that a new session is being processed
-	recordSessionCreated(namespace, session)
+	// Record that a new session is being processed (once, not on every queue re-check)
+	if phase != "Queued" {
+		recordSessionCreated(namespace, session)
+	}
 
 	// Check for desired-phase annotation (user-requested state transitions)
 	annotations := session.GetAnnotations()
@@ -169,7 +181,7 @@ func (r *AgenticSessionReconciler) reconcilePending(ctx context.Context, session
 	// If user wants to stop, don't create pod
 	if desiredPhase == "Stopped" {
 		logger.Info("Session has desired-phase=Stopped, skipping pod creation", "name", name)
-		recordPhaseTransition(namespace, "Pending", "Stopped")
+		recordPhaseTransition(namespace, phase, "Stopped")
 		recordSessionCompleted(namespace, "Stopped", session)
 		if err := handlers.TransitionToStopped(ctx, session); err != nil {
 			logger.Error(err, "Failed to transition to Stopped", "name", name)
@@ -181,6 +193,13 @@ func (r *AgenticSessionReconciler) reconcilePending(ctx context.Context, session
 	// Delegate to existing handler logic (refactored to be called from here)
 	// This preserves all the existing pod creation, secret handling, etc.
 	if err := handlers.ReconcilePendingSession(ctx, session, r.appConfig); err != nil {
+		if stderrors.Is(err, handlers.ErrSessionQueued) {
+			if phase != "Queued" {
+				recordPhaseTransition(namespace, phase, "Queued")
+			}
+			// Re-checked when a slot is released; the requeue only guards against missed events
+			return ctrl.Result{RequeueAfter: queuedSessionRecheckInterval}, nil
+		}
 		logger.Error(err, "Failed to reconcile pending session", "name", name)
 		RecordReconcileRetry(namespace, "Pending")
 		// Requeue with backoff
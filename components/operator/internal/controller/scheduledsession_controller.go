@@ -0,0 +1,529 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	_ "time/tzdata" // Embed tz database so spec.timeZone works on minimal base images
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// ScheduledSessionLabel is set on every AgenticSession created by a ScheduledSession
+	// and holds the ScheduledSession's name. Used to list a schedule's children.
+	ScheduledSessionLabel = "ambient-code.io/scheduled-session"
+	// scheduledAtAnnotation records the cron time a session was created for.
+	scheduledAtAnnotation = "ambient-code.io/scheduled-at"
+
+	concurrencyPolicyAllow   = "Allow"
+	concurrencyPolicyForbid  = "Forbid"
+	concurrencyPolicyReplace = "Replace"
+
+	defaultSuccessfulSessionsHistoryLimit = 3
+	defaultFailedSessionsHistoryLimit     = 1
+
+	// maxScheduledNameLen keeps generated session names (<schedule>-<minutes>) well
+	// under the 63 char limit once the operator adds its own prefixes/suffixes.
+	maxScheduledNameLen = 40
+
+	// maxMissedSchedules caps how many missed start times are walked before the
+	// schedule gives up on catching up and simply uses the most recent one.
+	maxMissedSchedules = 100
+)
+
+var (
+	scheduledSessionGVK = schema.GroupVersionKind{
+		Group:   "vteam.ambient-code",
+		Version: "v1alpha1",
+		Kind:    "ScheduledSession",
+	}
+	agenticSessionGVK = schema.GroupVersionKind{
+		Group:   "vteam.ambient-code",
+		Version: "v1alpha1",
+		Kind:    "AgenticSession",
+	}
+	projectSettingsGVK = schema.GroupVersionKind{
+		Group:   "vteam.ambient-code",
+		Version: "v1alpha1",
+		Kind:    "ProjectSettings",
+	}
+)
+
+// ScheduledSessionReconciler reconciles ScheduledSession resources by stamping out
+// AgenticSessions from spec.template whenever the cron schedule fires.
+type ScheduledSessionReconciler struct {
+	client.Client
+
+	// now is overridable for tests
+	now func() time.Time
+}
+
+// NewScheduledSessionReconciler creates a new ScheduledSession reconciler.
+func NewScheduledSessionReconciler(c client.Client) *ScheduledSessionReconciler {
+	return &ScheduledSessionReconciler{
+		Client: c,
+		now:    time.Now,
+	}
+}
+
+// scheduledSessionSpec is the subset of spec fields the reconciler acts on.
+type scheduledSessionSpec struct {
+	schedule                string
+	timeZone                string
+	suspend                 bool
+	concurrencyPolicy       string
+	startingDeadlineSeconds *int64
+	successfulHistoryLimit  int64
+	failedHistoryLimit      int64
+}
+
+func parseScheduledSessionSpec(obj *unstructured.Unstructured) scheduledSessionSpec {
+	spec := scheduledSessionSpec{
+		concurrencyPolicy:      concurrencyPolicyForbid,
+		successfulHistoryLimit: defaultSuccessfulSessionsHistoryLimit,
+		failedHistoryLimit:     defaultFailedSessionsHistoryLimit,
+	}
+	spec.schedule, _, _ = unstructured.NestedString(obj.Object, "spec", "schedule")
+	spec.timeZone, _, _ = unstructured.NestedString(obj.Object, "spec", "timeZone")
+	spec.suspend, _, _ = unstructured.NestedBool(obj.Object, "spec", "suspend")
+	if p, found, _ := unstructured.NestedString(obj.Object, "spec", "concurrencyPolicy"); found && p != "" {
+		spec.concurrencyPolicy = p
+	}
+	if v, found, _ := unstructured.NestedInt64(obj.Object, "spec", "startingDeadlineSeconds"); found {
+		spec.startingDeadlineSeconds = &v
+	}
+	if v, found, _ := unstructured.NestedInt64(obj.Object, "spec", "successfulSessionsHistoryLimit"); found && v >= 0 {
+		spec.successfulHistoryLimit = v
+	}
+	if v, found, _ := unstructured.NestedInt64(obj.Object, "spec", "failedSessionsHistoryLimit"); found && v >= 0 {
+		spec.failedHistoryLimit = v
+	}
+	return spec
+}
+
+// Reconcile evaluates a single ScheduledSession: it prunes finished sessions beyond the
+// history limits, creates a new AgenticSession if a scheduled time has passed, and
+// requeues itself for the next scheduled time.
+func (r *ScheduledSessionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	ss := &unstructured.Unstructured{}
+	ss.SetGroupVersionKind(scheduledSessionGVK)
+	if err := r.Get(ctx, req.NamespacedName, ss); err != nil {
+		if errors.IsNotFound(err) {
+			// Object deleted - child sessions are cleaned up via OwnerReferences
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, fmt.Errorf("failed to get ScheduledSession: %w", err)
+	}
+
+	if !namespaceIsManaged(ctx, r.Client, ss.GetNamespace()) {
+		logger.V(2).Info("Skipping unmanaged namespace", "namespace", ss.GetNamespace())
+		return ctrl.Result{}, nil
+	}
+
+	spec := parseScheduledSessionSpec(ss)
+	now := r.now()
+
+	children, err := r.listChildSessions(ctx, ss)
+	if err != nil {
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, err
+	}
+
+	active, successful, failed := classifyChildSessions(children)
+	r.pruneHistory(ctx, successful, spec.successfulHistoryLimit)
+	r.pruneHistory(ctx, failed, spec.failedHistoryLimit)
+
+	if t := latestCompletionTime(successful); t != "" {
+		_ = unstructured.SetNestedField(ss.Object, t, "status", "lastSuccessfulTime")
+	}
+	setScheduledSessionActive(ss, active)
+
+	sched, loc, err := resolveSchedule(spec)
+	if err != nil {
+		logger.Info("Invalid schedule", "name", ss.GetName(), "schedule", spec.schedule, "error", err.Error())
+		setScheduledSessionCondition(ss, "False", "InvalidSchedule", err.Error())
+		unstructured.RemoveNestedField(ss.Object, "status", "nextScheduleTime")
+		return ctrl.Result{}, r.updateStatus(ctx, ss)
+	}
+
+	if spec.suspend {
+		setScheduledSessionCondition(ss, "False", "Suspended", "Schedule is suspended")
+		unstructured.RemoveNestedField(ss.Object, "status", "nextScheduleTime")
+		return ctrl.Result{}, r.updateStatus(ctx, ss)
+	}
+
+	nowLocal := now.In(loc)
+	next := sched.Next(nowLocal)
+	if next.IsZero() {
+		setScheduledSessionCondition(ss, "False", "InvalidSchedule", "Schedule never fires")
+		unstructured.RemoveNestedField(ss.Object, "status", "nextScheduleTime")
+		return ctrl.Result{}, r.updateStatus(ctx, ss)
+	}
+	_ = unstructured.SetNestedField(ss.Object, next.UTC().Format(time.RFC3339), "status", "nextScheduleTime")
+	setScheduledSessionCondition(ss, "True", "Scheduled", fmt.Sprintf("Next run at %s", next.UTC().Format(time.RFC3339)))
+	result := ctrl.Result{RequeueAfter: next.Sub(now)}
+
+	scheduledTime := mostRecentScheduleTime(sched, r.earliestScheduleTime(ss, spec, now).In(loc), nowLocal)
+	if scheduledTime.IsZero() {
+		return result, r.updateStatus(ctx, ss)
+	}
+
+	if spec.startingDeadlineSeconds != nil && now.Sub(scheduledTime) > time.Duration(*spec.startingDeadlineSeconds)*time.Second {
+		logger.Info("Missed starting deadline for scheduled run", "name", ss.GetName(), "scheduledTime", scheduledTime)
+		return result, r.updateStatus(ctx, ss)
+	}
+
+	if len(active) > 0 {
+		switch spec.concurrencyPolicy {
+		case concurrencyPolicyForbid:
+			logger.V(1).Info("Skipping scheduled run, previous session still active",
+				"name", ss.GetName(), "active", len(active))
+			return result, r.updateStatus(ctx, ss)
+		case concurrencyPolicyReplace:
+			for _, s := range active {
+				if err := r.requestStop(ctx, s); err != nil {
+					return ctrl.Result{RequeueAfter: 5 * time.Second}, err
+				}
+				logger.Info("Stopping active session to replace it", "name", ss.GetName(), "session", s.GetName())
+			}
+		}
+	}
+
+	session, err := buildScheduledAgenticSession(ss, scheduledTime)
+	if err != nil {
+		setScheduledSessionCondition(ss, "False", "InvalidTemplate", err.Error())
+		return ctrl.Result{}, r.updateStatus(ctx, ss)
+	}
+	if err := r.Create(ctx, session); err != nil && !errors.IsAlreadyExists(err) {
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, fmt.Errorf("failed to create scheduled AgenticSession: %w", err)
+	}
+	logger.Info("Created scheduled AgenticSession",
+		"name", ss.GetName(),
+		"namespace", ss.GetNamespace(),
+		"session", session.GetName(),
+		"scheduledTime", scheduledTime.UTC().Format(time.RFC3339),
+	)
+
+	setScheduledSessionActive(ss, append(active, session))
+	_ = unstructured.SetNestedField(ss.Object, scheduledTime.UTC().Format(time.RFC3339), "status", "lastScheduleTime")
+	return result, r.updateStatus(ctx, ss)
+}
+
+// resolveSchedule parses the cron expression and time zone (UTC by default).
+func resolveSchedule(spec scheduledSessionSpec) (*cronSchedule, *time.Location, error) {
+	sched, err := parseCronSchedule(spec.schedule)
+	if err != nil {
+		return nil, nil, err
+	}
+	loc := time.UTC
+	if spec.timeZone != "" {
+		loc, err = time.LoadLocation(spec.timeZone)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid timeZone %q: %w", spec.timeZone, err)
+		}
+	}
+	return sched, loc, nil
+}
+
+// earliestScheduleTime returns the point after which missed runs are considered:
+// the last schedule time, or creation time for a schedule that has never run,
+// bounded by startingDeadlineSeconds.
+func (r *ScheduledSessionReconciler) earliestScheduleTime(ss *unstructured.Unstructured, spec scheduledSessionSpec, now time.Time) time.Time {
+	earliest := ss.GetCreationTimestamp().Time
+	if last, found, _ := unstructured.NestedString(ss.Object, "status", "lastScheduleTime"); found && last != "" {
+		if t, err := time.Parse(time.RFC3339, last); err == nil {
+			earliest = t
+		}
+	}
+	if spec.startingDeadlineSeconds != nil {
+		deadline := now.Add(-time.Duration(*spec.startingDeadlineSeconds) * time.Second)
+		if earliest.Before(deadline) {
+			earliest = deadline
+		}
+	}
+	return earliest
+}
+
+// mostRecentScheduleTime returns the latest activation in (earliest, now], or the
+// zero time if none is due.
+func mostRecentScheduleTime(sched *cronSchedule, earliest, now time.Time) time.Time {
+	var latest time.Time
+	missed := 0
+	for t := sched.Next(earliest); !t.IsZero() && !t.After(now); t = sched.Next(t) {
+		latest = t
+		missed++
+		if missed > maxMissedSchedules {
+			// Too many missed runs (long outage); jump to the last window before now.
+			for t2 := sched.Next(now.Add(-24 * time.Hour)); !t2.IsZero() && !t2.After(now); t2 = sched.Next(t2) {
+				latest = t2
+			}
+			break
+		}
+	}
+	return latest
+}
+
+// listChildSessions returns the AgenticSessions owned by the given ScheduledSession.
+func (r *ScheduledSessionReconciler) listChildSessions(ctx context.Context, ss *unstructured.Unstructured) ([]unstructured.Unstructured, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(agenticSessionGVK.GroupVersion().WithKind("AgenticSessionList"))
+	if err := r.List(ctx, list,
+		client.InNamespace(ss.GetNamespace()),
+		client.MatchingLabels{ScheduledSessionLabel: ss.GetName()},
+	); err != nil {
+		return nil, fmt.Errorf("failed to list scheduled sessions: %w", err)
+	}
+
+	children := make([]unstructured.Unstructured, 0, len(list.Items))
+	for _, item := range list.Items {
+		for _, ref := range item.GetOwnerReferences() {
+			if ref.UID == ss.GetUID() {
+				children = append(children, item)
+				break
+			}
+		}
+	}
+	return children, nil
+}
+
+// classifyChildSessions splits sessions into active, successful (Completed) and
+// failed (Failed or Stopped) buckets. Finished buckets are sorted oldest first.
+func classifyChildSessions(children []unstructured.Unstructured) (active, successful, failed []*unstructured.Unstructured) {
+	for i := range children {
+		s := &children[i]
+		phase, _, _ := unstructured.NestedString(s.Object, "status", "phase")
+		switch phase {
+		case "Completed":
+			successful = append(successful, s)
+		case "Failed", "Stopped":
+			failed = append(failed, s)
+		default:
+			active = append(active, s)
+		}
+	}
+	byCreation := func(list []*unstructured.Unstructured) {
+		sort.SliceStable(list, func(i, j int) bool {
+			return list[i].GetCreationTimestamp().Time.Before(list[j].GetCreationTimestamp().Time)
+		})
+	}
+	byCreation(active)
+	byCreation(successful)
+	byCreation(failed)
+	return active, successful, failed
+}
+
+// pruneHistory deletes the oldest finished sessions beyond limit.
+func (r *ScheduledSessionReconciler) pruneHistory(ctx context.Context, finished []*unstructured.Unstructured, limit int64) {
+	logger := log.FromContext(ctx)
+	excess := len(finished) - int(limit)
+	for i := 0; i < excess; i++ {
+		s := finished[i]
+		if err := r.Delete(ctx, s, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !errors.IsNotFound(err) {
+			logger.Error(err, "Failed to prune scheduled session", "session", s.GetName())
+			continue
+		}
+		logger.Info("Pruned scheduled session beyond history limit", "session", s.GetName(), "namespace", s.GetNamespace())
+	}
+}
+
+// requestStop signals the operator to stop a session the same way the backend's
+// StopSession handler does (desired-phase annotation).
+func (r *ScheduledSessionReconciler) requestStop(ctx context.Context, session *unstructured.Unstructured) error {
+	patch := client.MergeFrom(session.DeepCopy())
+	annotations := session.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if annotations["ambient-code.io/desired-phase"] == "Stopped" {
+		return nil
+	}
+	annotations["ambient-code.io/desired-phase"] = "Stopped"
+	annotations["ambient-code.io/stop-requested-at"] = time.Now().Format(time.RFC3339)
+	session.SetAnnotations(annotations)
+	if err := r.Patch(ctx, session, patch); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to stop session %s: %w", session.GetName(), err)
+	}
+	return nil
+}
+
+// buildScheduledAgenticSession renders spec.template into a new AgenticSession for
+// the given scheduled time. The name is derived from the scheduled time so that a
+// retried reconcile for the same run hits AlreadyExists instead of duplicating it.
+func buildScheduledAgenticSession(ss *unstructured.Unstructured, scheduledTime time.Time) (*unstructured.Unstructured, error) {
+	templateSpec, found, err := unstructured.NestedMap(ss.Object, "spec", "template", "spec")
+	if err != nil || !found {
+		return nil, fmt.Errorf("spec.template.spec is missing or invalid")
+	}
+
+	name := scheduledSessionName(ss.GetName(), scheduledTime)
+	templateSpec["project"] = ss.GetNamespace()
+	if dn, _ := templateSpec["displayName"].(string); strings.TrimSpace(dn) == "" {
+		templateSpec["displayName"] = fmt.Sprintf("%s (%s)", ss.GetName(), scheduledTime.UTC().Format("2006-01-02 15:04 UTC"))
+	}
+	// Fill in auto-branch for repos without an explicit branch (see backend ComputeAutoBranch)
+	if repos, ok := templateSpec["repos"].([]interface{}); ok {
+		for _, entry := range repos {
+			if repo, ok := entry.(map[string]interface{}); ok {
+				if b, _ := repo["branch"].(string); strings.TrimSpace(b) == "" {
+					repo["branch"] = fmt.Sprintf("ambient/%s", name)
+				}
+			}
+		}
+	}
+
+	labels := map[string]string{}
+	if tmplLabels, found, _ := unstructured.NestedStringMap(ss.Object, "spec", "template", "metadata", "labels"); found {
+		for k, v := range tmplLabels {
+			labels[k] = v
+		}
+	}
+	labels[ScheduledSessionLabel] = ss.GetName()
+
+	annotations := map[string]string{}
+	if tmplAnns, found, _ := unstructured.NestedStringMap(ss.Object, "spec", "template", "metadata", "annotations"); found {
+		for k, v := range tmplAnns {
+			annotations[k] = v
+		}
+	}
+	annotations[scheduledAtAnnotation] = scheduledTime.UTC().Format(time.RFC3339)
+	if createdBy := ss.GetAnnotations()["ambient-code.io/created-by"]; createdBy != "" {
+		annotations["ambient-code.io/created-by"] = createdBy
+	}
+
+	session := &unstructured.Unstructured{Object: map[string]interface{}{"spec": templateSpec}}
+	session.SetGroupVersionKind(agenticSessionGVK)
+	session.SetName(name)
+	session.SetNamespace(ss.GetNamespace())
+	session.SetLabels(labels)
+	session.SetAnnotations(annotations)
+	controllerRef := true
+	session.SetOwnerReferences([]metav1.OwnerReference{{
+		APIVersion: ss.GetAPIVersion(),
+		Kind:       ss.GetKind(),
+		Name:       ss.GetName(),
+		UID:        ss.GetUID(),
+		Controller: &controllerRef,
+	}})
+	return session, nil
+}
+
+// scheduledSessionName returns "<schedule>-<minutes since epoch>".
+func scheduledSessionName(scheduleName string, scheduledTime time.Time) string {
+	base := scheduleName
+	if len(base) > maxScheduledNameLen {
+		base = strings.TrimRight(base[:maxScheduledNameLen], "-")
+	}
+	return fmt.Sprintf("%s-%d", base, scheduledTime.Unix()/60)
+}
+
+func latestCompletionTime(sessions []*unstructured.Unstructured) string {
+	latest := ""
+	for _, s := range sessions {
+		// RFC3339 timestamps in UTC compare correctly as strings
+		if t, _, _ := unstructured.NestedString(s.Object, "status", "completionTime"); t > latest {
+			latest = t
+		}
+	}
+	return latest
+}
+
+func setScheduledSessionActive(ss *unstructured.Unstructured, active []*unstructured.Unstructured) {
+	if len(active) == 0 {
+		unstructured.RemoveNestedField(ss.Object, "status", "active")
+		return
+	}
+	names := make([]interface{}, 0, len(active))
+	for _, s := range active {
+		names = append(names, s.GetName())
+	}
+	_ = unstructured.SetNestedSlice(ss.Object, names, "status", "active")
+}
+
+// setScheduledSessionCondition upserts the Ready condition, preserving
+// lastTransitionTime when the status does not change.
+func setScheduledSessionCondition(ss *unstructured.Unstructured, status, reason, message string) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	conditions, _, _ := unstructured.NestedSlice(ss.Object, "status", "conditions")
+	for i, entry := range conditions {
+		cond, ok := entry.(map[string]interface{})
+		if !ok || cond["type"] != "Ready" {
+			continue
+		}
+		if cond["status"] != status {
+			cond["lastTransitionTime"] = now
+		}
+		cond["status"] = status
+		cond["reason"] = reason
+		cond["message"] = message
+		conditions[i] = cond
+		_ = unstructured.SetNestedSlice(ss.Object, conditions, "status", "conditions")
+		return
+	}
+	conditions = append(conditions, map[string]interface{}{
+		"type":               "Ready",
+		"status":             status,
+		"reason":             reason,
+		"message":            message,
+		"lastTransitionTime": now,
+	})
+	_ = unstructured.SetNestedSlice(ss.Object, conditions, "status", "conditions")
+}
+
+func (r *ScheduledSessionReconciler) updateStatus(ctx context.Context, ss *unstructured.Unstructured) error {
+	_ = unstructured.SetNestedField(ss.Object, ss.GetGeneration(), "status", "observedGeneration")
+	if err := r.Status().Update(ctx, ss); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		// Conflicts are returned as well so the request is requeued with a fresh read
+		return fmt.Errorf("failed to update ScheduledSession status: %w", err)
+	}
+	return nil
+}
+
+// SetupWithManager registers the controller. It watches ScheduledSessions and the
+// AgenticSessions they own, so a child finishing re-evaluates concurrency and history.
+func (r *ScheduledSessionReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	c, err := controller.New("scheduledsession-controller", mgr, controller.Options{
+		Reconciler:              r,
+		MaxConcurrentReconciles: 1,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to create controller: %w", err)
+	}
+
+	ss := &unstructured.Unstructured{}
+	ss.SetGroupVersionKind(scheduledSessionGVK)
+	if err := c.Watch(
+		source.Kind(mgr.GetCache(), ss, &handler.TypedEnqueueRequestForObject[*unstructured.Unstructured]{}),
+	); err != nil {
+		return fmt.Errorf("unable to watch ScheduledSessions: %w", err)
+	}
+
+	session := &unstructured.Unstructured{}
+	session.SetGroupVersionKind(agenticSessionGVK)
+	ownerHandler := handler.TypedEnqueueRequestForOwner[*unstructured.Unstructured](
+		mgr.GetScheme(),
+		mgr.GetRESTMapper(),
+		ss,
+		handler.OnlyControllerOwner(),
+	)
+	if err := c.Watch(source.Kind(mgr.GetCache(), session, ownerHandler)); err != nil {
+		return fmt.Errorf("unable to watch scheduled AgenticSessions: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,265 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testScheduleNamespace = "team-a"
+
+// scheduleCreated is the creation time of every test schedule; "0 9 * * *" first fires an hour later.
+var scheduleCreated = time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+
+func newTestScheduledSession(spec map[string]interface{}) *unstructured.Unstructured {
+	full := map[string]interface{}{
+		"schedule": "0 9 * * *",
+		"template": map[string]interface{}{
+			"spec": map[string]interface{}{"initialPrompt": "Triage new issues"},
+		},
+	}
+	for k, v := range spec {
+		full[k] = v
+	}
+	ss := &unstructured.Unstructured{Object: map[string]interface{}{"spec": full}}
+	ss.SetGroupVersionKind(scheduledSessionGVK)
+	ss.SetName("nightly")
+	ss.SetNamespace(testScheduleNamespace)
+	ss.SetUID("schedule-uid")
+	ss.SetCreationTimestamp(metav1.NewTime(scheduleCreated))
+	return ss
+}
+
+// newTestChildSession returns a session owned by ss in the given phase, created minutesAgo before 09:00.
+func newTestChildSession(ss *unstructured.Unstructured, name, phase string, minutesAgo int) *unstructured.Unstructured {
+	s := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec":   map[string]interface{}{},
+		"status": map[string]interface{}{"phase": phase},
+	}}
+	s.SetGroupVersionKind(agenticSessionGVK)
+	s.SetName(name)
+	s.SetNamespace(ss.GetNamespace())
+	s.SetLabels(map[string]string{ScheduledSessionLabel: ss.GetName()})
+	s.SetCreationTimestamp(metav1.NewTime(scheduleCreated.Add(time.Hour - time.Duration(minutesAgo)*time.Minute)))
+	s.SetOwnerReferences([]metav1.OwnerReference{{
+		APIVersion: ss.GetAPIVersion(),
+		Kind:       ss.GetKind(),
+		Name:       ss.GetName(),
+		UID:        ss.GetUID(),
+	}})
+	return s
+}
+
+// newTestClient returns a fake client holding objs and a managed testScheduleNamespace.
+func newTestClient(t *testing.T, objs ...client.Object) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	scheme.AddKnownTypeWithName(scheduledSessionGVK, &unstructured.Unstructured{})
+	scheme.AddKnownTypeWithName(scheduledSessionGVK.GroupVersion().WithKind("ScheduledSessionList"), &unstructured.UnstructuredList{})
+	scheme.AddKnownTypeWithName(agenticSessionGVK, &unstructured.Unstructured{})
+	scheme.AddKnownTypeWithName(agenticSessionGVK.GroupVersion().WithKind("AgenticSessionList"), &unstructured.UnstructuredList{})
+
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   testScheduleNamespace,
+		Labels: map[string]string{"ambient-code.io/managed": "true"},
+	}}
+	statusObj := &unstructured.Unstructured{}
+	statusObj.SetGroupVersionKind(scheduledSessionGVK)
+
+	return fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(append([]client.Object{ns}, objs...)...).
+		WithStatusSubresource(statusObj).
+		Build()
+}
+
+func newTestScheduledSessionReconciler(t *testing.T, now time.Time, objs ...client.Object) *ScheduledSessionReconciler {
+	t.Helper()
+	r := NewScheduledSessionReconciler(newTestClient(t, objs...))
+	r.now = func() time.Time { return now }
+	return r
+}
+
+func reconcileSchedule(t *testing.T, r *ScheduledSessionReconciler) ctrl.Result {
+	t.Helper()
+	result, err := r.Reconcile(context.Background(), ctrl.Request{
+		NamespacedName: k8stypes.NamespacedName{Namespace: testScheduleNamespace, Name: "nightly"},
+	})
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	return result
+}
+
+func listTestChildren(t *testing.T, r *ScheduledSessionReconciler) map[string]*unstructured.Unstructured {
+	t.Helper()
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(agenticSessionGVK.GroupVersion().WithKind("AgenticSessionList"))
+	if err := r.List(context.Background(), list, client.InNamespace(testScheduleNamespace)); err != nil {
+		t.Fatal(err)
+	}
+	children := map[string]*unstructured.Unstructured{}
+	for i := range list.Items {
+		children[list.Items[i].GetName()] = &list.Items[i]
+	}
+	return children
+}
+
+func getTestSchedule(t *testing.T, r *ScheduledSessionReconciler) *unstructured.Unstructured {
+	t.Helper()
+	ss := &unstructured.Unstructured{}
+	ss.SetGroupVersionKind(scheduledSessionGVK)
+	if err := r.Get(context.Background(), k8stypes.NamespacedName{Namespace: testScheduleNamespace, Name: "nightly"}, ss); err != nil {
+		t.Fatal(err)
+	}
+	return ss
+}
+
+func TestScheduledSessionReconcile_CreatesDueSession(t *testing.T) {
+	now := time.Date(2026, 3, 2, 9, 0, 30, 0, time.UTC)
+	r := newTestScheduledSessionReconciler(t, now, newTestScheduledSession(nil))
+
+	result := reconcileSchedule(t, r)
+
+	want := scheduledSessionName("nightly", time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC))
+	children := listTestChildren(t, r)
+	child, ok := children[want]
+	if !ok || len(children) != 1 {
+		t.Fatalf("expected exactly session %s, got %v", want, keys(children))
+	}
+	if prompt, _, _ := unstructured.NestedString(child.Object, "spec", "initialPrompt"); prompt != "Triage new issues" {
+		t.Errorf("template not applied, initialPrompt = %q", prompt)
+	}
+	if result.RequeueAfter != 24*time.Hour-30*time.Second {
+		t.Errorf("RequeueAfter = %v, want next run in 23h59m30s", result.RequeueAfter)
+	}
+
+	ss := getTestSchedule(t, r)
+	if last, _, _ := unstructured.NestedString(ss.Object, "status", "lastScheduleTime"); last != "2026-03-02T09:00:00Z" {
+		t.Errorf("status.lastScheduleTime = %q", last)
+	}
+
+	// A second reconcile for the same run must not create another session
+	reconcileSchedule(t, r)
+	if got := len(listTestChildren(t, r)); got != 1 {
+		t.Errorf("expected 1 session after re-reconcile, got %d", got)
+	}
+}
+
+func TestScheduledSessionReconcile_ConcurrencyPolicy(t *testing.T) {
+	now := time.Date(2026, 3, 2, 9, 0, 30, 0, time.UTC)
+	due := scheduledSessionName("nightly", time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		policy      string
+		wantCreated bool
+		wantStopped bool
+	}{
+		{concurrencyPolicyForbid, false, false},
+		{concurrencyPolicyAllow, true, false},
+		{concurrencyPolicyReplace, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			ss := newTestScheduledSession(map[string]interface{}{"concurrencyPolicy": tt.policy})
+			running := newTestChildSession(ss, "nightly-previous", "Running", 60)
+			r := newTestScheduledSessionReconciler(t, now, ss, running)
+
+			reconcileSchedule(t, r)
+
+			children := listTestChildren(t, r)
+			if _, created := children[due]; created != tt.wantCreated {
+				t.Errorf("session created = %v, want %v", created, tt.wantCreated)
+			}
+			stopped := children["nightly-previous"].GetAnnotations()["ambient-code.io/desired-phase"] == "Stopped"
+			if stopped != tt.wantStopped {
+				t.Errorf("previous session stopped = %v, want %v", stopped, tt.wantStopped)
+			}
+		})
+	}
+}
+
+func TestScheduledSessionReconcile_PrunesHistory(t *testing.T) {
+	now := time.Date(2026, 3, 2, 8, 30, 0, 0, time.UTC) // nothing due
+	ss := newTestScheduledSession(map[string]interface{}{
+		"successfulSessionsHistoryLimit": int64(2),
+		"failedSessionsHistoryLimit":     int64(0),
+	})
+	objs := []client.Object{ss}
+	for i := 1; i <= 4; i++ {
+		objs = append(objs, newTestChildSession(ss, fmt.Sprintf("completed-%d", i), "Completed", 100-i))
+	}
+	objs = append(objs, newTestChildSession(ss, "failed-1", "Failed", 50))
+	r := newTestScheduledSessionReconciler(t, now, objs...)
+
+	reconcileSchedule(t, r)
+
+	children := listTestChildren(t, r)
+	for _, name := range []string{"completed-1", "completed-2", "failed-1"} {
+		if _, ok := children[name]; ok {
+			t.Errorf("expected %s to be pruned", name)
+		}
+	}
+	for _, name := range []string{"completed-3", "completed-4"} {
+		if _, ok := children[name]; !ok {
+			t.Errorf("expected %s to be kept", name)
+		}
+	}
+}
+
+func TestScheduledSessionReconcile_MissedStartingDeadline(t *testing.T) {
+	now := time.Date(2026, 3, 2, 9, 10, 0, 0, time.UTC) // ten minutes late
+	ss := newTestScheduledSession(map[string]interface{}{"startingDeadlineSeconds": int64(60)})
+	r := newTestScheduledSessionReconciler(t, now, ss)
+
+	result := reconcileSchedule(t, r)
+
+	if got := len(listTestChildren(t, r)); got != 0 {
+		t.Errorf("expected the missed run to be skipped, got %d sessions", got)
+	}
+	if result.RequeueAfter <= 0 {
+		t.Errorf("expected a requeue for the next run, got %v", result)
+	}
+	if next, _, _ := unstructured.NestedString(getTestSchedule(t, r).Object, "status", "nextScheduleTime"); next != "2026-03-03T09:00:00Z" {
+		t.Errorf("status.nextScheduleTime = %q", next)
+	}
+}
+
+func TestScheduledSessionReconcile_Suspended(t *testing.T) {
+	now := time.Date(2026, 3, 2, 9, 0, 30, 0, time.UTC)
+	r := newTestScheduledSessionReconciler(t, now, newTestScheduledSession(map[string]interface{}{"suspend": true}))
+
+	result := reconcileSchedule(t, r)
+
+	if got := len(listTestChildren(t, r)); got != 0 {
+		t.Errorf("expected no sessions while suspended, got %d", got)
+	}
+	if result.RequeueAfter != 0 {
+		t.Errorf("suspended schedules wait for the next spec change, got RequeueAfter %v", result.RequeueAfter)
+	}
+	conditions, _, _ := unstructured.NestedSlice(getTestSchedule(t, r).Object, "status", "conditions")
+	if len(conditions) != 1 || conditions[0].(map[string]interface{})["reason"] != "Suspended" {
+		t.Errorf("unexpected conditions %v", conditions)
+	}
+}
+
+func keys(m map[string]*unstructured.Unstructured) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+)
+
+const (
+	conditionQueued = "Queued"
+
+	admissionPolicyFIFO     = "FIFO"
+	admissionPolicyPriority = "Priority"
+)
+
+// ErrSessionQueued is returned by ReconcilePendingSession when the project's concurrency
+// quota or budget is exhausted and the session has been placed (or kept) in the Queued phase.
+var ErrSessionQueued = stderrors.New("session queued by project quota or budget")
+
+// sessionQuota holds the admission settings from ProjectSettings.
+type sessionQuota struct {
+	MaxConcurrent int64
+	MaxPerUser    int64
+	Policy        string
+}
+
+func (q sessionQuota) enabled() bool {
+	return q.MaxConcurrent > 0 || q.MaxPerUser > 0
+}
+
+// admissionDecision is the outcome of evaluating a waiting session against the quota.
+type admissionDecision struct {
+	Admitted bool
+	Position int
+	Reason   string
+	Message  string
+}
+
+// admissionLocks serializes admission per namespace so two sessions reconciled in
+// parallel cannot both take the last free slot. The locks are in-process, which is
+// sufficient because reconcilers run on a single operator: either the deployment has one
+// replica or --leader-elect=true limits reconciliation to the leader. Running several
+// replicas without leader election can admit more sessions than the quota allows.
+var admissionLocks sync.Map
+
+func lockAdmission(namespace string) func() {
+	mu, _ := admissionLocks.LoadOrStore(namespace, &sync.Mutex{})
+	m := mu.(*sync.Mutex)
+	m.Lock()
+	return m.Unlock
+}
+
+// getProjectSessionQuota reads maxConcurrentSessions, maxSessionsPerUser and admissionPolicy
+// from the namespace's ProjectSettings. A missing ProjectSettings means no quota.
+func getProjectSessionQuota(namespace string) sessionQuota {
+	quota := sessionQuota{Policy: admissionPolicyFIFO}
+	gvr := types.GetProjectSettingsResource()
+	obj, err := config.DynamicClient.Resource(gvr).Namespace(namespace).Get(context.TODO(), projectSettingsName, v1.GetOptions{})
+	if err != nil {
+		return quota
+	}
+	quota.MaxConcurrent, _, _ = unstructured.NestedInt64(obj.Object, "spec", "maxConcurrentSessions")
+	quota.MaxPerUser, _, _ = unstructured.NestedInt64(obj.Object, "spec", "maxSessionsPerUser")
+	if policy, _, _ := unstructured.NestedString(obj.Object, "spec", "admissionPolicy"); policy == admissionPolicyPriority {
+		quota.Policy = admissionPolicyPriority
+	}
+	return quota
+}
+
+// admitSession decides whether a Pending or Queued session may start now. When it may not,
+// the session is moved to (or kept in) the Queued phase with its queue position surfaced in
+// the Queued condition. A previously queued session that is admitted is moved back to Pending.
+// Callers must hold the namespace admission lock.
+func admitSession(ctx context.Context, session *unstructured.Unstructured, quota sessionQuota) (bool, error) {
+	namespace := session.GetNamespace()
+	name := session.GetName()
+
+	gvr := types.GetAgenticSessionResource()
+	list, err := config.DynamicClient.Resource(gvr).Namespace(namespace).List(ctx, v1.ListOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to list sessions for admission: %w", err)
+	}
+
+	decision := evaluateAdmission(name, list.Items, quota)
+	if decision.Admitted {
+		return true, releaseQueuedSession(session)
+	}
+	log.Printf("[Admission] Session %s/%s queued at position %d: %s", namespace, name, decision.Position, decision.Message)
+	return false, setSessionQueued(session, decision.Reason, decision.Message)
+}
+
+// setSessionQueued moves a session to (or keeps it in) the Queued phase with reason and
+// message in its Queued condition. The status is only patched when the message changed.
+func setSessionQueued(session *unstructured.Unstructured, reason, message string) error {
+	phase, _, _ := unstructured.NestedString(session.Object, "status", "phase")
+	if phase == "Queued" && queuedConditionMessage(session) == message {
+		return nil
+	}
+	statusPatch := NewStatusPatch(session.GetNamespace(), session.GetName())
+	statusPatch.SetField("phase", "Queued")
+	statusPatch.AddCondition(conditionUpdate{
+		Type:    conditionQueued,
+		Status:  "True",
+		Reason:  reason,
+		Message: message,
+	})
+	return statusPatch.Apply()
+}
+
+// releaseQueuedSession moves a Queued session back to Pending. Other phases are left as is.
+func releaseQueuedSession(session *unstructured.Unstructured) error {
+	if phase, _, _ := unstructured.NestedString(session.Object, "status", "phase"); phase != "Queued" {
+		return nil
+	}
+	log.Printf("[Admission] Admitting queued session %s/%s", session.GetNamespace(), session.GetName())
+	statusPatch := NewStatusPatch(session.GetNamespace(), session.GetName())
+	statusPatch.SetField("phase", "Pending")
+	statusPatch.AddCondition(conditionUpdate{
+		Type:    conditionQueued,
+		Status:  "False",
+		Reason:  "Admitted",
+		Message: "Admitted from the queue",
+	})
+	return statusPatch.Apply()
+}
+
+// evaluateAdmission simulates admitting the waiting sessions in queue order against the
+// free slots. A session blocked only by its owner's per-user limit does not hold back the
+// sessions behind it; once the project limit is reached everything behind it waits.
+func evaluateAdmission(name string, sessions []unstructured.Unstructured, quota sessionQuota) admissionDecision {
+	var active int64
+	activePerUser := map[string]int64{}
+	var waiting []*unstructured.Unstructured
+
+	for i := range sessions {
+		s := &sessions[i]
+		phase, _, _ := unstructured.NestedString(s.Object, "status", "phase")
+		switch phase {
+		case "Creating", "Running", "Stopping":
+			active++
+			activePerUser[sessionUserID(s)]++
+		case "", "Pending", "Queued":
+			if s.GetName() != name && s.GetAnnotations()["ambient-code.io/desired-phase"] == "Stopped" {
+				continue
+			}
+			waiting = append(waiting, s)
+		}
+	}
+
+	sort.SliceStable(waiting, func(i, j int) bool {
+		if quota.Policy == admissionPolicyPriority {
+			pi, _, _ := unstructured.NestedInt64(waiting[i].Object, "spec", "priority")
+			pj, _, _ := unstructured.NestedInt64(waiting[j].Object, "spec", "priority")
+			if pi != pj {
+				return pi > pj
+			}
+		}
+		ti, tj := waiting[i].GetCreationTimestamp(), waiting[j].GetCreationTimestamp()
+		if !ti.Equal(&tj) {
+			return ti.Before(&tj)
+		}
+		return waiting[i].GetName() < waiting[j].GetName()
+	})
+
+	position := 0
+	for _, s := range waiting {
+		user := sessionUserID(s)
+		reason := ""
+		switch {
+		case quota.MaxConcurrent > 0 && active >= quota.MaxConcurrent:
+			reason = "ProjectConcurrencyLimit"
+		case quota.MaxPerUser > 0 && user != "" && activePerUser[user] >= quota.MaxPerUser:
+			reason = "UserConcurrencyLimit"
+		}
+
+		if reason == "" {
+			if s.GetName() == name {
+				return admissionDecision{Admitted: true}
+			}
+			active++
+			activePerUser[user]++
+			continue
+		}
+
+		position++
+		if s.GetName() != name {
+			continue
+		}
+		message := fmt.Sprintf("Queue position %d: %d/%d project sessions running", position, active, quota.MaxConcurrent)
+		if reason == "UserConcurrencyLimit" {
+			message = fmt.Sprintf("Queue position %d: %s has %d/%d sessions running", position, user, activePerUser[user], quota.MaxPerUser)
+		}
+		return admissionDecision{Position: position, Reason: reason, Message: message}
+	}
+
+	// Not found among waiting sessions (e.g. already transitioned); let the caller proceed
+	return admissionDecision{Admitted: true}
+}
+
+func sessionUserID(session *unstructured.Unstructured) string {
+	userID, _, _ := unstructured.NestedString(session.Object, "spec", "userContext", "userId")
+	return userID
+}
+
+func queuedConditionMessage(session *unstructured.Unstructured) string {
+	conditions, _, _ := unstructured.NestedSlice(session.Object, "status", "conditions")
+	for _, c := range conditions {
+		if m, ok := c.(map[string]interface{}); ok && m["type"] == conditionQueued {
+			msg, _ := m["message"].(string)
+			return msg
+		}
+	}
+	return ""
+}
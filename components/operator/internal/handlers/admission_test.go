@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var admissionBase = time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC)
+
+func queueSession(name, phase, user string, priority int64, age time.Duration) *unstructured.Unstructured {
+	return newSessionObj(name, "ns1",
+		withCreationTimestamp(admissionBase.Add(-age)),
+		withSpec(map[string]any{
+			"priority":    priority,
+			"userContext": map[string]any{"userId": user},
+		}),
+		withStatus(map[string]any{"phase": phase}),
+	)
+}
+
+func sessionItems(objs ...*unstructured.Unstructured) []unstructured.Unstructured {
+	items := make([]unstructured.Unstructured, 0, len(objs))
+	for _, o := range objs {
+		items = append(items, *o)
+	}
+	return items
+}
+
+func TestEvaluateAdmission(t *testing.T) {
+	sessions := sessionItems(
+		queueSession("running-a", "Running", "alice", 0, 10*time.Hour),
+		queueSession("old-a", "Pending", "alice", 0, 5*time.Hour),
+		queueSession("mid-b", "Queued", "bob", 0, 4*time.Hour),
+		queueSession("new-b", "Pending", "bob", 5, 1*time.Hour),
+		queueSession("newest-c", "Pending", "carol", 0, 0),
+	)
+
+	tests := []struct {
+		name      string
+		session   string
+		quota     sessionQuota
+		admitted  bool
+		position  int
+		reasonHas string
+	}{
+		{"unlimited", "newest-c", sessionQuota{}, true, 0, ""},
+		{"fifo head admitted", "old-a", sessionQuota{MaxConcurrent: 2}, true, 0, ""},
+		{"fifo second queued", "mid-b", sessionQuota{MaxConcurrent: 2}, false, 1, "Project"},
+		{"fifo third queued", "newest-c", sessionQuota{MaxConcurrent: 2}, false, 3, "Project"},
+		{"priority jumps queue", "new-b", sessionQuota{MaxConcurrent: 2, Policy: admissionPolicyPriority}, true, 0, ""},
+		{"priority pushes head back", "old-a", sessionQuota{MaxConcurrent: 2, Policy: admissionPolicyPriority}, false, 1, "Project"},
+		// alice already runs one session: her queued session waits but does not block others
+		{"per-user blocked", "old-a", sessionQuota{MaxPerUser: 1}, false, 1, "User"},
+		{"per-user does not block others", "mid-b", sessionQuota{MaxPerUser: 1}, true, 0, ""},
+		{"per-user second for same user", "new-b", sessionQuota{MaxPerUser: 1}, false, 2, "User"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.quota.Policy == "" {
+				tt.quota.Policy = admissionPolicyFIFO
+			}
+			got := evaluateAdmission(tt.session, sessions, tt.quota)
+			if got.Admitted != tt.admitted || got.Position != tt.position {
+				t.Fatalf("evaluateAdmission(%s) = %+v, want admitted=%v position=%d", tt.session, got, tt.admitted, tt.position)
+			}
+			if !strings.Contains(got.Reason, tt.reasonHas) {
+				t.Errorf("reason = %q, want it to contain %q", got.Reason, tt.reasonHas)
+			}
+		})
+	}
+}
+
+func TestReconcilePendingSession_QueuesAndAdmits(t *testing.T) {
+	settings := newProjectSettingsObj("ns1", nil)
+	settings.Object["spec"].(map[string]any)["maxConcurrentSessions"] = int64(1)
+	running := queueSession("running-a", "Running", "alice", 0, time.Hour)
+	waiting := queueSession("waiting-b", "Pending", "bob", 0, 0)
+	setupFakeDynamicClient(settings, running, waiting)
+	gvr := types.GetAgenticSessionResource()
+	ctx := context.Background()
+
+	if err := ReconcilePendingSession(ctx, waiting, nil); err != ErrSessionQueued {
+		t.Fatalf("expected ErrSessionQueued, got %v", err)
+	}
+	queued, _ := config.DynamicClient.Resource(gvr).Namespace("ns1").Get(ctx, "waiting-b", metav1.GetOptions{})
+	if phase, _, _ := unstructured.NestedString(queued.Object, "status", "phase"); phase != "Queued" {
+		t.Fatalf("expected phase Queued, got %q", phase)
+	}
+	if msg := queuedConditionMessage(queued); !strings.HasPrefix(msg, "Queue position 1") {
+		t.Errorf("unexpected Queued condition message %q", msg)
+	}
+
+	// Free the slot: the running session completes
+	done, _ := config.DynamicClient.Resource(gvr).Namespace("ns1").Get(ctx, "running-a", metav1.GetOptions{})
+	_ = unstructured.SetNestedField(done.Object, "Completed", "status", "phase")
+	if _, err := config.DynamicClient.Resource(gvr).Namespace("ns1").UpdateStatus(ctx, done, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	quota := getProjectSessionQuota("ns1")
+	admitted, err := admitSession(ctx, queued, quota)
+	if err != nil || !admitted {
+		t.Fatalf("expected admission once a slot is free, got admitted=%v err=%v", admitted, err)
+	}
+	after, _ := config.DynamicClient.Resource(gvr).Namespace("ns1").Get(ctx, "waiting-b", metav1.GetOptions{})
+	if phase, _, _ := unstructured.NestedString(after.Object, "status", "phase"); phase != "Pending" {
+		t.Errorf("expected admitted session back in Pending, got %q", phase)
+	}
+}
+
+func TestGetProjectSessionQuota_NoSettings(t *testing.T) {
+	setupFakeDynamicClient()
+	if quota := getProjectSessionQuota("ns1"); quota.enabled() || quota.Policy != admissionPolicyFIFO {
+		t.Errorf("expected no quota without ProjectSettings, got %+v", quota)
+	}
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+	"ambient-code-shared/budget"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// stopReasonBudget is the stopReasonAnnotation value used when a Running
+	// session is stopped because the project crossed its budget hard cap.
+	stopReasonBudget = "budget"
+
+	conditionReasonBudgetExceeded = "BudgetExceeded"
+
+	// projectBudgetCacheTTL bounds how often the budget and the project's spend are
+	// re-read. Every Running session's monitor checks the budget on each tick, and
+	// every Pending session checks it before admission.
+	projectBudgetCacheTTL = 30 * time.Second
+)
+
+type projectBudgetEntry struct {
+	limits    budget.Limits
+	fetchedAt time.Time
+}
+
+type projectSpendEntry struct {
+	spend     budget.Spend
+	fetchedAt time.Time
+}
+
+var (
+	projectBudgetMu    sync.Mutex
+	projectBudgetCache = map[string]projectBudgetEntry{}
+	projectSpendCache  = map[string]projectSpendEntry{}
+)
+
+// getProjectBudget reads spec.budget from the namespace's ProjectSettings.
+// A missing ProjectSettings or budget means no limits. Results are cached per
+// namespace for projectBudgetCacheTTL.
+func getProjectBudget(namespace string) budget.Limits {
+	projectBudgetMu.Lock()
+	if entry, ok := projectBudgetCache[namespace]; ok && time.Since(entry.fetchedAt) < projectBudgetCacheTTL {
+		projectBudgetMu.Unlock()
+		return entry.limits
+	}
+	projectBudgetMu.Unlock()
+
+	var limits budget.Limits
+	gvr := types.GetProjectSettingsResource()
+	obj, err := config.DynamicClient.Resource(gvr).Namespace(namespace).Get(context.TODO(), projectSettingsName, v1.GetOptions{})
+	if err == nil {
+		spec, _, _ := unstructured.NestedMap(obj.Object, "spec", "budget")
+		limits = budget.ParseLimits(spec)
+	} else if !errors.IsNotFound(err) {
+		// Not cached, so the next check retries the read
+		log.Printf("[Budget] %s: failed to read project settings: %v", namespace, err)
+		return limits
+	}
+
+	projectBudgetMu.Lock()
+	projectBudgetCache[namespace] = projectBudgetEntry{limits: limits, fetchedAt: time.Now()}
+	projectBudgetMu.Unlock()
+	return limits
+}
+
+// getProjectSpend aggregates the project's spend for the day and month containing now,
+// from its sessions and the usage ledger of its deleted sessions. Results are cached
+// per namespace for projectBudgetCacheTTL.
+func getProjectSpend(namespace string, now time.Time) (budget.Spend, error) {
+	projectBudgetMu.Lock()
+	if entry, ok := projectSpendCache[namespace]; ok && time.Since(entry.fetchedAt) < projectBudgetCacheTTL {
+		projectBudgetMu.Unlock()
+		return entry.spend, nil
+	}
+	projectBudgetMu.Unlock()
+
+	var spend budget.Spend
+	settings, err := config.DynamicClient.Resource(types.GetProjectSettingsResource()).Namespace(namespace).Get(context.TODO(), projectSettingsName, v1.GetOptions{})
+	if err == nil {
+		ledger, _, _ := unstructured.NestedMap(settings.Object, "status", "usageLedger")
+		spend.AddLedger(ledger, now)
+	} else if !errors.IsNotFound(err) {
+		return budget.Spend{}, fmt.Errorf("failed to read the usage ledger for budget: %w", err)
+	}
+
+	gvr := types.GetAgenticSessionResource()
+	list, err := config.DynamicClient.Resource(gvr).Namespace(namespace).List(context.TODO(), v1.ListOptions{})
+	if err != nil {
+		return budget.Spend{}, fmt.Errorf("failed to list sessions for budget: %w", err)
+	}
+	for i := range list.Items {
+		usage, _, _ := unstructured.NestedMap(list.Items[i].Object, "status", "usage")
+		spend.Add(usage, now)
+	}
+
+	projectBudgetMu.Lock()
+	projectSpendCache[namespace] = projectSpendEntry{spend: spend, fetchedAt: time.Now()}
+	projectBudgetMu.Unlock()
+	return spend, nil
+}
+
+// RecordDeletedSessionUsage adds the status.usage of a session being deleted to its
+// project's usage ledger (ProjectSettings status.usageLedger), so deleting sessions does
+// not lower the project's spend. Sessions carry budget.UsageFinalizer until it has run.
+// A project without ProjectSettings, normally one being deleted, keeps no ledger.
+func RecordDeletedSessionUsage(ctx context.Context, session *unstructured.Unstructured) error {
+	usage, _, _ := unstructured.NestedMap(session.Object, "status", "usage")
+	if len(usage) == 0 {
+		return nil
+	}
+	user, _, _ := unstructured.NestedString(session.Object, "spec", "userContext", "userId")
+	model, _, _ := unstructured.NestedString(session.Object, "spec", "llmSettings", "model")
+
+	namespace := session.GetNamespace()
+	gvr := types.GetProjectSettingsResource()
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		obj, err := config.DynamicClient.Resource(gvr).Namespace(namespace).Get(ctx, projectSettingsName, v1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		ledger, _, _ := unstructured.NestedMap(obj.Object, "status", "usageLedger")
+		ledger = budget.RecordUsage(ledger, user, model, usage, time.Now())
+		if err := unstructured.SetNestedMap(obj.Object, ledger, "status", "usageLedger"); err != nil {
+			return err
+		}
+		_, err = config.DynamicClient.Resource(gvr).Namespace(namespace).UpdateStatus(ctx, obj, v1.UpdateOptions{})
+		return err
+	})
+	if errors.IsNotFound(err) {
+		log.Printf("[Budget] %s: no ProjectSettings, usage of deleted session %s not kept", namespace, session.GetName())
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add the usage of %s/%s to the usage ledger: %w", namespace, session.GetName(), err)
+	}
+
+	projectBudgetMu.Lock()
+	delete(projectSpendCache, namespace)
+	projectBudgetMu.Unlock()
+	return nil
+}
+
+// checkProjectBudget returns the first limit of the namespace's budget that its spend
+// has reached once scaled by percent, or nil.
+func checkProjectBudget(namespace string, limits budget.Limits, percent int64) *budget.Exceeded {
+	spend, err := getProjectSpend(namespace, time.Now())
+	if err != nil {
+		log.Printf("[Budget] %s: %v", namespace, err)
+		return nil
+	}
+	return budget.Check(limits, spend, percent)
+}
+
+// shouldStopForBudget checks whether a Running session must be stopped because the
+// project's spend crossed the budget hard cap. Returns a description of the exceeded
+// budget. Projects without hardCapPercent only hold back new sessions.
+func shouldStopForBudget(sessionObj *unstructured.Unstructured) (bool, string) {
+	phase, _, _ := unstructured.NestedString(sessionObj.Object, "status", "phase")
+	if phase != "Running" {
+		return false, ""
+	}
+
+	namespace := sessionObj.GetNamespace()
+	limits := getProjectBudget(namespace)
+	if limits.HardCapPercent <= 0 {
+		return false, ""
+	}
+	if exceeded := checkProjectBudget(namespace, limits, limits.HardCapPercent); exceeded != nil {
+		return true, fmt.Sprintf("%s (hard cap %d%%)", exceeded.Message, limits.HardCapPercent)
+	}
+	return false, ""
+}
+
+// holdForBudget keeps a Pending or Queued session from starting while the project's
+// budget is used up. This covers sessions the backend's create-time check never sees:
+// scheduled runs, spec.next follow-ups and restarts. The session is moved to (or kept
+// in) Queued with a BudgetExceeded Queued condition and admitted again once spend is
+// below every limit (the next day or month) or the budget is raised.
+func holdForBudget(session *unstructured.Unstructured) (bool, error) {
+	namespace := session.GetNamespace()
+	limits := getProjectBudget(namespace)
+	if !limits.Enabled() {
+		return false, nil
+	}
+	exceeded := checkProjectBudget(namespace, limits, 100)
+	if exceeded == nil {
+		return false, nil
+	}
+	message := "Project " + exceeded.Message
+	return true, setSessionQueued(session, conditionReasonBudgetExceeded, message)
+}
+
+// triggerBudgetStop sets the desired-phase annotation to Stopped with a stop-reason
+// annotation for the budget, mirroring triggerInactivityStop.
+func triggerBudgetStop(namespace, name string) error {
+	gvr := types.GetAgenticSessionResource()
+
+	obj, err := config.DynamicClient.Resource(gvr).Namespace(namespace).Get(context.TODO(), name, v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to re-read session %s/%s: %w", namespace, name, err)
+	}
+
+	if phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase"); phase != "Running" {
+		return nil
+	}
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations["ambient-code.io/desired-phase"] = "Stopped"
+	annotations[stopReasonAnnotation] = stopReasonBudget
+	obj.SetAnnotations(annotations)
+
+	_, err = config.DynamicClient.Resource(gvr).Namespace(namespace).Update(context.TODO(), obj, v1.UpdateOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to set desired-phase for %s/%s: %w", namespace, name, err)
+	}
+
+	log.Printf("[Budget] Session %s/%s: set desired-phase=Stopped with reason=%s", namespace, name, stopReasonBudget)
+	return nil
+}
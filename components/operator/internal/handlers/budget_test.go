@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func usageSession(name, phase string, daily map[string]any) *unstructured.Unstructured {
+	return newSessionObj(name, "ns1", withStatus(map[string]any{
+		"phase": phase,
+		"usage": map[string]any{"daily": daily},
+	}))
+}
+
+func resetBudgetCache() {
+	projectBudgetMu.Lock()
+	projectBudgetCache = map[string]projectBudgetEntry{}
+	projectSpendCache = map[string]projectSpendEntry{}
+	projectBudgetMu.Unlock()
+}
+
+func budgetSettings(spec map[string]any) *unstructured.Unstructured {
+	settings := newProjectSettingsObj("ns1", nil)
+	settings.Object["spec"].(map[string]any)["budget"] = spec
+	return settings
+}
+
+func TestGetProjectBudget_Cached(t *testing.T) {
+	setupFakeDynamicClient(budgetSettings(map[string]any{"dailyCostUsd": int64(20)}))
+	if got := getProjectBudget("ns1"); got.DailyCostUSD != 20 {
+		t.Fatalf("getProjectBudget() = %+v", got)
+	}
+
+	// The monitor's 5s ticks must not re-read ProjectSettings every time
+	gvr := types.GetProjectSettingsResource()
+	if err := config.DynamicClient.Resource(gvr).Namespace("ns1").Delete(context.Background(), projectSettingsName, metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := getProjectBudget("ns1"); got.DailyCostUSD != 20 {
+		t.Errorf("expected the cached budget, got %+v", got)
+	}
+
+	resetBudgetCache()
+	if got := getProjectBudget("ns1"); got.Enabled() {
+		t.Errorf("expected no budget once ProjectSettings is gone, got %+v", got)
+	}
+}
+
+func TestShouldStopForBudget(t *testing.T) {
+	today := time.Now().UTC().Format("2006-01-02")
+	spent := map[string]any{today: map[string]any{"costUsd": 30.0}}
+
+	tests := []struct {
+		name     string
+		settings *unstructured.Unstructured
+		phase    string
+		want     bool
+	}{
+		{"hard cap crossed", budgetSettings(map[string]any{"dailyCostUsd": int64(20), "hardCapPercent": int64(120)}), "Running", true},
+		{"below hard cap", budgetSettings(map[string]any{"dailyCostUsd": int64(20), "hardCapPercent": int64(200)}), "Running", false},
+		{"no hard cap only blocks new sessions", budgetSettings(map[string]any{"dailyCostUsd": int64(20)}), "Running", false},
+		{"not running", budgetSettings(map[string]any{"dailyCostUsd": int64(20), "hardCapPercent": int64(100)}), "Creating", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := usageSession("s1", tt.phase, spent)
+			setupFakeDynamicClient(tt.settings, session)
+
+			got, detail := shouldStopForBudget(session)
+			if got != tt.want {
+				t.Errorf("shouldStopForBudget() = %v (%q), want %v", got, detail, tt.want)
+			}
+		})
+	}
+}
+
+func TestTriggerBudgetStop(t *testing.T) {
+	session := usageSession("s1", "Running", nil)
+	setupFakeDynamicClient(session)
+
+	if err := triggerBudgetStop("ns1", "s1"); err != nil {
+		t.Fatalf("triggerBudgetStop() error = %v", err)
+	}
+
+	gvr := types.GetAgenticSessionResource()
+	obj, err := config.DynamicClient.Resource(gvr).Namespace("ns1").Get(context.Background(), "s1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	annotations := obj.GetAnnotations()
+	if annotations["ambient-code.io/desired-phase"] != "Stopped" || annotations[stopReasonAnnotation] != stopReasonBudget {
+		t.Errorf("unexpected annotations %v", annotations)
+	}
+
+	stop := resolveStopDetails(annotations)
+	if stop.StoppedReason != "budget" || stop.ConditionReason != "BudgetExceeded" {
+		t.Errorf("resolveStopDetails() = %+v", stop)
+	}
+}
+
+func TestReconcilePendingSession_HoldsWhileOverBudget(t *testing.T) {
+	today := time.Now().UTC().Format("2006-01-02")
+	spent := usageSession("spent", "Completed", map[string]any{today: map[string]any{"costUsd": 30.0}})
+	scheduled := usageSession("scheduled", "Pending", nil)
+	setupFakeDynamicClient(budgetSettings(map[string]any{"dailyCostUsd": int64(20)}), spent, scheduled)
+	gvr := types.GetAgenticSessionResource()
+	ctx := context.Background()
+
+	if err := ReconcilePendingSession(ctx, scheduled, nil); err != ErrSessionQueued {
+		t.Fatalf("expected ErrSessionQueued, got %v", err)
+	}
+	held, _ := config.DynamicClient.Resource(gvr).Namespace("ns1").Get(ctx, "scheduled", metav1.GetOptions{})
+	if phase, _, _ := unstructured.NestedString(held.Object, "status", "phase"); phase != "Queued" {
+		t.Fatalf("expected phase Queued, got %q", phase)
+	}
+	if msg := queuedConditionMessage(held); !strings.Contains(msg, "daily cost budget exhausted ($30.00 of $20.00)") {
+		t.Errorf("unexpected Queued condition message %q", msg)
+	}
+
+	// Raising the budget lets the session through and moves it back to Pending
+	settings := budgetSettings(map[string]any{"dailyCostUsd": int64(50)})
+	if _, err := config.DynamicClient.Resource(types.GetProjectSettingsResource()).Namespace("ns1").Update(ctx, settings, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	resetBudgetCache()
+	if stillHeld, err := holdForBudget(held); err != nil || stillHeld {
+		t.Fatalf("holdForBudget() = %v, %v after raising the budget", stillHeld, err)
+	}
+	if err := releaseQueuedSession(held); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := config.DynamicClient.Resource(gvr).Namespace("ns1").Get(ctx, "scheduled", metav1.GetOptions{})
+	if phase, _, _ := unstructured.NestedString(after.Object, "status", "phase"); phase != "Pending" {
+		t.Errorf("expected phase Pending, got %q", phase)
+	}
+}
+
+func TestRecordDeletedSessionUsage(t *testing.T) {
+	today := time.Now().UTC().Format("2006-01-02")
+	deleted := usageSession("deleted", "Completed", map[string]any{today: map[string]any{"costUsd": 30.0}})
+	deleted.Object["spec"] = map[string]any{"userContext": map[string]any{"userId": "alice"}}
+	setupFakeDynamicClient(budgetSettings(map[string]any{"dailyCostUsd": int64(20)}))
+	ctx := context.Background()
+
+	if err := RecordDeletedSessionUsage(ctx, deleted); err != nil {
+		t.Fatalf("RecordDeletedSessionUsage() error = %v", err)
+	}
+	settings, err := config.DynamicClient.Resource(types.GetProjectSettingsResource()).Namespace("ns1").Get(ctx, projectSettingsName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, found, _ := unstructured.NestedMap(settings.Object, "status", "usageLedger", "alice", "daily", today); !found {
+		t.Errorf("usage ledger = %v", settings.Object["status"])
+	}
+
+	// The session is gone, but its spend still counts
+	if exceeded := checkProjectBudget("ns1", getProjectBudget("ns1"), 100); exceeded == nil {
+		t.Error("expected the deleted session's spend to exhaust the budget")
+	}
+
+	// Without ProjectSettings there is no ledger to keep it in
+	setupFakeDynamicClient()
+	if err := RecordDeletedSessionUsage(ctx, deleted); err != nil {
+		t.Errorf("RecordDeletedSessionUsage() without ProjectSettings error = %v", err)
+	}
+}
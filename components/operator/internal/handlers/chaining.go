@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	"ambient-code-shared/artifacts"
+)
+
+const (
+	// ChainRootLabel groups every session of a spec.next pipeline under the session that started it
+	ChainRootLabel = "ambient-code.io/chain-root"
+
+	chainStepAnnotation          = "ambient-code.io/chain-step"
+	nextSessionAnnotation        = "ambient-code.io/next-session"
+	parentSessionAnnotation      = "vteam.ambient-code/parent-session-id"
+	createdByAnnotation          = "ambient-code.io/created-by"
+	carryArtifactsFromAnnotation = "ambient-code.io/carry-artifacts-from"
+	carryArtifactsAnnotation     = "ambient-code.io/carry-artifacts"
+
+	// maxChainRootNameLen keeps "<root>-step<N>" within the 63 character label value limit
+	maxChainRootNameLen = 50
+)
+
+// StartNextSession creates the follow-up session declared in spec.next of a Completed session.
+// It is idempotent: the follow-up has a deterministic name and the parent records it in the
+// ambient-code.io/next-session annotation, so repeated reconciles create at most one session.
+func StartNextSession(ctx context.Context, session *unstructured.Unstructured) error {
+	next, found, _ := unstructured.NestedMap(session.Object, "spec", "next")
+	if !found || len(next) == 0 {
+		return nil
+	}
+	if session.GetAnnotations()[nextSessionAnnotation] != "" {
+		return nil
+	}
+
+	namespace := session.GetNamespace()
+	child, err := buildNextSession(session, next)
+	if err != nil {
+		log.Printf("[Chain] Invalid spec.next on %s/%s: %v", namespace, session.GetName(), err)
+		return setAnnotation(namespace, session.GetName(), nextSessionAnnotation, "invalid")
+	}
+
+	gvr := types.GetAgenticSessionResource()
+	_, err = config.DynamicClient.Resource(gvr).Namespace(namespace).Create(ctx, child, v1.CreateOptions{})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create next session %s: %w", child.GetName(), err)
+	}
+	if err == nil {
+		log.Printf("[Chain] Created follow-up session %s/%s from completed session %s", namespace, child.GetName(), session.GetName())
+	}
+
+	return setAnnotation(namespace, session.GetName(), nextSessionAnnotation, child.GetName())
+}
+
+// buildNextSession renders the follow-up AgenticSession from the parent's spec.next.
+// Unset fields default to the parent's values and the user context, workflow and environment
+// variables are always inherited. Repos are carried over on the branch the parent ended on,
+// and the remaining spec.next (if any) becomes the follow-up's own spec.next.
+func buildNextSession(parent *unstructured.Unstructured, next map[string]interface{}) (*unstructured.Unstructured, error) {
+	prompt, _ := next["initialPrompt"].(string)
+	if strings.TrimSpace(prompt) == "" {
+		return nil, fmt.Errorf("spec.next.initialPrompt is required")
+	}
+
+	carried, err := nextArtifacts(next)
+	if err != nil {
+		return nil, err
+	}
+
+	parentAnnotations := parent.GetAnnotations()
+	root := parent.GetLabels()[ChainRootLabel]
+	if root == "" {
+		root = parent.GetName()
+	}
+	step, _ := strconv.Atoi(parentAnnotations[chainStepAnnotation])
+	step++
+
+	rootPrefix := root
+	if len(rootPrefix) > maxChainRootNameLen {
+		rootPrefix = strings.TrimRight(rootPrefix[:maxChainRootNameLen], "-")
+	}
+	name := fmt.Sprintf("%s-step%d", rootPrefix, step)
+
+	parentSpec, _, _ := unstructured.NestedMap(parent.Object, "spec")
+	spec := map[string]interface{}{
+		"initialPrompt": prompt,
+		"project":       parent.GetNamespace(),
+		"interactive":   false,
+	}
+	if v, ok := next["displayName"].(string); ok && v != "" {
+		spec["displayName"] = v
+	}
+	if v, ok := next["interactive"].(bool); ok {
+		spec["interactive"] = v
+	}
+	for _, key := range []string{"llmSettings", "timeout"} {
+		if v, ok := next[key]; ok {
+			spec[key] = v
+		} else if v, ok := parentSpec[key]; ok {
+			spec[key] = v
+		}
+	}
+	for _, key := range []string{"userContext", "activeWorkflow", "environmentVariables"} {
+		if v, ok := parentSpec[key]; ok {
+			spec[key] = v
+		}
+	}
+	if carry, ok := next["carryRepos"].(bool); !ok || carry {
+		if repos := carriedRepos(parent); len(repos) > 0 {
+			spec["repos"] = repos
+		}
+	}
+	if following, ok := next["next"].(map[string]interface{}); ok && len(following) > 0 {
+		spec["next"] = following
+	}
+
+	annotations := map[string]interface{}{
+		parentSessionAnnotation: parent.GetName(),
+		chainStepAnnotation:     strconv.Itoa(step),
+	}
+	if createdBy := parentAnnotations[createdByAnnotation]; createdBy != "" {
+		annotations[createdByAnnotation] = createdBy
+	}
+	if len(carried) > 0 {
+		b, _ := json.Marshal(carried)
+		annotations[carryArtifactsFromAnnotation] = parent.GetName()
+		annotations[carryArtifactsAnnotation] = string(b)
+	}
+
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "vteam.ambient-code/v1alpha1",
+			"kind":       "AgenticSession",
+			"metadata": map[string]interface{}{
+				"name":        name,
+				"namespace":   parent.GetNamespace(),
+				"labels":      map[string]interface{}{ChainRootLabel: root},
+				"annotations": annotations,
+			},
+			"spec": spec,
+		},
+	}, nil
+}
+
+// carriedRepos copies the parent's repos, replacing each branch with the branch the parent
+// ended on according to status.reconciledRepos (currentActiveBranch, falling back to branch).
+func carriedRepos(parent *unstructured.Unstructured) []interface{} {
+	specRepos, _, _ := unstructured.NestedSlice(parent.Object, "spec", "repos")
+	if len(specRepos) == 0 {
+		return nil
+	}
+
+	resultingBranch := map[string]string{}
+	reconciled, _, _ := unstructured.NestedSlice(parent.Object, "status", "reconciledRepos")
+	for _, entry := range reconciled {
+		m, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		url, _ := m["url"].(string)
+		branch, _ := m["currentActiveBranch"].(string)
+		if branch == "" {
+			branch, _ = m["branch"].(string)
+		}
+		if url != "" && branch != "" {
+			resultingBranch[url] = branch
+		}
+	}
+
+	repos := make([]interface{}, 0, len(specRepos))
+	for _, entry := range specRepos {
+		m, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		repo := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			repo[k] = v
+		}
+		if url, _ := m["url"].(string); resultingBranch[url] != "" {
+			repo["branch"] = resultingBranch[url]
+		}
+		repos = append(repos, repo)
+	}
+	return repos
+}
+
+// nextArtifacts validates spec.next.artifacts with the same rules the backend applied
+// when the session was created.
+func nextArtifacts(next map[string]interface{}) ([]string, error) {
+	raw, _ := next["artifacts"].([]interface{})
+	paths := make([]string, 0, len(raw))
+	for _, item := range raw {
+		p, _ := item.(string)
+		paths = append(paths, p)
+	}
+	return artifacts.CleanPaths(paths)
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func completedPlanSession() *unstructured.Unstructured {
+	return newSessionObj("plan", "ns1",
+		withSpec(map[string]any{
+			"initialPrompt": "Write a plan",
+			"timeout":       int64(600),
+			"llmSettings":   map[string]any{"model": "sonnet"},
+			"userContext":   map[string]any{"userId": "alice"},
+			"environmentVariables": map[string]any{
+				"JIRA_PROJECT": "APP",
+			},
+			"repos": []any{
+				map[string]any{"url": "https://github.com/org/app.git", "branch": "main", "autoPush": true},
+				map[string]any{"url": "https://github.com/org/docs.git", "branch": "main"},
+			},
+			"next": map[string]any{
+				"initialPrompt": "Implement the plan",
+				"artifacts":     []any{"plan.md", "notes/"},
+				"next": map[string]any{
+					"initialPrompt": "Review the implementation",
+				},
+			},
+		}),
+		withStatus(map[string]any{
+			"phase": "Completed",
+			"reconciledRepos": []any{
+				map[string]any{"url": "https://github.com/org/app.git", "branch": "main", "currentActiveBranch": "ambient/plan"},
+			},
+		}),
+	)
+}
+
+func TestStartNextSession(t *testing.T) {
+	parent := completedPlanSession()
+	setupFakeDynamicClient(parent)
+	gvr := types.GetAgenticSessionResource()
+
+	if err := StartNextSession(context.Background(), parent); err != nil {
+		t.Fatalf("StartNextSession: %v", err)
+	}
+
+	child, err := config.DynamicClient.Resource(gvr).Namespace("ns1").Get(context.Background(), "plan-step1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected follow-up session plan-step1: %v", err)
+	}
+
+	if got, _, _ := unstructured.NestedString(child.Object, "spec", "initialPrompt"); got != "Implement the plan" {
+		t.Errorf("initialPrompt = %q", got)
+	}
+	if got, _, _ := unstructured.NestedInt64(child.Object, "spec", "timeout"); got != 600 {
+		t.Errorf("timeout should default to the parent's, got %d", got)
+	}
+	if got, _, _ := unstructured.NestedString(child.Object, "spec", "userContext", "userId"); got != "alice" {
+		t.Errorf("userContext not carried over, got %q", got)
+	}
+	if got, _, _ := unstructured.NestedString(child.Object, "spec", "environmentVariables", "JIRA_PROJECT"); got != "APP" {
+		t.Errorf("environmentVariables not carried over, got %q", got)
+	}
+	if got, _, _ := unstructured.NestedString(child.Object, "spec", "next", "initialPrompt"); got != "Review the implementation" {
+		t.Errorf("nested next not propagated, got %q", got)
+	}
+
+	repos, _, _ := unstructured.NestedSlice(child.Object, "spec", "repos")
+	if len(repos) != 2 {
+		t.Fatalf("expected 2 carried repos, got %d", len(repos))
+	}
+	if branch := repos[0].(map[string]any)["branch"]; branch != "ambient/plan" {
+		t.Errorf("expected resulting branch ambient/plan, got %v", branch)
+	}
+	if branch := repos[1].(map[string]any)["branch"]; branch != "main" {
+		t.Errorf("expected unreconciled repo to keep its branch, got %v", branch)
+	}
+
+	annotations := child.GetAnnotations()
+	if annotations[parentSessionAnnotation] != "plan" || annotations[chainStepAnnotation] != "1" {
+		t.Errorf("unexpected chain annotations: %v", annotations)
+	}
+	if annotations[carryArtifactsFromAnnotation] != "plan" || annotations[carryArtifactsAnnotation] != `["plan.md","notes"]` {
+		t.Errorf("unexpected artifact annotations: %v", annotations)
+	}
+	if child.GetLabels()[ChainRootLabel] != "plan" {
+		t.Errorf("expected chain root label, got %v", child.GetLabels())
+	}
+
+	updatedParent, _ := config.DynamicClient.Resource(gvr).Namespace("ns1").Get(context.Background(), "plan", metav1.GetOptions{})
+	if got := updatedParent.GetAnnotations()[nextSessionAnnotation]; got != "plan-step1" {
+		t.Errorf("parent next-session annotation = %q", got)
+	}
+
+	// A second reconcile of the (now annotated) parent is a no-op
+	if err := StartNextSession(context.Background(), updatedParent); err != nil {
+		t.Fatalf("second StartNextSession: %v", err)
+	}
+	list, _ := config.DynamicClient.Resource(gvr).Namespace("ns1").List(context.Background(), metav1.ListOptions{})
+	if len(list.Items) != 2 {
+		t.Errorf("expected exactly 2 sessions, got %d", len(list.Items))
+	}
+
+	// The follow-up carries the chain forward under the same root
+	grandchild, err := buildNextSession(child, child.Object["spec"].(map[string]any)["next"].(map[string]any))
+	if err != nil {
+		t.Fatalf("buildNextSession: %v", err)
+	}
+	if grandchild.GetName() != "plan-step2" || grandchild.GetLabels()[ChainRootLabel] != "plan" {
+		t.Errorf("unexpected grandchild %s labels=%v", grandchild.GetName(), grandchild.GetLabels())
+	}
+	if _, found, _ := unstructured.NestedMap(grandchild.Object, "spec", "next"); found {
+		t.Errorf("last step should not declare spec.next")
+	}
+}
+
+func TestBuildNextSession_CarryReposDisabled(t *testing.T) {
+	parent := completedPlanSession()
+	next := map[string]any{"initialPrompt": "Summarize", "carryRepos": false}
+
+	child, err := buildNextSession(parent, next)
+	if err != nil {
+		t.Fatalf("buildNextSession: %v", err)
+	}
+	if _, found, _ := unstructured.NestedSlice(child.Object, "spec", "repos"); found {
+		t.Errorf("repos should not be carried when carryRepos=false")
+	}
+}
+
+func TestBuildNextSession_Invalid(t *testing.T) {
+	parent := completedPlanSession()
+	for _, next := range []map[string]any{
+		{"initialPrompt": "  "},
+		{"initialPrompt": "x", "artifacts": []any{"/etc/passwd"}},
+		{"initialPrompt": "x", "artifacts": []any{"../other-session"}},
+		{"initialPrompt": "x", "artifacts": []any{"."}},
+	} {
+		if _, err := buildNextSession(parent, next); err == nil {
+			t.Errorf("expected error for spec.next %v", next)
+		}
+	}
+}
@@ -144,11 +144,13 @@ func mutateAgenticSessionStatus(sessionNamespace, name string, mutator func(stat
 		obj.Object["status"] = status
 	}
 
+	previousPhase, _ := status["phase"].(string)
+
 	mutator(status)
 
 	// Phase is set explicitly by callers - no derivation needed
 
-	_, err = config.DynamicClient.Resource(gvr).Namespace(sessionNamespace).UpdateStatus(context.TODO(), obj, v1.UpdateOptions{})
+	updated, err := config.DynamicClient.Resource(gvr).Namespace(sessionNamespace).UpdateStatus(context.TODO(), obj, v1.UpdateOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
 			log.Printf("AgenticSession %s was deleted during status update, skipping", name)
@@ -157,6 +159,10 @@ func mutateAgenticSessionStatus(sessionNamespace, name string, mutator func(stat
 		return fmt.Errorf("failed to update AgenticSession status: %w", err)
 	}
 
+	if phase, _ := status["phase"].(string); phase != previousPhase {
+		notifySessionPhaseChange(updated, previousPhase, phase)
+	}
+
 	return nil
 }
 
@@ -253,6 +259,37 @@ func clearAnnotation(sessionNamespace, name, annotationKey string) error {
 	return nil
 }
 
+// setAnnotation sets a single annotation on the AgenticSession CR, preserving the others.
+func setAnnotation(sessionNamespace, name, annotationKey, value string) error {
+	gvr := types.GetAgenticSessionResource()
+
+	obj, err := config.DynamicClient.Resource(gvr).Namespace(sessionNamespace).Get(context.TODO(), name, v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get AgenticSession %s: %w", name, err)
+	}
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if annotations[annotationKey] == value {
+		return nil
+	}
+
+	annotations[annotationKey] = value
+	obj.SetAnnotations(annotations)
+
+	_, err = config.DynamicClient.Resource(gvr).Namespace(sessionNamespace).Update(context.TODO(), obj, v1.UpdateOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to set annotation %s for %s: %w", annotationKey, name, err)
+	}
+
+	return nil
+}
+
 // setCondition upserts a condition entry on the provided status map.
 func setCondition(status map[string]interface{}, update conditionUpdate) {
 	now := time.Now().UTC().Format(time.RFC3339)
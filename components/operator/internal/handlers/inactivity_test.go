@@ -77,6 +77,8 @@ func setupFakeDynamicClient(objects ...*unstructured.Unstructured) {
 	}
 	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme, gvrToListKind)
 	config.DynamicClient = client
+	// Budget lookups run on every Pending reconcile; don't let one test's budget leak into the next
+	resetBudgetCache()
 
 	for _, obj := range objects {
 		gvr := gvrForKind(obj.GetKind())
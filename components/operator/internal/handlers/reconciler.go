@@ -24,7 +24,34 @@ import (
 // 2. Use controller-runtime patterns (Patch, StatusWriter, etc.) instead of direct API calls
 // 3. Remove handleAgenticSessionEvent() entirely
 // This approach allows adopting controller-runtime framework without rewriting all logic at once.
+//
+// While the project's budget is used up, or when the project sets maxConcurrentSessions or
+// maxSessionsPerUser and no slot is free, the session is moved to Queued and
+// ErrSessionQueued is returned.
 func ReconcilePendingSession(ctx context.Context, session *unstructured.Unstructured, appConfig *config.Config) error {
+	held, err := holdForBudget(session)
+	if err != nil {
+		return err
+	}
+	if held {
+		return ErrSessionQueued
+	}
+
+	if quota := getProjectSessionQuota(session.GetNamespace()); quota.enabled() {
+		// Hold the lock until the pod is created so the next admission sees this session as Creating
+		unlock := lockAdmission(session.GetNamespace())
+		defer unlock()
+		admitted, err := admitSession(ctx, session, quota)
+		if err != nil {
+			return err
+		}
+		if !admitted {
+			return ErrSessionQueued
+		}
+	} else if err := releaseQueuedSession(session); err != nil {
+		return err
+	}
+
 	// Delegate to existing handleAgenticSessionEvent logic
 	// This is a wrapper that allows the existing code to be called from the controller
 	return handleAgenticSessionEvent(session)
@@ -60,47 +87,74 @@ func ResetToPending(ctx context.Context, session *unstructured.Unstructured) err
 	return nil
 }
 
+// stopDetails describes a stop for status.stoppedReason and the stop conditions.
+type stopDetails struct {
+	StoppedReason   string
+	ConditionReason string
+	PodMsg          string
+	RunnerMsg       string
+	ReadyMsg        string
+}
+
+// resolveStopDetails maps the stop-reason annotation to the status written on Stopped.
+// A missing or unknown reason means the user requested the stop.
+func resolveStopDetails(annotations map[string]string) stopDetails {
+	switch annotations[stopReasonAnnotation] {
+	case "inactivity":
+		return stopDetails{
+			StoppedReason:   "inactivity",
+			ConditionReason: "InactivityTimeout",
+			PodMsg:          "Pod deleted due to inactivity timeout",
+			RunnerMsg:       "Runner stopped due to inactivity",
+			ReadyMsg:        "Session stopped due to inactivity",
+		}
+	case stopReasonBudget:
+		return stopDetails{
+			StoppedReason:   stopReasonBudget,
+			ConditionReason: conditionReasonBudgetExceeded,
+			PodMsg:          "Pod deleted because the project budget hard cap was exceeded",
+			RunnerMsg:       "Runner stopped due to project budget",
+			ReadyMsg:        "Session stopped due to project budget",
+		}
+	}
+	return stopDetails{
+		StoppedReason:   "user",
+		ConditionReason: "UserStopped",
+		PodMsg:          "Pod deleted by user stop request",
+		RunnerMsg:       "Runner stopped by user",
+		ReadyMsg:        "Session stopped by user",
+	}
+}
+
 // TransitionToStopped transitions a session to Stopped phase.
 func TransitionToStopped(ctx context.Context, session *unstructured.Unstructured) error {
 	namespace := session.GetNamespace()
 	name := session.GetName()
 
-	// Determine stop reason from annotation (inactivity vs user)
-	stopReason := "user"
-	conditionReason := "UserStopped"
-	conditionPodMsg := "Pod deleted by user stop request"
-	conditionRunnerMsg := "Runner stopped by user"
-	conditionReadyMsg := "Session stopped by user"
-	annotations := session.GetAnnotations()
-	if annotations != nil && annotations[stopReasonAnnotation] == "inactivity" {
-		stopReason = "inactivity"
-		conditionReason = "InactivityTimeout"
-		conditionPodMsg = "Pod deleted due to inactivity timeout"
-		conditionRunnerMsg = "Runner stopped due to inactivity"
-		conditionReadyMsg = "Session stopped due to inactivity"
-	}
+	// Determine stop reason from annotation (user, inactivity or budget)
+	stop := resolveStopDetails(session.GetAnnotations())
 
 	statusPatch := NewStatusPatch(namespace, name)
 	statusPatch.SetField("phase", "Stopped")
 	statusPatch.SetField("completionTime", time.Now().UTC().Format(time.RFC3339))
-	statusPatch.SetField("stoppedReason", stopReason)
+	statusPatch.SetField("stoppedReason", stop.StoppedReason)
 	statusPatch.AddCondition(conditionUpdate{
 		Type:    conditionReady,
 		Status:  "False",
-		Reason:  conditionReason,
-		Message: conditionReadyMsg,
+		Reason:  stop.ConditionReason,
+		Message: stop.ReadyMsg,
 	})
 	statusPatch.AddCondition(conditionUpdate{
 		Type:    conditionPodCreated,
 		Status:  "False",
-		Reason:  conditionReason,
-		Message: conditionPodMsg,
+		Reason:  stop.ConditionReason,
+		Message: stop.PodMsg,
 	})
 	statusPatch.AddCondition(conditionUpdate{
 		Type:    conditionRunnerStarted,
 		Status:  "False",
-		Reason:  conditionReason,
-		Message: conditionRunnerMsg,
+		Reason:  stop.ConditionReason,
+		Message: stop.RunnerMsg,
 	})
 
 	if err := statusPatch.Apply(); err != nil {
@@ -0,0 +1,246 @@
+// Package handlers provides garbage collection of finished sessions. Sessions that
+// reach Completed or Failed are deleted ttlSecondsAfterFinished later, together with
+// their S3 state and, through the backend, their event log, unless they are annotated
+// ambient-code.io/retain.
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	"ambient-code-shared/objstore"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// retainAnnotation set to "true" exempts a session from garbage collection
+	retainAnnotation = "ambient-code.io/retain"
+
+	// sessionGCInterval is how often finished sessions are checked for expiry
+	sessionGCInterval = time.Minute
+
+	// backendRequestTimeout bounds a call to the backend to delete an event log
+	backendRequestTimeout = 30 * time.Second
+)
+
+// sessionRetention is a project's ttlSecondsAfterFinished default and maximum; -1 means unset.
+type sessionRetention struct {
+	defaultTTL int64
+	maxTTL     int64
+}
+
+// getProjectSessionRetention reads defaultTtlSecondsAfterFinished and maxTtlSecondsAfterFinished
+// from the ProjectSettings CR in namespace.
+func getProjectSessionRetention(namespace string) sessionRetention {
+	retention := sessionRetention{defaultTTL: -1, maxTTL: -1}
+	gvr := types.GetProjectSettingsResource()
+	obj, err := config.DynamicClient.Resource(gvr).Namespace(namespace).Get(context.TODO(), projectSettingsName, v1.GetOptions{})
+	if err != nil {
+		return retention
+	}
+	if val, found, _ := unstructured.NestedInt64(obj.Object, "spec", "defaultTtlSecondsAfterFinished"); found {
+		retention.defaultTTL = val
+	}
+	if val, found, _ := unstructured.NestedInt64(obj.Object, "spec", "maxTtlSecondsAfterFinished"); found {
+		retention.maxTTL = val
+	}
+	return retention
+}
+
+// resolveSessionTTL determines how long a session is kept after it finishes.
+// Precedence: session spec > project default; the project maximum caps both and
+// applies to sessions that set neither. ok is false when the session is kept.
+func resolveSessionTTL(sessionObj *unstructured.Unstructured, retention sessionRetention) (ttl time.Duration, ok bool) {
+	seconds, found, _ := unstructured.NestedInt64(sessionObj.Object, "spec", "ttlSecondsAfterFinished")
+	if !found {
+		seconds = retention.defaultTTL
+	}
+	if retention.maxTTL >= 0 && (seconds < 0 || seconds > retention.maxTTL) {
+		seconds = retention.maxTTL
+	}
+	if seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// sessionExpired reports whether a Completed or Failed session has outlived its TTL at now.
+// Retained sessions and sessions without a completionTime never expire.
+func sessionExpired(sessionObj *unstructured.Unstructured, retention sessionRetention, now time.Time) bool {
+	if sessionObj.GetAnnotations()[retainAnnotation] == "true" || sessionObj.GetDeletionTimestamp() != nil {
+		return false
+	}
+	phase, _, _ := unstructured.NestedString(sessionObj.Object, "status", "phase")
+	if phase != "Completed" && phase != "Failed" {
+		return false
+	}
+	ttl, ok := resolveSessionTTL(sessionObj, retention)
+	if !ok {
+		return false
+	}
+	completion, _, _ := unstructured.NestedString(sessionObj.Object, "status", "completionTime")
+	finished, err := time.Parse(time.RFC3339, completion)
+	if err != nil {
+		return false
+	}
+	return !now.Before(finished.Add(ttl))
+}
+
+// CollectExpiredSessions runs the session garbage collector until ctx is cancelled,
+// calling deleted with the namespace and final phase of each session it deletes. It
+// must only run on the leader.
+func CollectExpiredSessions(ctx context.Context, appConfig *config.Config, deleted func(namespace, phase string)) error {
+	ticker := time.NewTicker(sessionGCInterval)
+	defer ticker.Stop()
+	for {
+		collectExpiredSessions(ctx, appConfig, time.Now(), deleted)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// collectExpiredSessions deletes every session that expired by now
+func collectExpiredSessions(ctx context.Context, appConfig *config.Config, now time.Time, deleted func(namespace, phase string)) {
+	if config.DynamicClient == nil {
+		return
+	}
+	list, err := config.DynamicClient.Resource(types.GetAgenticSessionResource()).List(ctx, v1.ListOptions{})
+	if err != nil {
+		log.Printf("[SessionGC] Failed to list sessions: %v", err)
+		return
+	}
+
+	// The backend keys event logs by session name alone, and follow-up sessions copy their
+	// parent's artifacts from S3 when their pod starts
+	namespacesByName := make(map[string]int)
+	pinned := make(map[string]bool)
+	for i := range list.Items {
+		s := &list.Items[i]
+		namespacesByName[s.GetName()]++
+		if parent := s.GetAnnotations()[carryArtifactsFromAnnotation]; parent != "" && !podStarted(s) {
+			pinned[s.GetNamespace()+"/"+parent] = true
+		}
+	}
+
+	retention := make(map[string]sessionRetention)
+	for i := range list.Items {
+		s := &list.Items[i]
+		namespace, name := s.GetNamespace(), s.GetName()
+		if pinned[namespace+"/"+name] {
+			continue
+		}
+		if _, ok := retention[namespace]; !ok {
+			retention[namespace] = getProjectSessionRetention(namespace)
+		}
+		if !sessionExpired(s, retention[namespace], now) {
+			continue
+		}
+		if err := deleteExpiredSession(ctx, appConfig, s, namespacesByName[name] == 1); err != nil {
+			log.Printf("[SessionGC] Failed to delete session %s/%s: %v", namespace, name, err)
+			continue
+		}
+		phase, _, _ := unstructured.NestedString(s.Object, "status", "phase")
+		log.Printf("[SessionGC] Deleted %s session %s/%s after its TTL expired", phase, namespace, name)
+		if deleted != nil {
+			deleted(namespace, phase)
+		}
+	}
+}
+
+// podStarted reports whether a session got past pod creation, after which its
+// workspace has been hydrated
+func podStarted(sessionObj *unstructured.Unstructured) bool {
+	phase, _, _ := unstructured.NestedString(sessionObj.Object, "status", "phase")
+	switch phase {
+	case "", "Pending", "Queued", "Creating":
+		return false
+	}
+	return true
+}
+
+// deleteExpiredSession deletes the session's S3 state, its event log when no other
+// session shares it, and then the CR, so a failed cleanup is retried on the next pass.
+func deleteExpiredSession(ctx context.Context, appConfig *config.Config, sessionObj *unstructured.Unstructured, ownsEventLog bool) error {
+	namespace, name := sessionObj.GetNamespace(), sessionObj.GetName()
+
+	if err := deleteSessionState(ctx, appConfig, namespace, name); err != nil {
+		return fmt.Errorf("failed to delete S3 state: %w", err)
+	}
+	if ownsEventLog {
+		if err := deleteSessionEventLog(ctx, appConfig, namespace, name); err != nil {
+			return fmt.Errorf("failed to delete event log: %w", err)
+		}
+	} else {
+		log.Printf("[SessionGC] Keeping the event log of %s/%s: another project has a session with the same name", namespace, name)
+	}
+
+	// Only delete the CR that was found expired; a recreated session keeps its name but not its UID
+	uid := sessionObj.GetUID()
+	err := config.DynamicClient.Resource(types.GetAgenticSessionResource()).Namespace(namespace).Delete(ctx, name, v1.DeleteOptions{
+		Preconditions: &v1.Preconditions{UID: &uid},
+	})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// deleteSessionState removes the runner's synced state, s3://<bucket>/<namespace>/<session>/
+func deleteSessionState(ctx context.Context, appConfig *config.Config, namespace, name string) error {
+	endpoint, bucket, accessKey, secretKey, err := getS3ConfigForProject(namespace, appConfig)
+	if err != nil {
+		// Without S3 configuration the runner has not synced any state either
+		log.Printf("[SessionGC] No S3 state to delete for %s/%s: %v", namespace, name, err)
+		return nil
+	}
+	client, err := objstore.New(objstore.Config{Endpoint: endpoint, Bucket: bucket, AccessKey: accessKey, SecretKey: secretKey})
+	if err != nil {
+		return err
+	}
+	_, err = client.DeletePrefix(ctx, namespace+"/"+name+"/")
+	return err
+}
+
+// deleteSessionEventLog asks the backend to delete the session's event log and
+// snapshots, which it does through its event store, whichever store that is. The
+// backend keeps the log (409) while another project has a session with the same name.
+func deleteSessionEventLog(ctx context.Context, appConfig *config.Config, namespace, name string) error {
+	ctx, cancel := context.WithTimeout(ctx, backendRequestTimeout)
+	defer cancel()
+	url := fmt.Sprintf("%s/projects/%s/agentic-sessions/%s/events", strings.TrimSuffix(appConfig.BackendAPIURL, "/"), namespace, name)
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url, nil)
+	if err != nil {
+		return err
+	}
+	if token := config.BearerToken(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	switch {
+	case resp.StatusCode == http.StatusConflict:
+		log.Printf("[SessionGC] Keeping the event log of %s/%s: %s", namespace, name, strings.TrimSpace(string(body)))
+		return nil
+	case resp.StatusCode/100 != 2:
+		return fmt.Errorf("backend returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
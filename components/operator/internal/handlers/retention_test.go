@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	"ambient-code-shared/objstore"
+	"ambient-code-shared/objstore/objstoretest"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func finishedSession(name, namespace, phase string, finished time.Time, spec map[string]any) *unstructured.Unstructured {
+	return newSessionObj(name, namespace, withSpec(spec), withStatus(map[string]any{
+		"phase":          phase,
+		"completionTime": finished.UTC().Format(time.RFC3339),
+	}))
+}
+
+func TestResolveSessionTTL(t *testing.T) {
+	unset := sessionRetention{defaultTTL: -1, maxTTL: -1}
+	tests := []struct {
+		name      string
+		spec      map[string]any
+		retention sessionRetention
+		want      time.Duration
+		ok        bool
+	}{
+		{"unset keeps the session", map[string]any{}, unset, 0, false},
+		{"session TTL", map[string]any{"ttlSecondsAfterFinished": int64(60)}, unset, time.Minute, true},
+		{"session TTL zero", map[string]any{"ttlSecondsAfterFinished": int64(0)}, sessionRetention{defaultTTL: 3600, maxTTL: -1}, 0, true},
+		{"project default", map[string]any{}, sessionRetention{defaultTTL: 3600, maxTTL: -1}, time.Hour, true},
+		{"session TTL over the maximum", map[string]any{"ttlSecondsAfterFinished": int64(7200)}, sessionRetention{defaultTTL: -1, maxTTL: 3600}, time.Hour, true},
+		{"maximum applies without TTL", map[string]any{}, sessionRetention{defaultTTL: -1, maxTTL: 3600}, time.Hour, true},
+		{"session TTL under the maximum", map[string]any{"ttlSecondsAfterFinished": int64(60)}, sessionRetention{defaultTTL: 600, maxTTL: 3600}, time.Minute, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := resolveSessionTTL(newSessionObj("s", "ns", withSpec(tt.spec)), tt.retention)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("resolveSessionTTL() = %v, %v; want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestSessionExpired(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	retention := sessionRetention{defaultTTL: 3600, maxTTL: -1}
+
+	if !sessionExpired(finishedSession("s", "ns", "Completed", now.Add(-time.Hour), nil), retention, now) {
+		t.Error("Completed session at its TTL: want expired")
+	}
+	if !sessionExpired(finishedSession("s", "ns", "Failed", now.Add(-2*time.Hour), nil), retention, now) {
+		t.Error("Failed session past its TTL: want expired")
+	}
+	if sessionExpired(finishedSession("s", "ns", "Completed", now.Add(-59*time.Minute), nil), retention, now) {
+		t.Error("session within its TTL: want not expired")
+	}
+	if sessionExpired(finishedSession("s", "ns", "Stopped", now.Add(-2*time.Hour), nil), retention, now) {
+		t.Error("Stopped session: want not expired")
+	}
+	retained := finishedSession("s", "ns", "Completed", now.Add(-2*time.Hour), nil)
+	retained.SetAnnotations(map[string]string{retainAnnotation: "true"})
+	if sessionExpired(retained, retention, now) {
+		t.Error("retained session: want not expired")
+	}
+	noCompletion := newSessionObj("s", "ns", withStatus(map[string]any{"phase": "Failed"}))
+	if sessionExpired(noCompletion, retention, now) {
+		t.Error("session without completionTime: want not expired")
+	}
+}
+
+func TestCollectExpiredSessions(t *testing.T) {
+	now := time.Now()
+	server := objstoretest.NewServer()
+	defer server.Close()
+	// The backend deletes event logs
+	var mu sync.Mutex
+	var eventDeletes []string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		eventDeletes = append(eventDeletes, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer backend.Close()
+	appConfig := &config.Config{BackendAPIURL: backend.URL + "/api"}
+	setupTestClient(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "ambient-non-vertex-integrations", Namespace: "proj"},
+		Data: map[string][]byte{
+			"STORAGE_MODE":  []byte("custom"),
+			"S3_ENDPOINT":   []byte(server.URL),
+			"S3_BUCKET":     []byte("state"),
+			"S3_ACCESS_KEY": []byte("key"),
+			"S3_SECRET_KEY": []byte("secret"),
+		},
+	})
+
+	ttl := map[string]any{"ttlSecondsAfterFinished": int64(60)}
+	expired := finishedSession("expired", "proj", "Completed", now.Add(-time.Hour), ttl)
+	retained := finishedSession("retained", "proj", "Completed", now.Add(-time.Hour), ttl)
+	retained.SetAnnotations(map[string]string{retainAnnotation: "true"})
+	// The follow-up has not hydrated its parent's artifacts yet
+	parent := finishedSession("parent", "proj", "Completed", now.Add(-time.Hour), ttl)
+	followUp := newSessionObj("follow-up", "proj", withStatus(map[string]any{"phase": "Queued"}))
+	followUp.SetAnnotations(map[string]string{carryArtifactsFromAnnotation: "parent"})
+	// Another project's session has the same name, so they share an event log
+	shared := finishedSession("shared", "proj", "Failed", now.Add(-time.Hour), ttl)
+	sharedElsewhere := newSessionObj("shared", "other", withStatus(map[string]any{"phase": "Running"}))
+	setupFakeDynamicClient(expired, retained, parent, followUp, shared, sharedElsewhere)
+
+	ctx := context.Background()
+	state, _ := objstore.New(objstore.Config{Endpoint: server.URL, Bucket: "state", AccessKey: "key", SecretKey: "secret"})
+	for _, name := range []string{"expired", "retained", "shared"} {
+		if err := state.Put(ctx, "proj/"+name+"/.claude/history.jsonl", []byte("{}")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var deleted []string
+	collectExpiredSessions(ctx, appConfig, now, func(namespace, phase string) {
+		deleted = append(deleted, namespace+"/"+phase)
+	})
+
+	if len(deleted) != 2 || deleted[0] != "proj/Completed" || deleted[1] != "proj/Failed" {
+		t.Errorf("deleted = %v, want proj/Completed and proj/Failed", deleted)
+	}
+	sessions := config.DynamicClient.Resource(types.GetAgenticSessionResource())
+	for _, name := range []string{"expired", "shared"} {
+		if _, err := sessions.Namespace("proj").Get(ctx, name, metav1.GetOptions{}); err == nil {
+			t.Errorf("session %s was not deleted", name)
+		}
+	}
+	for _, name := range []string{"retained", "parent", "follow-up"} {
+		if _, err := sessions.Namespace("proj").Get(ctx, name, metav1.GetOptions{}); err != nil {
+			t.Errorf("session %s: %v", name, err)
+		}
+	}
+
+	if got := server.Keys("state"); len(got) != 1 || got[0] != "proj/retained/.claude/history.jsonl" {
+		t.Errorf("state keys = %v", got)
+	}
+	if len(eventDeletes) != 1 || eventDeletes[0] != "DELETE /api/projects/proj/agentic-sessions/expired/events" {
+		t.Errorf("event log deletions = %v, want only expired's", eventDeletes)
+	}
+}
+
+func TestDeleteSessionEventLog(t *testing.T) {
+	status := http.StatusConflict
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer backend.Close()
+	appConfig := &config.Config{BackendAPIURL: backend.URL + "/api"}
+	ctx := context.Background()
+
+	// A log the backend keeps does not hold up the session's deletion...
+	if err := deleteSessionEventLog(ctx, appConfig, "proj", "s"); err != nil {
+		t.Errorf("deleteSessionEventLog() on 409 = %v, want nil", err)
+	}
+	// ...but a failed deletion is retried on the next pass
+	status = http.StatusInternalServerError
+	if err := deleteSessionEventLog(ctx, appConfig, "proj", "s"); err == nil {
+		t.Error("deleteSessionEventLog() on 500: want error")
+	}
+}
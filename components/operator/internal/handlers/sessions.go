@@ -186,38 +186,29 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 			// Pod is gone - safe to transition to Stopped
 			log.Printf("[Stopping] Session %s/%s: pod deleted, transitioning to Stopped", sessionNamespace, name)
 
-			// Determine stop reason from annotation (inactivity vs user)
+			// Determine stop reason from annotation (user, inactivity or budget)
 			// TODO(controller-runtime-migration): This duplicates the logic in
 			// reconciler.go:TransitionToStopped(). Once the legacy watch handler
 			// is fully replaced by the controller-runtime reconciler, remove this
 			// block and rely solely on TransitionToStopped().
-			stopReason := "user"
-			conditionReason := "UserStopped"
-			conditionPodMsg := "Pod deleted by user stop request"
-			conditionRunnerMsg := "Runner stopped by user"
-			if annotations != nil && annotations[stopReasonAnnotation] == "inactivity" {
-				stopReason = "inactivity"
-				conditionReason = "InactivityTimeout"
-				conditionPodMsg = "Pod deleted due to inactivity timeout"
-				conditionRunnerMsg = "Runner stopped due to inactivity"
-			}
+			stop := resolveStopDetails(annotations)
 
 			// Set phase=Stopped explicitly
 			statusPatch.SetField("phase", "Stopped")
 			statusPatch.SetField("completionTime", time.Now().UTC().Format(time.RFC3339))
-			statusPatch.SetField("stoppedReason", stopReason)
+			statusPatch.SetField("stoppedReason", stop.StoppedReason)
 			// Update progress-tracking conditions to reflect stopped state
 			statusPatch.AddCondition(conditionUpdate{
 				Type:    conditionPodCreated,
 				Status:  "False",
-				Reason:  conditionReason,
-				Message: conditionPodMsg,
+				Reason:  stop.ConditionReason,
+				Message: stop.PodMsg,
 			})
 			statusPatch.AddCondition(conditionUpdate{
 				Type:    conditionRunnerStarted,
 				Status:  "False",
-				Reason:  conditionReason,
-				Message: conditionRunnerMsg,
+				Reason:  stop.ConditionReason,
+				Message: stop.RunnerMsg,
 			})
 
 			if err := statusPatch.Apply(); err != nil {
@@ -793,6 +784,14 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 						base = append(base, corev1.EnvVar{Name: "REPOS_JSON", Value: string(b)})
 					}
 
+					// Artifacts carried over from the previous session of a spec.next chain
+					if from := annotations[carryArtifactsFromAnnotation]; from != "" {
+						base = append(base,
+							corev1.EnvVar{Name: "CARRY_ARTIFACTS_FROM", Value: from},
+							corev1.EnvVar{Name: "CARRY_ARTIFACTS_JSON", Value: annotations[carryArtifactsAnnotation]},
+						)
+					}
+
 					// Add workflow info if present
 					if workflow, ok := spec["activeWorkflow"].(map[string]interface{}); ok {
 						if gitURL, ok := workflow["gitUrl"].(string); ok && strings.TrimSpace(gitURL) != "" {
@@ -919,7 +918,7 @@ func handleAgenticSessionEvent(obj *unstructured.Unstructured) error {
 						corev1.EnvVar{Name: "LLM_MAX_TOKENS", Value: fmt.Sprintf("%d", maxTokens)},
 						corev1.EnvVar{Name: "USE_AGUI", Value: "true"},
 						corev1.EnvVar{Name: "TIMEOUT", Value: fmt.Sprintf("%d", timeout)},
-						corev1.EnvVar{Name: "BACKEND_API_URL", Value: fmt.Sprintf("http://backend-service.%s.svc.cluster.local:8080/api", appConfig.BackendNamespace)},
+						corev1.EnvVar{Name: "BACKEND_API_URL", Value: appConfig.BackendAPIURL},
 						// LEGACY: WEBSOCKET_URL removed - runner now uses AG-UI server pattern (FastAPI)
 						// Backend proxies to runner's HTTP endpoint instead of WebSocket
 					)
@@ -1726,6 +1725,16 @@ func monitorPod(podName, sessionName, sessionNamespace string) {
 			return
 		}
 
+		// Check the project budget hard cap for running sessions
+		if exceeded, detail := shouldStopForBudget(sessionObj); exceeded {
+			log.Printf("[Budget] Session %s/%s: project %s, triggering stop", sessionNamespace, sessionName, detail)
+			if err := triggerBudgetStop(sessionNamespace, sessionName); err != nil {
+				log.Printf("[Budget] Failed to stop %s/%s: %v", sessionNamespace, sessionName, err)
+				continue
+			}
+			return
+		}
+
 		if err := ensureFreshRunnerToken(context.TODO(), sessionObj); err != nil {
 			log.Printf("Failed to refresh runner token for %s/%s: %v", sessionNamespace, sessionName, err)
 		}
@@ -0,0 +1,567 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/retry"
+
+	"ambient-code-operator/internal/config"
+	"ambient-code-operator/internal/types"
+
+	"ambient-code-shared/webhook"
+)
+
+const (
+	webhookDeliveryLogMax = 100
+	// webhookPendingKey holds the deliveries still waiting for an attempt in the
+	// webhook.DeliveryLogConfigMap, which carries webhookPendingLabel while it is non-empty.
+	webhookPendingKey   = "pending"
+	webhookPendingLabel = "ambient-code.io/webhook-pending"
+	webhookPollInterval = 15 * time.Second
+
+	webhookEventPhaseChanged = "session.phase_changed"
+	webhookSignatureHeader   = "X-Ambient-Signature-256"
+	webhookEventHeader       = "X-Ambient-Event"
+	webhookDeliveryHeader    = "X-Ambient-Delivery"
+	webhookMaxAttempts       = 5
+	webhookDefaultSecretKey  = "secret"
+)
+
+var (
+	// webhookTerminalPhases are the phases that trigger webhook delivery
+	webhookTerminalPhases = map[string]bool{"Completed": true, "Failed": true, "Stopped": true}
+
+	// webhookHTTPClient and webhookURLAllowed block internal destinations. Overridden in tests.
+	webhookHTTPClient = webhook.NewHTTPClient(10 * time.Second)
+	webhookURLAllowed = webhook.ValidateURL
+
+	// webhookWake wakes ProcessWebhookDeliveries when a delivery is queued
+	webhookWake = make(chan struct{}, 1)
+
+	// webhookBackoff returns the delay before retry attempt n (1-based). Overridden in tests.
+	webhookBackoff = func(attempt int) time.Duration {
+		d := time.Duration(1<<uint(attempt-1)) * 2 * time.Second
+		if d > time.Minute {
+			d = time.Minute
+		}
+		return d
+	}
+)
+
+// webhookConfig is a single entry from ProjectSettings spec.webhooks
+type webhookConfig struct {
+	Name       string
+	URL        string
+	SecretName string
+	SecretKey  string
+	Events     []string
+	Enabled    bool
+}
+
+func (w webhookConfig) wantsPhase(phase string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == phase {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookPayload is the JSON body delivered to webhook endpoints
+type webhookPayload struct {
+	ID             string `json:"id"`
+	Event          string `json:"event"`
+	Timestamp      string `json:"timestamp"`
+	Project        string `json:"project"`
+	Session        string `json:"session"`
+	DisplayName    string `json:"displayName,omitempty"`
+	PreviousPhase  string `json:"previousPhase,omitempty"`
+	Phase          string `json:"phase"`
+	StoppedReason  string `json:"stoppedReason,omitempty"`
+	StartTime      string `json:"startTime,omitempty"`
+	CompletionTime string `json:"completionTime,omitempty"`
+	UserID         string `json:"userId,omitempty"`
+}
+
+// pendingWebhook is a delivery waiting for its next attempt. Pending deliveries are kept
+// in the delivery log ConfigMap so retries survive an operator restart.
+type pendingWebhook struct {
+	Webhook     string         `json:"webhook"`
+	Payload     webhookPayload `json:"payload"`
+	Attempts    int            `json:"attempts"`
+	StatusCode  int            `json:"statusCode,omitempty"`
+	Error       string         `json:"error,omitempty"`
+	QueuedAt    time.Time      `json:"queuedAt"`
+	NextAttempt time.Time      `json:"nextAttempt"`
+}
+
+// notifySessionPhaseChange queues a delivery for every matching webhook when a session
+// enters a terminal phase and wakes the delivery loop. Called after every successful
+// status update that changes the phase.
+func notifySessionPhaseChange(session *unstructured.Unstructured, previousPhase, phase string) {
+	if !webhookTerminalPhases[phase] || previousPhase == phase {
+		return
+	}
+	namespace := session.GetNamespace()
+
+	hooks := getProjectWebhooks(namespace)
+	if len(hooks) == 0 {
+		return
+	}
+
+	now := time.Now().UTC()
+	var queued []pendingWebhook
+	for _, hook := range hooks {
+		if !hook.Enabled || !hook.wantsPhase(phase) {
+			continue
+		}
+		payload := buildWebhookPayload(session, previousPhase, phase)
+		if err := webhookURLAllowed(hook.URL); err != nil {
+			log.Printf("[Webhook] Refusing to deliver to %s/%s: %v", namespace, hook.Name, err)
+			recordWebhookDelivery(namespace, webhook.Delivery{
+				ID: payload.ID, Webhook: hook.Name, URL: hook.URL, Session: payload.Session, Phase: phase,
+				Error: err.Error(), Timestamp: now.Format(time.RFC3339),
+			})
+			continue
+		}
+		queued = append(queued, pendingWebhook{Webhook: hook.Name, Payload: payload, QueuedAt: now, NextAttempt: now})
+	}
+	if len(queued) == 0 {
+		return
+	}
+
+	err := updateWebhookLog(namespace, func(l *webhookLog) {
+		l.Pending = append(l.Pending, queued...)
+	})
+	if err != nil {
+		log.Printf("[Webhook] Failed to queue %d deliveries for %s/%s: %v", len(queued), namespace, session.GetName(), err)
+		return
+	}
+	wakeWebhookDeliveries()
+}
+func buildWebhookPayload(session *unstructured.Unstructured, previousPhase, phase string) webhookPayload {
+	p := webhookPayload{
+		ID:            newDeliveryID(),
+		Event:         webhookEventPhaseChanged,
+		Timestamp:     time.Now().UTC().Format(time.RFC3339),
+		Project:       session.GetNamespace(),
+		Session:       session.GetName(),
+		PreviousPhase: previousPhase,
+		Phase:         phase,
+	}
+	p.DisplayName, _, _ = unstructured.NestedString(session.Object, "spec", "displayName")
+	p.UserID, _, _ = unstructured.NestedString(session.Object, "spec", "userContext", "userId")
+	p.StoppedReason, _, _ = unstructured.NestedString(session.Object, "status", "stoppedReason")
+	p.StartTime, _, _ = unstructured.NestedString(session.Object, "status", "startTime")
+	p.CompletionTime, _, _ = unstructured.NestedString(session.Object, "status", "completionTime")
+	return p
+}
+
+// getProjectWebhooks reads spec.webhooks from the namespace's ProjectSettings.
+func getProjectWebhooks(namespace string) []webhookConfig {
+	if config.DynamicClient == nil {
+		return nil
+	}
+	obj, err := config.DynamicClient.Resource(types.GetProjectSettingsResource()).Namespace(namespace).Get(
+		context.TODO(), projectSettingsName, v1.GetOptions{},
+	)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Printf("[Webhook] Failed to read ProjectSettings in %s: %v", namespace, err)
+		}
+		return nil
+	}
+
+	entries, found, _ := unstructured.NestedSlice(obj.Object, "spec", "webhooks")
+	if !found {
+		return nil
+	}
+	hooks := make([]webhookConfig, 0, len(entries))
+	for _, entry := range entries {
+		m, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		hook := webhookConfig{Enabled: true, SecretKey: webhookDefaultSecretKey}
+		hook.Name, _ = m["name"].(string)
+		hook.URL, _ = m["url"].(string)
+		hook.SecretName, _ = m["secretName"].(string)
+		if key, ok := m["secretKey"].(string); ok && key != "" {
+			hook.SecretKey = key
+		}
+		if enabled, ok := m["enabled"].(bool); ok {
+			hook.Enabled = enabled
+		}
+		if events, ok := m["events"].([]interface{}); ok {
+			for _, e := range events {
+				if s, ok := e.(string); ok {
+					hook.Events = append(hook.Events, s)
+				}
+			}
+		}
+		if hook.URL == "" {
+			continue
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks
+}
+
+// ProcessWebhookDeliveries runs the webhook delivery loop until ctx is cancelled. It wakes
+// up whenever a delivery is queued and every webhookPollInterval to pick up retries,
+// including those queued before an operator restart. It must only run on the leader so a
+// delivery is not sent once per replica.
+func ProcessWebhookDeliveries(ctx context.Context) error {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+	for {
+		processDueWebhooks()
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-webhookWake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func wakeWebhookDeliveries() {
+	select {
+	case webhookWake <- struct{}{}:
+	default:
+	}
+}
+
+// processDueWebhooks processes every project whose delivery log has pending deliveries.
+func processDueWebhooks() {
+	if config.K8sClient == nil {
+		return
+	}
+	cms, err := config.K8sClient.CoreV1().ConfigMaps("").List(context.TODO(), v1.ListOptions{
+		LabelSelector: webhookPendingLabel + "=true",
+	})
+	if err != nil {
+		log.Printf("[Webhook] Failed to list pending deliveries: %v", err)
+		return
+	}
+	for _, cm := range cms.Items {
+		if cm.Name == webhook.DeliveryLogConfigMap {
+			processPendingWebhooks(cm.Namespace)
+		}
+	}
+}
+
+// processPendingWebhooks makes one attempt for each due delivery in namespace. Finished
+// deliveries move to the delivery log, failed ones are rescheduled using webhookBackoff.
+func processPendingWebhooks(namespace string) {
+	current, err := readWebhookLog(namespace)
+	if err != nil {
+		log.Printf("[Webhook] Failed to read pending deliveries in %s: %v", namespace, err)
+		return
+	}
+	now := time.Now().UTC()
+	var attempted []pendingWebhook
+	for _, p := range current.Pending {
+		if !p.NextAttempt.After(now) {
+			attempted = append(attempted, p)
+		}
+	}
+	if len(attempted) == 0 {
+		return
+	}
+
+	hooks := make(map[string]webhookConfig)
+	for _, hook := range getProjectWebhooks(namespace) {
+		hooks[hook.Name] = hook
+	}
+	updated := make(map[string]pendingWebhook, len(attempted))
+	finished := make(map[string]webhook.Delivery)
+	for _, p := range attempted {
+		hook, configured := hooks[p.Webhook]
+		if delivery, done := attemptWebhook(namespace, hook, configured, &p); done {
+			finished[p.Payload.ID] = delivery
+		}
+		updated[p.Payload.ID] = p
+	}
+
+	err = updateWebhookLog(namespace, func(l *webhookLog) {
+		pending := make([]pendingWebhook, 0, len(l.Pending))
+		for _, p := range l.Pending {
+			if _, done := finished[p.Payload.ID]; done {
+				continue
+			}
+			if u, ok := updated[p.Payload.ID]; ok {
+				p = u
+			}
+			pending = append(pending, p)
+		}
+		l.Pending = pending
+		for _, p := range attempted {
+			if delivery, done := finished[p.Payload.ID]; done {
+				l.Deliveries = append([]webhook.Delivery{delivery}, l.Deliveries...)
+			}
+		}
+	})
+	if err != nil {
+		log.Printf("[Webhook] Failed to update delivery log in %s: %v", namespace, err)
+	}
+}
+
+// attemptWebhook makes one delivery attempt for p and updates it in place. It returns the
+// delivery log entry and true once the delivery succeeded or will not be retried.
+func attemptWebhook(namespace string, hook webhookConfig, configured bool, p *pendingWebhook) (webhook.Delivery, bool) {
+	p.Attempts++
+	var retry bool
+	var err error
+	if configured && hook.Enabled {
+		p.StatusCode, retry, err = sendWebhook(namespace, hook, p.Payload)
+	} else {
+		p.StatusCode, err = 0, fmt.Errorf("webhook %q was removed or disabled before delivery", p.Webhook)
+	}
+	p.Error = ""
+	if err != nil {
+		p.Error = err.Error()
+	}
+
+	if retry && p.Attempts < webhookMaxAttempts {
+		p.NextAttempt = time.Now().UTC().Add(webhookBackoff(p.Attempts))
+		log.Printf("[Webhook] Attempt %d to deliver %s for %s/%s to %s failed, retrying at %s: %s",
+			p.Attempts, p.Payload.Phase, namespace, p.Payload.Session, p.Webhook, p.NextAttempt.Format(time.RFC3339), p.Error)
+		return webhook.Delivery{}, false
+	}
+
+	now := time.Now().UTC()
+	delivery := webhook.Delivery{
+		ID:         p.Payload.ID,
+		Webhook:    p.Webhook,
+		URL:        hook.URL,
+		Session:    p.Payload.Session,
+		Phase:      p.Payload.Phase,
+		Attempts:   p.Attempts,
+		StatusCode: p.StatusCode,
+		Success:    err == nil,
+		Error:      p.Error,
+		Timestamp:  now.Format(time.RFC3339),
+		DurationMs: now.Sub(p.QueuedAt).Milliseconds(),
+	}
+	if delivery.Success {
+		log.Printf("[Webhook] Delivered %s for %s/%s to %s (attempts=%d)", p.Payload.Phase, namespace, p.Payload.Session, p.Webhook, p.Attempts)
+	} else {
+		log.Printf("[Webhook] Failed to deliver %s for %s/%s to %s after %d attempts: %s",
+			p.Payload.Phase, namespace, p.Payload.Session, p.Webhook, p.Attempts, p.Error)
+	}
+	return delivery, true
+}
+
+// sendWebhook POSTs the signed payload once. retry reports whether a failure may succeed
+// later: network errors, 5xx, 408 and 429 are retried, anything else is final.
+func sendWebhook(namespace string, hook webhookConfig, payload webhookPayload) (statusCode int, retry bool, err error) {
+	if err := webhookURLAllowed(hook.URL); err != nil {
+		return 0, false, err
+	}
+	secret, err := getWebhookSecret(namespace, hook)
+	if err != nil {
+		return 0, false, err
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	statusCode, err = postWebhook(hook.URL, body, secret, payload.ID)
+	if err == nil {
+		return statusCode, false, nil
+	}
+	if stderrors.Is(err, webhook.ErrBlockedAddress) {
+		return statusCode, false, err
+	}
+	retry = statusCode == 0 || statusCode >= 500 ||
+		statusCode == http.StatusRequestTimeout || statusCode == http.StatusTooManyRequests
+	return statusCode, retry, err
+}
+
+func postWebhook(url string, body []byte, secret []byte, deliveryID string) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("invalid webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ambient-code-operator")
+	req.Header.Set(webhookEventHeader, webhookEventPhaseChanged)
+	req.Header.Set(webhookDeliveryHeader, deliveryID)
+	if len(secret) > 0 {
+		req.Header.Set(webhookSignatureHeader, signWebhookPayload(secret, body))
+	}
+
+	resp, err := webhookHTTPClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// signWebhookPayload returns the GitHub-style "sha256=<hex>" HMAC of body.
+func signWebhookPayload(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func getWebhookSecret(namespace string, hook webhookConfig) ([]byte, error) {
+	if hook.SecretName == "" {
+		return nil, nil
+	}
+	if config.K8sClient == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+	secret, err := config.K8sClient.CoreV1().Secrets(namespace).Get(context.TODO(), hook.SecretName, v1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing secret %s: %w", hook.SecretName, err)
+	}
+	value, ok := secret.Data[hook.SecretKey]
+	if !ok || len(value) == 0 {
+		return nil, fmt.Errorf("signing secret %s has no key %q", hook.SecretName, hook.SecretKey)
+	}
+	return value, nil
+}
+
+// webhookLog is the content of a project's delivery log ConfigMap: the most recent
+// finished deliveries, newest first, and the deliveries waiting for an attempt.
+type webhookLog struct {
+	Deliveries []webhook.Delivery
+	Pending    []pendingWebhook
+}
+
+func decodeWebhookLog(cm *corev1.ConfigMap) webhookLog {
+	var l webhookLog
+	if raw := cm.Data[webhook.DeliveryLogKey]; raw != "" {
+		_ = json.Unmarshal([]byte(raw), &l.Deliveries)
+	}
+	if raw := cm.Data[webhookPendingKey]; raw != "" {
+		_ = json.Unmarshal([]byte(raw), &l.Pending)
+	}
+	return l
+}
+
+func readWebhookLog(namespace string) (webhookLog, error) {
+	if config.K8sClient == nil {
+		return webhookLog{}, fmt.Errorf("kubernetes client not initialized")
+	}
+	cm, err := config.K8sClient.CoreV1().ConfigMaps(namespace).Get(context.TODO(), webhook.DeliveryLogConfigMap, v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return webhookLog{}, nil
+	}
+	if err != nil {
+		return webhookLog{}, err
+	}
+	return decodeWebhookLog(cm), nil
+}
+
+// updateWebhookLog applies mutate to the project's delivery log ConfigMap, creating it if
+// needed. The log is capped at webhookDeliveryLogMax entries and the ConfigMap carries
+// webhookPendingLabel while deliveries are pending so the delivery loop can find it.
+func updateWebhookLog(namespace string, mutate func(*webhookLog)) error {
+	if config.K8sClient == nil {
+		return fmt.Errorf("kubernetes client not initialized")
+	}
+	cms := config.K8sClient.CoreV1().ConfigMaps(namespace)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := cms.Get(context.TODO(), webhook.DeliveryLogConfigMap, v1.GetOptions{})
+		create := errors.IsNotFound(err)
+		if create {
+			cm = &corev1.ConfigMap{ObjectMeta: v1.ObjectMeta{
+				Name:      webhook.DeliveryLogConfigMap,
+				Namespace: namespace,
+				Labels:    map[string]string{"app.kubernetes.io/managed-by": "ambient-code-operator"},
+			}}
+		} else if err != nil {
+			return err
+		}
+
+		l := decodeWebhookLog(cm)
+		mutate(&l)
+		if len(l.Deliveries) > webhookDeliveryLogMax {
+			l.Deliveries = l.Deliveries[:webhookDeliveryLogMax]
+		}
+		if l.Deliveries == nil {
+			l.Deliveries = []webhook.Delivery{}
+		}
+		deliveries, err := json.Marshal(l.Deliveries)
+		if err != nil {
+			return err
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		if cm.Labels == nil {
+			cm.Labels = map[string]string{}
+		}
+		cm.Data[webhook.DeliveryLogKey] = string(deliveries)
+		if len(l.Pending) > 0 {
+			pending, err := json.Marshal(l.Pending)
+			if err != nil {
+				return err
+			}
+			cm.Data[webhookPendingKey] = string(pending)
+			cm.Labels[webhookPendingLabel] = "true"
+		} else {
+			delete(cm.Data, webhookPendingKey)
+			delete(cm.Labels, webhookPendingLabel)
+		}
+
+		if create {
+			_, err = cms.Create(context.TODO(), cm, v1.CreateOptions{})
+			if errors.IsAlreadyExists(err) {
+				// Lost a create race - retry as an update
+				return errors.NewConflict(corev1.Resource("configmaps"), webhook.DeliveryLogConfigMap, err)
+			}
+			return err
+		}
+		_, err = cms.Update(context.TODO(), cm, v1.UpdateOptions{})
+		return err
+	})
+}
+
+// recordWebhookDelivery prepends a finished delivery to the project's delivery log.
+func recordWebhookDelivery(namespace string, delivery webhook.Delivery) {
+	err := updateWebhookLog(namespace, func(l *webhookLog) {
+		l.Deliveries = append([]webhook.Delivery{delivery}, l.Deliveries...)
+	})
+	if err != nil {
+		log.Printf("[Webhook] Failed to record delivery %s in %s: %v", delivery.ID, namespace, err)
+	}
+}
+
+func newDeliveryID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
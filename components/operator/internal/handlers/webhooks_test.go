@@ -0,0 +1,348 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ambient-code-operator/internal/config"
+
+	"ambient-code-shared/webhook"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+func newProjectSettingsObj(namespace string, webhooks []any) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]any{
+			"apiVersion": "vteam.ambient-code/v1alpha1",
+			"kind":       "ProjectSettings",
+			"metadata": map[string]any{
+				"name":      projectSettingsName,
+				"namespace": namespace,
+			},
+			"spec": map[string]any{
+				"groupAccess": []any{},
+				"webhooks":    webhooks,
+			},
+		},
+	}
+}
+
+// allowTestWebhooks lets deliveries reach httptest servers on loopback and makes every
+// retry due immediately.
+func allowTestWebhooks(t *testing.T) {
+	t.Helper()
+	origClient, origAllowed, origBackoff := webhookHTTPClient, webhookURLAllowed, webhookBackoff
+	webhookHTTPClient = &http.Client{Timeout: 5 * time.Second}
+	webhookURLAllowed = func(string) error { return nil }
+	webhookBackoff = func(int) time.Duration { return 0 }
+	t.Cleanup(func() {
+		webhookHTTPClient, webhookURLAllowed, webhookBackoff = origClient, origAllowed, origBackoff
+	})
+}
+
+func readWebhookLogMap(t *testing.T, namespace string) *corev1.ConfigMap {
+	t.Helper()
+	cm, err := config.K8sClient.CoreV1().ConfigMaps(namespace).Get(context.Background(), webhook.DeliveryLogConfigMap, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected delivery log configmap: %v", err)
+	}
+	return cm
+}
+
+func readDeliveryLog(t *testing.T, namespace string) []webhook.Delivery {
+	t.Helper()
+	var deliveries []webhook.Delivery
+	if err := json.Unmarshal([]byte(readWebhookLogMap(t, namespace).Data[webhook.DeliveryLogKey]), &deliveries); err != nil {
+		t.Fatalf("invalid delivery log: %v", err)
+	}
+	return deliveries
+}
+
+func readPendingWebhooks(t *testing.T, namespace string) []pendingWebhook {
+	t.Helper()
+	return decodeWebhookLog(readWebhookLogMap(t, namespace)).Pending
+}
+
+func completedSession(namespace string) *unstructured.Unstructured {
+	return newSessionObj("s1", namespace,
+		withSpec(map[string]any{"displayName": "Fix the build", "userContext": map[string]any{"userId": "alice"}}),
+		withStatus(map[string]any{"phase": "Completed"}))
+}
+
+func TestSignWebhookPayload(t *testing.T) {
+	// Known HMAC-SHA256 vector
+	got := signWebhookPayload([]byte("key"), []byte("The quick brown fox jumps over the lazy dog"))
+	want := "sha256=f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"
+	if got != want {
+		t.Errorf("signWebhookPayload = %q, want %q", got, want)
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	want := []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 32 * time.Second, time.Minute, time.Minute}
+	for i, w := range want {
+		if got := webhookBackoff(i + 1); got != w {
+			t.Errorf("webhookBackoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+}
+
+func TestGetProjectWebhooks(t *testing.T) {
+	setupFakeDynamicClient(newProjectSettingsObj("ns1", []any{
+		map[string]any{"name": "slack", "url": "https://hooks.example.com/a", "events": []any{"Failed"}},
+		map[string]any{"name": "ci", "url": "https://ci.example.com/hook", "enabled": false, "secretName": "hook-secret"},
+		map[string]any{"name": "no-url"},
+	}))
+
+	hooks := getProjectWebhooks("ns1")
+	if len(hooks) != 2 {
+		t.Fatalf("expected 2 webhooks, got %d", len(hooks))
+	}
+	if !hooks[0].Enabled || !hooks[0].wantsPhase("Failed") || hooks[0].wantsPhase("Completed") {
+		t.Errorf("unexpected event filtering for %+v", hooks[0])
+	}
+	if hooks[1].Enabled || hooks[1].SecretKey != webhookDefaultSecretKey || !hooks[1].wantsPhase("Stopped") {
+		t.Errorf("unexpected defaults for %+v", hooks[1])
+	}
+
+	if got := getProjectWebhooks("missing"); got != nil {
+		t.Errorf("expected no webhooks for namespace without ProjectSettings, got %v", got)
+	}
+}
+
+func TestNotifySessionPhaseChange_QueuesMatchingWebhooks(t *testing.T) {
+	config.K8sClient = k8sfake.NewSimpleClientset()
+	setupFakeDynamicClient(newProjectSettingsObj("ns1", []any{
+		map[string]any{"name": "all", "url": "https://hooks.example.com/all"},
+		map[string]any{"name": "failed-only", "url": "https://hooks.example.com/failed", "events": []any{"Failed"}},
+		map[string]any{"name": "disabled", "url": "https://hooks.example.com/off", "enabled": false},
+		map[string]any{"name": "metadata", "url": "http://169.254.169.254/latest/meta-data/"},
+		map[string]any{"name": "in-cluster", "url": "http://backend-service.other-project.svc:8080/"},
+	}))
+	session := completedSession("ns1")
+
+	notifySessionPhaseChange(session, "Running", "Running")
+	notifySessionPhaseChange(session, "Pending", "Running")
+	if _, err := config.K8sClient.CoreV1().ConfigMaps("ns1").Get(context.Background(), webhook.DeliveryLogConfigMap, metav1.GetOptions{}); err == nil {
+		t.Fatal("expected no deliveries for non-terminal phases")
+	}
+
+	notifySessionPhaseChange(session, "Running", "Completed")
+
+	pending := readPendingWebhooks(t, "ns1")
+	if len(pending) != 1 || pending[0].Webhook != "all" {
+		t.Fatalf("expected only the 'all' webhook to be queued, got %+v", pending)
+	}
+	if p := pending[0].Payload; p.Session != "s1" || p.Phase != "Completed" || p.PreviousPhase != "Running" || p.UserID != "alice" || p.DisplayName != "Fix the build" {
+		t.Errorf("unexpected payload %+v", p)
+	}
+	if got := readWebhookLogMap(t, "ns1").Labels[webhookPendingLabel]; got != "true" {
+		t.Errorf("expected %s=true while deliveries are pending, got %q", webhookPendingLabel, got)
+	}
+
+	refused := map[string]webhook.Delivery{}
+	for _, d := range readDeliveryLog(t, "ns1") {
+		refused[d.Webhook] = d
+	}
+	for _, name := range []string{"metadata", "in-cluster"} {
+		d, ok := refused[name]
+		if !ok || d.Success || !strings.Contains(d.Error, "internal address") {
+			t.Errorf("expected %s to be refused before delivery, got %+v", name, d)
+		}
+	}
+}
+
+func TestProcessPendingWebhooks_SignsAndRetries(t *testing.T) {
+	allowTestWebhooks(t)
+	config.K8sClient = k8sfake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "hook-secret", Namespace: "ns1"},
+		Data:       map[string][]byte{"secret": []byte("s3cr3t")},
+	})
+
+	var calls int32
+	var gotSignature, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		gotSignature = r.Header.Get(webhookSignatureHeader)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	setupFakeDynamicClient(newProjectSettingsObj("ns1", []any{
+		map[string]any{"name": "ci", "url": server.URL, "secretName": "hook-secret"},
+	}))
+	notifySessionPhaseChange(completedSession("ns1"), "Running", "Completed")
+
+	for i := 1; i <= 2; i++ {
+		processDueWebhooks()
+		pending := readPendingWebhooks(t, "ns1")
+		if len(pending) != 1 || pending[0].Attempts != i || pending[0].StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("after attempt %d expected the delivery to stay pending, got %+v", i, pending)
+		}
+	}
+	processDueWebhooks()
+
+	if calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", calls)
+	}
+	if want := signWebhookPayload([]byte("s3cr3t"), []byte(gotBody)); gotSignature != want {
+		t.Errorf("signature = %q, want %q", gotSignature, want)
+	}
+	deliveries := readDeliveryLog(t, "ns1")
+	if len(deliveries) != 1 || !deliveries[0].Success || deliveries[0].Attempts != 3 || deliveries[0].StatusCode != http.StatusNoContent {
+		t.Errorf("unexpected delivery log: %+v", deliveries)
+	}
+	cm := readWebhookLogMap(t, "ns1")
+	if _, ok := cm.Data[webhookPendingKey]; ok {
+		t.Errorf("expected no pending deliveries, got %s", cm.Data[webhookPendingKey])
+	}
+	if _, ok := cm.Labels[webhookPendingLabel]; ok {
+		t.Errorf("expected %s to be removed", webhookPendingLabel)
+	}
+}
+
+func TestProcessPendingWebhooks_ResumesPersistedRetries(t *testing.T) {
+	allowTestWebhooks(t)
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	setupFakeDynamicClient(newProjectSettingsObj("ns1", []any{
+		map[string]any{"name": "ci", "url": server.URL},
+	}))
+
+	// Written by a previous operator process before it restarted
+	queued := time.Now().Add(-time.Minute).UTC()
+	pending, _ := json.Marshal([]pendingWebhook{
+		{Webhook: "ci", Payload: webhookPayload{ID: "due", Session: "s1", Phase: "Failed"}, Attempts: 2, QueuedAt: queued, NextAttempt: queued},
+		{Webhook: "ci", Payload: webhookPayload{ID: "later", Session: "s2", Phase: "Failed"}, Attempts: 1, QueuedAt: queued, NextAttempt: time.Now().Add(time.Hour)},
+	})
+	config.K8sClient = k8sfake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      webhook.DeliveryLogConfigMap,
+			Namespace: "ns1",
+			Labels:    map[string]string{webhookPendingLabel: "true"},
+		},
+		Data: map[string]string{webhookPendingKey: string(pending)},
+	})
+
+	processDueWebhooks()
+
+	if calls != 1 {
+		t.Errorf("expected only the due delivery to be attempted, got %d calls", calls)
+	}
+	deliveries := readDeliveryLog(t, "ns1")
+	if len(deliveries) != 1 || deliveries[0].ID != "due" || !deliveries[0].Success || deliveries[0].Attempts != 3 {
+		t.Errorf("unexpected delivery log: %+v", deliveries)
+	}
+	if deliveries[0].DurationMs < time.Minute.Milliseconds() {
+		t.Errorf("expected duration measured from when the delivery was queued, got %dms", deliveries[0].DurationMs)
+	}
+	if left := readPendingWebhooks(t, "ns1"); len(left) != 1 || left[0].Payload.ID != "later" {
+		t.Errorf("expected the delivery scheduled later to stay pending, got %+v", left)
+	}
+}
+
+func TestProcessPendingWebhooks_NoRetryOnClientError(t *testing.T) {
+	allowTestWebhooks(t)
+	config.K8sClient = k8sfake.NewSimpleClientset()
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+	setupFakeDynamicClient(newProjectSettingsObj("ns1", []any{
+		map[string]any{"name": "bad", "url": server.URL},
+	}))
+
+	notifySessionPhaseChange(completedSession("ns1"), "Running", "Failed")
+	processPendingWebhooks("ns1")
+	processPendingWebhooks("ns1")
+
+	if calls != 1 {
+		t.Errorf("expected a single attempt for 400, got %d", calls)
+	}
+	deliveries := readDeliveryLog(t, "ns1")
+	if len(deliveries) != 1 || deliveries[0].Success || deliveries[0].StatusCode != http.StatusBadRequest {
+		t.Errorf("unexpected delivery log: %+v", deliveries)
+	}
+}
+
+func TestProcessPendingWebhooks_GivesUpAfterMaxAttempts(t *testing.T) {
+	allowTestWebhooks(t)
+	config.K8sClient = k8sfake.NewSimpleClientset()
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+	setupFakeDynamicClient(newProjectSettingsObj("ns1", []any{
+		map[string]any{"name": "flaky", "url": server.URL},
+	}))
+
+	notifySessionPhaseChange(completedSession("ns1"), "Running", "Stopped")
+	for i := 0; i < webhookMaxAttempts+2; i++ {
+		processPendingWebhooks("ns1")
+	}
+
+	if calls != webhookMaxAttempts {
+		t.Errorf("expected %d attempts, got %d", webhookMaxAttempts, calls)
+	}
+	deliveries := readDeliveryLog(t, "ns1")
+	if len(deliveries) != 1 || deliveries[0].Success || deliveries[0].Attempts != webhookMaxAttempts {
+		t.Errorf("unexpected delivery log: %+v", deliveries)
+	}
+}
+
+func TestProcessPendingWebhooks_DropsRemovedWebhook(t *testing.T) {
+	allowTestWebhooks(t)
+	config.K8sClient = k8sfake.NewSimpleClientset()
+	setupFakeDynamicClient(newProjectSettingsObj("ns1", []any{
+		map[string]any{"name": "ci", "url": "https://ci.example.com/hook"},
+	}))
+	notifySessionPhaseChange(completedSession("ns1"), "Running", "Completed")
+
+	setupFakeDynamicClient(newProjectSettingsObj("ns1", []any{}))
+	processPendingWebhooks("ns1")
+
+	deliveries := readDeliveryLog(t, "ns1")
+	if len(deliveries) != 1 || deliveries[0].Success || deliveries[0].Attempts != 1 {
+		t.Errorf("expected the delivery to fail without a request, got %+v", deliveries)
+	}
+	if left := readPendingWebhooks(t, "ns1"); len(left) != 0 {
+		t.Errorf("expected no pending deliveries, got %+v", left)
+	}
+}
+
+func TestRecordWebhookDelivery_CapsLog(t *testing.T) {
+	config.K8sClient = k8sfake.NewSimpleClientset()
+	for i := 0; i < webhookDeliveryLogMax+5; i++ {
+		recordWebhookDelivery("ns1", webhook.Delivery{ID: newDeliveryID(), Webhook: "w"})
+	}
+	if got := len(readDeliveryLog(t, "ns1")); got != webhookDeliveryLogMax {
+		t.Errorf("expected log capped at %d, got %d", webhookDeliveryLogMax, got)
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
 	"ambient-code-operator/internal/config"
@@ -138,6 +139,28 @@ func main() {
 		"maxConcurrentReconciles", maxConcurrentReconciles,
 	)
 
+	// Set up ScheduledSession controller (cron-driven AgenticSession creation)
+	if err := controller.NewScheduledSessionReconciler(mgr.GetClient()).SetupWithManager(mgr); err != nil {
+		logger.Error(err, "Unable to create ScheduledSession controller")
+		os.Exit(1)
+	}
+	logger.Info("ScheduledSession controller registered")
+
+	// Deliver queued session webhooks, resuming retries persisted before a restart.
+	// Runnables added to the manager only run on the elected leader.
+	if err := mgr.Add(manager.RunnableFunc(handlers.ProcessWebhookDeliveries)); err != nil {
+		logger.Error(err, "Unable to register webhook delivery loop")
+		os.Exit(1)
+	}
+
+	// Delete finished sessions whose ttlSecondsAfterFinished has expired
+	if err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		return handlers.CollectExpiredSessions(ctx, appConfig, controller.RecordSessionGarbageCollected)
+	})); err != nil {
+		logger.Error(err, "Unable to register session garbage collector")
+		os.Exit(1)
+	}
+
 	// Add health check endpoints
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		logger.Error(err, "Unable to set up health check")
@@ -0,0 +1,294 @@
+// Package client is a Go client for the /v1 public API.
+//
+// Every request carries the bearer token and, when set, the X-Ambient-Project
+// header expected by the API's AuthMiddleware. Requests rejected with 429 by the
+// rate limiter are retried after the delay the server asks for.
+//
+//	c := client.New("https://api.example.com", token, client.WithProject("my-project"))
+//	session, err := c.CreateSession(ctx, types.CreateSessionRequest{Task: "Fix the flaky test"})
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"ambient-code-public-api/types"
+)
+
+const (
+	defaultTimeout    = 30 * time.Second
+	defaultMaxRetries = 3
+	// defaultMaxRetryWait bounds how long a single 429 is waited out; longer
+	// Retry-After values (e.g. an exhausted daily budget) are returned as errors.
+	defaultMaxRetryWait = 30 * time.Second
+	initialBackoff      = 500 * time.Millisecond
+)
+
+// Client calls the public API. It is safe for concurrent use.
+type Client struct {
+	baseURL      string
+	token        string
+	project      string
+	httpClient   *http.Client
+	streamClient *http.Client
+	maxRetries   int
+	maxRetryWait time.Duration
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithProject sets the X-Ambient-Project header. It may be omitted for service
+// account tokens, whose namespace is used as the project.
+func WithProject(project string) Option {
+	return func(c *Client) { c.project = project }
+}
+
+// WithHTTPClient replaces the client used for requests. Its timeout does not apply
+// to event streams, which run until their context is cancelled.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+		stream := *hc
+		stream.Timeout = 0
+		c.streamClient = &stream
+	}
+}
+
+// WithMaxRetries sets how often a request rejected with 429 is retried (default 3)
+func WithMaxRetries(n int) Option {
+	return func(c *Client) { c.maxRetries = n }
+}
+
+// WithMaxRetryWait sets the longest Retry-After that is waited out (default 30s)
+func WithMaxRetryWait(d time.Duration) Option {
+	return func(c *Client) { c.maxRetryWait = d }
+}
+
+// New returns a client for the public API at baseURL authenticating with token
+func New(baseURL, token string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+		token:        token,
+		httpClient:   &http.Client{Timeout: defaultTimeout},
+		streamClient: &http.Client{},
+		maxRetries:   defaultMaxRetries,
+		maxRetryWait: defaultMaxRetryWait,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// CallOption sets per-request headers
+type CallOption func(http.Header)
+
+// WithIdempotencyKey makes CreateSession safe to retry: the API returns the session
+// created by the first request with the same key and body instead of a duplicate,
+// and a 409 APIError when the body differs.
+func WithIdempotencyKey(key string) CallOption {
+	return func(h http.Header) { h.Set("Idempotency-Key", key) }
+}
+
+// WithLastEventID resumes StreamEvents after the event with this id (see
+// EventStream.LastEventID), so only the events missed while disconnected are sent.
+func WithLastEventID(id string) CallOption {
+	return func(h http.Header) {
+		if id != "" {
+			h.Set("Last-Event-ID", id)
+		}
+	}
+}
+
+// APIError is returned for non-2xx responses
+type APIError struct {
+	StatusCode int
+	// Message is the error field of the ErrorResponse body, or the status text
+	Message string
+	// RetryAfter is the delay requested by a 429 or 503 response, if any
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("public API returned %d: %s", e.StatusCode, e.Message)
+}
+
+// ListSessions returns the sessions of the project
+func (c *Client) ListSessions(ctx context.Context) (*types.SessionListResponse, error) {
+	var out types.SessionListResponse
+	if err := c.do(ctx, http.MethodGet, "/v1/sessions", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetSession returns a single session
+func (c *Client) GetSession(ctx context.Context, id string) (*types.SessionResponse, error) {
+	var out types.SessionResponse
+	if err := c.do(ctx, http.MethodGet, sessionPath(id, ""), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateSession creates a session running req.Task
+func (c *Client) CreateSession(ctx context.Context, req types.CreateSessionRequest, opts ...CallOption) (*types.CreateSessionResponse, error) {
+	var out types.CreateSessionResponse
+	if err := c.do(ctx, http.MethodPost, "/v1/sessions", req, &out, opts...); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteSession deletes a session
+func (c *Client) DeleteSession(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, sessionPath(id, ""), nil, nil)
+}
+
+// StartSession starts a stopped session
+func (c *Client) StartSession(ctx context.Context, id string) (*types.SessionResponse, error) {
+	var out types.SessionResponse
+	if err := c.do(ctx, http.MethodPost, sessionPath(id, "/start"), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// StopSession stops a running session
+func (c *Client) StopSession(ctx context.Context, id string) (*types.SessionResponse, error) {
+	var out types.SessionResponse
+	if err := c.do(ctx, http.MethodPost, sessionPath(id, "/stop"), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SendMessage sends a user message to a session and returns the run it started
+func (c *Client) SendMessage(ctx context.Context, id string, req types.SendMessageRequest) (*types.SendMessageResponse, error) {
+	var out types.SendMessageResponse
+	if err := c.do(ctx, http.MethodPost, sessionPath(id, "/messages"), req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// StreamEvents opens the event stream of a session (history, then live events).
+// The stream ends when ctx is cancelled, the server closes it or Close is called.
+// Pass WithLastEventID to resume a stream that ended early.
+func (c *Client) StreamEvents(ctx context.Context, id string, opts ...CallOption) (*EventStream, error) {
+	resp, err := c.send(ctx, c.streamClient, http.MethodGet, sessionPath(id, "/events"), nil, "text/event-stream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	return newEventStream(resp.Body), nil
+}
+
+func sessionPath(id, suffix string) string {
+	return "/v1/sessions/" + url.PathEscape(id) + suffix
+}
+
+// do sends a JSON request and decodes a JSON response into out, if non-nil
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}, opts ...CallOption) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("encode request: %w", err)
+		}
+	}
+	resp, err := c.send(ctx, c.httpClient, method, path, body, "application/json", opts...)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode %s %s response: %w", method, path, err)
+	}
+	return nil
+}
+
+// send performs the request, retrying on 429, and returns a 2xx response
+func (c *Client) send(ctx context.Context, hc *http.Client, method, path string, body []byte, accept string, opts ...CallOption) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		req.Header.Set("Accept", accept)
+		if c.project != "" {
+			req.Header.Set("X-Ambient-Project", c.project)
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		for _, opt := range opts {
+			opt(req.Header)
+		}
+
+		resp, err := hc.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return resp, nil
+		}
+		apiErr := readAPIError(resp)
+		if resp.StatusCode != http.StatusTooManyRequests || attempt >= c.maxRetries {
+			return nil, apiErr
+		}
+
+		wait := apiErr.RetryAfter
+		if wait == 0 {
+			wait = initialBackoff << attempt
+		}
+		if wait > c.maxRetryWait {
+			return nil, apiErr
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func readAPIError(resp *http.Response) *APIError {
+	defer resp.Body.Close()
+	apiErr := &APIError{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+
+	var body struct {
+		types.ErrorResponse
+		RetryAfter string `json:"retry_after"`
+	}
+	if data, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024)); err == nil && json.Unmarshal(data, &body) == nil {
+		if body.Error != "" {
+			apiErr.Message = body.Error
+		}
+		if d, err := time.ParseDuration(body.RetryAfter); err == nil {
+			apiErr.RetryAfter = d
+		}
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return apiErr
+}
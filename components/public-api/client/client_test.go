@@ -0,0 +1,279 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ambient-code-public-api/types"
+)
+
+// newFakeAPI serves the /v1 endpoints with canned responses and checks the auth headers
+func newFakeAPI(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	writeJSON := func(w http.ResponseWriter, status int, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(v)
+	}
+	session := types.SessionResponse{ID: "session-1", Status: "running", Task: "Fix the tests", CreatedAt: "2026-01-01T00:00:00Z"}
+
+	mux.HandleFunc("GET /v1/sessions", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, types.SessionListResponse{Items: []types.SessionResponse{session}, Total: 1})
+	})
+	mux.HandleFunc("POST /v1/sessions", func(w http.ResponseWriter, r *http.Request) {
+		var req types.CreateSessionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Task == "" {
+			writeJSON(w, http.StatusBadRequest, types.ErrorResponse{Error: "task is required"})
+			return
+		}
+		if key := r.Header.Get("Idempotency-Key"); key != "" {
+			if key != "ci-run-42" {
+				writeJSON(w, http.StatusConflict, types.ErrorResponse{Error: "Idempotency-Key was already used with a different request body"})
+				return
+			}
+			writeJSON(w, http.StatusOK, types.CreateSessionResponse{ID: "session-1", Message: "Session already created for this Idempotency-Key"})
+			return
+		}
+		writeJSON(w, http.StatusCreated, types.CreateSessionResponse{ID: "session-1", Message: "Session created"})
+	})
+	mux.HandleFunc("GET /v1/sessions/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != session.ID {
+			writeJSON(w, http.StatusNotFound, types.ErrorResponse{Error: "Session not found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, session)
+	})
+	mux.HandleFunc("DELETE /v1/sessions/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /v1/sessions/{id}/start", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusAccepted, types.SessionResponse{ID: r.PathValue("id"), Status: "pending"})
+	})
+	mux.HandleFunc("POST /v1/sessions/{id}/stop", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusAccepted, types.SessionResponse{ID: r.PathValue("id"), Status: "stopping"})
+	})
+	mux.HandleFunc("POST /v1/sessions/{id}/messages", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusAccepted, types.SendMessageResponse{RunID: "run-1", ThreadID: r.PathValue("id"), MessageID: "msg-1"})
+	})
+	mux.HandleFunc("GET /v1/sessions/{id}/events", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		if r.Header.Get("Last-Event-ID") != "2" {
+			fmt.Fprint(w, "id: 1\ndata: {\"version\":\"v1\",\"type\":\"run.started\",\"sessionId\":\"session-1\",\"runId\":\"run-1\"}\n\n")
+			fmt.Fprint(w, ": heartbeat\n\n")
+			fmt.Fprint(w, "id: 2\ndata: {\"version\":\"v1\",\"type\":\"message.delta\",\"sessionId\":\"session-1\",\"delta\":\"Hi\"}\n\n")
+		}
+		fmt.Fprint(w, "id: 3\ndata: {\"version\":\"v1\",\"type\":\"run.finished\",\"sessionId\":\"session-1\",\"runId\":\"run-1\"}\n\n")
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			writeJSON(w, http.StatusUnauthorized, types.ErrorResponse{Error: "Missing or invalid authorization"})
+			return
+		}
+		if r.Header.Get("X-Ambient-Project") != "test-project" {
+			writeJSON(w, http.StatusBadRequest, types.ErrorResponse{Error: "Project required"})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient_Endpoints(t *testing.T) {
+	server := newFakeAPI(t)
+	c := New(server.URL, "test-token", WithProject("test-project"))
+	ctx := context.Background()
+
+	list, err := c.ListSessions(ctx)
+	if err != nil || list.Total != 1 || list.Items[0].ID != "session-1" {
+		t.Fatalf("ListSessions() = %+v, %v", list, err)
+	}
+	created, err := c.CreateSession(ctx, types.CreateSessionRequest{Task: "Fix the tests"})
+	if err != nil || created.ID != "session-1" {
+		t.Fatalf("CreateSession() = %+v, %v", created, err)
+	}
+	session, err := c.GetSession(ctx, "session-1")
+	if err != nil || session.Task != "Fix the tests" {
+		t.Fatalf("GetSession() = %+v, %v", session, err)
+	}
+	if s, err := c.StartSession(ctx, "session-1"); err != nil || s.Status != "pending" {
+		t.Fatalf("StartSession() = %+v, %v", s, err)
+	}
+	if s, err := c.StopSession(ctx, "session-1"); err != nil || s.Status != "stopping" {
+		t.Fatalf("StopSession() = %+v, %v", s, err)
+	}
+	sent, err := c.SendMessage(ctx, "session-1", types.SendMessageRequest{Content: "hello"})
+	if err != nil || sent.RunID != "run-1" || sent.ThreadID != "session-1" {
+		t.Fatalf("SendMessage() = %+v, %v", sent, err)
+	}
+	if err := c.DeleteSession(ctx, "session-1"); err != nil {
+		t.Fatalf("DeleteSession() error = %v", err)
+	}
+}
+
+func TestClient_CreateSessionIdempotencyKey(t *testing.T) {
+	server := newFakeAPI(t)
+	c := New(server.URL, "test-token", WithProject("test-project"))
+	req := types.CreateSessionRequest{Task: "Fix the tests"}
+
+	created, err := c.CreateSession(context.Background(), req, WithIdempotencyKey("ci-run-42"))
+	if err != nil || created.ID != "session-1" {
+		t.Fatalf("CreateSession() = %+v, %v", created, err)
+	}
+
+	_, err = c.CreateSession(context.Background(), req, WithIdempotencyKey("reused-key"))
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict {
+		t.Errorf("CreateSession() error = %v, want a 409 APIError", err)
+	}
+}
+
+func TestClient_APIError(t *testing.T) {
+	server := newFakeAPI(t)
+	ctx := context.Background()
+
+	_, err := New(server.URL, "test-token", WithProject("test-project")).GetSession(ctx, "missing")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Message != "Session not found" {
+		t.Errorf("GetSession() error = %v, want a 404 APIError", err)
+	}
+
+	_, err = New(server.URL, "wrong-token", WithProject("test-project")).ListSessions(ctx)
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("ListSessions() error = %v, want a 401 APIError", err)
+	}
+}
+
+func TestClient_RetriesRateLimitedRequests(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 2 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"error":"Rate limit exceeded","retry_after":"10ms"}`)
+			return
+		}
+		fmt.Fprint(w, `{"items":[],"total":0}`)
+	}))
+	defer server.Close()
+
+	list, err := New(server.URL, "test-token").ListSessions(context.Background())
+	if err != nil || list.Total != 0 {
+		t.Fatalf("ListSessions() = %+v, %v", list, err)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("expected 3 attempts, got %d", got)
+	}
+}
+
+func TestClient_RateLimitGivesUp(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter string
+		maxRetries int
+		wantCalls  int32
+	}{
+		{"retries exhausted", "", 1, 2},
+		{"wait longer than allowed", "3600", 3, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(http.StatusTooManyRequests)
+				fmt.Fprint(w, `{"error":"Daily project budget exhausted"}`)
+			}))
+			defer server.Close()
+
+			c := New(server.URL, "test-token", WithMaxRetries(tt.maxRetries))
+			_, err := c.ListSessions(context.Background())
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+				t.Fatalf("ListSessions() error = %v, want a 429 APIError", err)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("expected %d attempts, got %d", tt.wantCalls, got)
+			}
+		})
+	}
+}
+
+func TestClient_StreamEvents(t *testing.T) {
+	server := newFakeAPI(t)
+	c := New(server.URL, "test-token", WithProject("test-project"))
+
+	stream, err := c.StreamEvents(context.Background(), "session-1")
+	if err != nil {
+		t.Fatalf("StreamEvents() error = %v", err)
+	}
+	defer stream.Close()
+
+	var got []string
+	for stream.Next() {
+		got = append(got, stream.Event().Type)
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("stream error = %v", err)
+	}
+	want := []string{types.EventRunStarted, types.EventMessageDelta, types.EventRunFinished}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+	if id := stream.LastEventID(); id != "3" {
+		t.Errorf("LastEventID() = %q, want 3", id)
+	}
+}
+
+func TestClient_StreamEventsResume(t *testing.T) {
+	server := newFakeAPI(t)
+	c := New(server.URL, "test-token", WithProject("test-project"))
+
+	stream, err := c.StreamEvents(context.Background(), "session-1", WithLastEventID("2"))
+	if err != nil {
+		t.Fatalf("StreamEvents() error = %v", err)
+	}
+	defer stream.Close()
+
+	var got []string
+	for stream.Next() {
+		got = append(got, stream.Event().Type)
+	}
+	if want := []string{types.EventRunFinished}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("events after id 2 = %v, want %v", got, want)
+	}
+}
+
+func TestClient_StreamEventsCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	stream, err := New(server.URL, "test-token").StreamEvents(ctx, "session-1")
+	if err != nil {
+		t.Fatalf("StreamEvents() error = %v", err)
+	}
+	defer stream.Close()
+	if stream.Next() {
+		t.Fatal("expected no events")
+	}
+	if stream.Err() == nil {
+		t.Error("expected the cancelled stream to report an error")
+	}
+}
@@ -0,0 +1,94 @@
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"ambient-code-public-api/types"
+)
+
+// maxEventSize bounds a single SSE event; tool results can be large
+const maxEventSize = 10 * 1024 * 1024
+
+// EventStream iterates over the events of a session stream:
+//
+//	stream, err := c.StreamEvents(ctx, id)
+//	...
+//	defer stream.Close()
+//	for stream.Next() {
+//		event := stream.Event()
+//	}
+//	if err := stream.Err(); err != nil { ... }
+type EventStream struct {
+	body        io.ReadCloser
+	scanner     *bufio.Scanner
+	event       types.SessionEvent
+	lastEventID string
+	err         error
+}
+
+func newEventStream(body io.ReadCloser) *EventStream {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEventSize)
+	return &EventStream{body: body, scanner: scanner}
+}
+
+// Next advances to the next event. It returns false when the stream ends or fails;
+// Err tells the two apart. Keepalive comments are skipped.
+func (s *EventStream) Next() bool {
+	if s.err != nil {
+		return false
+	}
+	var data []string
+	var id string
+	for s.scanner.Scan() {
+		line := s.scanner.Text()
+		switch {
+		case line == "":
+			if len(data) == 0 {
+				continue
+			}
+			var event types.SessionEvent
+			if err := json.Unmarshal([]byte(strings.Join(data, "\n")), &event); err != nil {
+				s.err = fmt.Errorf("decode event: %w", err)
+				return false
+			}
+			s.event = event
+			if id != "" {
+				s.lastEventID = id
+			}
+			return true
+		case strings.HasPrefix(line, "id:"):
+			id = strings.TrimPrefix(strings.TrimPrefix(line, "id:"), " ")
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	s.err = s.scanner.Err()
+	return false
+}
+
+// Event returns the event read by the last successful call to Next
+func (s *EventStream) Event() types.SessionEvent {
+	return s.event
+}
+
+// LastEventID returns the id of the last event read, for resuming the stream
+// with WithLastEventID after a disconnect. It is empty until an event with an id
+// has been read.
+func (s *EventStream) LastEventID() string {
+	return s.lastEventID
+}
+
+// Err returns the error that ended the stream, or nil if the server closed it
+func (s *EventStream) Err() error {
+	return s.err
+}
+
+// Close closes the underlying connection
+func (s *EventStream) Close() error {
+	return s.body.Close()
+}
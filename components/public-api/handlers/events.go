@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"ambient-code-public-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxEventLineSize bounds a single SSE line read from the backend (large tool results)
+const maxEventLineSize = 10 * 1024 * 1024
+
+// StreamSessionEvents handles GET /v1/sessions/:id/events
+// Relays the backend AG-UI event stream (history + live) translated to the
+// versioned SessionEvent schema. Event ids and Last-Event-ID pass through, so a
+// reconnecting client only receives the events it missed.
+func StreamSessionEvents(c *gin.Context) {
+	project := GetProject(c)
+	if !ValidateProjectName(project) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project name"})
+		return
+	}
+	sessionID := c.Param("id")
+	if !ValidateSessionID(sessionID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+	path := fmt.Sprintf("/api/projects/%s/agentic-sessions/%s/agui/events", project, sessionID)
+
+	resp, err := ProxyStream(c, path)
+	if err != nil {
+		log.Printf("Backend event stream failed for session %s: %v", sessionID, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Backend unavailable"})
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			log.Printf("Failed to read backend response: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		forwardErrorResponse(c, resp.StatusCode, body)
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Writer.WriteHeader(http.StatusOK)
+	c.Writer.Flush()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEventLineSize)
+	// The backend's event ids are relayed so clients can resume with Last-Event-ID
+	var eventID string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			eventID = ""
+		case strings.HasPrefix(line, "id: "):
+			eventID = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			event, ok := translateEvent(sessionID, strings.TrimPrefix(line, "data: "))
+			if !ok {
+				continue
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			if eventID != "" {
+				fmt.Fprintf(c.Writer, "id: %s\n", eventID)
+			}
+			fmt.Fprintf(c.Writer, "data: %s\n\n", data)
+			c.Writer.Flush()
+		case strings.HasPrefix(line, ":"):
+			// Relay keepalive comments so idle connections stay open
+			fmt.Fprintf(c.Writer, "%s\n\n", line)
+			c.Writer.Flush()
+		}
+	}
+	if err := scanner.Err(); err != nil && c.Request.Context().Err() == nil {
+		log.Printf("Event stream for session %s ended with error: %v", sessionID, err)
+	}
+}
+
+// translateEvent converts an AG-UI event to the public SessionEvent schema.
+// Event types without a public equivalent are relayed untranslated as EventOther.
+// Returns false only for malformed events.
+func translateEvent(sessionID, data string) (types.SessionEvent, bool) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &raw); err != nil {
+		return types.SessionEvent{}, false
+	}
+
+	aguiType, _ := raw["type"].(string)
+	if aguiType == "" {
+		return types.SessionEvent{}, false
+	}
+	eventType, known := aguiEventTypes[aguiType]
+	if !known {
+		eventType = types.EventOther
+	}
+
+	event := types.SessionEvent{
+		Version:    types.EventSchemaVersion,
+		Type:       eventType,
+		SessionID:  sessionID,
+		RunID:      stringField(raw, "runId"),
+		MessageID:  stringField(raw, "messageId"),
+		Role:       stringField(raw, "role"),
+		ToolCallID: stringField(raw, "toolCallId"),
+		ToolName:   stringField(raw, "toolCallName"),
+	}
+	if ts, ok := raw["timestamp"].(float64); ok {
+		event.Timestamp = int64(ts)
+	}
+
+	switch eventType {
+	case types.EventMessageDelta, types.EventToolCallDelta:
+		event.Delta = stringField(raw, "delta")
+	case types.EventRunError:
+		event.Error = stringField(raw, "message")
+	case types.EventRunFinished:
+		if result, ok := raw["result"].(map[string]interface{}); ok {
+			event.Result = result
+		}
+	case types.EventToolCallResult:
+		event.Content = stringField(raw, "content")
+	case types.EventStepStarted, types.EventStepFinished:
+		event.StepName = stringField(raw, "stepName")
+	case types.EventStateSnapshot:
+		event.Snapshot = raw["snapshot"]
+	case types.EventStateDelta:
+		event.Patch, _ = raw["delta"].([]interface{})
+	case types.EventMessagesSnapshot:
+		event.Messages, _ = raw["messages"].([]interface{})
+	case types.EventActivitySnapshot:
+		event.ActivityType = stringField(raw, "activityType")
+		event.Snapshot = raw["content"]
+	case types.EventActivityDelta:
+		event.ActivityType = stringField(raw, "activityType")
+		event.Patch, _ = raw["patch"].([]interface{})
+	case types.EventCustom:
+		event.Name = stringField(raw, "name")
+		event.Value = raw["value"]
+	case types.EventMeta:
+		event.Name = stringField(raw, "metaType")
+		event.Value = raw["payload"]
+	case types.EventRaw:
+		event.Name = stringField(raw, "source")
+		event.Data = raw["event"]
+	case types.EventOther:
+		event.SourceType = aguiType
+		event.Data = raw
+	}
+
+	return event, true
+}
+
+// aguiEventTypes maps internal AG-UI event types to public event types
+var aguiEventTypes = map[string]string{
+	"RUN_STARTED":          types.EventRunStarted,
+	"RUN_FINISHED":         types.EventRunFinished,
+	"RUN_ERROR":            types.EventRunError,
+	"STEP_STARTED":         types.EventStepStarted,
+	"STEP_FINISHED":        types.EventStepFinished,
+	"TEXT_MESSAGE_START":   types.EventMessageStarted,
+	"TEXT_MESSAGE_CONTENT": types.EventMessageDelta,
+	"TEXT_MESSAGE_END":     types.EventMessageCompleted,
+	"TOOL_CALL_START":      types.EventToolCallStarted,
+	"TOOL_CALL_ARGS":       types.EventToolCallDelta,
+	"TOOL_CALL_END":        types.EventToolCallComplete,
+	"TOOL_CALL_RESULT":     types.EventToolCallResult,
+	"STATE_SNAPSHOT":       types.EventStateSnapshot,
+	"STATE_DELTA":          types.EventStateDelta,
+	"MESSAGES_SNAPSHOT":    types.EventMessagesSnapshot,
+	"ACTIVITY_SNAPSHOT":    types.EventActivitySnapshot,
+	"ACTIVITY_DELTA":       types.EventActivityDelta,
+	"CUSTOM":               types.EventCustom,
+	"META":                 types.EventMeta,
+	"RAW":                  types.EventRaw,
+}
+
+func stringField(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ambient-code-public-api/types"
+)
+
+func TestTranslateEvent(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		wantOK   bool
+		expected types.SessionEvent
+	}{
+		{
+			name:   "Text delta",
+			data:   `{"type":"TEXT_MESSAGE_CONTENT","runId":"r1","messageId":"m1","delta":"Hello","timestamp":1700000000000}`,
+			wantOK: true,
+			expected: types.SessionEvent{
+				Type: types.EventMessageDelta, RunID: "r1", MessageID: "m1", Delta: "Hello", Timestamp: 1700000000000,
+			},
+		},
+		{
+			name:     "Tool call start",
+			data:     `{"type":"TOOL_CALL_START","toolCallId":"t1","toolCallName":"Bash"}`,
+			wantOK:   true,
+			expected: types.SessionEvent{Type: types.EventToolCallStarted, ToolCallID: "t1", ToolName: "Bash"},
+		},
+		{
+			name:     "Run error",
+			data:     `{"type":"RUN_ERROR","runId":"r1","message":"Runner is not available"}`,
+			wantOK:   true,
+			expected: types.SessionEvent{Type: types.EventRunError, RunID: "r1", Error: "Runner is not available"},
+		},
+		{
+			name:   "State delta carries the JSON Patch",
+			data:   `{"type":"STATE_DELTA","delta":[{"op":"replace","path":"/phase","value":"done"}]}`,
+			wantOK: true,
+			expected: types.SessionEvent{Type: types.EventStateDelta, Patch: []interface{}{
+				map[string]interface{}{"op": "replace", "path": "/phase", "value": "done"},
+			}},
+		},
+		{
+			name:     "State snapshot",
+			data:     `{"type":"STATE_SNAPSHOT","snapshot":{"phase":"planning"}}`,
+			wantOK:   true,
+			expected: types.SessionEvent{Type: types.EventStateSnapshot, Snapshot: map[string]interface{}{"phase": "planning"}},
+		},
+		{
+			name:   "Messages snapshot",
+			data:   `{"type":"MESSAGES_SNAPSHOT","messages":[{"id":"m1","role":"user","content":"hi"}]}`,
+			wantOK: true,
+			expected: types.SessionEvent{Type: types.EventMessagesSnapshot, Messages: []interface{}{
+				map[string]interface{}{"id": "m1", "role": "user", "content": "hi"},
+			}},
+		},
+		{
+			name:     "Step started",
+			data:     `{"type":"STEP_STARTED","runId":"r1","stepName":"plan"}`,
+			wantOK:   true,
+			expected: types.SessionEvent{Type: types.EventStepStarted, RunID: "r1", StepName: "plan"},
+		},
+		{
+			name:     "Custom event",
+			data:     `{"type":"CUSTOM","name":"workflow.progress","value":{"done":2}}`,
+			wantOK:   true,
+			expected: types.SessionEvent{Type: types.EventCustom, Name: "workflow.progress", Value: map[string]interface{}{"done": float64(2)}},
+		},
+		{
+			name:     "Raw event",
+			data:     `{"type":"RAW","source":"claude-agent-sdk","event":{"type":"system"}}`,
+			wantOK:   true,
+			expected: types.SessionEvent{Type: types.EventRaw, Name: "claude-agent-sdk", Data: map[string]interface{}{"type": "system"}},
+		},
+		{
+			name:   "Unknown type relayed in a generic envelope",
+			data:   `{"type":"THINKING_START","runId":"r1"}`,
+			wantOK: true,
+			expected: types.SessionEvent{Type: types.EventOther, RunID: "r1", SourceType: "THINKING_START",
+				Data: map[string]interface{}{"type": "THINKING_START", "runId": "r1"}},
+		},
+		{
+			name:   "Missing type dropped",
+			data:   `{"delta":"x"}`,
+			wantOK: false,
+		},
+		{
+			name:   "Malformed JSON dropped",
+			data:   `{"type":`,
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, ok := translateEvent("session-1", tt.data)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			tt.expected.Version = types.EventSchemaVersion
+			tt.expected.SessionID = "session-1"
+			got, _ := json.Marshal(event)
+			want, _ := json.Marshal(tt.expected)
+			if string(got) != string(want) {
+				t.Errorf("translateEvent() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestE2E_StreamSessionEvents(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/agentic-sessions/test-session/agui/events") {
+			t.Errorf("Unexpected backend path %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"type\":\"RUN_STARTED\",\"runId\":\"r1\"}\n\n")
+		fmt.Fprint(w, "data: {\"type\":\"STATE_SNAPSHOT\",\"snapshot\":{}}\n\n")
+		fmt.Fprint(w, ": heartbeat\n\n")
+		fmt.Fprint(w, "data: {\"type\":\"RUN_FINISHED\",\"runId\":\"r1\",\"result\":{\"num_turns\":2}}\n\n")
+	}))
+	defer backend.Close()
+
+	originalURL := BackendURL
+	BackendURL = backend.URL
+	defer func() { BackendURL = originalURL }()
+
+	router := setupTestRouter()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/sessions/test-session/events", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	req.Header.Set("X-Ambient-Project", "test-project")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	var events []types.SessionEvent
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		if strings.HasPrefix(line, "data: ") {
+			var e types.SessionEvent
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e); err != nil {
+				t.Fatalf("invalid event %q: %v", line, err)
+			}
+			events = append(events, e)
+		}
+	}
+	if len(events) != 3 || events[0].Type != types.EventRunStarted || events[1].Type != types.EventStateSnapshot || events[2].Type != types.EventRunFinished {
+		t.Fatalf("Unexpected events %+v", events)
+	}
+	if events[2].Result["num_turns"] != float64(2) {
+		t.Errorf("Expected run result to be relayed, got %v", events[2].Result)
+	}
+	if !strings.Contains(w.Body.String(), ": heartbeat") {
+		t.Error("Expected heartbeat comments to be relayed")
+	}
+}
+
+func TestE2E_StreamSessionEvents_Resume(t *testing.T) {
+	var lastEventID string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastEventID = r.Header.Get("Last-Event-ID")
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "id: 8\ndata: {\"type\":\"TEXT_MESSAGE_CONTENT\",\"messageId\":\"m1\",\"delta\":\"Hi\"}\n\n")
+		fmt.Fprint(w, "id: 9\ndata: {\"type\":\"RUN_FINISHED\",\"runId\":\"r1\"}\n\n")
+	}))
+	defer backend.Close()
+
+	originalURL := BackendURL
+	BackendURL = backend.URL
+	defer func() { BackendURL = originalURL }()
+
+	router := setupTestRouter()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/sessions/test-session/events", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	req.Header.Set("X-Ambient-Project", "test-project")
+	req.Header.Set("Last-Event-ID", "7")
+	router.ServeHTTP(w, req)
+
+	if lastEventID != "7" {
+		t.Errorf("Last-Event-ID not forwarded, got %q", lastEventID)
+	}
+	body := w.Body.String()
+	if !strings.HasPrefix(body, "id: 8\ndata: ") || !strings.Contains(body, "\n\nid: 9\ndata: ") {
+		t.Errorf("Expected event ids to be relayed, got %q", body)
+	}
+}
+
+func TestE2E_StreamSessionEvents_BackendForbidden(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"error":"Unauthorized"}`)
+	}))
+	defer backend.Close()
+
+	originalURL := BackendURL
+	BackendURL = backend.URL
+	defer func() { BackendURL = originalURL }()
+
+	router := setupTestRouter()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/sessions/test-session/events", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	req.Header.Set("X-Ambient-Project", "test-project")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", w.Code)
+	}
+}
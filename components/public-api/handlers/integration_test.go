@@ -22,12 +22,7 @@ func setupTestRouter() *gin.Engine {
 	v1 := r.Group("/v1")
 	v1.Use(AuthMiddleware())
 	v1.Use(LoggingMiddleware())
-	{
-		v1.GET("/sessions", ListSessions)
-		v1.POST("/sessions", CreateSession)
-		v1.GET("/sessions/:id", GetSession)
-		v1.DELETE("/sessions/:id", DeleteSession)
-	}
+	registerV1Routes(v1)
 
 	return r
 }
@@ -114,6 +109,51 @@ func TestE2E_CreateSession(t *testing.T) {
 	}
 }
 
+func TestE2E_CreateSession_IdempotencyKey(t *testing.T) {
+	tests := []struct {
+		name          string
+		backendStatus int
+		backendBody   string
+		wantStatus    int
+	}{
+		{"first request creates", http.StatusCreated, `{"name":"session-123"}`, http.StatusCreated},
+		{"retry returns the existing session", http.StatusOK, `{"name":"session-123"}`, http.StatusOK},
+		{"payload mismatch is a conflict", http.StatusConflict, `{"error":"Idempotency-Key was already used with a different request body"}`, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyReceived := ""
+			backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				keyReceived = r.Header.Get("Idempotency-Key")
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.backendStatus)
+				w.Write([]byte(tt.backendBody))
+			}))
+			defer backend.Close()
+
+			originalURL := BackendURL
+			BackendURL = backend.URL
+			defer func() { BackendURL = originalURL }()
+
+			router := setupTestRouter()
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/v1/sessions", strings.NewReader(`{"task": "Fix the bug"}`))
+			req.Header.Set("Authorization", "Bearer test-token")
+			req.Header.Set("X-Ambient-Project", "test-project")
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Idempotency-Key", "ci-run-42")
+			router.ServeHTTP(w, req)
+
+			if keyReceived != "ci-run-42" {
+				t.Errorf("Idempotency-Key not forwarded, got %q", keyReceived)
+			}
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
 func TestE2E_BackendReturns500(t *testing.T) {
 	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -267,3 +307,95 @@ func TestE2E_DeleteSession(t *testing.T) {
 		t.Error("Expected backend delete to be called")
 	}
 }
+
+func TestE2E_StartStopSession(t *testing.T) {
+	for _, action := range []string{"start", "stop"} {
+		t.Run(action, func(t *testing.T) {
+			backendPath := ""
+			backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				backendPath = r.URL.Path
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusAccepted)
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"metadata": map[string]interface{}{"name": "test-session"},
+					"status":   map[string]interface{}{"phase": "Stopping"},
+				})
+			}))
+			defer backend.Close()
+
+			originalURL := BackendURL
+			BackendURL = backend.URL
+			defer func() { BackendURL = originalURL }()
+
+			router := setupTestRouter()
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/v1/sessions/test-session/"+action, nil)
+			req.Header.Set("Authorization", "Bearer test-token")
+			req.Header.Set("X-Ambient-Project", "test-project")
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusAccepted {
+				t.Fatalf("Expected status 202, got %d: %s", w.Code, w.Body.String())
+			}
+			if want := "/api/projects/test-project/agentic-sessions/test-session/" + action; backendPath != want {
+				t.Errorf("Backend path = %q, want %q", backendPath, want)
+			}
+			if !strings.Contains(w.Body.String(), `"status":"stopping"`) {
+				t.Errorf("Expected transformed session, got %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestE2E_SendMessage(t *testing.T) {
+	var runInput map[string]interface{}
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/agentic-sessions/test-session/agui/run") {
+			t.Errorf("Unexpected backend path %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&runInput)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"runId": "run-1", "threadId": "test-session"})
+	}))
+	defer backend.Close()
+
+	originalURL := BackendURL
+	BackendURL = backend.URL
+	defer func() { BackendURL = originalURL }()
+
+	router := setupTestRouter()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/sessions/test-session/messages", strings.NewReader(`{"content": "Run the tests"}`))
+	req.Header.Set("Authorization", "Bearer test-token")
+	req.Header.Set("X-Ambient-Project", "test-project")
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d: %s", w.Code, w.Body.String())
+	}
+	messages, _ := runInput["messages"].([]interface{})
+	if len(messages) != 1 || runInput["threadId"] != "test-session" {
+		t.Fatalf("Unexpected run input %v", runInput)
+	}
+	if msg := messages[0].(map[string]interface{}); msg["role"] != "user" || msg["content"] != "Run the tests" {
+		t.Errorf("Unexpected message %v", msg)
+	}
+	if !strings.Contains(w.Body.String(), `"runId":"run-1"`) {
+		t.Errorf("Expected runId in response, got %s", w.Body.String())
+	}
+}
+
+func TestE2E_SendMessage_EmptyContent(t *testing.T) {
+	router := setupTestRouter()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/sessions/test-session/messages", strings.NewReader(`{}`))
+	req.Header.Set("Authorization", "Bearer test-token")
+	req.Header.Set("X-Ambient-Project", "test-project")
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
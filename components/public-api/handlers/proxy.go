@@ -24,6 +24,10 @@ var (
 	HTTPClient = &http.Client{
 		Timeout: BackendTimeout,
 	}
+
+	// StreamHTTPClient is used for long-lived SSE requests. It has no overall
+	// timeout; streams end when the client disconnects or the backend closes.
+	StreamHTTPClient = &http.Client{}
 )
 
 func getTimeoutFromEnv(key string, defaultValue time.Duration) time.Duration {
@@ -72,6 +76,11 @@ func ProxyRequest(c *gin.Context, method, path string, body []byte) (*http.Respo
 		req.Header.Set("Content-Type", contentType)
 	}
 
+	// Forward the idempotency key so retried creates return the first session
+	if key := c.GetHeader("Idempotency-Key"); key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+
 	// Set accept header
 	req.Header.Set("Accept", "application/json")
 
@@ -105,3 +114,29 @@ func ProxyAndRespond(c *gin.Context, method, path string, body []byte) {
 	// Forward response
 	c.Data(resp.StatusCode, resp.Header.Get("Content-Type"), respBody)
 }
+
+// ProxyStream opens a long-lived SSE request to the backend. The request is bound
+// to the client's context so the backend stream closes when the client goes away.
+func ProxyStream(c *gin.Context, path string) (*http.Response, error) {
+	fullURL := fmt.Sprintf("%s%s", BackendURL, path)
+
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, fullURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if token := GetToken(c); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if lastEventID := c.GetHeader("Last-Event-ID"); lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := StreamHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("backend stream request failed: %w", err)
+	}
+
+	return resp, nil
+}
@@ -73,6 +73,7 @@ func RateLimitMiddleware() gin.HandlerFunc {
 
 		// Check if request is allowed
 		if !limiter.Allow() {
+			c.Header("Retry-After", "1")
 			c.JSON(http.StatusTooManyRequests, gin.H{
 				"error":       "Rate limit exceeded",
 				"retry_after": "1s",
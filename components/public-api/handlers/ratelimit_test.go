@@ -88,6 +88,9 @@ func TestRateLimitMiddleware_LimitsAPIEndpoints(t *testing.T) {
 	if w.Code != http.StatusTooManyRequests {
 		t.Errorf("Expected rate limit (429), got %d", w.Code)
 	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Expected Retry-After: 1, got %q", got)
+	}
 }
 
 func TestRateLimitMiddleware_PerIPLimiting(t *testing.T) {
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"ambient-code-public-api/openapi"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// RegisterRoutes registers all public API routes on r.
+// Every route registered here must be described in openapi/openapi.json.
+func RegisterRoutes(r *gin.Engine) {
+	// Health endpoint (no auth required)
+	r.GET("/health", func(c *gin.Context) {
+		c.JSON(200, gin.H{"status": "ok"})
+	})
+
+	// Readiness endpoint
+	r.GET("/ready", func(c *gin.Context) {
+		c.JSON(200, gin.H{"status": "ready"})
+	})
+
+	// Metrics endpoint (Prometheus format)
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	// OpenAPI specification (no auth required)
+	r.GET("/openapi.json", openapi.Handler)
+
+	// v1 API routes
+	// IMPORTANT: AuthMiddleware must run BEFORE LoggingMiddleware
+	// to ensure we only log authenticated requests with valid project context
+	v1 := r.Group("/v1")
+	v1.Use(AuthMiddleware())
+	registerV1Routes(v1)
+}
+
+func registerV1Routes(v1 *gin.RouterGroup) {
+	// Sessions
+	v1.GET("/sessions", ListSessions)
+	v1.POST("/sessions", CreateSession)
+	v1.GET("/sessions/:id", GetSession)
+	v1.DELETE("/sessions/:id", DeleteSession)
+
+	// Session lifecycle and interaction
+	v1.POST("/sessions/:id/start", StartSession)
+	v1.POST("/sessions/:id/stop", StopSession)
+	v1.POST("/sessions/:id/messages", SendMessage)
+	v1.GET("/sessions/:id/events", StreamSessionEvents)
+}
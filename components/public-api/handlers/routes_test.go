@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"ambient-code-public-api/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ginParam matches gin path parameters (:name) and wildcards (*name)
+var ginParam = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+type openAPIDocument struct {
+	OpenAPI string                                `json:"openapi"`
+	Paths   map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+// TestOpenAPICoversRoutes fails when a route registered by RegisterRoutes is
+// not described in openapi/openapi.json.
+func TestOpenAPICoversRoutes(t *testing.T) {
+	var spec openAPIDocument
+	if err := json.Unmarshal(openapi.Spec, &spec); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %v", err)
+	}
+
+	r := gin.New()
+	RegisterRoutes(r)
+
+	for _, route := range r.Routes() {
+		path := ginParam.ReplaceAllString(route.Path, "{$1}")
+		if _, ok := spec.Paths[path][strings.ToLower(route.Method)]; !ok {
+			t.Errorf("route %s %s is missing from openapi/openapi.json (as %s)", route.Method, route.Path, path)
+		}
+	}
+}
+
+func TestOpenAPIServedWithoutAuth(t *testing.T) {
+	r := gin.New()
+	RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var spec openAPIDocument
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Errorf("Expected an OpenAPI 3 document, got version %q", spec.OpenAPI)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"ambient-code-public-api/types"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 // ListSessions handles GET /v1/sessions
@@ -179,9 +180,15 @@ func CreateSession(c *gin.Context) {
 	}
 
 	// Return simplified response
-	c.JSON(http.StatusCreated, gin.H{
-		"id":      backendResp["name"],
-		"message": "Session created",
+	// The backend answers 200 when an Idempotency-Key matched an existing session
+	status, message := http.StatusCreated, "Session created"
+	if resp.StatusCode == http.StatusOK {
+		status, message = http.StatusOK, "Session already created for this Idempotency-Key"
+	}
+	name, _ := backendResp["name"].(string)
+	c.JSON(status, types.CreateSessionResponse{
+		ID:      name,
+		Message: message,
 	})
 }
 
@@ -221,6 +228,144 @@ func DeleteSession(c *gin.Context) {
 	forwardErrorResponse(c, resp.StatusCode, body)
 }
 
+// StartSession handles POST /v1/sessions/:id/start
+func StartSession(c *gin.Context) {
+	sessionAction(c, "start")
+}
+
+// StopSession handles POST /v1/sessions/:id/stop
+func StopSession(c *gin.Context) {
+	sessionAction(c, "stop")
+}
+
+// sessionAction forwards a lifecycle action (start or stop) to the backend and
+// returns the updated session. The transition completes asynchronously.
+func sessionAction(c *gin.Context, action string) {
+	project := GetProject(c)
+	if !ValidateProjectName(project) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project name"})
+		return
+	}
+	sessionID := c.Param("id")
+	if !ValidateSessionID(sessionID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+	path := fmt.Sprintf("/api/projects/%s/agentic-sessions/%s/%s", project, sessionID, action)
+
+	resp, err := ProxyRequest(c, http.MethodPost, path, nil)
+	if err != nil {
+		log.Printf("Backend request failed for %s session %s: %v", action, sessionID, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Backend unavailable"})
+		return
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Printf("Failed to read backend response: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		forwardErrorResponse(c, resp.StatusCode, body)
+		return
+	}
+
+	var backendResp map[string]interface{}
+	if err := json.Unmarshal(body, &backendResp); err != nil {
+		log.Printf("Failed to parse backend response: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	session := transformSession(backendResp)
+	if session.ID == "" {
+		session.ID = sessionID
+	}
+	c.JSON(http.StatusAccepted, session)
+}
+
+// SendMessage handles POST /v1/sessions/:id/messages
+// Starts an agent run with the message; output is streamed on GET /v1/sessions/:id/events.
+func SendMessage(c *gin.Context) {
+	project := GetProject(c)
+	if !ValidateProjectName(project) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project name"})
+		return
+	}
+	sessionID := c.Param("id")
+	if !ValidateSessionID(sessionID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	var req types.SendMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Transform to an AG-UI run input on the session's thread
+	messageID := uuid.New().String()
+	backendReq := map[string]interface{}{
+		"threadId": sessionID,
+		"messages": []map[string]interface{}{
+			{
+				"id":      messageID,
+				"role":    "user",
+				"content": req.Content,
+			},
+		},
+	}
+
+	reqBody, err := json.Marshal(backendReq)
+	if err != nil {
+		log.Printf("Failed to marshal request: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	path := fmt.Sprintf("/api/projects/%s/agentic-sessions/%s/agui/run", project, sessionID)
+
+	resp, err := ProxyRequest(c, http.MethodPost, path, reqBody)
+	if err != nil {
+		log.Printf("Backend request failed for message to session %s: %v", sessionID, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Backend unavailable"})
+		return
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Printf("Failed to read backend response: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read response"})
+		return
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		forwardErrorResponse(c, resp.StatusCode, respBody)
+		return
+	}
+
+	var backendResp struct {
+		RunID    string `json:"runId"`
+		ThreadID string `json:"threadId"`
+	}
+	if err := json.Unmarshal(respBody, &backendResp); err != nil {
+		log.Printf("Failed to parse backend response: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse response"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, types.SendMessageResponse{
+		RunID:     backendResp.RunID,
+		ThreadID:  backendResp.ThreadID,
+		MessageID: messageID,
+	})
+}
+
 // forwardErrorResponse forwards backend error with consistent JSON format
 func forwardErrorResponse(c *gin.Context, statusCode int, body []byte) {
 	// Try to parse as JSON error response
@@ -293,10 +438,14 @@ func transformSession(data map[string]interface{}) types.SessionResponse {
 // normalizePhase converts K8s phase to simplified status
 func normalizePhase(phase string) string {
 	switch phase {
-	case "Pending", "Creating", "Initializing":
+	case "Pending", "Queued", "Creating", "Initializing":
 		return "pending"
 	case "Running", "Active":
 		return "running"
+	case "Stopping":
+		return "stopping"
+	case "Stopped":
+		return "stopped"
 	case "Completed", "Succeeded":
 		return "completed"
 	case "Failed", "Error":
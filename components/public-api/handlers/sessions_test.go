@@ -151,10 +151,13 @@ func TestNormalizePhase(t *testing.T) {
 		expected string
 	}{
 		{"Pending", "pending"},
+		{"Queued", "pending"},
 		{"Creating", "pending"},
 		{"Initializing", "pending"},
 		{"Running", "running"},
 		{"Active", "running"},
+		{"Stopping", "stopping"},
+		{"Stopped", "stopped"},
 		{"Completed", "completed"},
 		{"Succeeded", "completed"},
 		{"Failed", "failed"},
@@ -27,7 +27,6 @@ import (
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
@@ -72,31 +71,8 @@ func main() {
 	// Rate limiting middleware
 	r.Use(handlers.RateLimitMiddleware())
 
-	// Health endpoint (no auth required)
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok"})
-	})
-
-	// Readiness endpoint
-	r.GET("/ready", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ready"})
-	})
-
-	// Metrics endpoint (Prometheus format)
-	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
-
-	// v1 API routes
-	// IMPORTANT: AuthMiddleware must run BEFORE LoggingMiddleware
-	// to ensure we only log authenticated requests with valid project context
-	v1 := r.Group("/v1")
-	v1.Use(handlers.AuthMiddleware())
-	{
-		// Sessions
-		v1.GET("/sessions", handlers.ListSessions)
-		v1.POST("/sessions", handlers.CreateSession)
-		v1.GET("/sessions/:id", handlers.GetSession)
-		v1.DELETE("/sessions/:id", handlers.DeleteSession)
-	}
+	// Health, metrics, OpenAPI spec and v1 API routes
+	handlers.RegisterRoutes(r)
 
 	// Get port from environment or default to 8081
 	port := os.Getenv("PORT")
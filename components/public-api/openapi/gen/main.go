@@ -0,0 +1,22 @@
+// Command gen writes openapi.json from the hand-written operations in paths.json and
+// component schemas reflected from the Go types in schemas.go.
+//
+// Run it from the public-api module with: go generate ./openapi
+package main
+
+import (
+	"log"
+	"os"
+
+	"ambient-code-shared/openapigen"
+)
+
+func main() {
+	spec, err := openapigen.Generate(".", schemas)
+	if err != nil {
+		log.Fatalf("Failed to generate the OpenAPI spec: %v", err)
+	}
+	if err := os.WriteFile("openapi.json", spec, 0644); err != nil {
+		log.Fatalf("Failed to write openapi.json: %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"ambient-code-shared/openapigen"
+)
+
+// TestSpecIsUpToDate fails when openapi.json was edited by hand or a Go type in
+// schemas.go changed without running go generate ./openapi.
+func TestSpecIsUpToDate(t *testing.T) {
+	want, err := openapigen.Generate("..", schemas)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	got, err := os.ReadFile("../openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("openapi.json is out of date; run go generate ./openapi")
+	}
+}
@@ -0,0 +1,18 @@
+package main
+
+import "ambient-code-public-api/types"
+
+// schemas lists every component schema of the spec. Each one is reflected from its Go
+// type: json tags name the properties, fields without omitempty are required and doc
+// comments become descriptions.
+var schemas = map[string]interface{}{
+	"CreateSessionRequest":  types.CreateSessionRequest{},
+	"CreateSessionResponse": types.CreateSessionResponse{},
+	"ErrorResponse":         types.ErrorResponse{},
+	"Repo":                  types.Repo{},
+	"SendMessageRequest":    types.SendMessageRequest{},
+	"SendMessageResponse":   types.SendMessageResponse{},
+	"SessionEvent":          types.SessionEvent{},
+	"SessionListResponse":   types.SessionListResponse{},
+	"SessionResponse":       types.SessionResponse{},
+}
@@ -3,7 +3,7 @@ package types
 // SessionResponse is the simplified session response for the public API
 type SessionResponse struct {
 	ID          string `json:"id"`
-	Status      string `json:"status"` // "pending", "running", "completed", "failed"
+	Status      string `json:"status"` // "pending", "running", "stopping", "stopped", "completed", "failed"
 	Task        string `json:"task"`
 	Model       string `json:"model,omitempty"`
 	CreatedAt   string `json:"createdAt"`
@@ -25,6 +25,12 @@ type CreateSessionRequest struct {
 	Repos []Repo `json:"repos,omitempty"`
 }
 
+// CreateSessionResponse is the response for creating a session
+type CreateSessionResponse struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+}
+
 // Repo represents a repository configuration
 type Repo struct {
 	URL    string `json:"url" binding:"required"`
@@ -36,3 +42,83 @@ type ErrorResponse struct {
 	Error   string `json:"error"`
 	Message string `json:"message,omitempty"`
 }
+
+// SendMessageRequest is the request body for sending a user message to a session
+type SendMessageRequest struct {
+	Content string `json:"content" binding:"required"`
+}
+
+// SendMessageResponse identifies the run started by a message.
+// Progress is reported on GET /v1/sessions/:id/events.
+type SendMessageResponse struct {
+	RunID     string `json:"runId"`
+	ThreadID  string `json:"threadId"`
+	MessageID string `json:"messageId"`
+}
+
+// EventSchemaVersion is the version of the SessionEvent schema streamed by
+// GET /v1/sessions/:id/events. Adding fields or event types does not change
+// the version; renaming or removing them does.
+const EventSchemaVersion = "v1"
+
+// Public session event types
+const (
+	EventRunStarted       = "run.started"
+	EventRunFinished      = "run.finished"
+	EventRunError         = "run.error"
+	EventMessageStarted   = "message.started"
+	EventMessageDelta     = "message.delta"
+	EventMessageCompleted = "message.completed"
+	EventToolCallStarted  = "tool_call.started"
+	EventToolCallDelta    = "tool_call.delta"
+	EventToolCallComplete = "tool_call.completed"
+	EventToolCallResult   = "tool_call.result"
+	EventStepStarted      = "step.started"
+	EventStepFinished     = "step.finished"
+	EventStateSnapshot    = "state.snapshot"
+	EventStateDelta       = "state.delta"
+	EventMessagesSnapshot = "messages.snapshot"
+	EventActivitySnapshot = "activity.snapshot"
+	EventActivityDelta    = "activity.delta"
+	EventRaw              = "raw"
+	EventCustom           = "custom"
+	EventMeta             = "meta"
+	// EventOther carries an internal event type without a public equivalent yet.
+	// SourceType holds the internal type and Data the untranslated event.
+	EventOther = "other"
+)
+
+// SessionEvent is the stable event envelope of the public event stream.
+// Internal AG-UI events are translated into this schema; event types without
+// a public equivalent are relayed as EventOther.
+type SessionEvent struct {
+	Version    string                 `json:"version"`
+	Type       string                 `json:"type"`
+	SessionID  string                 `json:"sessionId"`
+	RunID      string                 `json:"runId,omitempty"`
+	MessageID  string                 `json:"messageId,omitempty"`
+	Role       string                 `json:"role,omitempty"`
+	Delta      string                 `json:"delta,omitempty"`
+	ToolCallID string                 `json:"toolCallId,omitempty"`
+	ToolName   string                 `json:"toolName,omitempty"`
+	Content    string                 `json:"content,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	Result     map[string]interface{} `json:"result,omitempty"`
+	// StepName is set on step.started and step.finished
+	StepName string `json:"stepName,omitempty"`
+	// Snapshot is the full state (state.snapshot) or activity content (activity.snapshot)
+	Snapshot interface{} `json:"snapshot,omitempty"`
+	// Patch is an RFC 6902 JSON Patch (state.delta, activity.delta)
+	Patch []interface{} `json:"patch,omitempty"`
+	// Messages is the conversation so far (messages.snapshot)
+	Messages     []interface{} `json:"messages,omitempty"`
+	ActivityType string        `json:"activityType,omitempty"`
+	// Name is the custom event name, meta event type or raw event source
+	Name string `json:"name,omitempty"`
+	// Value is the custom event value or meta event payload
+	Value interface{} `json:"value,omitempty"`
+	// Data is the relayed event for raw and other
+	Data       interface{} `json:"data,omitempty"`
+	SourceType string      `json:"sourceType,omitempty"`
+	Timestamp  int64       `json:"timestamp,omitempty"`
+}
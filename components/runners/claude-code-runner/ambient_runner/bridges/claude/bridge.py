@@ -316,11 +316,13 @@ class ClaudeBridge(PlatformBridge):
         log_auth_status(mcp_servers)
         allowed_tools = build_allowed_tools(mcp_servers)
 
-        # System prompt
+        # System prompt (with the conversation so far, for a fork)
         from ambient_runner.bridges.claude.prompts import build_sdk_system_prompt
+        from ambient_runner.platform.fork import fetch_fork_context
 
+        fork_context = await fetch_fork_context(self._context)
         system_prompt = build_sdk_system_prompt(
-            self._context.workspace_path, cwd_path
+            self._context.workspace_path, cwd_path, fork_context
         )
 
         # Store results
@@ -9,15 +9,21 @@ import logging
 import os
 
 from ambient_runner.platform.config import get_repos_config, load_ambient_config
-from ambient_runner.platform.prompts import build_workspace_context_prompt
+from ambient_runner.platform.prompts import (
+    build_fork_context_prompt,
+    build_workspace_context_prompt,
+)
 
 logger = logging.getLogger(__name__)
 
 
-def build_sdk_system_prompt(workspace_path: str, cwd_path: str) -> dict:
+def build_sdk_system_prompt(
+    workspace_path: str, cwd_path: str, fork_context: str = ""
+) -> dict:
     """Build the full system prompt config dict for the Claude SDK.
 
-    Wraps the platform workspace context prompt in the Claude Code preset.
+    Wraps the platform workspace context prompt, and the conversation a
+    forked session starts from, in the Claude Code preset.
     """
     repos_cfg = get_repos_config()
     active_workflow_url = (os.getenv("ACTIVE_WORKFLOW_GIT_URL") or "").strip()
@@ -36,6 +42,7 @@ def build_sdk_system_prompt(workspace_path: str, cwd_path: str) -> dict:
         ambient_config=ambient_config,
         workspace_path=workspace_path,
     )
+    workspace_prompt += build_fork_context_prompt(fork_context)
 
     return {
         "type": "preset",
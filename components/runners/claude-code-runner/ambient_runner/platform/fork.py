@@ -0,0 +1,63 @@
+"""
+Platform fork context — the conversation a forked session starts from.
+
+A fork's log holds a copy of its source's events up to the fork point, but
+its runner has no Claude state of its own.  The backend sets
+``FORK_CONTEXT_SEQ`` to the last copied event; the runner reads the copied
+conversation back as a Markdown transcript and gives it to the model.
+"""
+
+import asyncio
+import logging
+import os
+from urllib import request as _urllib_request
+
+from ambient_runner.platform.context import RunnerContext
+
+logger = logging.getLogger(__name__)
+
+# Longer transcripts keep their end, the conversation closest to the fork
+MAX_FORK_CONTEXT_CHARS = 200_000
+
+
+async def fetch_fork_context(context: RunnerContext) -> str:
+    """Fetch the transcript of the events copied into a fork's log.
+
+    Returns an empty string when the session is not a fork or the
+    transcript cannot be fetched.
+    """
+    seq = (context.get_env("FORK_CONTEXT_SEQ") or "").strip()
+    if not seq.isdigit() or int(seq) <= 0:
+        return ""
+
+    base = os.getenv("BACKEND_API_URL", "").rstrip("/")
+    project = os.getenv("PROJECT_NAME") or os.getenv("AGENTIC_SESSION_NAMESPACE", "")
+    project = project.strip()
+    session_id = context.session_id
+    if not base or not project or not session_id:
+        logger.warning("Cannot fetch fork context: BACKEND_API_URL or PROJECT_NAME not set")
+        return ""
+
+    url = (
+        f"{base}/projects/{project}/agentic-sessions/{session_id}/export"
+        f"?format=markdown&throughSeq={seq}"
+    )
+    req = _urllib_request.Request(url, method="GET")
+    bot = (os.getenv("BOT_TOKEN") or "").strip()
+    if bot:
+        req.add_header("Authorization", f"Bearer {bot}")
+
+    def _do_req():
+        try:
+            with _urllib_request.urlopen(req, timeout=30) as resp:
+                return resp.read().decode("utf-8", errors="replace")
+        except Exception as e:
+            logger.warning(f"Fork context fetch failed: {e}")
+            return ""
+
+    transcript = await asyncio.get_running_loop().run_in_executor(None, _do_req)
+    if len(transcript) > MAX_FORK_CONTEXT_CHARS:
+        transcript = transcript[-MAX_FORK_CONTEXT_CHARS:]
+    if transcript:
+        logger.info(f"Fetched fork context through event {seq} ({len(transcript)} chars)")
+    return transcript
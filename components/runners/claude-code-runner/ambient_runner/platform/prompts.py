@@ -42,6 +42,13 @@ GIT_PUSH_STEPS = (
     "3. Use `git push origin {branch}` to push to the remote repository\n\n"
 )
 
+FORK_CONTEXT_HEADER = "## Conversation Before This Session\n\n"
+
+FORK_CONTEXT_INTRO = (
+    "This session is a fork of an earlier session. Its conversation up to "
+    "the fork point is transcribed below; continue from where it ends.\n\n"
+)
+
 RUBRIC_EVALUATION_HEADER = "## Rubric Evaluation\n\n"
 
 RUBRIC_EVALUATION_INTRO = (
@@ -199,3 +206,13 @@ def _build_rubric_prompt_section(ambient_config: dict) -> str:
     section += RUBRIC_EVALUATION_PROCESS
 
     return section
+
+
+def build_fork_context_prompt(transcript: str) -> str:
+    """Build the section that gives a forked session its conversation so far.
+
+    Returns empty string if there is no transcript.
+    """
+    if not transcript.strip():
+        return ""
+    return FORK_CONTEXT_HEADER + FORK_CONTEXT_INTRO + transcript.strip() + "\n\n"
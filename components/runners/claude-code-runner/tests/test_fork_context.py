@@ -0,0 +1,81 @@
+"""Unit tests for the conversation a forked session starts from."""
+
+from unittest.mock import MagicMock, patch
+
+import pytest
+
+from ambient_runner.platform.context import RunnerContext
+from ambient_runner.platform.fork import MAX_FORK_CONTEXT_CHARS, fetch_fork_context
+from ambient_runner.platform.prompts import build_fork_context_prompt
+
+
+def _context(**env) -> RunnerContext:
+    return RunnerContext(session_id="s-fork", workspace_path="/workspace", environment=env)
+
+
+def _response(body: str) -> MagicMock:
+    resp = MagicMock()
+    resp.read.return_value = body.encode("utf-8")
+    resp.__enter__.return_value = resp
+    return resp
+
+
+class TestFetchForkContext:
+    """Tests for fork.fetch_fork_context."""
+
+    @pytest.mark.asyncio
+    async def test_not_a_fork(self):
+        with patch("ambient_runner.platform.fork._urllib_request.urlopen") as urlopen:
+            assert await fetch_fork_context(_context()) == ""
+            urlopen.assert_not_called()
+
+    @pytest.mark.asyncio
+    async def test_fetches_transcript_through_fork_point(self, monkeypatch):
+        monkeypatch.setenv("BACKEND_API_URL", "http://backend/api/")
+        monkeypatch.setenv("PROJECT_NAME", "proj")
+        monkeypatch.setenv("BOT_TOKEN", "tok")
+        with patch(
+            "ambient_runner.platform.fork._urllib_request.urlopen",
+            return_value=_response("# Transcript"),
+        ) as urlopen:
+            assert await fetch_fork_context(_context(FORK_CONTEXT_SEQ="42")) == "# Transcript"
+        req = urlopen.call_args[0][0]
+        assert req.full_url == (
+            "http://backend/api/projects/proj/agentic-sessions/s-fork/export"
+            "?format=markdown&throughSeq=42"
+        )
+        assert req.get_header("Authorization") == "Bearer tok"
+
+    @pytest.mark.asyncio
+    async def test_long_transcript_keeps_its_end(self, monkeypatch):
+        monkeypatch.setenv("BACKEND_API_URL", "http://backend/api")
+        monkeypatch.setenv("PROJECT_NAME", "proj")
+        body = "a" * MAX_FORK_CONTEXT_CHARS + "end"
+        with patch(
+            "ambient_runner.platform.fork._urllib_request.urlopen",
+            return_value=_response(body),
+        ):
+            got = await fetch_fork_context(_context(FORK_CONTEXT_SEQ="3"))
+        assert len(got) == MAX_FORK_CONTEXT_CHARS and got.endswith("end")
+
+    @pytest.mark.asyncio
+    async def test_fetch_failure_is_not_fatal(self, monkeypatch):
+        monkeypatch.setenv("BACKEND_API_URL", "http://backend/api")
+        monkeypatch.setenv("PROJECT_NAME", "proj")
+        with patch(
+            "ambient_runner.platform.fork._urllib_request.urlopen",
+            side_effect=OSError("refused"),
+        ):
+            assert await fetch_fork_context(_context(FORK_CONTEXT_SEQ="3")) == ""
+
+
+class TestBuildForkContextPrompt:
+    """Tests for prompts.build_fork_context_prompt."""
+
+    def test_empty_transcript(self):
+        assert build_fork_context_prompt("  \n") == ""
+
+    def test_section_holds_transcript(self):
+        prompt = build_fork_context_prompt("**User**: fix it\n")
+        assert prompt.startswith("## Conversation Before This Session")
+        assert "**User**: fix it" in prompt
@@ -139,6 +139,32 @@ if rclone --config /tmp/.config/rclone/rclone.conf lsf "${S3_PATH}/" 2>/dev/null
     echo "State hydration complete!"
 else
     echo "No existing state found, starting fresh session"
+
+    # Copy selected artifacts from the previous session of a spec.next chain
+    CARRY_ARTIFACTS_FROM="${CARRY_ARTIFACTS_FROM//[^a-zA-Z0-9-]/}"
+    if [ -n "${CARRY_ARTIFACTS_FROM}" ] && [ -n "${CARRY_ARTIFACTS_JSON}" ]; then
+        echo "Carrying artifacts over from ${CARRY_ARTIFACTS_FROM}..."
+        PARENT_ARTIFACTS="s3:${S3_BUCKET}/${NAMESPACE}/${CARRY_ARTIFACTS_FROM}/artifacts"
+        echo "${CARRY_ARTIFACTS_JSON}" | jq -r '.[]?' 2>/dev/null | while IFS= read -r artifact; do
+            # Reject absolute paths and traversal outside the artifacts directory
+            case "${artifact}" in
+                ""|/*|..|../*|*/..|*/../*)
+                    echo "  Skipping invalid artifact path: ${artifact}"
+                    continue
+                    ;;
+            esac
+            echo "  Copying artifacts/${artifact}..."
+            if rclone --config /tmp/.config/rclone/rclone.conf lsf "${PARENT_ARTIFACTS}/${artifact}/" 2>/dev/null | grep -q .; then
+                rclone --config /tmp/.config/rclone/rclone.conf copy "${PARENT_ARTIFACTS}/${artifact}/" "/workspace/artifacts/${artifact}/" \
+                    --copy-links \
+                    --transfers 8 \
+                    --fast-list 2>&1 || echo "  Warning: failed to copy ${artifact}"
+            else
+                rclone --config /tmp/.config/rclone/rclone.conf copyto "${PARENT_ARTIFACTS}/${artifact}" "/workspace/artifacts/${artifact}" \
+                    --copy-links 2>&1 || echo "  Warning: artifact ${artifact} not found"
+            fi
+        done
+    fi
 fi
 
 # Set ownership and permissions on subdirectories after S3 download
@@ -8,6 +8,7 @@ set -euo pipefail
 SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
 REPO_ROOT="$(cd "${SCRIPT_DIR}/../../.." && pwd)"
 BACKEND_DIR="${REPO_ROOT}/components/backend"
+SHARED_DIR="${REPO_ROOT}/components/shared"
 FRONTEND_DIR="${REPO_ROOT}/components/frontend"
 
 PROJECT_NAME="${PROJECT_NAME:-vteam-dev}"
@@ -48,7 +49,8 @@ sync_backend() {
   
   log "Syncing to backend pod: $pod_name"
   
-  # Initial full sync
+  # Initial full sync (go.mod replaces ambient-code-shared with ../shared, i.e. /shared)
+  oc rsync "$SHARED_DIR/" "$pod_name:/shared/" -n "$PROJECT_NAME"
   oc rsync "$BACKEND_DIR/" "$pod_name:/app/" \
     --exclude=tmp \
     --exclude=.git \
@@ -58,8 +60,9 @@ sync_backend() {
   
   # Watch for changes and sync
   log "Watching backend directory for changes..."
-  fswatch -o "$BACKEND_DIR" | while read -r _; do
+  fswatch -o "$BACKEND_DIR" "$SHARED_DIR" | while read -r _; do
     log "Detected backend changes, syncing..."
+    oc rsync "$SHARED_DIR/" "$pod_name:/shared/" -n "$PROJECT_NAME" || warn "Sync failed, will retry on next change"
     oc rsync "$BACKEND_DIR/" "$pod_name:/app/" \
       --exclude=tmp \
       --exclude=.git \
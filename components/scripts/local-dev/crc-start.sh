@@ -26,9 +26,8 @@ PROJECT_NAME="${PROJECT_NAME:-vteam-dev}"
 DEV_MODE="${DEV_MODE:-false}"
 
 # Component directories
-BACKEND_DIR="${REPO_ROOT}/components/backend"
+COMPONENTS_DIR="${REPO_ROOT}/components"
 FRONTEND_DIR="${REPO_ROOT}/components/frontend"
-OPERATOR_DIR="${REPO_ROOT}/components/operator"
 CRDS_DIR="${REPO_ROOT}/components/manifests/crds"
 
 ###############
@@ -292,15 +291,17 @@ build_and_deploy() {
   oc apply -f "${MANIFESTS_DIR}/build-configs.yaml" -n "$PROJECT_NAME"
   oc apply -f "${MANIFESTS_DIR}/operator-build-config.yaml" -n "$PROJECT_NAME"
   
-  # Start builds
+  # Start builds. The backend and operator build from components/ (their go.mod files
+  # replace ambient-code-shared with ../shared), so their BuildConfigs set
+  # dockerStrategy.dockerfilePath to backend/Dockerfile and operator/Dockerfile.
   log "Building backend image..."
-  oc start-build vteam-backend --from-dir="$BACKEND_DIR" --wait -n "$PROJECT_NAME"
+  oc start-build vteam-backend --from-dir="$COMPONENTS_DIR" --wait -n "$PROJECT_NAME"
   
   log "Building frontend image..."  
   oc start-build vteam-frontend --from-dir="$FRONTEND_DIR" --wait -n "$PROJECT_NAME"
   
   log "Building operator image..."
-  oc start-build vteam-operator --from-dir="$OPERATOR_DIR" --wait -n "$PROJECT_NAME"
+  oc start-build vteam-operator --from-dir="$COMPONENTS_DIR" --wait -n "$PROJECT_NAME"
   
   # Deploy services
   log "Creating backend PVC..."
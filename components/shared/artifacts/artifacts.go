@@ -0,0 +1,33 @@
+// Package artifacts validates the artifact paths a session hands to its spec.next
+// follow-up. The backend checks them when the session is created and the operator
+// again when it creates the follow-up, so both must apply the same rules.
+package artifacts
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// CleanPath returns p cleaned, or an error unless p names a file or directory inside
+// the artifacts directory.
+func CleanPath(p string) (string, error) {
+	cleaned := path.Clean(strings.TrimSpace(p))
+	if cleaned == "." || path.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("artifact path %q must be relative to the artifacts directory", p)
+	}
+	return cleaned, nil
+}
+
+// CleanPaths applies CleanPath to every entry, stopping at the first invalid one.
+func CleanPaths(paths []string) ([]string, error) {
+	cleaned := make([]string, 0, len(paths))
+	for _, p := range paths {
+		c, err := CleanPath(p)
+		if err != nil {
+			return nil, err
+		}
+		cleaned = append(cleaned, c)
+	}
+	return cleaned, nil
+}
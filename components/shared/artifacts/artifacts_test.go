@@ -0,0 +1,37 @@
+package artifacts
+
+import "testing"
+
+func TestCleanPath(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"plan.md", "plan.md", false},
+		{" notes/ ", "notes", false},
+		{"a/./b/../c.txt", "a/c.txt", false},
+		{"", "", true},
+		{".", "", true},
+		{"/etc/passwd", "", true},
+		{"..", "", true},
+		{"../secrets", "", true},
+		{"a/../../b", "", true},
+	}
+	for _, tt := range tests {
+		got, err := CleanPath(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("CleanPath(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestCleanPaths(t *testing.T) {
+	got, err := CleanPaths([]string{"plan.md", "notes/"})
+	if err != nil || len(got) != 2 || got[1] != "notes" {
+		t.Errorf("CleanPaths = %v, %v", got, err)
+	}
+	if _, err := CleanPaths([]string{"plan.md", "../x"}); err == nil {
+		t.Error("expected an error for a path outside the artifacts directory")
+	}
+}
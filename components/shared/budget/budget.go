@@ -0,0 +1,130 @@
+// Package budget evaluates a project's ProjectSettings spec.budget against the usage
+// recorded in its AgenticSessions' status.usage and in the usage ledger of its deleted
+// sessions (see RecordUsage). It works on the unstructured maps read through the dynamic
+// client so the backend (which rejects new sessions) and the operator (which holds
+// scheduled or restarted sessions and stops running ones) agree on the result.
+package budget
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	PeriodDaily   = "daily"
+	PeriodMonthly = "monthly"
+
+	dayFormat   = "2006-01-02"
+	monthFormat = "2006-01"
+)
+
+// Limits is ProjectSettings spec.budget. Zero limits are unset.
+type Limits struct {
+	DailyCostUSD   float64
+	MonthlyCostUSD float64
+	DailyTokens    int64
+	MonthlyTokens  int64
+	// HardCapPercent is the share of a limit at which running sessions are stopped
+	HardCapPercent int64
+}
+
+// ParseLimits reads spec.budget. A nil map means no limits.
+func ParseLimits(spec map[string]interface{}) Limits {
+	return Limits{
+		DailyCostUSD:   Number(spec["dailyCostUsd"]),
+		MonthlyCostUSD: Number(spec["monthlyCostUsd"]),
+		DailyTokens:    int64(Number(spec["dailyTokens"])),
+		MonthlyTokens:  int64(Number(spec["monthlyTokens"])),
+		HardCapPercent: int64(Number(spec["hardCapPercent"])),
+	}
+}
+
+// Enabled reports whether any limit is set.
+func (l Limits) Enabled() bool {
+	return l.DailyCostUSD > 0 || l.MonthlyCostUSD > 0 || l.DailyTokens > 0 || l.MonthlyTokens > 0
+}
+
+// Spend is a project's usage in one UTC day and the month containing it.
+// Tokens count input plus output tokens; cache traffic is reflected in cost only.
+type Spend struct {
+	DailyCostUSD   float64
+	MonthlyCostUSD float64
+	DailyTokens    int64
+	MonthlyTokens  int64
+}
+
+// Add adds one session's status.usage for the UTC day and month of now. Day buckets
+// count towards the month they fall in; a monthly bucket for the current month (days
+// rolled out of daily) counts towards the month only.
+func (s *Spend) Add(usage map[string]interface{}, now time.Time) {
+	now = now.UTC()
+	today := now.Format(dayFormat)
+	month := now.Format(monthFormat)
+
+	daily, _ := usage["daily"].(map[string]interface{})
+	for day, v := range daily {
+		if len(day) != len(dayFormat) || day[:len(monthFormat)] != month {
+			continue
+		}
+		cost, tokens := bucketSpend(v)
+		s.MonthlyCostUSD += cost
+		s.MonthlyTokens += tokens
+		if day == today {
+			s.DailyCostUSD += cost
+			s.DailyTokens += tokens
+		}
+	}
+	if monthly, ok := usage["monthly"].(map[string]interface{}); ok {
+		cost, tokens := bucketSpend(monthly[month])
+		s.MonthlyCostUSD += cost
+		s.MonthlyTokens += tokens
+	}
+}
+
+func bucketSpend(v interface{}) (float64, int64) {
+	bucket, ok := v.(map[string]interface{})
+	if !ok {
+		return 0, 0
+	}
+	return Number(bucket["costUsd"]), int64(Number(bucket["inputTokens"]) + Number(bucket["outputTokens"]))
+}
+
+// Exceeded describes the first limit that spend has reached.
+type Exceeded struct {
+	// Period is PeriodDaily or PeriodMonthly
+	Period  string
+	Message string
+}
+
+// Check returns the first limit that spend has reached once scaled by percent
+// (100 = the limit itself), or nil. Monthly limits are checked before daily ones
+// because waiting for the next day does not lift them.
+func Check(l Limits, s Spend, percent int64) *Exceeded {
+	factor := float64(percent) / 100
+	switch {
+	case l.MonthlyCostUSD > 0 && s.MonthlyCostUSD >= l.MonthlyCostUSD*factor:
+		return &Exceeded{Period: PeriodMonthly, Message: fmt.Sprintf("monthly cost budget exhausted ($%.2f of $%.2f)", s.MonthlyCostUSD, l.MonthlyCostUSD)}
+	case l.MonthlyTokens > 0 && float64(s.MonthlyTokens) >= float64(l.MonthlyTokens)*factor:
+		return &Exceeded{Period: PeriodMonthly, Message: fmt.Sprintf("monthly token budget exhausted (%d of %d tokens)", s.MonthlyTokens, l.MonthlyTokens)}
+	case l.DailyCostUSD > 0 && s.DailyCostUSD >= l.DailyCostUSD*factor:
+		return &Exceeded{Period: PeriodDaily, Message: fmt.Sprintf("daily cost budget exhausted ($%.2f of $%.2f)", s.DailyCostUSD, l.DailyCostUSD)}
+	case l.DailyTokens > 0 && float64(s.DailyTokens) >= float64(l.DailyTokens)*factor:
+		return &Exceeded{Period: PeriodDaily, Message: fmt.Sprintf("daily token budget exhausted (%d of %d tokens)", s.DailyTokens, l.DailyTokens)}
+	}
+	return nil
+}
+
+// Number converts an unstructured JSON number (int64 or float64) to float64.
+func Number(v interface{}) float64 {
+	switch n := v.(type) {
+	case int64:
+		return float64(n)
+	case int32:
+		return float64(n)
+	case int:
+		return float64(n)
+	case float64:
+		return n
+	}
+	return 0
+}
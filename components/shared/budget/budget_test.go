@@ -0,0 +1,123 @@
+package budget
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLimits(t *testing.T) {
+	got := ParseLimits(map[string]interface{}{
+		"dailyCostUsd":   int64(20),
+		"monthlyCostUsd": 150.5,
+		"monthlyTokens":  int64(1000000),
+		"hardCapPercent": int64(120),
+	})
+	want := Limits{DailyCostUSD: 20, MonthlyCostUSD: 150.5, MonthlyTokens: 1000000, HardCapPercent: 120}
+	if got != want {
+		t.Errorf("ParseLimits() = %+v, want %+v", got, want)
+	}
+	if ParseLimits(nil).Enabled() {
+		t.Error("a missing budget must not be enabled")
+	}
+}
+
+func TestSpendAdd(t *testing.T) {
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	var spend Spend
+	spend.Add(map[string]interface{}{
+		"daily": map[string]interface{}{
+			"2026-02-28": map[string]interface{}{"costUsd": 50.0, "inputTokens": int64(1000)},
+			"2026-03-01": map[string]interface{}{"costUsd": int64(4), "inputTokens": int64(100), "outputTokens": int64(50)},
+			"2026-03-15": map[string]interface{}{"costUsd": 1.5, "inputTokens": int64(10), "outputTokens": int64(5), "cacheReadInputTokens": int64(9000)},
+			"malformed":  "x",
+		},
+		"monthly": map[string]interface{}{
+			"2026-03": map[string]interface{}{"costUsd": 1.0, "inputTokens": int64(10)},
+			"2026-01": map[string]interface{}{"costUsd": 99.0},
+		},
+	}, now)
+	spend.Add(map[string]interface{}{
+		"daily": map[string]interface{}{"2026-03-15": map[string]interface{}{"costUsd": 0.5, "outputTokens": int64(20)}},
+	}, now)
+	spend.Add(nil, now)
+
+	want := Spend{DailyCostUSD: 2, MonthlyCostUSD: 7, DailyTokens: 35, MonthlyTokens: 195}
+	if spend != want {
+		t.Errorf("Spend = %+v, want %+v", spend, want)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	spend := Spend{DailyCostUSD: 12, MonthlyCostUSD: 90, DailyTokens: 500, MonthlyTokens: 4000}
+
+	tests := []struct {
+		name       string
+		limits     Limits
+		percent    int64
+		wantPeriod string
+		wantMsg    string
+	}{
+		{"no budget", Limits{}, 100, "", ""},
+		{"daily cost reached", Limits{DailyCostUSD: 10}, 100, PeriodDaily, "daily cost budget exhausted ($12.00 of $10.00)"},
+		{"daily cost below hard cap", Limits{DailyCostUSD: 10}, 150, "", ""},
+		{"monthly cost under", Limits{MonthlyCostUSD: 100}, 100, "", ""},
+		{"monthly tokens reached", Limits{MonthlyTokens: 4000}, 100, PeriodMonthly, "4000 of 4000 tokens"},
+		{"monthly reported before daily", Limits{DailyCostUSD: 10, MonthlyCostUSD: 80}, 100, PeriodMonthly, "monthly cost"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Check(tt.limits, spend, tt.percent)
+			if tt.wantPeriod == "" {
+				if got != nil {
+					t.Errorf("Check() = %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil || got.Period != tt.wantPeriod || !strings.Contains(got.Message, tt.wantMsg) {
+				t.Errorf("Check() = %+v, want %s containing %q", got, tt.wantPeriod, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestRecordUsage(t *testing.T) {
+	now := time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+	ledger := RecordUsage(nil, "alice", "claude-sonnet", map[string]interface{}{
+		"daily": map[string]interface{}{
+			"2026-06-15": map[string]interface{}{"costUsd": 1.5, "inputTokens": int64(10), "outputTokens": int64(5)},
+			// Past the retention: folded into March
+			"2026-03-01": map[string]interface{}{"costUsd": 2.0, "inputTokens": int64(100), "models": map[string]interface{}{
+				"claude-opus": map[string]interface{}{"costUsd": 2.0, "inputTokens": int64(100)},
+			}},
+		},
+		"monthly": map[string]interface{}{"2026-03": map[string]interface{}{"costUsd": 1.0, "runs": int64(1)}},
+	}, now)
+	ledger = RecordUsage(ledger, "alice", "claude-opus", map[string]interface{}{
+		"daily": map[string]interface{}{"2026-06-15": map[string]interface{}{"costUsd": 0.5, "outputTokens": int64(20)}},
+	}, now)
+
+	alice := ledger["alice"].(map[string]interface{})
+	today := alice["daily"].(map[string]interface{})["2026-06-15"].(map[string]interface{})
+	if today["costUsd"] != 2.0 || today["outputTokens"] != int64(25) {
+		t.Errorf("today = %v", today)
+	}
+	models := today["models"].(map[string]interface{})
+	if models["claude-sonnet"].(map[string]interface{})["costUsd"] != 1.5 || models["claude-opus"].(map[string]interface{})["costUsd"] != 0.5 {
+		t.Errorf("today's models = %v", models)
+	}
+	if _, ok := alice["daily"].(map[string]interface{})["2026-03-01"]; ok {
+		t.Error("a day past the retention was kept in daily")
+	}
+	march := alice["monthly"].(map[string]interface{})["2026-03"].(map[string]interface{})
+	if march["costUsd"] != 3.0 || march["inputTokens"] != int64(100) {
+		t.Errorf("march = %v", march)
+	}
+
+	var spend Spend
+	spend.AddLedger(ledger, now)
+	want := Spend{DailyCostUSD: 2, MonthlyCostUSD: 2, DailyTokens: 35, MonthlyTokens: 35}
+	if spend != want {
+		t.Errorf("Spend = %+v, want %+v", spend, want)
+	}
+}
@@ -0,0 +1,123 @@
+package budget
+
+import "time"
+
+// The usage ledger keeps the usage of deleted sessions, so that deleting a session (by
+// hand or through the session garbage collector) does not give its spend back. It is
+// ProjectSettings status.usageLedger: for each user ID, usage in the form of a session's
+// status.usage, with daily and monthly buckets only, every bucket split by model.
+const (
+	// UsageFinalizer holds a deleted AgenticSession until the operator has added its
+	// status.usage to the project's ledger.
+	UsageFinalizer = "ambient-code.io/usage-ledger"
+
+	// LedgerDailyDays is how many days the ledger keeps per day, as sessions do; older
+	// day buckets are folded into the bucket of their month.
+	LedgerDailyDays = 90
+
+	// UnknownKey stands for the user or model of usage that has none
+	UnknownKey = "unknown"
+)
+
+// usageCounters are the integer fields of a usage bucket; costUsd is the only float.
+var usageCounters = []string{"inputTokens", "outputTokens", "cacheReadInputTokens", "cacheCreationInputTokens", "runs"}
+
+// RecordUsage adds one session's status.usage to the ledger under user and returns the
+// ledger (a new one if ledger is nil). Buckets the session did not split by model are
+// credited to model, the session's spec.llmSettings.model. An empty user or model is
+// recorded as UnknownKey.
+func RecordUsage(ledger map[string]interface{}, user, model string, usage map[string]interface{}, now time.Time) map[string]interface{} {
+	if user == "" {
+		user = UnknownKey
+	}
+	if model == "" {
+		model = UnknownKey
+	}
+	if ledger == nil {
+		ledger = map[string]interface{}{}
+	}
+	entry, _ := ledger[user].(map[string]interface{})
+	if entry == nil {
+		entry = map[string]interface{}{}
+	}
+	for _, period := range []string{"daily", "monthly"} {
+		buckets, _ := usage[period].(map[string]interface{})
+		for key, v := range buckets {
+			bucket, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			addLedgerBucket(entry, period, key, bucket, model)
+		}
+	}
+
+	// Fold days past the retention into their month, as the backend does for sessions
+	cutoff := now.UTC().AddDate(0, 0, -(LedgerDailyDays - 1)).Format(dayFormat)
+	daily, _ := entry["daily"].(map[string]interface{})
+	for day, v := range daily {
+		if day >= cutoff || len(day) != len(dayFormat) {
+			continue
+		}
+		if bucket, ok := v.(map[string]interface{}); ok {
+			addLedgerBucket(entry, "monthly", day[:len(monthFormat)], bucket, model)
+		}
+		delete(daily, day)
+	}
+
+	ledger[user] = entry
+	return ledger
+}
+
+// addLedgerBucket adds bucket to entry[period][key], with its share per model.
+func addLedgerBucket(entry map[string]interface{}, period, key string, bucket map[string]interface{}, model string) {
+	buckets, _ := entry[period].(map[string]interface{})
+	if buckets == nil {
+		buckets = map[string]interface{}{}
+		entry[period] = buckets
+	}
+	dst, _ := buckets[key].(map[string]interface{})
+	if dst == nil {
+		dst = map[string]interface{}{}
+		buckets[key] = dst
+	}
+	addCounters(dst, bucket)
+
+	models, _ := dst["models"].(map[string]interface{})
+	if models == nil {
+		models = map[string]interface{}{}
+		dst["models"] = models
+	}
+	split, _ := bucket["models"].(map[string]interface{})
+	if len(split) == 0 {
+		split = map[string]interface{}{model: bucket}
+	}
+	for name, v := range split {
+		src, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		total, _ := models[name].(map[string]interface{})
+		if total == nil {
+			total = map[string]interface{}{}
+			models[name] = total
+		}
+		addCounters(total, src)
+	}
+}
+
+func addCounters(dst, src map[string]interface{}) {
+	for _, field := range usageCounters {
+		dst[field] = int64(Number(dst[field]) + Number(src[field]))
+	}
+	dst["costUsd"] = Number(dst["costUsd"]) + Number(src["costUsd"])
+}
+
+// AddLedger adds the usage of the project's deleted sessions, ProjectSettings
+// status.usageLedger, for the UTC day and month of now.
+func (s *Spend) AddLedger(ledger map[string]interface{}, now time.Time) {
+	for _, v := range ledger {
+		if usage, ok := v.(map[string]interface{}); ok {
+			s.Add(usage, now)
+		}
+	}
+}
@@ -0,0 +1,4 @@
+package objstore
+
+// Sign exposes the request signer to the external tests
+var Sign = sign
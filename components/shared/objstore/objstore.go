@@ -0,0 +1,326 @@
+// Package objstore is a minimal client for S3-compatible object stores (AWS S3, MinIO).
+// The backend keeps session event logs in a bucket and the operator deletes them when a
+// session is garbage collected. Requests use path-style URLs and are signed with AWS
+// Signature Version 4.
+package objstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned by Get for a missing key
+	ErrNotFound = errors.New("object not found")
+	// ErrExists is returned by Create when the key is already taken
+	ErrExists = errors.New("object already exists")
+)
+
+// Config locates a bucket. Region defaults to us-east-1, which MinIO accepts.
+type Config struct {
+	Endpoint  string
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+}
+
+// Client reads and writes the objects of one bucket. It is safe for concurrent use.
+type Client struct {
+	cfg        Config
+	endpoint   *url.URL
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+// New returns a client for cfg.Bucket at cfg.Endpoint
+func New(cfg Config) (*Client, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, errors.New("objstore: endpoint and bucket are required")
+	}
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("objstore: invalid endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	return &Client{
+		cfg:        cfg,
+		endpoint:   endpoint,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		now:        time.Now,
+	}, nil
+}
+
+// Put stores data under key, replacing any existing object
+func (c *Client) Put(ctx context.Context, key string, data []byte) error {
+	resp, err := c.do(ctx, http.MethodPut, key, nil, data, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Create stores data under key only if the key does not exist yet, and returns
+// ErrExists otherwise (a conditional If-None-Match: * write)
+func (c *Client) Create(ctx context.Context, key string, data []byte) error {
+	resp, err := c.do(ctx, http.MethodPut, key, nil, data, http.Header{"If-None-Match": {"*"}})
+	if err != nil {
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusPreconditionFailed || statusErr.StatusCode == http.StatusConflict) {
+			return ErrExists
+		}
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Get returns the object stored under key
+func (c *Client) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := c.do(ctx, http.MethodGet, key, nil, nil, nil)
+	if err != nil {
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// Delete removes key. Deleting a missing key is not an error.
+func (c *Client) Delete(ctx context.Context, key string) error {
+	resp, err := c.do(ctx, http.MethodDelete, key, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// List calls fn with every key under prefix that sorts after startAfter, in
+// lexicographic order, until fn returns false
+func (c *Client) List(ctx context.Context, prefix, startAfter string, fn func(key string) bool) error {
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		} else if startAfter != "" {
+			query.Set("start-after", startAfter)
+		}
+		resp, err := c.do(ctx, http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return err
+		}
+		var page struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("objstore: decode list response: %w", err)
+		}
+		for _, obj := range page.Contents {
+			if !fn(obj.Key) {
+				return nil
+			}
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// DeletePrefix removes every object under prefix and returns how many were deleted
+func (c *Client) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	var keys []string
+	if err := c.List(ctx, prefix, "", func(key string) bool {
+		keys = append(keys, key)
+		return true
+	}); err != nil {
+		return 0, err
+	}
+	for i, key := range keys {
+		if err := c.Delete(ctx, key); err != nil {
+			return i, err
+		}
+	}
+	return len(keys), nil
+}
+
+// StatusError is returned for unexpected HTTP responses
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("objstore: unexpected status %d: %s", e.StatusCode, e.Body)
+}
+
+func (c *Client) do(ctx context.Context, method, key string, query url.Values, body []byte, header http.Header) (*http.Response, error) {
+	u := *c.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + c.cfg.Bucket
+	if key != "" {
+		u.Path += "/" + key
+	}
+	u.RawPath = uriEncode(u.Path, false)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		req.Body = http.NoBody
+	}
+	req.ContentLength = int64(len(body))
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	sign(req, body, c.cfg, c.now().UTC())
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(data))}
+	}
+	return resp, nil
+}
+
+// ─── Signature Version 4 ─────────────────────────────────────────────
+
+const (
+	amzDateFormat = "20060102T150405Z"
+	service       = "s3"
+)
+
+// sign adds the x-amz-date, x-amz-content-sha256 and Authorization headers
+func sign(req *http.Request, body []byte, cfg Config, now time.Time) {
+	payloadHash := sha256Hex(body)
+	amzDate := now.Format(amzDateFormat)
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	// Host, every x-amz-* header and the headers S3 checks conditions on are signed
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		lower := strings.ToLower(k)
+		if strings.HasPrefix(lower, "x-amz-") || lower == "range" || lower == "if-none-match" || lower == "content-type" {
+			headers[lower] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalPath(req.URL.EscapedPath()),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := now.Format("20060102") + "/" + cfg.Region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+cfg.SecretKey), now.Format("20060102"))
+	key = hmacSHA256(key, cfg.Region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		cfg.AccessKey, scope, signedHeaders, signature))
+}
+
+// canonicalQuery encodes query parameters sorted by key, as both the request URL
+// and the signature require
+func canonicalQuery(query url.Values) string {
+	if len(query) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func canonicalPath(escapedPath string) string {
+	if escapedPath == "" {
+		return "/"
+	}
+	return escapedPath
+}
+
+// uriEncode percent-encodes everything but unreserved characters (and '/' in paths)
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch >= 'A' && ch <= 'Z', ch >= 'a' && ch <= 'z', ch >= '0' && ch <= '9',
+			ch == '-', ch == '_', ch == '.', ch == '~':
+			b.WriteByte(ch)
+		case ch == '/' && !encodeSlash:
+			b.WriteByte(ch)
+		default:
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,134 @@
+// Package objstoretest provides an in-memory S3-compatible server for tests of code
+// that uses objstore. It implements the subset of the S3 API the client uses.
+package objstoretest
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Server is an in-memory bucket store served over HTTP with path-style URLs
+type Server struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	objects map[string][]byte // "bucket/key" → data
+	// PageSize limits the keys per list response, to exercise pagination
+	PageSize int
+}
+
+// NewServer starts a fake S3 server. Close it when done.
+func NewServer() *Server {
+	s := &Server{objects: map[string][]byte{}, PageSize: 1000}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Keys returns the keys stored in bucket, sorted
+func (s *Server) Keys(bucket string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var keys []string
+	for k := range s.objects {
+		if b, key, _ := strings.Cut(k, "/"); b == bucket {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+		http.Error(w, "AccessDenied", http.StatusForbidden)
+		return
+	}
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket == "" {
+		http.Error(w, "InvalidBucketName", http.StatusBadRequest)
+		return
+	}
+	if key == "" {
+		if r.Method != http.MethodGet || r.URL.Query().Get("list-type") != "2" {
+			http.Error(w, "NotImplemented", http.StatusNotImplemented)
+			return
+		}
+		s.list(w, r, bucket)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name := bucket + "/" + key
+	switch r.Method {
+	case http.MethodPut:
+		if _, exists := s.objects[name]; exists && r.Header.Get("If-None-Match") == "*" {
+			http.Error(w, "PreconditionFailed", http.StatusPreconditionFailed)
+			return
+		}
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.objects[name] = data
+	case http.MethodGet:
+		data, ok := s.objects[name]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(data)
+	case http.MethodDelete:
+		delete(s.objects, name)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "NotImplemented", http.StatusNotImplemented)
+	}
+}
+
+type listResult struct {
+	XMLName  xml.Name `xml:"ListBucketResult"`
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken,omitempty"`
+}
+
+// list implements ListObjectsV2; the continuation token is the last key returned
+func (s *Server) list(w http.ResponseWriter, r *http.Request, bucket string) {
+	query := r.URL.Query()
+	prefix := query.Get("prefix")
+	after := query.Get("start-after")
+	if token := query.Get("continuation-token"); token != "" {
+		after = token
+	}
+	limit := s.PageSize
+	if maxKeys, err := strconv.Atoi(query.Get("max-keys")); err == nil && maxKeys < limit {
+		limit = maxKeys
+	}
+
+	var result listResult
+	for _, key := range s.Keys(bucket) {
+		if !strings.HasPrefix(key, prefix) || key <= after {
+			continue
+		}
+		if len(result.Contents) == limit {
+			result.IsTruncated = true
+			result.NextContinuationToken = result.Contents[len(result.Contents)-1].Key
+			break
+		}
+		result.Contents = append(result.Contents, struct {
+			Key string `xml:"Key"`
+		}{key})
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(result)
+}
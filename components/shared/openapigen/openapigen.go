@@ -0,0 +1,333 @@
+// Package openapigen writes an OpenAPI 3 spec from hand-written operations in a
+// paths.json file and component schemas reflected from Go types. The backend and the
+// public API each call Generate from a small command run by go generate ./openapi.
+package openapigen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// document mirrors paths.json; Components.Schemas is filled in by generate.
+type document struct {
+	OpenAPI    string          `json:"openapi"`
+	Info       json.RawMessage `json:"info"`
+	Paths      json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas         map[string]*schema `json:"schemas"`
+		SecuritySchemes json.RawMessage    `json:"securitySchemes"`
+	} `json:"components"`
+	Security json.RawMessage `json:"security"`
+}
+
+type schema struct {
+	Ref                  string      `json:"$ref,omitempty"`
+	Type                 string      `json:"type,omitempty"`
+	Format               string      `json:"format,omitempty"`
+	Description          string      `json:"description,omitempty"`
+	Items                *schema     `json:"items,omitempty"`
+	AdditionalProperties interface{} `json:"additionalProperties,omitempty"`
+	Properties           properties  `json:"properties,omitempty"`
+	Required             []string    `json:"required,omitempty"`
+}
+
+type property struct {
+	name   string
+	schema *schema
+}
+
+// properties keeps struct field order in the generated document
+type properties []property
+
+func (p properties) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, prop := range p {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(prop.name); err != nil {
+			return nil, err
+		}
+		buf.Truncate(buf.Len() - 1)
+		buf.WriteByte(':')
+		if err := enc.Encode(prop.schema); err != nil {
+			return nil, err
+		}
+		buf.Truncate(buf.Len() - 1)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	rawType  = reflect.TypeOf(json.RawMessage{})
+	refRe    = regexp.MustCompile(`"#/components/schemas/([^"]+)"`)
+)
+
+// Generate reads paths.json from dir and returns the complete, indented spec with a
+// component schema for each entry of schemas. Each schema is reflected from the Go type
+// of its value: json tags name the properties, fields without omitempty are required and
+// doc comments become descriptions.
+func Generate(dir string, schemas map[string]interface{}) ([]byte, error) {
+	base, err := os.ReadFile(filepath.Join(dir, "paths.json"))
+	if err != nil {
+		return nil, err
+	}
+	var doc document
+	if err := json.Unmarshal(base, &doc); err != nil {
+		return nil, fmt.Errorf("parse paths.json: %w", err)
+	}
+
+	r := newReflector()
+	doc.Components.Schemas = map[string]*schema{}
+	for name, v := range schemas {
+		t := reflect.TypeOf(v)
+		if other, ok := r.names[t]; ok {
+			return nil, fmt.Errorf("%s is registered as both %s and %s", t, other, name)
+		}
+		r.names[t] = name
+	}
+	for name, v := range schemas {
+		s, err := r.object(reflect.TypeOf(v))
+		if err != nil {
+			return nil, fmt.Errorf("schema %s: %w", name, err)
+		}
+		doc.Components.Schemas[name] = s
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+
+	for _, m := range refRe.FindAllSubmatch(buf.Bytes(), -1) {
+		if _, ok := schemas[string(m[1])]; !ok {
+			return nil, fmt.Errorf("paths.json references unknown schema %s", m[1])
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+type reflector struct {
+	names map[reflect.Type]string
+	docs  map[string]map[string]string // package path -> "Type" or "Type.Field" -> comment
+}
+
+func newReflector() *reflector {
+	return &reflector{names: map[reflect.Type]string{}, docs: map[string]map[string]string{}}
+}
+
+// object returns the schema of struct type t, inlining embedded structs like encoding/json
+func (r *reflector) object(t reflect.Type) (*schema, error) {
+	s := &schema{Type: "object", Description: r.doc(t, "")}
+	seen := map[string]bool{}
+	if err := r.addFields(s, t, seen); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (r *reflector) addFields(s *schema, t reflect.Type, seen map[string]bool) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if err := r.addFields(s, ft, seen); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if seen[name] {
+			return fmt.Errorf("%s: duplicate property %q", t, name)
+		}
+		seen[name] = true
+
+		prop, err := r.schemaOf(f.Type)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", t, f.Name, err)
+		}
+		if prop.Ref == "" {
+			prop.Description = r.doc(t, f.Name)
+		}
+		s.Properties = append(s.Properties, property{name: name, schema: prop})
+		if !strings.Contains(opts, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return nil
+}
+
+func (r *reflector) schemaOf(t reflect.Type) (*schema, error) {
+	if name, ok := r.names[t]; ok {
+		return &schema{Ref: "#/components/schemas/" + name}, nil
+	}
+	switch t {
+	case timeType:
+		return &schema{Type: "string", Format: "date-time"}, nil
+	case rawType:
+		return &schema{}, nil
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return r.schemaOf(t.Elem())
+	case reflect.String:
+		return &schema{Type: "string"}, nil
+	case reflect.Bool:
+		return &schema{Type: "boolean"}, nil
+	case reflect.Int64, reflect.Uint64:
+		return &schema{Type: "integer", Format: "int64"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &schema{Type: "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return &schema{Type: "number"}, nil
+	case reflect.Interface:
+		return &schema{}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &schema{Type: "string", Format: "byte"}, nil
+		}
+		items, err := r.schemaOf(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &schema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("map key %s is not a string", t.Key())
+		}
+		if t.Elem().Kind() == reflect.Interface {
+			return &schema{Type: "object", AdditionalProperties: true}, nil
+		}
+		values, err := r.schemaOf(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &schema{Type: "object", AdditionalProperties: values}, nil
+	case reflect.Struct:
+		if t.Name() != "" {
+			return nil, fmt.Errorf("%s is not registered in schemas.go", t)
+		}
+		return r.object(t)
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// doc returns the doc comment of named type t, or of its field when field is set
+func (r *reflector) doc(t reflect.Type, field string) string {
+	if t.Name() == "" {
+		return ""
+	}
+	docs, ok := r.docs[t.PkgPath()]
+	if !ok {
+		docs = parseDocs(t.PkgPath())
+		r.docs[t.PkgPath()] = docs
+	}
+	key := t.Name()
+	if field != "" {
+		key += "." + field
+	}
+	return docs[key]
+}
+
+// parseDocs collects type and struct field comments of a package, each joined into one line
+func parseDocs(pkgPath string) map[string]string {
+	docs := map[string]string{}
+	pkg, err := build.Import(pkgPath, ".", build.FindOnly)
+	if err != nil {
+		log.Printf("Skipping descriptions for %s: %v", pkgPath, err)
+		return docs
+	}
+	files, _ := filepath.Glob(filepath.Join(pkg.Dir, "*.go"))
+	sort.Strings(files)
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
+		if err != nil {
+			log.Printf("Skipping descriptions in %s: %v", file, err)
+			continue
+		}
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				doc := ts.Doc
+				if doc == nil && len(gen.Specs) == 1 {
+					doc = gen.Doc
+				}
+				docs[ts.Name.Name] = commentText(doc)
+				st, ok := ts.Type.(*ast.StructType)
+				if !ok {
+					continue
+				}
+				for _, field := range st.Fields.List {
+					for _, name := range field.Names {
+						docs[ts.Name.Name+"."+name.Name] = fieldDoc(field, name.Name)
+					}
+				}
+			}
+		}
+	}
+	return docs
+}
+
+// fieldDoc prefers the trailing comment of a field. A comment above it only counts when
+// it starts with the field name, so section headers like "// Optional fields" are skipped.
+func fieldDoc(field *ast.Field, name string) string {
+	if text := commentText(field.Comment); text != "" {
+		return text
+	}
+	if text := commentText(field.Doc); strings.HasPrefix(text, name+" ") {
+		return text
+	}
+	return ""
+}
+
+func commentText(g *ast.CommentGroup) string {
+	if g == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(g.Text()), " ")
+}
@@ -0,0 +1,52 @@
+package openapigen
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+type testBase struct {
+	Type      string `json:"type"`
+	Timestamp int64  `json:"timestamp,omitempty"`
+}
+
+type testEvent struct {
+	testBase
+	Labels  map[string]string      `json:"labels,omitempty"`
+	Payload map[string]interface{} `json:"payload"`
+	Nested  *testBase              `json:"nested,omitempty"`
+	Items   []testBase             `json:"items"`
+	Value   interface{}            `json:"value,omitempty"`
+	Ignored string                 `json:"-"`
+}
+
+func TestReflectorObject(t *testing.T) {
+	r := newReflector()
+	r.names[reflect.TypeOf(testBase{})] = "TestBase"
+
+	s, err := r.object(reflect.TypeOf(testEvent{}))
+	if err != nil {
+		t.Fatalf("object() error = %v", err)
+	}
+	got, _ := json.Marshal(s)
+	want := `{"type":"object","properties":{` +
+		`"type":{"type":"string"},` +
+		`"timestamp":{"type":"integer","format":"int64"},` +
+		`"labels":{"type":"object","additionalProperties":{"type":"string"}},` +
+		`"payload":{"type":"object","additionalProperties":true},` +
+		`"nested":{"$ref":"#/components/schemas/TestBase"},` +
+		`"items":{"type":"array","items":{"$ref":"#/components/schemas/TestBase"}},` +
+		`"value":{}},` +
+		`"required":["type","payload","items"]}`
+	if string(got) != want {
+		t.Errorf("object() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestReflectorRejectsUnregisteredStructs(t *testing.T) {
+	r := newReflector()
+	if _, err := r.object(reflect.TypeOf(testEvent{})); err == nil {
+		t.Error("expected an error for the unregistered testBase field")
+	}
+}
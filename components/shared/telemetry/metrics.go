@@ -0,0 +1,71 @@
+// Package telemetry sets up the OpenTelemetry meter provider shared by the backend and
+// the operator. It is a separate module so the parent ambient-code-shared module stays
+// free of third-party dependencies.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// InitMeter configures an OTLP/gRPC meter provider for serviceName, installs it as the
+// global provider and returns a meter named meterName with its shutdown function.
+// Set OTEL_EXPORTER_OTLP_ENDPOINT to configure the collector address. When it is unset
+// the returned meter is nil and the shutdown function is a no-op.
+func InitMeter(ctx context.Context, serviceName, meterName string) (metric.Meter, func(), error) {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	if endpoint == "" {
+		log.Println("OTEL_EXPORTER_OTLP_ENDPOINT not set, metrics export disabled")
+		return nil, func() {}, nil
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceName(serviceName),
+			semconv.ServiceVersion(os.Getenv("VERSION")),
+			attribute.String("deployment.environment", os.Getenv("DEPLOYMENT_ENV")),
+		),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create resource: %w", err)
+	}
+
+	exporter, err := otlpmetricgrpc.New(ctx,
+		otlpmetricgrpc.WithEndpoint(endpoint),
+		otlpmetricgrpc.WithInsecure(), // Use TLS in production
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	meterProvider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithResource(res),
+		sdkmetric.WithReader(
+			sdkmetric.NewPeriodicReader(exporter,
+				sdkmetric.WithInterval(30*time.Second),
+			),
+		),
+	)
+	otel.SetMeterProvider(meterProvider)
+
+	log.Println("OpenTelemetry metrics initialized, exporting to:", endpoint)
+
+	return meterProvider.Meter(meterName), func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := meterProvider.Shutdown(ctx); err != nil {
+			log.Printf("Error shutting down meter provider: %v", err)
+		}
+	}, nil
+}
@@ -0,0 +1,19 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+)
+
+func TestInitMeter_DisabledWithoutEndpoint(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+
+	meter, shutdown, err := InitMeter(context.Background(), "test-service", "test")
+	if err != nil {
+		t.Fatalf("InitMeter() error = %v", err)
+	}
+	if meter != nil {
+		t.Error("expected a nil meter when export is disabled")
+	}
+	shutdown()
+}
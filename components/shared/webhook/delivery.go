@@ -0,0 +1,25 @@
+// Package webhook holds the webhook types and checks shared by the operator, which
+// delivers session webhooks, and the backend, which serves the delivery log.
+package webhook
+
+const (
+	// DeliveryLogConfigMap holds the most recent webhook deliveries per project.
+	DeliveryLogConfigMap = "ambient-webhook-deliveries"
+	// DeliveryLogKey is the ConfigMap key holding the JSON array of Delivery entries, newest first.
+	DeliveryLogKey = "deliveries"
+)
+
+// Delivery is one entry in a project's webhook delivery log.
+type Delivery struct {
+	ID         string `json:"id"`
+	Webhook    string `json:"webhook"`
+	URL        string `json:"url"`
+	Session    string `json:"session"`
+	Phase      string `json:"phase"`
+	Attempts   int    `json:"attempts"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	Success    bool   `json:"success"`
+	Error      string `json:"error,omitempty"`
+	Timestamp  string `json:"timestamp"`
+	DurationMs int64  `json:"durationMs"`
+}
@@ -0,0 +1,102 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedAddress is returned when a webhook would connect to a loopback, private,
+// link-local or otherwise internal address.
+var ErrBlockedAddress = errors.New("webhook destination is an internal address")
+
+// blockedHostSuffixes are DNS names that only resolve inside the cluster or the cloud
+// provider network.
+var blockedHostSuffixes = []string{".localhost", ".svc", ".cluster.local", ".internal"}
+
+// carrierGradeNAT (RFC 6598) is not covered by net.IP.IsPrivate but is used for pod
+// networks by several providers.
+var carrierGradeNAT = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// ValidateURL rejects webhook URLs that are not absolute http(s) URLs or that name an
+// in-cluster service, another namespace or the metadata service. Hostnames that pass
+// are checked again at connect time by NewHTTPClient, after DNS resolution.
+func ValidateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return fmt.Errorf("webhook URL must use http or https, got %q", u.Scheme)
+	}
+	if u.User != nil {
+		return fmt.Errorf("webhook URL must not contain credentials")
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return fmt.Errorf("webhook URL has no host")
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if IsBlockedIP(ip) {
+			return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+		}
+		return nil
+	}
+	// Single-label names resolve through the pod's search path to services
+	if host == "localhost" || !strings.Contains(host, ".") {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+	}
+	for _, suffix := range blockedHostSuffixes {
+		if strings.HasSuffix(host, suffix) {
+			return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+		}
+	}
+	return nil
+}
+
+// IsBlockedIP reports whether ip is loopback, private, link-local (including the
+// 169.254.169.254 metadata service), carrier-grade NAT, multicast or unspecified.
+func IsBlockedIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || carrierGradeNAT.Contains(ip)
+}
+
+// NewHTTPClient returns a client for webhook delivery that refuses to connect to blocked
+// addresses, so a public name that resolves to an internal IP is rejected as well.
+// Redirects are validated with ValidateURL and proxies from the environment are ignored.
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || IsBlockedIP(ip) {
+				return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return fmt.Errorf("stopped after %d redirects", len(via))
+			}
+			return ValidateURL(req.URL.String())
+		},
+	}
+}